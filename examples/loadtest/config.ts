@@ -8,6 +8,7 @@ export interface LoadTestConfig {
   centrifugoUrl: string;
   centrifugoWsUrl: string;
   redisUrl: string;
+  adminSecret: string;        // 签名 POST /rooms 的 ADMIN_SECRET
 
   // 测试参数
   duration: number;           // 测试持续时间 (秒)
@@ -31,6 +32,7 @@ export const defaultConfig: LoadTestConfig = {
   centrifugoUrl: process.env.CENTRIFUGO_URL || 'http://localhost:8000',
   centrifugoWsUrl: process.env.CENTRIFUGO_WS_URL || 'ws://localhost:8000/connection/websocket',
   redisUrl: process.env.REDIS_URL || 'redis://localhost:6379',
+  adminSecret: process.env.ADMIN_SECRET || '',
 
   duration: parseInt(process.env.DURATION || '60', 10),
   rampUpTime: parseInt(process.env.RAMP_UP || '10', 10),
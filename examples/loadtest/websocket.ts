@@ -5,7 +5,7 @@
  */
 
 import WebSocket from 'ws';
-import { randomUUID } from 'crypto';
+import { createHmac, randomBytes, randomUUID } from 'crypto';
 import type { LoadTestConfig } from './config.js';
 import { MetricsCollector } from './metrics.js';
 
@@ -113,6 +113,36 @@ async function createConnection(
   });
 }
 
+/**
+ * 网关管理接口的签名头：hex(HMAC-SHA256(ADMIN_SECRET, timestamp + "\n" + nonce + "\n" + method + "\n" + path + "\n" + query + "\n" + body))
+ */
+function adminHeaders(secret: string, method: string, path: string, body: string): Record<string, string> {
+  const timestamp = String(Math.floor(Date.now() / 1000));
+  const nonce = randomBytes(16).toString('hex');
+  const [pathname, query = ''] = path.split('?', 2);
+  const signature = createHmac('sha256', secret)
+    .update(`${timestamp}\n${nonce}\n${method}\n${pathname}\n${query}\n${body}`)
+    .digest('hex');
+  return { 'X-Gateway-Timestamp': timestamp, 'X-Gateway-Nonce': nonce, 'X-Gateway-Signature': signature };
+}
+
+/**
+ * 创建压测使用的房间（chat:room-* 频道须先通过 POST /rooms 创建，已存在返回 409）
+ */
+async function createRooms(config: LoadTestConfig): Promise<void> {
+  for (let i = 0; i < config.numChannels; i++) {
+    const body = JSON.stringify({ id: String(i), name: `Load Test Room ${i}` });
+    const res = await fetch(`${config.callbackUrl}/rooms`, {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json', ...adminHeaders(config.adminSecret, 'POST', '/rooms', body) },
+      body,
+    });
+    if (!res.ok && res.status !== 409) {
+      throw new Error(`Failed to create room ${i}: HTTP ${res.status}`);
+    }
+  }
+}
+
 /**
  * 运行 WebSocket 连接压测
  */
@@ -125,6 +155,8 @@ export async function runWebSocketLoadTest(
   console.log(`   Connections/sec: ${config.connectionsPerSecond}`);
   console.log(`   Duration: ${config.duration}s`);
 
+  await createRooms(config);
+
   const intervalMs = 1000 / config.connectionsPerSecond;
   const endTime = Date.now() + config.duration * 1000;
 
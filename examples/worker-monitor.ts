@@ -11,7 +11,7 @@
 import Redis from 'ioredis';
 import { randomUUID } from 'crypto';
 import {
-  getWorkerStreamKey,
+  getWorkerStreamKeys,
   registerWorker,
   unregisterWorker,
 } from '../realtime-message-worker-sdk/src/index.js';
@@ -76,25 +76,27 @@ async function processMessage(message: Message): Promise<void> {
 }
 
 /**
- * Consume messages from this worker's dedicated stream
+ * Consume messages from this worker's dedicated streams
  */
 async function consumeStream(): Promise<void> {
-  const streamKey = getWorkerStreamKey(WORKER_ID);
-  let lastId = '$'; // Start from latest messages
+  const streamKeys = getWorkerStreamKeys(WORKER_ID); // High priority first
+  const lastIds = streamKeys.map(() => '$'); // Start from latest messages
 
-  console.log(`Started consuming stream: ${streamKey}`);
+  console.log(`Started consuming streams: ${streamKeys.join(', ')}`);
 
   while (!isShuttingDown) {
     try {
       const results = await redis.xread(
         'COUNT', CONSUME_BATCH_SIZE,
         'BLOCK', CONSUME_BLOCK_TIME,
-        'STREAMS', streamKey, lastId
+        'STREAMS', ...streamKeys, ...lastIds
       );
 
       if (!results) continue;
 
-      for (const [, messages] of results) {
+      // Streams are returned in request order, so high priority comes first
+      for (const [streamKey, messages] of results) {
+        const streamIndex = streamKeys.indexOf(streamKey);
         for (const [messageId, fields] of messages as [string, string[]][]) {
           try {
             const payloadIndex = fields.indexOf('payload');
@@ -102,11 +104,11 @@ async function consumeStream(): Promise<void> {
               const message: Message = JSON.parse(fields[payloadIndex + 1]);
               await processMessage(message);
             }
-            lastId = messageId;
+            lastIds[streamIndex] = messageId;
           } catch (err) {
             console.error(`Error processing message ${messageId}:`, err);
             totalErrors++;
-            lastId = messageId; // Advance to avoid getting stuck
+            lastIds[streamIndex] = messageId; // Advance to avoid getting stuck
           }
         }
       }
@@ -236,7 +238,7 @@ async function main(): Promise<void> {
   console.log('Monitored Sticky Channel Worker');
   console.log('='.repeat(60));
   console.log(`  Worker ID: ${WORKER_ID}`);
-  console.log(`  Stream: ${getWorkerStreamKey(WORKER_ID)}`);
+  console.log(`  Streams: ${getWorkerStreamKeys(WORKER_ID).join(', ')}`);
   console.log(`  Redis: ${REDIS_URL}`);
   console.log('='.repeat(60));
   console.log('Stats will be reported every 10 seconds\n');
@@ -9,7 +9,7 @@
 import Redis from 'ioredis';
 import { randomUUID } from 'crypto';
 import {
-  getWorkerStreamKey,
+  getWorkerStreamKeys,
   registerWorker,
   unregisterWorker,
 } from '../realtime-message-worker-sdk/src/index.js';
@@ -131,13 +131,13 @@ function printStats(): void {
 }
 
 /**
- * Consume messages from this worker's dedicated stream
+ * Consume messages from this worker's dedicated streams
  */
 async function consumeStream(): Promise<void> {
-  const streamKey = getWorkerStreamKey(WORKER_ID);
-  let lastId = '$';
+  const streamKeys = getWorkerStreamKeys(WORKER_ID); // High priority first
+  const lastIds = streamKeys.map(() => '$');
 
-  console.log(`Started consuming stream: ${streamKey}`);
+  console.log(`Started consuming streams: ${streamKeys.join(', ')}`);
 
   // Start stats reporting
   const statsInterval = setInterval(printStats, STATS_INTERVAL);
@@ -147,12 +147,14 @@ async function consumeStream(): Promise<void> {
       const results = await redis.xread(
         'COUNT', CONSUME_BATCH_SIZE,
         'BLOCK', CONSUME_BLOCK_TIME,
-        'STREAMS', streamKey, lastId
+        'STREAMS', ...streamKeys, ...lastIds
       );
 
       if (!results) continue;
 
-      for (const [, messages] of results) {
+      // Streams are returned in request order, so high priority comes first
+      for (const [streamKey, messages] of results) {
+        const streamIndex = streamKeys.indexOf(streamKey);
         for (const [messageId, fields] of messages as [string, string[]][]) {
           try {
             const payloadIndex = fields.indexOf('payload');
@@ -160,9 +162,9 @@ async function consumeStream(): Promise<void> {
               const message: Message = JSON.parse(fields[payloadIndex + 1]);
               await processMessage(message);
             }
-            lastId = messageId;
+            lastIds[streamIndex] = messageId;
           } catch (err) {
-            lastId = messageId;
+            lastIds[streamIndex] = messageId;
           }
         }
       }
@@ -212,7 +214,7 @@ async function main(): Promise<void> {
   console.log('Worker with Statistics');
   console.log('='.repeat(60));
   console.log(`  Worker ID:      ${WORKER_ID}`);
-  console.log(`  Stream:         ${getWorkerStreamKey(WORKER_ID)}`);
+  console.log(`  Streams:        ${getWorkerStreamKeys(WORKER_ID).join(', ')}`);
   console.log(`  Redis:          ${REDIS_URL}`);
   console.log(`  Stats Interval: ${STATS_INTERVAL}ms`);
   console.log('='.repeat(60));
@@ -3,37 +3,77 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/centrifugal/centrifuge"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 
+	"realtime-message-gateway/internal/admin"
+	"realtime-message-gateway/internal/adminauth"
 	"realtime-message-gateway/internal/config"
 	"realtime-message-gateway/internal/gateway"
+	"realtime-message-gateway/internal/metrics"
+	"realtime-message-gateway/internal/middleware"
 	"realtime-message-gateway/internal/redis"
+	"realtime-message-gateway/internal/requestid"
+	"realtime-message-gateway/internal/routing"
+	"realtime-message-gateway/internal/tracing"
 )
 
 func main() {
-	// Setup structured logging
-	slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+	// Setup structured logging; records logged with a request context carry its requestId
+	slog.SetDefault(slog.New(requestid.NewLogHandler(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
 		Level: slog.LevelInfo,
-	})))
+	}))))
 
 	slog.Info("Starting realtime-message-gateway")
 
 	// Load configuration
 	cfg := config.Load()
 
-	// Validate required config
-	if cfg.TokenHMACSecret == "" {
-		slog.Warn("CENTRIFUGO_TOKEN_HMAC_SECRET_KEY not set, authentication disabled")
+	// Validate config
+	hasErrors := false
+	for _, err := range cfg.Validate() {
+		if config.IsWarning(err) {
+			slog.Warn("config warning", "warning", err)
+			continue
+		}
+		slog.Error("invalid config", "error", err)
+		hasErrors = true
+	}
+	if hasErrors {
+		os.Exit(1)
+	}
+
+	// Setup tracing; spans are no-ops when disabled
+	if cfg.OTELEnabled {
+		shutdownTracing, err := tracing.Init(context.Background(), cfg.OTELEndpoint, cfg.OTELServiceName)
+		if err != nil {
+			slog.Error("failed to initialize tracing", "error", err)
+			os.Exit(1)
+		}
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := shutdownTracing(ctx); err != nil {
+				slog.Error("tracing shutdown error", "error", err)
+			}
+		}()
+		slog.Info("OpenTelemetry tracing enabled", "endpoint", cfg.OTELEndpoint, "serviceName", cfg.OTELServiceName)
 	}
 
 	// Connect to Redis
@@ -45,7 +85,7 @@ func main() {
 	defer redisClient.Close()
 
 	// Create gateway
-	gw, err := gateway.NewGateway(cfg, redisClient)
+	gw, err := gateway.NewGateway(gateway.WithConfig(cfg), gateway.WithRedis(redisClient))
 	if err != nil {
 		slog.Error("failed to create gateway", "error", err)
 		os.Exit(1)
@@ -60,63 +100,55 @@ func main() {
 	// Setup HTTP handlers
 	mux := http.NewServeMux()
 
-	// WebSocket endpoint
-	wsHandler := centrifuge.NewWebsocketHandler(gw.Node(), centrifuge.WebsocketConfig{
+	// WebSocket endpoint, compression is decided per request by cfg.CompressionPolicy
+	wsHandler := gw.WebsocketHandler(centrifuge.WebsocketConfig{
 		ReadBufferSize:     cfg.ReadBufferSize,
 		WriteBufferSize:    cfg.WriteBufferSize,
 		UseWriteBufferPool: true,
 		MessageSizeLimit:   cfg.MessageSizeLimit,
 		WriteTimeout:       cfg.WriteTimeout,
-		Compression:        true,
-		CompressionLevel:   4,
-		CompressionMinSize: 1024,
 		PingPongConfig: centrifuge.PingPongConfig{
 			PingInterval: cfg.PingInterval,
 			PongTimeout:  cfg.PongTimeout,
 		},
-		CheckOrigin: func(r *http.Request) bool {
-			// Allow all origins if not configured
-			if len(cfg.AllowedOrigins) == 0 {
-				return true
-			}
-			origin := r.Header.Get("Origin")
-			for _, allowed := range cfg.AllowedOrigins {
-				if origin == allowed {
-					return true
-				}
-			}
-			return false
-		},
+		CheckOrigin: gw.CheckOrigin,
 	})
-	mux.Handle("/connection/websocket", wsHandler)
+	mux.Handle("/connection/websocket", gw.WithClientIP(wsHandler))
+
+	// HTTP fallback transports
+	if cfg.SockJSEnabled {
+		mux.Handle(strings.TrimSuffix(cfg.SockJSURL, "/")+"/", gw.WithClientIP(gw.SockJSHandler()))
+		slog.Info("SockJS fallback transports enabled", "url", cfg.SockJSURL)
+	}
 
 	// Health check endpoint
 	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
-		defer cancel()
-
-		if err := redisClient.Ping(ctx); err != nil {
-			w.WriteHeader(http.StatusServiceUnavailable)
-			w.Write([]byte(`{"status":"unhealthy","error":"redis connection failed"}`))
-			return
-		}
-
-		w.Header().Set("Content-Type", "application/json")
-		w.Write([]byte(`{"status":"healthy"}`))
+		handleHealth(w, gw.HealthReport(r.Context()))
 	})
 
 	// Start WebSocket server
 	wsServer := &http.Server{
 		Addr:         fmt.Sprintf(":%d", cfg.WebSocketPort),
-		Handler:      mux,
+		Handler:      requestid.Middleware(mux),
 		ReadTimeout:  10 * time.Second,
 		WriteTimeout: 10 * time.Second,
 		IdleTimeout:  120 * time.Second,
 	}
 
+	if cfg.TLSCertFile != "" {
+		// Validate already rejected unknown versions and cipher suites
+		wsServer.TLSConfig, _ = cfg.WebSocketTLSConfig()
+	}
+
 	go func() {
-		slog.Info("WebSocket server starting", "port", cfg.WebSocketPort)
-		if err := wsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		slog.Info("WebSocket server starting", "port", cfg.WebSocketPort, "tls", cfg.TLSCertFile != "")
+		var err error
+		if cfg.TLSCertFile != "" {
+			err = wsServer.ListenAndServeTLS(cfg.TLSCertFile, cfg.TLSKeyFile)
+		} else {
+			err = wsServer.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
 			slog.Error("WebSocket server error", "error", err)
 			os.Exit(1)
 		}
@@ -125,30 +157,92 @@ func main() {
 	// Start HTTP server (for health checks and API endpoints)
 	httpMux := http.NewServeMux()
 	httpMux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
-		defer cancel()
+		handleHealth(w, gw.HealthReport(r.Context()))
+	})
 
-		if err := redisClient.Ping(ctx); err != nil {
-			w.WriteHeader(http.StatusServiceUnavailable)
-			w.Write([]byte(`{"status":"unhealthy","error":"redis connection failed"}`))
-			return
-		}
+	// Health report with the worker stream lengths, signed with
+	// ADMIN_SECRET: GET /admin/health
+	httpMux.Handle("/admin/health", adminauth.Middleware(cfg.AdminSecret)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handleHealth(w, gw.DetailedHealthReport(r.Context()))
+	})))
 
+	// Health event stream (SSE), signed with ADMIN_SECRET: GET /health/stream.
+	// All streams share one report per interval. Streams end when the HTTP
+	// server shuts down instead of holding up the shutdown timeout.
+	streamCtx, stopStreams := context.WithCancel(context.Background())
+	healthReports := &sharedHealthReport{gw: gw, interval: cfg.HealthStreamInterval}
+	healthStreamSlots := make(chan struct{}, cfg.HealthStreamMaxClients)
+	httpMux.Handle("/health/stream", adminauth.Middleware(cfg.AdminSecret)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handleHealthStream(streamCtx, w, r, healthReports, cfg.HealthStreamInterval, cfg.HealthWarnThreshold, healthStreamSlots)
+	})))
+
+	// Kubernetes probes:
+	//   livenessProbe:  httpGet /healthz/live on :3000. Always 200 while the
+	//                   process serves HTTP, so Redis outages never restart pods.
+	//   readinessProbe: httpGet /healthz/ready on :3000. 503 while Redis is
+	//                   unreachable (after READINESS_GRACE_PERIOD) or the node
+	//                   is stopped, taking the pod out of Service endpoints.
+	httpMux.HandleFunc("/healthz/live", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
-		w.Write([]byte(`{"status":"healthy"}`))
+		w.Write([]byte(`{"status":"alive"}`))
 	})
+	httpMux.HandleFunc("/healthz/ready", func(w http.ResponseWriter, r *http.Request) {
+		handleReady(w, r, gw)
+	})
+
+	registerChannelRoutes(httpMux, gw, cfg.AdminSecret, cfg.ChannelMetadataMaxSize)
+	registerRoomRoutes(httpMux, gw, cfg.AdminSecret)
 
-	// Channel presence API endpoint
-	httpMux.HandleFunc("/channels/", func(w http.ResponseWriter, r *http.Request) {
+	// Dead-letter list endpoint, signed with ADMIN_SECRET: GET /admin/deadletter?limit=N
+	httpMux.Handle("/admin/deadletter", adminauth.Middleware(cfg.AdminSecret)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
 			w.WriteHeader(http.StatusMethodNotAllowed)
 			return
 		}
 
-		// Parse channel name from path: /channels/{channel}/presence
+		limit := int64(100)
+		if v := r.URL.Query().Get("limit"); v != "" {
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil || n <= 0 {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"error":"invalid limit"}`))
+				return
+			}
+			limit = n
+		}
+
+		letters, err := gw.DeadLetters(r.Context(), limit)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "failed to read dead-letter stream", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"error":"failed to read dead-letter stream"}`))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		response := struct {
+			Messages []gateway.DeadLetter `json:"messages"`
+			Count    int                  `json:"count"`
+		}{
+			Messages: letters,
+			Count:    len(letters),
+		}
+
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			slog.ErrorContext(r.Context(), "failed to encode dead-letter response", "error", err)
+		}
+	})))
+
+	// Dead-letter retry endpoint, signed with ADMIN_SECRET: POST /admin/deadletter/{msgId}/retry
+	httpMux.Handle("/admin/deadletter/", adminauth.Middleware(cfg.AdminSecret)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
 		path := r.URL.Path
-		const prefix = "/channels/"
-		const suffix = "/presence"
+		const prefix = "/admin/deadletter/"
+		const suffix = "/retry"
 
 		if !strings.HasPrefix(path, prefix) || !strings.HasSuffix(path, suffix) {
 			w.WriteHeader(http.StatusNotFound)
@@ -156,48 +250,255 @@ func main() {
 			return
 		}
 
-		channel := path[len(prefix) : len(path)-len(suffix)]
-		if channel == "" {
+		msgID := path[len(prefix) : len(path)-len(suffix)]
+		if msgID == "" {
 			w.WriteHeader(http.StatusBadRequest)
-			w.Write([]byte(`{"error":"channel name required"}`))
+			w.Write([]byte(`{"error":"message id required"}`))
+			return
+		}
+
+		if err := gw.RetryDeadLetter(r.Context(), msgID); err != nil {
+			if errors.Is(err, gateway.ErrDeadLetterNotFound) {
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte(`{"error":"dead-letter entry not found"}`))
+				return
+			}
+			slog.ErrorContext(r.Context(), "failed to retry dead-letter entry", "id", msgID, "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"error":"failed to retry dead-letter entry"}`))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"status":"requeued"}`))
+	})))
+
+	// User endpoints, signed with ADMIN_SECRET:
+	//   POST /admin/users/{userId}/disconnect
+	//   GET  /admin/users/{userId}/recent-messages?limit=N
+	httpMux.Handle("/admin/users/", adminauth.Middleware(cfg.AdminSecret)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := r.URL.Path
+		const prefix = "/admin/users/"
+		const disconnectSuffix = "/disconnect"
+		const recentMessagesSuffix = "/recent-messages"
+
+		var suffix string
+		switch {
+		case strings.HasSuffix(path, disconnectSuffix):
+			suffix = disconnectSuffix
+		case strings.HasSuffix(path, recentMessagesSuffix):
+			suffix = recentMessagesSuffix
+		}
+		if suffix == "" || len(path) <= len(prefix)+len(suffix) {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":"not found"}`))
+			return
+		}
+
+		userID := path[len(prefix) : len(path)-len(suffix)]
+		if suffix == recentMessagesSuffix {
+			handleUserRecentMessages(w, r, gw, userID)
+			return
+		}
+
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if err := gw.DisconnectUser(userID); err != nil {
+			slog.ErrorContext(r.Context(), "failed to disconnect user", "userId", userID, "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"error":"failed to disconnect user"}`))
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	})))
+
+	// Worker endpoints, signed with ADMIN_SECRET:
+	//   POST /admin/workers/{workerId}/stream/trim?max_len=N[&exact=true]
+	//   POST /admin/workers/{workerId}/stream/replay?start={streamId}&end={streamId}
+	//   POST /admin/workers/{workerId}/stream/rescue
+	//   POST /admin/workers/{workerId}/migrate
+	httpMux.Handle("/admin/workers/", adminauth.Middleware(cfg.AdminSecret)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		path := r.URL.Path
+		const prefix = "/admin/workers/"
+		const trimSuffix = "/stream/trim"
+		const replaySuffix = "/stream/replay"
+		const rescueSuffix = "/stream/rescue"
+		const migrateSuffix = "/migrate"
+
+		var suffix string
+		switch {
+		case strings.HasSuffix(path, trimSuffix):
+			suffix = trimSuffix
+		case strings.HasSuffix(path, replaySuffix):
+			suffix = replaySuffix
+		case strings.HasSuffix(path, rescueSuffix):
+			suffix = rescueSuffix
+		case strings.HasSuffix(path, migrateSuffix):
+			suffix = migrateSuffix
+		}
+		if suffix == "" || len(path) <= len(prefix)+len(suffix) {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":"not found"}`))
+			return
+		}
+
+		workerID := path[len(prefix) : len(path)-len(suffix)]
+		switch suffix {
+		case replaySuffix:
+			handleReplayWorkerStream(w, r, gw, workerID)
+			return
+		case rescueSuffix:
+			handleRescueWorkerStream(w, r, gw, workerID)
+			return
+		case migrateSuffix:
+			handleMigrateWorker(w, r, gw, workerID)
+			return
+		}
+
+		maxLen, err := strconv.ParseInt(r.URL.Query().Get("max_len"), 10, 64)
+		if err != nil || maxLen < 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"invalid max_len"}`))
+			return
+		}
+		exact := r.URL.Query().Get("exact") == "true"
+
+		trimmed, err := gw.TrimWorkerStreams(r.Context(), workerID, maxLen, !exact)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "failed to trim worker streams", "workerId", workerID, "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"error":"failed to trim worker streams"}`))
+			return
+		}
+		slog.InfoContext(r.Context(), "worker streams trimmed", "workerId", workerID, "maxLen", maxLen, "exact", exact, "trimmed", trimmed)
+
+		w.Header().Set("Content-Type", "application/json")
+		response := struct {
+			WorkerID string `json:"workerId"`
+			MaxLen   int64  `json:"maxLen"`
+			Trimmed  int64  `json:"trimmed"`
+		}{
+			WorkerID: workerID,
+			MaxLen:   maxLen,
+			Trimmed:  trimmed,
+		}
+
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			slog.ErrorContext(r.Context(), "failed to encode trim response", "error", err)
+		}
+	})))
+
+	// Worker load endpoint: GET /workers/load
+	httpMux.HandleFunc("/workers/load", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
 			return
 		}
 
-		// Get presence info
-		users, err := gw.GetChannelPresence(channel)
+		loads, err := gw.WorkerLoad(r.Context())
 		if err != nil {
-			slog.Error("failed to get channel presence", "channel", channel, "error", err)
+			slog.ErrorContext(r.Context(), "failed to read worker load", "error", err)
 			w.WriteHeader(http.StatusInternalServerError)
-			w.Write([]byte(`{"error":"failed to get presence"}`))
+			w.Write([]byte(`{"error":"failed to read worker load"}`))
 			return
 		}
 
-		// Return response
 		w.Header().Set("Content-Type", "application/json")
 		response := struct {
-			Channel string                `json:"channel"`
-			Users   []gateway.PresenceInfo `json:"users"`
-			Count   int                   `json:"count"`
+			Workers []gateway.WorkerLoad `json:"workers"`
+			Count   int                  `json:"count"`
 		}{
-			Channel: channel,
-			Users:   users,
-			Count:   len(users),
+			Workers: loads,
+			Count:   len(loads),
 		}
 
 		if err := json.NewEncoder(w).Encode(response); err != nil {
-			slog.Error("failed to encode presence response", "error", err)
+			slog.ErrorContext(r.Context(), "failed to encode worker load response", "error", err)
 		}
 	})
 
+	// Channel admin endpoints, signed with ADMIN_SECRET:
+	//   DELETE /admin/channels/{channel}/route    the next publish or subscribe
+	//                                             assigns the channel a worker again
+	//   POST   /admin/channels/{channel}/publish  broadcast a server message to
+	//                                             the channel's subscribers
+	//   POST   /admin/channels/{channel}/recover  route the channel back to the
+	//                                             worker of its latest history message
+	httpMux.Handle("/admin/channels/", adminauth.Middleware(cfg.AdminSecret)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := r.URL.Path
+		const prefix = "/admin/channels/"
+		const routeSuffix = "/route"
+		const publishSuffix = "/publish"
+		const recoverSuffix = "/recover"
+
+		var suffix, method string
+		switch {
+		case strings.HasSuffix(path, routeSuffix):
+			suffix, method = routeSuffix, http.MethodDelete
+		case strings.HasSuffix(path, publishSuffix):
+			suffix, method = publishSuffix, http.MethodPost
+		case strings.HasSuffix(path, recoverSuffix):
+			suffix, method = recoverSuffix, http.MethodPost
+		}
+		if suffix == "" || len(path) <= len(prefix)+len(suffix) {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":"not found"}`))
+			return
+		}
+		if r.Method != method {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		channel := path[len(prefix) : len(path)-len(suffix)]
+		switch suffix {
+		case publishSuffix:
+			handlePublishServerMessage(w, r, gw, channel)
+			return
+		case recoverSuffix:
+			handleRecoverChannelRoute(w, r, gw, channel)
+			return
+		}
+
+		deleted, err := gw.DeleteChannelRoute(r.Context(), channel)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "failed to delete channel route", "channel", channel, "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"error":"failed to delete channel route"}`))
+			return
+		}
+		if !deleted {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":"channel has no route"}`))
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	})))
+
+	httpHandler := requestid.Middleware(middleware.CORSMiddleware(cfg.HTTPAllowedOrigins, cfg.HTTPAllowedMethods)(middleware.MaxBodySize(cfg.HTTPMaxBodySize)(httpMux)))
+	if cfg.H2Enabled {
+		httpHandler = middleware.H2C(httpHandler)
+	}
+
 	httpServer := &http.Server{
 		Addr:         fmt.Sprintf(":%d", cfg.HTTPPort),
-		Handler:      httpMux,
+		Handler:      httpHandler,
 		ReadTimeout:  10 * time.Second,
 		WriteTimeout: 10 * time.Second,
 	}
+	httpServer.RegisterOnShutdown(stopStreams)
 
 	go func() {
-		slog.Info("HTTP server starting", "port", cfg.HTTPPort)
+		slog.Info("HTTP server starting", "port", cfg.HTTPPort, "h2c", cfg.H2Enabled)
 		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			slog.Error("HTTP server error", "error", err)
 		}
@@ -206,29 +507,72 @@ func main() {
 	// Start metrics server
 	metricsMux := http.NewServeMux()
 	metricsMux.Handle("/metrics", promhttp.Handler())
+
+	// Live metrics snapshots as JSON lines: GET /metrics/stream with
+	// Accept: application/x-ndjson. Streams end when the metrics server
+	// shuts down.
+	metricsStreamCtx, stopMetricsStreams := context.WithCancel(context.Background())
+	metricsStreamSlots := make(chan struct{}, cfg.MetricsStreamMaxClients)
+	metricsMux.HandleFunc("/metrics/stream", func(w http.ResponseWriter, r *http.Request) {
+		handleMetricsStream(metricsStreamCtx, w, r, cfg.MetricsStreamInterval, metricsStreamSlots)
+	})
 	metricsMux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte("OK"))
 	})
 
+	var metricsHandler http.Handler = metricsMux
+	if cfg.H2Enabled {
+		metricsHandler = middleware.H2C(metricsHandler)
+	}
+
 	metricsServer := &http.Server{
 		Addr:    fmt.Sprintf(":%d", cfg.MetricsPort),
-		Handler: metricsMux,
+		Handler: metricsHandler,
 	}
+	metricsServer.RegisterOnShutdown(stopMetricsStreams)
 
 	go func() {
-		slog.Info("Metrics server starting", "port", cfg.MetricsPort)
+		slog.Info("Metrics server starting", "port", cfg.MetricsPort, "h2c", cfg.H2Enabled)
 		if err := metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			slog.Error("Metrics server error", "error", err)
 		}
 	}()
 
+	// Start gRPC admin server
+	grpcServer := admin.NewGRPCServer(gw, cfg.AdminSecret)
+	grpcListener, err := net.Listen("tcp", fmt.Sprintf(":%d", cfg.GRPCPort))
+	if err != nil {
+		slog.Error("failed to listen for gRPC", "port", cfg.GRPCPort, "error", err)
+		os.Exit(1)
+	}
+
+	go func() {
+		slog.Info("gRPC admin server starting", "port", cfg.GRPCPort)
+		if err := grpcServer.Serve(grpcListener); err != nil {
+			slog.Error("gRPC admin server error", "error", err)
+		}
+	}()
+
 	// Print startup info
 	slog.Info("realtime-message-gateway started",
+		"instance_id", gw.InstanceID(),
 		"websocket_port", cfg.WebSocketPort,
 		"http_port", cfg.HTTPPort,
 		"metrics_port", cfg.MetricsPort,
+		"grpc_port", cfg.GRPCPort,
 	)
 
+	// Reload the content filter blocklist on SIGHUP
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			if err := gw.ReloadContentFilter(); err != nil {
+				slog.Error("content filter reload failed", "error", err)
+			}
+		}
+	}()
+
 	// Wait for shutdown signal
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -236,25 +580,1010 @@ func main() {
 
 	slog.Info("Shutting down...")
 
-	// Graceful shutdown
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	// Shutdown servers concurrently so a slow one cannot starve the others
+	shutdownServers([]shutdownTarget{
+		{name: "ws", server: wsServer, timeout: cfg.ShutdownTimeoutWS},
+		{name: "http", server: httpServer, timeout: cfg.ShutdownTimeoutHTTP},
+		{name: "metrics", server: metricsServer, timeout: cfg.ShutdownTimeoutMetrics},
+	})
+	grpcServer.GracefulStop()
+
+	// Shutdown gateway
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeoutWS)
 	defer cancel()
+	if err := gw.Shutdown(ctx); err != nil {
+		slog.Error("Gateway shutdown error", "error", err)
+	}
+
+	slog.Info("Shutdown complete")
+}
 
-	// Shutdown servers
-	if err := wsServer.Shutdown(ctx); err != nil {
-		slog.Error("WebSocket server shutdown error", "error", err)
+// shutdowner is a server that can be shut down gracefully, like http.Server
+type shutdowner interface {
+	Shutdown(ctx context.Context) error
+}
+
+// shutdownTarget is a server stopped gracefully within its own timeout
+type shutdownTarget struct {
+	name    string
+	server  shutdowner
+	timeout time.Duration
+}
+
+// shutdownServers shuts down all targets concurrently and waits for them.
+// A target that runs out of time is counted in gateway_shutdown_timeout_total.
+func shutdownServers(targets []shutdownTarget) {
+	var wg sync.WaitGroup
+	for _, t := range targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), t.timeout)
+			defer cancel()
+			if err := t.server.Shutdown(ctx); err != nil {
+				if errors.Is(err, context.DeadlineExceeded) {
+					metrics.Default.ShutdownTimeoutTotal.WithLabelValues(t.name).Inc()
+				}
+				slog.Error("server shutdown error", "server", t.name, "timeout", t.timeout, "error", err)
+			}
+		}()
 	}
-	if err := httpServer.Shutdown(ctx); err != nil {
-		slog.Error("HTTP server shutdown error", "error", err)
+	wg.Wait()
+}
+
+// handleHealth writes a health report. A gateway that lost Redis but
+// queues publishes locally is degraded, not down, and still answers 200.
+func handleHealth(w http.ResponseWriter, report gateway.HealthStatus) {
+	w.Header().Set("Content-Type", "application/json")
+	if !report.Healthy && !report.Degraded {
+		w.WriteHeader(http.StatusServiceUnavailable)
 	}
-	if err := metricsServer.Shutdown(ctx); err != nil {
-		slog.Error("Metrics server shutdown error", "error", err)
+	json.NewEncoder(w).Encode(report)
+}
+
+// healthStreamRetry is how long /health/stream clients wait before
+// reconnecting
+const healthStreamRetry = 5 * time.Second
+
+// sharedHealthReport computes the detailed health report at most once per
+// interval for all /health/stream clients, so the Redis round trips of
+// the reports do not grow with the number of clients
+type sharedHealthReport struct {
+	gw       *gateway.Gateway
+	interval time.Duration
+
+	mu     sync.Mutex
+	report gateway.HealthStatus
+	at     time.Time
+}
+
+// get returns the latest report, computing a new one with ctx when it is
+// older than the interval. Concurrent callers wait for the same computation.
+func (s *sharedHealthReport) get(ctx context.Context) gateway.HealthStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.at.IsZero() || time.Since(s.at) >= s.interval {
+		s.report = s.gw.DetailedHealthReport(ctx)
+		s.at = time.Now()
 	}
+	return s.report
+}
 
-	// Shutdown gateway
-	if err := gw.Shutdown(ctx); err != nil {
-		slog.Error("Gateway shutdown error", "error", err)
+// handleHealthStream sends the shared health report as a health event
+// every interval until the client disconnects or ctx is done, followed by
+// a warning event when the Redis ping took longer than warnThreshold.
+// Event IDs count up from the Last-Event-ID of a reconnecting client. A
+// stream holds one of slots, so when all are taken further clients get 503.
+func handleHealthStream(ctx context.Context, w http.ResponseWriter, r *http.Request, reports *sharedHealthReport, interval, warnThreshold time.Duration, slots chan struct{}) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
 	}
 
-	slog.Info("Shutdown complete")
+	select {
+	case slots <- struct{}{}:
+		defer func() { <-slots }()
+	default:
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(`{"error":"too many health stream clients"}`))
+		return
+	}
+
+	sse, err := middleware.NewSSEWriter(w)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "failed to start health stream", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	seq, _ := strconv.ParseUint(r.Header.Get("Last-Event-ID"), 10, 64)
+	if err := sse.Retry(healthStreamRetry); err != nil {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		report := reports.get(ctx)
+		data, err := json.Marshal(report)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "failed to encode health report", "error", err)
+			return
+		}
+		seq++
+		if err := sse.Event("health", strconv.FormatUint(seq, 10), data); err != nil {
+			return
+		}
+
+		redisStatus := report.Components[gateway.ComponentRedis]
+		if warnThreshold > 0 && redisStatus.Healthy && redisStatus.Value > warnThreshold.Seconds() {
+			warning, err := json.Marshal(map[string]interface{}{
+				"component": gateway.ComponentRedis,
+				"latency":   redisStatus.Value,
+				"threshold": warnThreshold.Seconds(),
+			})
+			if err != nil {
+				slog.ErrorContext(r.Context(), "failed to encode health warning", "error", err)
+				return
+			}
+			seq++
+			if err := sse.Event("warning", strconv.FormatUint(seq, 10), warning); err != nil {
+				return
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// handleMetricsStream writes a metrics.StreamSnapshot as a JSON line every
+// interval until the client disconnects or ctx is done. The client must
+// accept application/x-ndjson; a stream holds one of slots, so when all are
+// taken further clients get 503.
+func handleMetricsStream(ctx context.Context, w http.ResponseWriter, r *http.Request, interval time.Duration, slots chan struct{}) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if !acceptsMediaType(r.Header.Get("Accept"), "application/x-ndjson") {
+		w.WriteHeader(http.StatusNotAcceptable)
+		w.Write([]byte(`{"error":"Accept must include application/x-ndjson"}`))
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	select {
+	case slots <- struct{}{}:
+		defer func() { <-slots }()
+	default:
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(`{"error":"too many metrics stream clients"}`))
+		return
+	}
+	metrics.Default.MetricsStreamClients.Inc()
+	defer metrics.Default.MetricsStreamClients.Dec()
+
+	snapshotter, err := metrics.NewSnapshotter(prometheus.DefaultGatherer, time.Now())
+	if err != nil {
+		slog.ErrorContext(r.Context(), "failed to gather metrics", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	enc := json.NewEncoder(w)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-r.Context().Done():
+			return
+		case now := <-ticker.C:
+			snapshot, err := snapshotter.Next(now)
+			if err != nil {
+				slog.ErrorContext(r.Context(), "failed to gather metrics", "error", err)
+				return
+			}
+			if err := enc.Encode(snapshot); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// acceptsMediaType reports whether the Accept header lists mediaType
+func acceptsMediaType(accept, mediaType string) bool {
+	for _, part := range strings.Split(accept, ",") {
+		name, _, _ := strings.Cut(part, ";")
+		if strings.EqualFold(strings.TrimSpace(name), mediaType) {
+			return true
+		}
+	}
+	return false
+}
+
+// handleReady answers the readiness probe with the result of gw.Probe
+func handleReady(w http.ResponseWriter, r *http.Request, gw *gateway.Gateway) {
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := gw.Probe(ctx); err != nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]string{"status": "not ready", "error": err.Error()})
+		return
+	}
+	w.Write([]byte(`{"status":"ready"}`))
+}
+
+// handleListChannels returns a page of the channels with subscribers on
+// this gateway
+func handleListChannels(w http.ResponseWriter, r *http.Request, gw *gateway.Gateway) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	minSubscribers, err := queryInt(query, "min_subscribers", 1)
+	if err != nil || minSubscribers < 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"invalid min_subscribers"}`))
+		return
+	}
+	offset, err := queryInt(query, "offset", 0)
+	if err != nil || offset < 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"invalid offset"}`))
+		return
+	}
+	limit, err := queryInt(query, "limit", gateway.DefaultChannelListLimit)
+	if err != nil || limit <= 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"invalid limit"}`))
+		return
+	}
+	limit = min(limit, gateway.MaxChannelListLimit)
+
+	channels, total := gw.ListChannels(query.Get("prefix"), minSubscribers, offset, limit)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+	response := struct {
+		Channels []gateway.ChannelSummary `json:"channels"`
+		Count    int                      `json:"count"`
+		Total    int                      `json:"total"`
+	}{
+		Channels: channels,
+		Count:    len(channels),
+		Total:    total,
+	}
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		slog.ErrorContext(r.Context(), "failed to encode channel list response", "error", err)
+	}
+}
+
+// handleChannelPresence returns the users currently subscribed to channel
+func handleChannelPresence(w http.ResponseWriter, r *http.Request, gw *gateway.Gateway, channel string) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	offset, err := queryInt(query, "offset", 0)
+	if err != nil || offset < 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"invalid offset"}`))
+		return
+	}
+	limit, err := queryInt(query, "limit", gateway.DefaultPresenceLimit)
+	if err != nil || limit <= 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"invalid limit"}`))
+		return
+	}
+	limit = min(limit, gateway.MaxPresenceLimit)
+
+	// Get presence info
+	users, total, err := gw.GetChannelPresence(channel, offset, limit)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "failed to get channel presence", "channel", channel, "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error":"failed to get presence"}`))
+		return
+	}
+
+	metadata, err := gw.ChannelMetadata(r.Context(), channel)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "failed to get channel metadata", "channel", channel, "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error":"failed to get metadata"}`))
+		return
+	}
+
+	// Return response
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+	response := struct {
+		Channel  string                 `json:"channel"`
+		Users    []gateway.PresenceInfo `json:"users"`
+		Count    int                    `json:"count"`
+		Total    int                    `json:"total"`
+		Metadata json.RawMessage        `json:"metadata,omitempty"`
+	}{
+		Channel:  channel,
+		Users:    users,
+		Count:    len(users),
+		Total:    total,
+		Metadata: metadata,
+	}
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		slog.ErrorContext(r.Context(), "failed to encode presence response", "error", err)
+	}
+}
+
+// handleChannelStats returns the message and subscriber counters of channel
+func handleChannelStats(w http.ResponseWriter, r *http.Request, gw *gateway.Gateway, channel string) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	window, err := queryInt(r.URL.Query(), "window", routing.RateWindowSeconds)
+	if err != nil || window <= 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"invalid window"}`))
+		return
+	}
+	window = min(window, routing.RateWindowSeconds)
+
+	stats, err := gw.ChannelStats(r.Context(), channel)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "failed to get channel stats", "channel", channel, "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error":"failed to get stats"}`))
+		return
+	}
+
+	response := struct {
+		gateway.ChannelStats
+		MessageRate float64 `json:"messageRate"`
+	}{
+		ChannelStats: stats,
+		MessageRate:  gw.ChannelMessageRate(channel, window),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		slog.ErrorContext(r.Context(), "failed to encode stats response", "error", err)
+	}
+}
+
+// handleReplayWorkerStream re-adds a range of a worker's stream entries and
+// returns how many were replayed
+func handleReplayWorkerStream(w http.ResponseWriter, r *http.Request, gw *gateway.Gateway, workerID string) {
+	// A paced replay can outlast the server's write timeout
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		slog.WarnContext(r.Context(), "failed to clear write deadline for replay", "error", err)
+	}
+	w.Header().Set("Content-Type", "application/json")
+
+	query := r.URL.Query()
+	replayed, err := gw.ReplayStream(r.Context(), workerID, query.Get("start"), query.Get("end"))
+	switch {
+	case err == nil:
+	case errors.Is(err, gateway.ErrInvalidReplayRange):
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	case errors.Is(err, gateway.ErrReplayOverlapsHead):
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	default:
+		slog.ErrorContext(r.Context(), "failed to replay worker stream", "workerId", workerID, "replayed", replayed, "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "failed to replay worker stream", "replayed": replayed})
+		return
+	}
+
+	response := struct {
+		WorkerID string `json:"workerId"`
+		Replayed int    `json:"replayed"`
+	}{
+		WorkerID: workerID,
+		Replayed: replayed,
+	}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		slog.ErrorContext(r.Context(), "failed to encode replay response", "error", err)
+	}
+}
+
+// handleRescueWorkerStream claims the pending entries listed in the request
+// body {"ids":[...]} from the streams of workerID and re-adds them
+func handleRescueWorkerStream(w http.ResponseWriter, r *http.Request, gw *gateway.Gateway, workerID string) {
+	var request struct {
+		IDs []string `json:"ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		if middleware.WriteBodyTooLarge(w, err) {
+			return
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"invalid JSON body"}`))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+
+	rescued, err := gw.RescueStreamMessages(r.Context(), workerID, request.IDs)
+	switch {
+	case err == nil:
+	case errors.Is(err, gateway.ErrInvalidRescueIDs):
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	default:
+		slog.ErrorContext(r.Context(), "failed to rescue worker stream messages", "workerId", workerID, "rescued", rescued, "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "failed to rescue messages", "rescued": rescued})
+		return
+	}
+
+	response := struct {
+		WorkerID string `json:"workerId"`
+		Rescued  int    `json:"rescued"`
+	}{
+		WorkerID: workerID,
+		Rescued:  rescued,
+	}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		slog.ErrorContext(r.Context(), "failed to encode rescue response", "error", err)
+	}
+}
+
+// handlePublishServerMessage broadcasts the server message in the request
+// body to the subscribers of channel on all gateways, bypassing the worker
+// streams, and returns the broadcast StreamMessage
+func handlePublishServerMessage(w http.ResponseWriter, r *http.Request, gw *gateway.Gateway, channel string) {
+	var request gateway.ServerMessage
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		if middleware.WriteBodyTooLarge(w, err) {
+			return
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"invalid JSON body"}`))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+
+	msg, err := gw.PublishServerMessage(r.Context(), channel, request)
+	switch {
+	case err == nil:
+	case errors.Is(err, gateway.ErrEmptyServerMessage):
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"text is required"}`))
+		return
+	case writeGatewayError(w, err):
+		slog.WarnContext(r.Context(), "server message rejected", "channel", channel, "error", err)
+		return
+	default:
+		slog.ErrorContext(r.Context(), "failed to publish server message", "channel", channel, "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error":"failed to publish message"}`))
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(msg); err != nil {
+		slog.ErrorContext(r.Context(), "failed to encode server message response", "error", err)
+	}
+}
+
+// handleRecoverChannelRoute routes channel back to the worker of its most
+// recent history message and returns the worker ID
+func handleRecoverChannelRoute(w http.ResponseWriter, r *http.Request, gw *gateway.Gateway, channel string) {
+	w.Header().Set("Content-Type", "application/json")
+
+	workerID, err := gw.RecoverRouteFromHistory(r.Context(), channel)
+	switch {
+	case err == nil:
+	case errors.Is(err, gateway.ErrNoChannelHistory):
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":"channel history names no worker"}`))
+		return
+	case errors.Is(err, gateway.ErrChannelRouteExists):
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte(`{"error":"channel already has a route"}`))
+		return
+	case errors.Is(err, routing.ErrWorkerNotActive):
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte(`{"error":"worker not active"}`))
+		return
+	default:
+		slog.ErrorContext(r.Context(), "failed to recover channel route", "channel", channel, "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error":"failed to recover channel route"}`))
+		return
+	}
+
+	response := struct {
+		Channel  string `json:"channel"`
+		WorkerID string `json:"workerId"`
+	}{channel, workerID}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		slog.ErrorContext(r.Context(), "failed to encode recover response", "error", err)
+	}
+}
+
+// handleMigrateWorker moves all channels routed to workerID to other
+// workers and returns the migrated and failed channels
+func handleMigrateWorker(w http.ResponseWriter, r *http.Request, gw *gateway.Gateway, workerID string) {
+	w.Header().Set("Content-Type", "application/json")
+
+	result, err := gw.MigrateWorkerChannels(r.Context(), workerID)
+	switch {
+	case err == nil:
+	case errors.Is(err, routing.ErrMigrationInProgress):
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte(`{"error":"migration already in progress"}`))
+		return
+	default:
+		slog.ErrorContext(r.Context(), "failed to migrate worker channels", "workerId", workerID, "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error":"failed to migrate worker channels"}`))
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		slog.ErrorContext(r.Context(), "failed to encode migrate response", "error", err)
+	}
+}
+
+// handleChannelHistory returns a page of channel messages in publish order,
+// continuing after ?cursor= with the nextCursor of the previous page. Only
+// the newest HISTORY_RETAIN messages of a channel can be paged.
+func handleChannelHistory(w http.ResponseWriter, r *http.Request, gw *gateway.Gateway, channel string) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	direction := gateway.HistoryDirection(query.Get("direction"))
+	switch direction {
+	case "":
+		direction = gateway.HistoryDesc
+	case gateway.HistoryAsc, gateway.HistoryDesc:
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"invalid direction"}`))
+		return
+	}
+	limit, err := queryInt(query, "limit", gateway.DefaultHistoryLimit)
+	if err != nil || limit <= 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"invalid limit"}`))
+		return
+	}
+	limit = min(limit, gateway.MaxHistoryLimit)
+
+	w.Header().Set("Content-Type", "application/json")
+	page, err := gw.ChannelHistoryPage(r.Context(), channel, direction, query.Get("cursor"), limit)
+	switch {
+	case err == nil:
+	case errors.Is(err, gateway.ErrInvalidCursor):
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"invalid cursor"}`))
+		return
+	case writeGatewayError(w, err):
+		slog.WarnContext(r.Context(), "failed to get channel history", "channel", channel, "error", err)
+		return
+	default:
+		slog.ErrorContext(r.Context(), "failed to get channel history", "channel", channel, "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error":"failed to get history"}`))
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(page); err != nil {
+		slog.ErrorContext(r.Context(), "failed to encode history response", "error", err)
+	}
+}
+
+// handleUserRecentMessages returns the last ?limit= messages userID
+// published, newest first, for moderation tools
+func handleUserRecentMessages(w http.ResponseWriter, r *http.Request, gw *gateway.Gateway, userID string) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	limit, err := queryInt(r.URL.Query(), "limit", gateway.DefaultRecentMessagesLimit)
+	if err != nil || limit <= 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"invalid limit"}`))
+		return
+	}
+
+	messages, err := gw.GetUserRecentMessages(r.Context(), userID, limit)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "failed to get user recent messages", "userId", userID, "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error":"failed to get recent messages"}`))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		UserID   string                  `json:"userId"`
+		Messages []gateway.StreamMessage `json:"messages"`
+		Count    int                     `json:"count"`
+	}{
+		UserID:   userID,
+		Messages: messages,
+		Count:    len(messages),
+	}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		slog.ErrorContext(r.Context(), "failed to encode recent messages response", "error", err)
+	}
+}
+
+// handleChannelMetadata replaces (PATCH, optional ?ttl=seconds) or deletes
+// (DELETE) the JSON metadata of channel
+func handleChannelMetadata(w http.ResponseWriter, r *http.Request, gw *gateway.Gateway, channel string, maxSize int) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodPatch:
+	case http.MethodDelete:
+		if err := gw.DeleteChannelMetadata(r.Context(), channel); err != nil {
+			slog.ErrorContext(r.Context(), "failed to delete channel metadata", "channel", channel, "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"error":"failed to delete metadata"}`))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	ttl, err := queryInt(r.URL.Query(), "ttl", 0)
+	if err != nil || ttl < 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"invalid ttl"}`))
+		return
+	}
+
+	// Read one byte past the limit so oversized bodies are detected
+	body, err := io.ReadAll(io.LimitReader(r.Body, int64(maxSize)+1))
+	if err != nil {
+		if middleware.WriteBodyTooLarge(w, err) {
+			return
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"failed to read body"}`))
+		return
+	}
+
+	err = gw.SetChannelMetadata(r.Context(), channel, body, time.Duration(ttl)*time.Second)
+	switch {
+	case errors.Is(err, gateway.ErrMetadataTooLarge):
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		fmt.Fprintf(w, `{"error":"metadata must not exceed %d bytes"}`, maxSize)
+		return
+	case errors.Is(err, gateway.ErrInvalidMetadata):
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"metadata must be valid JSON"}`))
+		return
+	case err != nil:
+		slog.ErrorContext(r.Context(), "failed to set channel metadata", "channel", channel, "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error":"failed to set metadata"}`))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// registerChannelRoutes registers the channel endpoints on mux:
+//
+//	GET  /channels?prefix=chat:&min_subscribers=1&offset=0&limit=100 (signed)
+//	GET  /channels/{channel}/presence (signed)
+//	GET  /channels/{channel}/stats
+//	GET  /channels/{channel}/history?direction=asc&cursor={streamId}&limit=50 (signed)
+//	POST /channels/{channel}/publish/batch (signed)
+//	PATCH, DELETE /channels/{channel}/metadata (signed)
+func registerChannelRoutes(mux *http.ServeMux, gw *gateway.Gateway, adminSecret string, metadataMaxSize int) {
+	// The list names user and private channels, so only admins may read it
+	mux.Handle("/channels", adminauth.Middleware(adminSecret)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handleListChannels(w, r, gw)
+	})))
+	mux.HandleFunc("/channels/", func(w http.ResponseWriter, r *http.Request) {
+		path := r.URL.Path
+		const prefix = "/channels/"
+		const presenceSuffix = "/presence"
+		const statsSuffix = "/stats"
+		const batchSuffix = "/publish/batch"
+		const metadataSuffix = "/metadata"
+		const historySuffix = "/history"
+
+		var suffix string
+		switch {
+		case strings.HasSuffix(path, presenceSuffix):
+			suffix = presenceSuffix
+		case strings.HasSuffix(path, statsSuffix):
+			suffix = statsSuffix
+		case strings.HasSuffix(path, batchSuffix):
+			suffix = batchSuffix
+		case strings.HasSuffix(path, metadataSuffix):
+			suffix = metadataSuffix
+		case strings.HasSuffix(path, historySuffix):
+			suffix = historySuffix
+		}
+		if !strings.HasPrefix(path, prefix) || suffix == "" || len(path) < len(prefix)+len(suffix) {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":"not found"}`))
+			return
+		}
+
+		channel := path[len(prefix) : len(path)-len(suffix)]
+		if channel == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"channel name required"}`))
+			return
+		}
+
+		switch suffix {
+		case presenceSuffix:
+			// Presence names the users of private and user channels, so only
+			// admins may read it
+			adminauth.Middleware(adminSecret)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				handleChannelPresence(w, r, gw, channel)
+			})).ServeHTTP(w, r)
+		case statsSuffix:
+			handleChannelStats(w, r, gw, channel)
+		case batchSuffix:
+			// Batch messages carry the userId to publish as, so only admins
+			// may send them
+			adminauth.Middleware(adminSecret)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				handleChannelPublishBatch(w, r, gw, channel)
+			})).ServeHTTP(w, r)
+		case metadataSuffix:
+			// Metadata is sent to every subscriber, so only admins may change it
+			adminauth.Middleware(adminSecret)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				handleChannelMetadata(w, r, gw, channel, metadataMaxSize)
+			})).ServeHTTP(w, r)
+		case historySuffix:
+			// History covers user and room channels, so only admins may read it
+			adminauth.Middleware(adminSecret)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				handleChannelHistory(w, r, gw, channel)
+			})).ServeHTTP(w, r)
+		}
+	})
+}
+
+// registerRoomRoutes registers the room endpoints on mux:
+//
+//	GET    /rooms
+//	POST   /rooms      (signed)
+//	DELETE /rooms/{id} (signed)
+//
+// Creating and deleting rooms changes which room channels clients may
+// subscribe to, so only admins may do it.
+func registerRoomRoutes(mux *http.ServeMux, gw *gateway.Gateway, adminSecret string) {
+	createRoom := adminauth.Middleware(adminSecret)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handleCreateRoom(w, r, gw)
+	}))
+	mux.HandleFunc("/rooms", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			handleListRooms(w, r, gw)
+		case http.MethodPost:
+			createRoom.ServeHTTP(w, r)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+
+	mux.Handle("/rooms/", adminauth.Middleware(adminSecret)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handleDeleteRoom(w, r, gw)
+	})))
+}
+
+// handleListRooms returns all rooms with their subscriber counts
+func handleListRooms(w http.ResponseWriter, r *http.Request, gw *gateway.Gateway) {
+	rooms, err := gw.ListRooms(r.Context())
+	if err != nil {
+		slog.ErrorContext(r.Context(), "failed to list rooms", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error":"failed to list rooms"}`))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Rooms []gateway.Room `json:"rooms"`
+		Count int            `json:"count"`
+	}{
+		Rooms: rooms,
+		Count: len(rooms),
+	}
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		slog.ErrorContext(r.Context(), "failed to encode rooms response", "error", err)
+	}
+}
+
+// handleCreateRoom creates the room described by the request body
+func handleCreateRoom(w http.ResponseWriter, r *http.Request, gw *gateway.Gateway) {
+	var room gateway.Room
+	if err := json.NewDecoder(r.Body).Decode(&room); err != nil {
+		if middleware.WriteBodyTooLarge(w, err) {
+			return
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"invalid JSON body"}`))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	room, err := gw.CreateRoom(r.Context(), room)
+	switch {
+	case errors.Is(err, gateway.ErrInvalidRoom):
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	case errors.Is(err, gateway.ErrRoomExists):
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte(`{"error":"room already exists"}`))
+		return
+	case err != nil:
+		slog.ErrorContext(r.Context(), "failed to create room", "roomId", room.ID, "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error":"failed to create room"}`))
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	response := struct {
+		gateway.Room
+		Channel string `json:"channel"`
+	}{
+		Room:    room,
+		Channel: room.Channel(),
+	}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		slog.ErrorContext(r.Context(), "failed to encode room response", "error", err)
+	}
+}
+
+// handleDeleteRoom deletes the room named by the path and unsubscribes its
+// subscribers
+func handleDeleteRoom(w http.ResponseWriter, r *http.Request, gw *gateway.Gateway) {
+	if r.Method != http.MethodDelete {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := strings.TrimPrefix(r.URL.Path, "/rooms/")
+	if id == "" || strings.Contains(id, "/") {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":"not found"}`))
+		return
+	}
+
+	err := gw.DeleteRoom(r.Context(), id)
+	switch {
+	case err == nil:
+		w.WriteHeader(http.StatusNoContent)
+	case errors.Is(err, gateway.ErrRoomNotFound):
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":"room not found"}`))
+	default:
+		slog.ErrorContext(r.Context(), "failed to delete room", "roomId", id, "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error":"failed to delete room"}`))
+	}
+}
+
+// handleChannelPublishBatch publishes up to gateway.MaxBatchSize messages to channel
+func handleChannelPublishBatch(w http.ResponseWriter, r *http.Request, gw *gateway.Gateway, channel string) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request struct {
+		Messages []gateway.BatchMessage `json:"messages"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		if middleware.WriteBodyTooLarge(w, err) {
+			return
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"invalid json"}`))
+		return
+	}
+
+	ids, err := gw.PublishBatch(r.Context(), channel, request.Messages)
+	status := http.StatusOK
+	switch {
+	case err == nil:
+	case errors.Is(err, gateway.ErrPartialBatch):
+		status = http.StatusMultiStatus
+	case writeGatewayError(w, err):
+		slog.WarnContext(r.Context(), "batch publish rejected", "channel", channel, "error", err)
+		return
+	case errors.Is(err, gateway.ErrInvalidBatch):
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	default:
+		slog.ErrorContext(r.Context(), "batch publish failed", "channel", channel, "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error":"failed to publish batch"}`))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	response := struct {
+		Channel string   `json:"channel"`
+		IDs     []string `json:"ids"`
+		Error   string   `json:"error,omitempty"`
+	}{
+		Channel: channel,
+		IDs:     ids,
+	}
+	if err != nil {
+		response.Error = err.Error()
+	}
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		slog.ErrorContext(r.Context(), "failed to encode batch publish response", "error", err)
+	}
+}
+
+// writeGatewayError writes the HTTP status and client message of the
+// gateway.GatewayError in err's chain, if any, and reports whether it did
+func writeGatewayError(w http.ResponseWriter, err error) bool {
+	var gwErr *gateway.GatewayError
+	if !errors.As(err, &gwErr) {
+		return false
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(gwErr.HTTPStatus())
+	json.NewEncoder(w).Encode(map[string]string{"error": gwErr.Message})
+	return true
+}
+
+// queryInt parses the integer query parameter key, returning defaultValue
+// when it is absent
+func queryInt(query url.Values, key string, defaultValue int) (int, error) {
+	v := query.Get(key)
+	if v == "" {
+		return defaultValue, nil
+	}
+	return strconv.Atoi(v)
 }
@@ -0,0 +1,421 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"realtime-message-gateway/internal/adminauth"
+	"realtime-message-gateway/internal/config"
+	"realtime-message-gateway/internal/gateway"
+	"realtime-message-gateway/internal/gatewaytest"
+	"realtime-message-gateway/internal/metrics"
+	"realtime-message-gateway/internal/middleware"
+	"realtime-message-gateway/internal/redis"
+)
+
+// slowServer never finishes shutting down before its context expires
+type slowServer struct{}
+
+func (slowServer) Shutdown(ctx context.Context) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestShutdownServersSlowWebSocket(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen() error = %v", err)
+	}
+	httpServer := &http.Server{Handler: http.NotFoundHandler()}
+	closed := make(chan time.Time, 1)
+	go func() {
+		if err := httpServer.Serve(listener); errors.Is(err, http.ErrServerClosed) {
+			closed <- time.Now()
+		}
+	}()
+
+	wsTimeouts := testutil.ToFloat64(metrics.Default.ShutdownTimeoutTotal.WithLabelValues("ws"))
+	httpTimeouts := testutil.ToFloat64(metrics.Default.ShutdownTimeoutTotal.WithLabelValues("http"))
+
+	start := time.Now()
+	shutdownServers([]shutdownTarget{
+		{name: "ws", server: slowServer{}, timeout: 500 * time.Millisecond},
+		{name: "http", server: httpServer, timeout: 5 * time.Second},
+	})
+
+	select {
+	case at := <-closed:
+		if d := at.Sub(start); d >= 500*time.Millisecond {
+			t.Errorf("HTTP server closed after %s, want before the WebSocket timeout", d)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("HTTP server did not close")
+	}
+	if got := testutil.ToFloat64(metrics.Default.ShutdownTimeoutTotal.WithLabelValues("ws")) - wsTimeouts; got != 1 {
+		t.Errorf("ws shutdown timeouts increased by %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(metrics.Default.ShutdownTimeoutTotal.WithLabelValues("http")) - httpTimeouts; got != 0 {
+		t.Errorf("http shutdown timeouts increased by %v, want 0", got)
+	}
+}
+
+func TestHandleChannelPublishBatchGatewayErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       []gatewaytest.Option
+		body       string
+		wantStatus int
+		wantError  string
+	}{
+		{"no workers", []gatewaytest.Option{gatewaytest.WithWorkers(nil)}, `{"messages":[{"text":"hi"}]}`, http.StatusServiceUnavailable, "worker unavailable"},
+		{"text too long", []gatewaytest.Option{gatewaytest.WithGatewayOptions(gateway.WithMaxTextLength(5))}, `{"messages":[{"text":"too long"}]}`, http.StatusRequestEntityTooLarge, "message too large"},
+		{"invalid batch", nil, `{"messages":[]}`, http.StatusBadRequest, "invalid batch: no messages"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gw := gatewaytest.New(t, tt.opts...)
+			req := httptest.NewRequest(http.MethodPost, "/channels/chat/publish/batch", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+
+			handleChannelPublishBatch(rec, req, gw, "chat")
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			var response struct {
+				Error string `json:"error"`
+			}
+			if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if response.Error != tt.wantError {
+				t.Errorf("error = %q, want %q", response.Error, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestHandleChannelPublishBatchBodyTooLarge(t *testing.T) {
+	gw := gatewaytest.New(t)
+	handler := middleware.MaxBodySize(65536)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handleChannelPublishBatch(w, r, gw, "chat")
+	}))
+	body := `{"messages":[{"text":"` + strings.Repeat("a", 1<<20) + `"}]}`
+
+	for _, unknownLength := range []bool{false, true} {
+		req := httptest.NewRequest(http.MethodPost, "/channels/chat/publish/batch", strings.NewReader(body))
+		if unknownLength {
+			req.ContentLength = -1
+		}
+		rec := httptest.NewRecorder()
+
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("status with unknown length %v = %d, want %d", unknownLength, rec.Code, http.StatusRequestEntityTooLarge)
+		}
+		if got := rec.Body.String(); got != `{"error":"request too large"}` {
+			t.Errorf("body with unknown length %v = %s, want request too large error", unknownLength, got)
+		}
+	}
+}
+
+func TestHandleUserRecentMessages(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client, err := redis.NewClient(&config.Config{RedisURL: "redis://" + mr.Addr()})
+	if err != nil {
+		t.Fatalf("redis.NewClient() error = %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	gw := gatewaytest.New(t, gatewaytest.WithGatewayOptions(gateway.WithRedis(client)))
+
+	// Client publishes record the user history
+	for i, text := range []string{"first", "second"} {
+		record := fmt.Sprintf(`{"id":"%d-0","message":{"schemaVersion":1,"type":"message","channel":"chat","userId":"u1","text":%q,"timestamp":"2026-01-01T00:00:00Z"}}`, i+1, text)
+		if _, err := mr.Lpush(gateway.UserHistoryPrefix+"u1", record); err != nil {
+			t.Fatalf("Lpush() error = %v", err)
+		}
+	}
+	// Batch messages name their user in the request body and are not recorded
+	body := `{"messages":[{"text":"forged","userId":"u1"}]}`
+	handleChannelPublishBatch(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/channels/chat/publish/batch", strings.NewReader(body)), gw, "chat")
+
+	tests := []struct {
+		name       string
+		method     string
+		query      string
+		wantStatus int
+		wantTexts  []string
+	}{
+		{"default limit", http.MethodGet, "", http.StatusOK, []string{"second", "first"}},
+		{"limit", http.MethodGet, "?limit=1", http.StatusOK, []string{"second"}},
+		{"invalid limit", http.MethodGet, "?limit=0", http.StatusBadRequest, nil},
+		{"wrong method", http.MethodPost, "", http.StatusMethodNotAllowed, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/admin/users/u1/recent-messages"+tt.query, nil)
+			rec := httptest.NewRecorder()
+
+			handleUserRecentMessages(rec, req, gw, "u1")
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var response struct {
+				UserID   string                  `json:"userId"`
+				Messages []gateway.StreamMessage `json:"messages"`
+				Count    int                     `json:"count"`
+			}
+			if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			var texts []string
+			for _, msg := range response.Messages {
+				texts = append(texts, msg.Text)
+				if msg.Channel != "chat" || msg.Timestamp == "" {
+					t.Errorf("message %+v, want channel chat and a timestamp", msg)
+				}
+			}
+			if !slices.Equal(texts, tt.wantTexts) || response.Count != len(tt.wantTexts) || response.UserID != "u1" {
+				t.Errorf("response = %+v, want texts %v", response, tt.wantTexts)
+			}
+		})
+	}
+}
+
+func TestChannelRoutesRequireSignature(t *testing.T) {
+	const secret = "admin-secret"
+	gw := gatewaytest.New(t)
+	mux := http.NewServeMux()
+	registerChannelRoutes(mux, gw, secret, 4096)
+
+	serve := func(method, path, body string, sign bool) int {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if sign {
+			adminauth.SignRequest(req, secret, []byte(body), time.Now())
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	const path = "/channels/chat:general/metadata"
+	if code := serve(http.MethodPatch, path, `{"topic":"go"}`, false); code != http.StatusUnauthorized {
+		t.Errorf("unsigned PATCH status = %d, want %d", code, http.StatusUnauthorized)
+	}
+	if code := serve(http.MethodDelete, path, "", false); code != http.StatusUnauthorized {
+		t.Errorf("unsigned DELETE status = %d, want %d", code, http.StatusUnauthorized)
+	}
+	if code := serve(http.MethodPatch, path, `{"topic":"go"}`, true); code != http.StatusNoContent {
+		t.Fatalf("signed PATCH status = %d, want %d", code, http.StatusNoContent)
+	}
+	if code := serve(http.MethodDelete, path, "", true); code != http.StatusNoContent {
+		t.Errorf("signed DELETE status = %d, want %d", code, http.StatusNoContent)
+	}
+
+	const presence = "/channels/chat:general/presence?offset=0&limit=10"
+	if code := serve(http.MethodGet, presence, "", false); code != http.StatusUnauthorized {
+		t.Errorf("unsigned GET presence status = %d, want %d", code, http.StatusUnauthorized)
+	}
+	if code := serve(http.MethodGet, presence, "", true); code != http.StatusOK {
+		t.Errorf("signed GET presence status = %d, want %d", code, http.StatusOK)
+	}
+}
+
+func TestRoomRoutesRequireSignature(t *testing.T) {
+	const secret = "admin-secret"
+	gw := gatewaytest.New(t)
+	mux := http.NewServeMux()
+	registerRoomRoutes(mux, gw, secret)
+
+	serve := func(method, path, body string, sign bool) int {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if sign {
+			adminauth.SignRequest(req, secret, []byte(body), time.Now())
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := serve(http.MethodPost, "/rooms", `{"id":"r1"}`, false); code != http.StatusUnauthorized {
+		t.Errorf("unsigned POST /rooms status = %d, want %d", code, http.StatusUnauthorized)
+	}
+	if code := serve(http.MethodPost, "/rooms", `{"id":"r1"}`, true); code != http.StatusCreated {
+		t.Fatalf("signed POST /rooms status = %d, want %d", code, http.StatusCreated)
+	}
+	if code := serve(http.MethodGet, "/rooms", "", false); code != http.StatusOK {
+		t.Errorf("unsigned GET /rooms status = %d, want %d", code, http.StatusOK)
+	}
+	if code := serve(http.MethodDelete, "/rooms/r1", "", false); code != http.StatusUnauthorized {
+		t.Errorf("unsigned DELETE /rooms/r1 status = %d, want %d", code, http.StatusUnauthorized)
+	}
+	if code := serve(http.MethodDelete, "/rooms/r1", "", true); code != http.StatusNoContent {
+		t.Errorf("signed DELETE /rooms/r1 status = %d, want %d", code, http.StatusNoContent)
+	}
+}
+
+func TestHandleHealth(t *testing.T) {
+	gw := gatewaytest.New(t)
+
+	rec := httptest.NewRecorder()
+	handleHealth(rec, gw.HealthReport(context.Background()))
+	if rec.Code != http.StatusOK {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	var report gateway.HealthStatus
+	if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if !report.Healthy || len(report.Components) != 3 {
+		t.Errorf("report = %+v, want healthy with 3 components", report)
+	}
+
+	if err := gw.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	rec = httptest.NewRecorder()
+	handleHealth(rec, gw.HealthReport(context.Background()))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status after Shutdown = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+}
+
+func TestHandleHealthStream(t *testing.T) {
+	gw := gatewaytest.New(t)
+
+	// A done request context ends the stream after the first report; a 1ns
+	// threshold makes every Redis ping slow enough for a warning
+	ctx := context.Background()
+	done, cancel := context.WithCancel(ctx)
+	cancel()
+	req := httptest.NewRequestWithContext(done, http.MethodGet, "/health/stream", nil)
+	req.Header.Set("Last-Event-ID", "41")
+	reports := &sharedHealthReport{gw: gw, interval: time.Minute}
+	slots := make(chan struct{}, 1)
+	rec := httptest.NewRecorder()
+	handleHealthStream(ctx, rec, req, reports, time.Minute, time.Nanosecond, slots)
+
+	if got := rec.Header().Get("Content-Type"); got != "text/event-stream" {
+		t.Errorf("Content-Type = %q, want text/event-stream", got)
+	}
+
+	// Frames are separated by a blank line, fields are "name: value" lines
+	var frames []map[string]string
+	for _, frame := range strings.Split(strings.TrimSuffix(rec.Body.String(), "\n\n"), "\n\n") {
+		fields := make(map[string]string)
+		for _, line := range strings.Split(frame, "\n") {
+			name, value, ok := strings.Cut(line, ": ")
+			if !ok {
+				t.Fatalf("malformed line %q in frame %q", line, frame)
+			}
+			fields[name] = value
+		}
+		frames = append(frames, fields)
+	}
+	if len(frames) != 3 {
+		t.Fatalf("got %d frames, want retry, health and warning: %q", len(frames), rec.Body.String())
+	}
+	if frames[0]["retry"] != "5000" {
+		t.Errorf("first frame = %v, want retry: 5000", frames[0])
+	}
+	if frames[1]["event"] != "health" || frames[1]["id"] != "42" {
+		t.Errorf("second frame = %v, want health event with id 42", frames[1])
+	}
+	var report gateway.HealthStatus
+	if err := json.Unmarshal([]byte(frames[1]["data"]), &report); err != nil || !report.Healthy {
+		t.Errorf("health data = %q, %v, want a healthy report", frames[1]["data"], err)
+	}
+	if frames[2]["event"] != "warning" || frames[2]["id"] != "43" || !strings.Contains(frames[2]["data"], `"component":"redis"`) {
+		t.Errorf("third frame = %v, want redis warning event with id 43", frames[2])
+	}
+
+	// Streams within the interval share the report
+	if got := reports.get(ctx); !got.Timestamp.Equal(report.Timestamp) {
+		t.Errorf("report timestamp = %s, want the shared %s", got.Timestamp, report.Timestamp)
+	}
+
+	// The only slot is taken
+	slots <- struct{}{}
+	rec = httptest.NewRecorder()
+	handleHealthStream(ctx, rec, httptest.NewRequest(http.MethodGet, "/health/stream", nil), reports, time.Minute, 0, slots)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status over the client limit = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+}
+
+func TestHandleMetricsStream(t *testing.T) {
+	slots := make(chan struct{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handleMetricsStream(context.Background(), w, r, 10*time.Millisecond, slots)
+	}))
+	defer server.Close()
+
+	get := func(accept string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+		req.Header.Set("Accept", accept)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("GET error = %v", err)
+		}
+		return resp
+	}
+
+	resp := get("application/json")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotAcceptable {
+		t.Errorf("status without ndjson Accept = %d, want %d", resp.StatusCode, http.StatusNotAcceptable)
+	}
+
+	stream := get("application/json, application/x-ndjson;q=0.9")
+	defer stream.Body.Close()
+	if stream.StatusCode != http.StatusOK || stream.Header.Get("Content-Type") != "application/x-ndjson" {
+		t.Fatalf("stream response = %d %q, want 200 application/x-ndjson", stream.StatusCode, stream.Header.Get("Content-Type"))
+	}
+	if got := testutil.ToFloat64(metrics.Default.MetricsStreamClients); got != 1 {
+		t.Errorf("gateway_metrics_stream_clients = %v, want 1", got)
+	}
+
+	// The only slot is taken
+	resp = get("application/x-ndjson")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("status over the client limit = %d, want %d", resp.StatusCode, http.StatusServiceUnavailable)
+	}
+
+	lines := bufio.NewScanner(stream.Body)
+	for i := 0; i < 2; i++ {
+		if !lines.Scan() {
+			t.Fatalf("stream ended after %d lines: %v", i, lines.Err())
+		}
+		var snapshot map[string]interface{}
+		if err := json.Unmarshal(lines.Bytes(), &snapshot); err != nil {
+			t.Fatalf("line %q: %v", lines.Text(), err)
+		}
+		for _, field := range []string{"ts", "connections", "publish_rate"} {
+			if _, ok := snapshot[field]; !ok {
+				t.Errorf("line %q has no %s", lines.Text(), field)
+			}
+		}
+	}
+}
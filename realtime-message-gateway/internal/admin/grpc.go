@@ -0,0 +1,178 @@
+package admin
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"log/slog"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"realtime-message-gateway/internal/adminpb"
+	"realtime-message-gateway/internal/gateway"
+	"realtime-message-gateway/internal/routing"
+)
+
+// defaultHistoryLimit is used when GetChannelHistory is called without a limit
+const defaultHistoryLimit = 50
+
+// Server implements the GatewayAdmin gRPC service on top of Gateway
+type Server struct {
+	adminpb.UnimplementedGatewayAdminServer
+
+	gw *gateway.Gateway
+}
+
+// NewGRPCServer creates a gRPC server exposing the admin API of gw.
+// Every call must carry secret as "authorization: Bearer <secret>" metadata.
+func NewGRPCServer(gw *gateway.Gateway, secret string) *grpc.Server {
+	server := grpc.NewServer(grpc.UnaryInterceptor(AuthInterceptor(secret)))
+	adminpb.RegisterGatewayAdminServer(server, &Server{gw: gw})
+	return server
+}
+
+// AuthInterceptor rejects calls that don't carry the admin secret.
+// An empty secret rejects every call.
+func AuthInterceptor(secret string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if secret == "" || !hasSecret(ctx, secret) {
+			slog.Warn("admin call rejected", "method", info.FullMethod, "reason", "unauthenticated")
+			return nil, status.Error(codes.Unauthenticated, "invalid admin secret")
+		}
+		return handler(ctx, req)
+	}
+}
+
+// hasSecret checks the authorization metadata in constant time
+func hasSecret(ctx context.Context, secret string) bool {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return false
+	}
+	for _, value := range md.Get("authorization") {
+		token, found := strings.CutPrefix(value, "Bearer ")
+		if found && subtle.ConstantTimeCompare([]byte(token), []byte(secret)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// DisconnectUser disconnects all connections of a user on this gateway
+func (s *Server) DisconnectUser(ctx context.Context, req *adminpb.DisconnectUserRequest) (*adminpb.DisconnectUserResponse, error) {
+	if req.GetUserId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "user_id required")
+	}
+	if err := s.gw.DisconnectUser(req.GetUserId()); err != nil {
+		return nil, toStatus(err)
+	}
+	return &adminpb.DisconnectUserResponse{}, nil
+}
+
+// PublishMessage routes a message to the channel's worker and broadcasts it
+func (s *Server) PublishMessage(ctx context.Context, req *adminpb.PublishMessageRequest) (*adminpb.PublishMessageResponse, error) {
+	if req.GetChannel() == "" {
+		return nil, status.Error(codes.InvalidArgument, "channel required")
+	}
+	ids, err := s.gw.PublishBatch(ctx, req.GetChannel(), []gateway.BatchMessage{{
+		Text:     req.GetText(),
+		UserID:   req.GetUserId(),
+		UserName: req.GetUserName(),
+	}})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &adminpb.PublishMessageResponse{MessageId: ids[0]}, nil
+}
+
+// GetPresence returns the users currently subscribed to a channel
+func (s *Server) GetPresence(ctx context.Context, req *adminpb.GetPresenceRequest) (*adminpb.GetPresenceResponse, error) {
+	if req.GetChannel() == "" {
+		return nil, status.Error(codes.InvalidArgument, "channel required")
+	}
+	users, _, err := s.gw.GetChannelPresence(req.GetChannel(), 0, 0)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	resp := &adminpb.GetPresenceResponse{Users: make([]*adminpb.PresenceInfo, 0, len(users))}
+	for _, u := range users {
+		resp.Users = append(resp.Users, &adminpb.PresenceInfo{
+			UserId:   u.UserID,
+			UserName: u.UserName,
+			ClientId: u.ClientID,
+		})
+	}
+	return resp, nil
+}
+
+// GetWorkerLoad returns active workers with their stream backlog
+func (s *Server) GetWorkerLoad(ctx context.Context, req *adminpb.GetWorkerLoadRequest) (*adminpb.GetWorkerLoadResponse, error) {
+	loads, err := s.gw.WorkerLoad(ctx)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	resp := &adminpb.GetWorkerLoadResponse{Workers: make([]*adminpb.WorkerLoad, 0, len(loads))}
+	for _, l := range loads {
+		resp.Workers = append(resp.Workers, &adminpb.WorkerLoad{
+			WorkerId:      l.WorkerID,
+			LastHeartbeat: l.LastHeartbeat,
+			StreamLength:  l.StreamLength,
+		})
+	}
+	return resp, nil
+}
+
+// GetChannelHistory returns the most recent messages routed for a channel
+func (s *Server) GetChannelHistory(ctx context.Context, req *adminpb.GetChannelHistoryRequest) (*adminpb.GetChannelHistoryResponse, error) {
+	if req.GetChannel() == "" {
+		return nil, status.Error(codes.InvalidArgument, "channel required")
+	}
+	limit := int(req.GetLimit())
+	if limit <= 0 {
+		limit = defaultHistoryLimit
+	}
+
+	messages, err := s.gw.ChannelHistory(ctx, req.GetChannel(), limit)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	resp := &adminpb.GetChannelHistoryResponse{Messages: make([]*adminpb.StreamMessage, 0, len(messages))}
+	for _, m := range messages {
+		resp.Messages = append(resp.Messages, &adminpb.StreamMessage{
+			Id:        m.ID,
+			Type:      string(m.Type),
+			Channel:   m.Channel,
+			WorkerId:  m.WorkerID,
+			UserId:    m.UserID,
+			UserName:  m.UserName,
+			Text:      m.Text,
+			Timestamp: m.Timestamp,
+			ClientId:  m.ClientID,
+		})
+	}
+	return resp, nil
+}
+
+// toStatus maps gateway errors to gRPC status codes
+func toStatus(err error) error {
+	switch {
+	case errors.Is(err, gateway.ErrInvalidBatch):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, gateway.ErrPermissionDenied):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, gateway.ErrChannelNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, routing.ErrNoActiveWorkers):
+		return status.Error(codes.Unavailable, err.Error())
+	default:
+		slog.Error("admin call failed", "error", err)
+		return status.Error(codes.Internal, "internal error")
+	}
+}
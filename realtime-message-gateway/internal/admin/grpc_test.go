@@ -0,0 +1,134 @@
+package admin
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"realtime-message-gateway/internal/adminpb"
+	"realtime-message-gateway/internal/gatewaytest"
+)
+
+const testSecret = "admin-secret"
+
+// newTestClient starts the admin server on an in-memory listener
+func newTestClient(t *testing.T) adminpb.GatewayAdminClient {
+	t.Helper()
+
+	gw := gatewaytest.New(t)
+
+	listener := bufconn.Listen(1024 * 1024)
+	server := NewGRPCServer(gw, testSecret)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("grpc.NewClient() error = %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return adminpb.NewGatewayAdminClient(conn)
+}
+
+// authContext returns a context carrying secret as bearer token
+func authContext(t *testing.T, secret string) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+secret)
+}
+
+func TestAuthInterceptor(t *testing.T) {
+	client := newTestClient(t)
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want codes.Code
+	}{
+		{"missing secret", context.Background(), codes.Unauthenticated},
+		{"wrong secret", authContext(t, "wrong"), codes.Unauthenticated},
+		{"valid secret", authContext(t, testSecret), codes.OK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := client.GetPresence(tt.ctx, &adminpb.GetPresenceRequest{Channel: "chat"})
+			if got := status.Code(err); got != tt.want {
+				t.Errorf("GetPresence() code = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetPresenceEmptyChannel(t *testing.T) {
+	client := newTestClient(t)
+
+	resp, err := client.GetPresence(authContext(t, testSecret), &adminpb.GetPresenceRequest{Channel: "chat"})
+	if err != nil {
+		t.Fatalf("GetPresence() error = %v", err)
+	}
+	if len(resp.GetUsers()) != 0 {
+		t.Errorf("GetPresence() users = %v, want none", resp.GetUsers())
+	}
+}
+
+func TestDisconnectUser(t *testing.T) {
+	client := newTestClient(t)
+
+	if _, err := client.DisconnectUser(authContext(t, testSecret), &adminpb.DisconnectUserRequest{UserId: "user-123"}); err != nil {
+		t.Errorf("DisconnectUser() error = %v", err)
+	}
+}
+
+func TestInvalidArguments(t *testing.T) {
+	client := newTestClient(t)
+	ctx := authContext(t, testSecret)
+
+	tests := []struct {
+		name string
+		call func() error
+	}{
+		{"disconnect without user", func() error {
+			_, err := client.DisconnectUser(ctx, &adminpb.DisconnectUserRequest{})
+			return err
+		}},
+		{"publish without channel", func() error {
+			_, err := client.PublishMessage(ctx, &adminpb.PublishMessageRequest{Text: "hi"})
+			return err
+		}},
+		{"publish without text", func() error {
+			_, err := client.PublishMessage(ctx, &adminpb.PublishMessageRequest{Channel: "chat"})
+			return err
+		}},
+		{"presence without channel", func() error {
+			_, err := client.GetPresence(ctx, &adminpb.GetPresenceRequest{})
+			return err
+		}},
+		{"history without channel", func() error {
+			_, err := client.GetChannelHistory(ctx, &adminpb.GetChannelHistoryRequest{})
+			return err
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := status.Code(tt.call()); got != codes.InvalidArgument {
+				t.Errorf("code = %v, want %v", got, codes.InvalidArgument)
+			}
+		})
+	}
+}
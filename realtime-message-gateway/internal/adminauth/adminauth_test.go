@@ -0,0 +1,138 @@
+package adminauth
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerify(t *testing.T) {
+	const secret = "admin-secret"
+	now := time.Unix(1700000000, 0)
+
+	signed := func(method, path, body string, at time.Time) *http.Request {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		SignRequest(r, secret, []byte(body), at)
+		return r
+	}
+
+	tests := []struct {
+		name   string
+		req    func() *http.Request
+		secret string
+		wantOK bool
+	}{
+		{"valid", func() *http.Request { return signed("POST", "/admin/users/u1/disconnect", "", now) }, secret, true},
+		{"valid with body", func() *http.Request { return signed("POST", "/channels/chat/publish/batch", `{"messages":[]}`, now) }, secret, true},
+		{"within skew", func() *http.Request { return signed("GET", "/x", "", now.Add(-4*time.Minute)) }, secret, true},
+		{"expired", func() *http.Request { return signed("GET", "/x", "", now.Add(-6*time.Minute)) }, secret, false},
+		{"future", func() *http.Request { return signed("GET", "/x", "", now.Add(6*time.Minute)) }, secret, false},
+		{"wrong secret", func() *http.Request { return signed("GET", "/x", "", now) }, "other", false},
+		{"empty secret", func() *http.Request { return signed("GET", "/x", "", now) }, "", false},
+		{"unsigned", func() *http.Request { return httptest.NewRequest("GET", "/x", nil) }, secret, false},
+		{"tampered body", func() *http.Request {
+			r := signed("POST", "/x", `{"text":"a"}`, now)
+			r.Body = io.NopCloser(strings.NewReader(`{"text":"b"}`))
+			return r
+		}, secret, false},
+		{"tampered path", func() *http.Request {
+			r := signed("POST", "/admin/users/u1/disconnect", "", now)
+			r.URL.Path = "/admin/users/u2/disconnect"
+			return r
+		}, secret, false},
+		{"valid with query", func() *http.Request { return signed("GET", "/channels/chat/presence?offset=0&limit=500", "", now) }, secret, true},
+		{"tampered query", func() *http.Request {
+			r := signed("GET", "/channels/chat/presence?offset=0&limit=10", "", now)
+			r.URL.RawQuery = "offset=0&limit=500"
+			return r
+		}, secret, false},
+		{"tampered nonce", func() *http.Request {
+			r := signed("GET", "/x", "", now)
+			r.Header.Set(NonceHeader, "other")
+			return r
+		}, secret, false},
+		{"missing nonce", func() *http.Request {
+			r := signed("GET", "/x", "", now)
+			r.Header.Del(NonceHeader)
+			return r
+		}, secret, false},
+		{"tampered method", func() *http.Request {
+			r := signed("GET", "/x", "", now)
+			r.Method = "DELETE"
+			return r
+		}, secret, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Verify(tt.req(), tt.secret, now); got != tt.wantOK {
+				t.Errorf("Verify() = %v, want %v", got, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestVerifyRestoresBody(t *testing.T) {
+	body := `{"messages":[{"text":"hi"}]}`
+	r := httptest.NewRequest("POST", "/x", strings.NewReader(body))
+	SignRequest(r, "secret", []byte(body), time.Now())
+
+	if !Verify(r, "secret", time.Now()) {
+		t.Fatal("Verify() = false, want true")
+	}
+	got, _ := io.ReadAll(r.Body)
+	if string(got) != body {
+		t.Errorf("body after Verify() = %q, want %q", got, body)
+	}
+}
+
+func TestVerifyRejectsReplay(t *testing.T) {
+	now := time.Now()
+	first := httptest.NewRequest("POST", "/admin/users/u1/disconnect", nil)
+	SignRequest(first, "secret", nil, now)
+	if !Verify(first, "secret", now) {
+		t.Fatal("first Verify() = false, want true")
+	}
+
+	replayed := httptest.NewRequest("POST", "/admin/users/u1/disconnect", nil)
+	replayed.Header = first.Header.Clone()
+	if Verify(replayed, "secret", now.Add(time.Minute)) {
+		t.Error("replayed Verify() = true, want false")
+	}
+
+	// Signing the same request again uses a new nonce
+	again := httptest.NewRequest("POST", "/admin/users/u1/disconnect", nil)
+	SignRequest(again, "secret", nil, now)
+	if !Verify(again, "secret", now) {
+		t.Error("re-signed Verify() = false, want true")
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	handler := Middleware("secret")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	unsigned := httptest.NewRecorder()
+	handler.ServeHTTP(unsigned, httptest.NewRequest("POST", "/x", nil))
+	if unsigned.Code != http.StatusUnauthorized {
+		t.Errorf("unsigned status = %d, want 401", unsigned.Code)
+	}
+
+	r := httptest.NewRequest("POST", "/x", nil)
+	SignRequest(r, "secret", nil, time.Now())
+	signed := httptest.NewRecorder()
+	handler.ServeHTTP(signed, r)
+	if signed.Code != http.StatusNoContent {
+		t.Errorf("signed status = %d, want 204", signed.Code)
+	}
+
+	replayed := httptest.NewRecorder()
+	handler.ServeHTTP(replayed, r)
+	if replayed.Code != http.StatusUnauthorized {
+		t.Errorf("replayed status = %d, want 401", replayed.Code)
+	}
+}
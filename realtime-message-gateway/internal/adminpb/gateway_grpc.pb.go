@@ -0,0 +1,289 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v5.29.3
+// source: gateway.proto
+
+package adminpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	GatewayAdmin_DisconnectUser_FullMethodName    = "/gateway.admin.v1.GatewayAdmin/DisconnectUser"
+	GatewayAdmin_PublishMessage_FullMethodName    = "/gateway.admin.v1.GatewayAdmin/PublishMessage"
+	GatewayAdmin_GetPresence_FullMethodName       = "/gateway.admin.v1.GatewayAdmin/GetPresence"
+	GatewayAdmin_GetWorkerLoad_FullMethodName     = "/gateway.admin.v1.GatewayAdmin/GetWorkerLoad"
+	GatewayAdmin_GetChannelHistory_FullMethodName = "/gateway.admin.v1.GatewayAdmin/GetChannelHistory"
+)
+
+// GatewayAdminClient is the client API for GatewayAdmin service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// GatewayAdmin exposes management operations of a gateway instance.
+// Every call must carry the admin secret as "authorization: Bearer <secret>" metadata.
+type GatewayAdminClient interface {
+	// DisconnectUser disconnects all connections of a user on this gateway
+	DisconnectUser(ctx context.Context, in *DisconnectUserRequest, opts ...grpc.CallOption) (*DisconnectUserResponse, error)
+	// PublishMessage routes a message to the channel's worker and broadcasts it
+	PublishMessage(ctx context.Context, in *PublishMessageRequest, opts ...grpc.CallOption) (*PublishMessageResponse, error)
+	// GetPresence returns the users currently subscribed to a channel
+	GetPresence(ctx context.Context, in *GetPresenceRequest, opts ...grpc.CallOption) (*GetPresenceResponse, error)
+	// GetWorkerLoad returns active workers with their stream backlog
+	GetWorkerLoad(ctx context.Context, in *GetWorkerLoadRequest, opts ...grpc.CallOption) (*GetWorkerLoadResponse, error)
+	// GetChannelHistory returns the most recent messages routed for a channel
+	GetChannelHistory(ctx context.Context, in *GetChannelHistoryRequest, opts ...grpc.CallOption) (*GetChannelHistoryResponse, error)
+}
+
+type gatewayAdminClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewGatewayAdminClient(cc grpc.ClientConnInterface) GatewayAdminClient {
+	return &gatewayAdminClient{cc}
+}
+
+func (c *gatewayAdminClient) DisconnectUser(ctx context.Context, in *DisconnectUserRequest, opts ...grpc.CallOption) (*DisconnectUserResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DisconnectUserResponse)
+	err := c.cc.Invoke(ctx, GatewayAdmin_DisconnectUser_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gatewayAdminClient) PublishMessage(ctx context.Context, in *PublishMessageRequest, opts ...grpc.CallOption) (*PublishMessageResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PublishMessageResponse)
+	err := c.cc.Invoke(ctx, GatewayAdmin_PublishMessage_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gatewayAdminClient) GetPresence(ctx context.Context, in *GetPresenceRequest, opts ...grpc.CallOption) (*GetPresenceResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetPresenceResponse)
+	err := c.cc.Invoke(ctx, GatewayAdmin_GetPresence_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gatewayAdminClient) GetWorkerLoad(ctx context.Context, in *GetWorkerLoadRequest, opts ...grpc.CallOption) (*GetWorkerLoadResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetWorkerLoadResponse)
+	err := c.cc.Invoke(ctx, GatewayAdmin_GetWorkerLoad_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gatewayAdminClient) GetChannelHistory(ctx context.Context, in *GetChannelHistoryRequest, opts ...grpc.CallOption) (*GetChannelHistoryResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetChannelHistoryResponse)
+	err := c.cc.Invoke(ctx, GatewayAdmin_GetChannelHistory_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GatewayAdminServer is the server API for GatewayAdmin service.
+// All implementations must embed UnimplementedGatewayAdminServer
+// for forward compatibility.
+//
+// GatewayAdmin exposes management operations of a gateway instance.
+// Every call must carry the admin secret as "authorization: Bearer <secret>" metadata.
+type GatewayAdminServer interface {
+	// DisconnectUser disconnects all connections of a user on this gateway
+	DisconnectUser(context.Context, *DisconnectUserRequest) (*DisconnectUserResponse, error)
+	// PublishMessage routes a message to the channel's worker and broadcasts it
+	PublishMessage(context.Context, *PublishMessageRequest) (*PublishMessageResponse, error)
+	// GetPresence returns the users currently subscribed to a channel
+	GetPresence(context.Context, *GetPresenceRequest) (*GetPresenceResponse, error)
+	// GetWorkerLoad returns active workers with their stream backlog
+	GetWorkerLoad(context.Context, *GetWorkerLoadRequest) (*GetWorkerLoadResponse, error)
+	// GetChannelHistory returns the most recent messages routed for a channel
+	GetChannelHistory(context.Context, *GetChannelHistoryRequest) (*GetChannelHistoryResponse, error)
+	mustEmbedUnimplementedGatewayAdminServer()
+}
+
+// UnimplementedGatewayAdminServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedGatewayAdminServer struct{}
+
+func (UnimplementedGatewayAdminServer) DisconnectUser(context.Context, *DisconnectUserRequest) (*DisconnectUserResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DisconnectUser not implemented")
+}
+func (UnimplementedGatewayAdminServer) PublishMessage(context.Context, *PublishMessageRequest) (*PublishMessageResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PublishMessage not implemented")
+}
+func (UnimplementedGatewayAdminServer) GetPresence(context.Context, *GetPresenceRequest) (*GetPresenceResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPresence not implemented")
+}
+func (UnimplementedGatewayAdminServer) GetWorkerLoad(context.Context, *GetWorkerLoadRequest) (*GetWorkerLoadResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetWorkerLoad not implemented")
+}
+func (UnimplementedGatewayAdminServer) GetChannelHistory(context.Context, *GetChannelHistoryRequest) (*GetChannelHistoryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetChannelHistory not implemented")
+}
+func (UnimplementedGatewayAdminServer) mustEmbedUnimplementedGatewayAdminServer() {}
+func (UnimplementedGatewayAdminServer) testEmbeddedByValue()                      {}
+
+// UnsafeGatewayAdminServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to GatewayAdminServer will
+// result in compilation errors.
+type UnsafeGatewayAdminServer interface {
+	mustEmbedUnimplementedGatewayAdminServer()
+}
+
+func RegisterGatewayAdminServer(s grpc.ServiceRegistrar, srv GatewayAdminServer) {
+	// If the following call pancis, it indicates UnimplementedGatewayAdminServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&GatewayAdmin_ServiceDesc, srv)
+}
+
+func _GatewayAdmin_DisconnectUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DisconnectUserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GatewayAdminServer).DisconnectUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GatewayAdmin_DisconnectUser_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GatewayAdminServer).DisconnectUser(ctx, req.(*DisconnectUserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GatewayAdmin_PublishMessage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PublishMessageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GatewayAdminServer).PublishMessage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GatewayAdmin_PublishMessage_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GatewayAdminServer).PublishMessage(ctx, req.(*PublishMessageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GatewayAdmin_GetPresence_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetPresenceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GatewayAdminServer).GetPresence(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GatewayAdmin_GetPresence_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GatewayAdminServer).GetPresence(ctx, req.(*GetPresenceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GatewayAdmin_GetWorkerLoad_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetWorkerLoadRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GatewayAdminServer).GetWorkerLoad(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GatewayAdmin_GetWorkerLoad_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GatewayAdminServer).GetWorkerLoad(ctx, req.(*GetWorkerLoadRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GatewayAdmin_GetChannelHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetChannelHistoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GatewayAdminServer).GetChannelHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GatewayAdmin_GetChannelHistory_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GatewayAdminServer).GetChannelHistory(ctx, req.(*GetChannelHistoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// GatewayAdmin_ServiceDesc is the grpc.ServiceDesc for GatewayAdmin service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var GatewayAdmin_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "gateway.admin.v1.GatewayAdmin",
+	HandlerType: (*GatewayAdminServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "DisconnectUser",
+			Handler:    _GatewayAdmin_DisconnectUser_Handler,
+		},
+		{
+			MethodName: "PublishMessage",
+			Handler:    _GatewayAdmin_PublishMessage_Handler,
+		},
+		{
+			MethodName: "GetPresence",
+			Handler:    _GatewayAdmin_GetPresence_Handler,
+		},
+		{
+			MethodName: "GetWorkerLoad",
+			Handler:    _GatewayAdmin_GetWorkerLoad_Handler,
+		},
+		{
+			MethodName: "GetChannelHistory",
+			Handler:    _GatewayAdmin_GetChannelHistory_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "gateway.proto",
+}
@@ -0,0 +1,210 @@
+// Package client is a Go SDK for application backends calling the
+// gateway's HTTP API.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"realtime-message-gateway/internal/adminauth"
+)
+
+// Defaults for retries
+const (
+	DefaultMaxRetries   = 3
+	DefaultRetryBackoff = 200 * time.Millisecond
+)
+
+// presencePageSize matches the gateway's maximum presence page size
+const presencePageSize = 500
+
+// roomChannelPrefix must match gateway.RoomChannelPrefix
+const roomChannelPrefix = "chat:room-"
+
+// APIError is returned for non-2xx and 207 Multi-Status responses
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("gateway API error %d: %s", e.StatusCode, e.Message)
+}
+
+// PresenceInfo represents a user in a channel
+type PresenceInfo struct {
+	UserID   string `json:"userId"`
+	UserName string `json:"userName"`
+	ClientID string `json:"clientId"`
+}
+
+// GatewayClient calls the gateway HTTP API, signing every request with the
+// admin secret. Requests failing with a network error or a 429, 502, 503 or
+// 504 response are retried up to MaxRetries times with exponential backoff
+// starting at RetryBackoff.
+type GatewayClient struct {
+	baseURL      string
+	secret       string
+	httpClient   *http.Client
+	MaxRetries   int
+	RetryBackoff time.Duration
+}
+
+// Option configures optional GatewayClient behavior
+type Option func(*GatewayClient)
+
+// WithHTTPClient replaces http.DefaultClient
+func WithHTTPClient(c *http.Client) Option {
+	return func(gc *GatewayClient) {
+		gc.httpClient = c
+	}
+}
+
+// WithRetries sets the retry count and initial backoff
+func WithRetries(maxRetries int, backoff time.Duration) Option {
+	return func(gc *GatewayClient) {
+		gc.MaxRetries = maxRetries
+		gc.RetryBackoff = backoff
+	}
+}
+
+// NewGatewayClient creates a client for the HTTP API at baseURL, e.g.
+// http://gateway:3000
+func NewGatewayClient(baseURL, adminSecret string, opts ...Option) *GatewayClient {
+	c := &GatewayClient{
+		baseURL:      strings.TrimSuffix(baseURL, "/"),
+		secret:       adminSecret,
+		httpClient:   http.DefaultClient,
+		MaxRetries:   DefaultMaxRetries,
+		RetryBackoff: DefaultRetryBackoff,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Publish routes a message to the channel's worker and broadcasts it
+func (c *GatewayClient) Publish(ctx context.Context, channel, text string) error {
+	body := map[string]interface{}{
+		"messages": []map[string]string{{"text": text}},
+	}
+	return c.do(ctx, http.MethodPost, "/channels/"+url.PathEscape(channel)+"/publish/batch", nil, body, nil)
+}
+
+// BroadcastRoom publishes a message to a room's channel
+func (c *GatewayClient) BroadcastRoom(ctx context.Context, roomID, text string) error {
+	return c.Publish(ctx, roomChannelPrefix+roomID, text)
+}
+
+// DisconnectUser disconnects all connections of a user on the gateway
+// without reconnect
+func (c *GatewayClient) DisconnectUser(ctx context.Context, userID string) error {
+	return c.do(ctx, http.MethodPost, "/admin/users/"+url.PathEscape(userID)+"/disconnect", nil, nil, nil)
+}
+
+// GetPresence returns all users subscribed to a channel, fetching as many
+// pages as needed
+func (c *GatewayClient) GetPresence(ctx context.Context, channel string) ([]PresenceInfo, error) {
+	var users []PresenceInfo
+	for {
+		query := url.Values{
+			"offset": {strconv.Itoa(len(users))},
+			"limit":  {strconv.Itoa(presencePageSize)},
+		}
+		var page struct {
+			Users []PresenceInfo `json:"users"`
+			Total int            `json:"total"`
+		}
+		if err := c.do(ctx, http.MethodGet, "/channels/"+url.PathEscape(channel)+"/presence", query, nil, &page); err != nil {
+			return nil, err
+		}
+		users = append(users, page.Users...)
+		if len(page.Users) == 0 || len(users) >= page.Total {
+			return users, nil
+		}
+	}
+}
+
+// do sends a signed request, retrying transient failures, and decodes the
+// JSON response into out when it is not nil
+func (c *GatewayClient) do(ctx context.Context, method, path string, query url.Values, in, out interface{}) error {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return err
+		}
+	}
+
+	var err error
+	for attempt := 0; ; attempt++ {
+		var retry bool
+		retry, err = c.attempt(ctx, method, path, query, body, out)
+		if !retry || attempt >= c.MaxRetries {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(c.RetryBackoff << attempt):
+		}
+	}
+}
+
+// attempt sends a request once and reports whether a failure is retryable
+func (c *GatewayClient) attempt(ctx context.Context, method, path string, query url.Values, body []byte, out interface{}) (bool, error) {
+	u := c.baseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	adminauth.SignRequest(req, c.secret, body, time.Now())
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, err
+	}
+	defer resp.Body.Close()
+
+	// 207 is a partially failed batch publish
+	if resp.StatusCode < 200 || resp.StatusCode > 299 || resp.StatusCode == http.StatusMultiStatus {
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		data, _ := io.ReadAll(resp.Body)
+		if json.Unmarshal(data, &apiErr) != nil || apiErr.Error == "" {
+			apiErr.Error = http.StatusText(resp.StatusCode)
+		}
+		return isRetryableStatus(resp.StatusCode), &APIError{StatusCode: resp.StatusCode, Message: apiErr.Error}
+	}
+
+	if out != nil {
+		return false, json.NewDecoder(resp.Body).Decode(out)
+	}
+	return false, nil
+}
+
+// isRetryableStatus reports whether a response status is transient
+func isRetryableStatus(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
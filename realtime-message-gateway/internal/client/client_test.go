@@ -0,0 +1,206 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"realtime-message-gateway/internal/adminauth"
+)
+
+const testSecret = "admin-secret"
+
+// fakeGateway mimics the gateway HTTP API, recording the last publish and
+// disconnect
+type fakeGateway struct {
+	presence      []PresenceInfo
+	published     []string // channel + "|" + text
+	disconnected  []string
+	failures      atomic.Int32 // respond 503 this many times first
+	requests      atomic.Int32
+	publishStatus int
+}
+
+func (f *fakeGateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.requests.Add(1)
+	if !adminauth.Verify(r, testSecret, time.Now()) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error":"invalid signature"}`))
+		return
+	}
+	if f.failures.Add(-1) >= 0 {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	path := r.URL.Path
+	switch {
+	case r.Method == http.MethodPost && strings.HasSuffix(path, "/publish/batch"):
+		var req struct {
+			Messages []struct {
+				Text string `json:"text"`
+			} `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		channel := strings.TrimSuffix(strings.TrimPrefix(path, "/channels/"), "/publish/batch")
+		for _, m := range req.Messages {
+			f.published = append(f.published, channel+"|"+m.Text)
+		}
+		if f.publishStatus != 0 {
+			w.WriteHeader(f.publishStatus)
+		}
+		w.Write([]byte(`{"ids":["id-1"]}`))
+	case r.Method == http.MethodPost && strings.HasPrefix(path, "/admin/users/"):
+		f.disconnected = append(f.disconnected, strings.TrimSuffix(strings.TrimPrefix(path, "/admin/users/"), "/disconnect"))
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodGet && strings.HasSuffix(path, "/presence"):
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		end := min(offset+limit, len(f.presence))
+		offset = min(offset, end)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"users": f.presence[offset:end],
+			"total": len(f.presence),
+		})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":"not found"}`))
+	}
+}
+
+func newTestClient(t *testing.T, fake *fakeGateway, secret string) *GatewayClient {
+	t.Helper()
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	return NewGatewayClient(server.URL+"/", secret, WithRetries(2, time.Millisecond))
+}
+
+func TestPublishAndBroadcastRoom(t *testing.T) {
+	fake := &fakeGateway{}
+	c := newTestClient(t, fake, testSecret)
+
+	if err := c.Publish(context.Background(), "chat", "hello"); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	if err := c.BroadcastRoom(context.Background(), "abc", "hi room"); err != nil {
+		t.Fatalf("BroadcastRoom() error = %v", err)
+	}
+
+	want := []string{"chat|hello", "chat:room-abc|hi room"}
+	if fmt.Sprint(fake.published) != fmt.Sprint(want) {
+		t.Errorf("published = %v, want %v", fake.published, want)
+	}
+}
+
+func TestPublishPartialFailure(t *testing.T) {
+	fake := &fakeGateway{publishStatus: http.StatusMultiStatus}
+	c := newTestClient(t, fake, testSecret)
+
+	var apiErr *APIError
+	if err := c.Publish(context.Background(), "chat", "hello"); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusMultiStatus {
+		t.Errorf("Publish() error = %v, want 207 APIError", err)
+	}
+}
+
+func TestDisconnectUser(t *testing.T) {
+	fake := &fakeGateway{}
+	c := newTestClient(t, fake, testSecret)
+
+	if err := c.DisconnectUser(context.Background(), "user-1"); err != nil {
+		t.Fatalf("DisconnectUser() error = %v", err)
+	}
+	if len(fake.disconnected) != 1 || fake.disconnected[0] != "user-1" {
+		t.Errorf("disconnected = %v, want [user-1]", fake.disconnected)
+	}
+}
+
+func TestGetPresencePaginates(t *testing.T) {
+	fake := &fakeGateway{}
+	for i := 0; i < 1200; i++ {
+		fake.presence = append(fake.presence, PresenceInfo{ClientID: fmt.Sprintf("client-%04d", i)})
+	}
+	c := newTestClient(t, fake, testSecret)
+
+	users, err := c.GetPresence(context.Background(), "chat")
+	if err != nil {
+		t.Fatalf("GetPresence() error = %v", err)
+	}
+	if len(users) != 1200 {
+		t.Fatalf("len(users) = %d, want 1200", len(users))
+	}
+	if users[1199].ClientID != "client-1199" {
+		t.Errorf("last user = %q, want client-1199", users[1199].ClientID)
+	}
+	if got := fake.requests.Load(); got != 3 {
+		t.Errorf("requests = %d, want 3 pages", got)
+	}
+}
+
+func TestRetries(t *testing.T) {
+	// fakeGateway verifies every attempt, so each retry must be signed anew
+	tests := []struct {
+		name         string
+		failures     int32
+		wantErr      bool
+		wantRequests int32
+	}{
+		{"succeeds first try", 0, false, 1},
+		{"recovers after retries", 2, false, 3},
+		{"gives up after max retries", 3, true, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeGateway{}
+			fake.failures.Store(tt.failures)
+			c := newTestClient(t, fake, testSecret)
+
+			err := c.DisconnectUser(context.Background(), "user-1")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("DisconnectUser() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := fake.requests.Load(); got != tt.wantRequests {
+				t.Errorf("requests = %d, want %d", got, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestNoRetryOnClientError(t *testing.T) {
+	fake := &fakeGateway{}
+	c := newTestClient(t, fake, "wrong-secret")
+
+	err := c.DisconnectUser(context.Background(), "user-1")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("DisconnectUser() error = %v, want 401 APIError", err)
+	}
+	if apiErr.Message != "invalid signature" {
+		t.Errorf("Message = %q, want %q", apiErr.Message, "invalid signature")
+	}
+	if got := fake.requests.Load(); got != 1 {
+		t.Errorf("requests = %d, want 1 (no retry)", got)
+	}
+}
+
+func TestRetryStopsOnContextCancel(t *testing.T) {
+	fake := &fakeGateway{}
+	fake.failures.Store(10)
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	c := NewGatewayClient(server.URL, testSecret, WithRetries(5, time.Hour))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := c.DisconnectUser(ctx, "user-1"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("DisconnectUser() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
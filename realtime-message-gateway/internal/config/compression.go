@@ -0,0 +1,56 @@
+package config
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Valid WebSocket compression levels (flate.HuffmanOnly to flate.BestCompression)
+const (
+	MinCompressionLevel = -2
+	MaxCompressionLevel = 9
+)
+
+// CompressionPolicy decides WebSocket compression for an upgrade request.
+// minSize is the smallest message in bytes that gets compressed.
+type CompressionPolicy func(r *http.Request) (enabled bool, level int, minSize int)
+
+// DefaultCompressionPolicy compresses with level and minSize unless the
+// client's Accept-Encoding only accepts identity
+func DefaultCompressionPolicy(level, minSize int) CompressionPolicy {
+	return func(r *http.Request) (bool, int, int) {
+		if identityOnly(r.Header.Get("Accept-Encoding")) {
+			return false, 0, 0
+		}
+		return true, level, minSize
+	}
+}
+
+// identityOnly reports whether an Accept-Encoding header rules out every
+// coding except identity. An empty header expresses no preference.
+func identityOnly(header string) bool {
+	if strings.TrimSpace(header) == "" {
+		return false
+	}
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(part, ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding == "" || coding == "identity" || isZeroQuality(params) {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+// isZeroQuality reports whether Accept-Encoding params carry q=0
+func isZeroQuality(params string) bool {
+	for _, param := range strings.Split(params, ";") {
+		key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+		if strings.EqualFold(key, "q") {
+			value = strings.TrimRight(strings.TrimSpace(value), "0")
+			return value == "" || value == "0." || value == "."
+		}
+	}
+	return false
+}
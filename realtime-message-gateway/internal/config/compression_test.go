@@ -0,0 +1,44 @@
+package config
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDefaultCompressionPolicy(t *testing.T) {
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		wantEnabled    bool
+	}{
+		{"no header", "", true},
+		{"identity", "identity", false},
+		{"identity uppercase", "IDENTITY", false},
+		{"identity with quality", "identity;q=1", false},
+		{"gzip and deflate", "gzip, deflate", true},
+		{"identity and deflate", "identity, deflate", true},
+		{"deflate refused", "identity, deflate;q=0", false},
+		{"deflate refused with decimals", "deflate;q=0.000, identity", false},
+		{"deflate low quality", "deflate;q=0.1, identity", true},
+		{"wildcard", "*", true},
+	}
+
+	policy := DefaultCompressionPolicy(6, 512)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/connection/websocket", nil)
+			if tt.acceptEncoding != "" {
+				r.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+
+			enabled, level, minSize := policy(r)
+			if enabled != tt.wantEnabled {
+				t.Fatalf("enabled = %v, want %v", enabled, tt.wantEnabled)
+			}
+			if enabled && (level != 6 || minSize != 512) {
+				t.Errorf("level, minSize = %d, %d, want 6, 512", level, minSize)
+			}
+		})
+	}
+}
@@ -1,16 +1,76 @@
 package config
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
 	"os"
+	"path"
+	"regexp"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 )
 
+// ReconnectPolicy is the client reconnect backoff sent on planned disconnects
+type ReconnectPolicy struct {
+	InitialDelay time.Duration
+	MaxDelay     time.Duration
+	Multiplier   float64
+}
+
+// NamespaceConfig configures the channels of a namespace, the part of a
+// channel name before its first colon
+type NamespaceConfig struct {
+	Name string `json:"name"`
+	// Subscribing requires a subscription token, as for private channels
+	Protected bool `json:"protected"`
+	// Push join and leave events to the channel's subscribers (nil = true)
+	PushJoinLeave *bool `json:"pushJoinLeave"`
+	// Subscriber limit per channel, replacing MaxSubscribersPerChannel
+	// (0 = use MaxSubscribersPerChannel)
+	MaxSubscribers int `json:"maxSubscribers"`
+}
+
+// PushesJoinLeave reports whether join and leave events are pushed to the
+// subscribers of the namespace's channels, which they are unless disabled
+func (ns NamespaceConfig) PushesJoinLeave() bool {
+	return ns.PushJoinLeave == nil || *ns.PushJoinLeave
+}
+
 type Config struct {
+	// Instance
+	InstanceID string
+
 	// Server ports
 	WebSocketPort int
 	HTTPPort      int
 	MetricsPort   int
+	GRPCPort      int
+
+	// Readiness probe ignores Redis failures this long after startup
+	ReadinessGracePeriod time.Duration
+
+	// /health/stream reports every HealthStreamInterval to at most
+	// HealthStreamMaxClients clients and sends a warning event when the
+	// Redis ping exceeds HealthWarnThreshold (0 = never)
+	HealthStreamInterval   time.Duration
+	HealthStreamMaxClients int
+	HealthWarnThreshold    time.Duration
+
+	// /metrics/stream sends a snapshot every MetricsStreamInterval to at most
+	// MetricsStreamMaxClients consumers at a time
+	MetricsStreamInterval   time.Duration
+	MetricsStreamMaxClients int
+
+	// Graceful shutdown timeout of each server; the WebSocket timeout also
+	// bounds closing the Centrifuge node
+	ShutdownTimeoutWS      time.Duration
+	ShutdownTimeoutHTTP    time.Duration
+	ShutdownTimeoutMetrics time.Duration
 
 	// Redis
 	RedisURL         string
@@ -18,32 +78,261 @@ type Config struct {
 	RedisMinIdle     int
 	RedisMaxRetries  int
 	RedisDialTimeout time.Duration
+	// Cluster node addresses; when set, REDIS_URL only supplies credentials
+	RedisClusterAddrs []string
+	// Redis serialization protocol version, 2 or 3
+	RedisProtocol int
+
+	// Retries for stream writes on the publish path
+	RedisMaxPublishRetries int
+	DeadLetterEnabled      bool
+	// Deadline of the Redis operations of a client subscribe, publish or
+	// disconnect, which are also cancelled when the client disconnects
+	PublishContextTimeout time.Duration
+
+	// Per-client publish queue absorbing short Redis outages
+	ClientQueueDepth         int
+	ClientQueueFlushInterval time.Duration
 
 	// JWT
 	TokenHMACSecret string
 
+	// Admin API
+	AdminSecret string
+
+	// HMAC key of the signature of published messages (empty = unsigned)
+	MessageSigningKey string
+
+	// Presence webhook: join/leave events written to a worker stream are
+	// also POSTed to WebhookURL, signed with WebhookSecret
+	WebhookURL       string
+	WebhookSecret    string
+	WebhookWorkers   int
+	WebhookQueueSize int
+
+	// Defer leave events of clients that disconnect in a reconnectable way
+	// and drop them with the join of the reconnect of the same user ID;
+	// anonymous clients get a new ID per connection and are not covered
+	SuppressJoinLeaveForReconnect bool
+
+	// CORS for the HTTP API
+	HTTPAllowedOrigins []string
+	HTTPAllowedMethods []string
+	// Largest request body the HTTP API accepts, in bytes
+	HTTPMaxBodySize int64
+
+	// Serve HTTP/2 cleartext (h2c) on the HTTP API and metrics servers
+	H2Enabled bool
+
+	// Channels
+	PrivateChannelPrefix string
+
 	// Routing
-	RouteCacheTTL time.Duration
+	RouteCacheTTL   time.Duration
+	ChannelRouteTTL time.Duration // channel:route: key expiration, reset by publishes (0 = none)
+	Region          string
+	// How new channels pick a worker: round-robin or least-channels
+	WorkerSelectionStrategy string
+	// How often workers:channel_count is recounted from the channel routes (0 = never)
+	ChannelCountReconcileInterval time.Duration
+	// Invalidate cached routes when their channel:route key is deleted or
+	// expires; requires notify-keyspace-events to include Egx
+	KeyspaceNotificationsEnabled bool
+
+	// Stream message schema version written by this gateway
+	StreamSchemaVersion int
+
+	// Approximate max entries kept per stream
+	StreamMaxLen int
+
+	// Worker stream backlog monitor (active when StreamMaxLen > 0)
+	StreamBacklogCheckInterval time.Duration
+	WorkerCooldownDuration     time.Duration
+
+	// Removal of workers whose workers:active heartbeat score is older than
+	// this (disabled when 0)
+	WorkerHeartbeatTimeout time.Duration
+
+	// Entries in a worker stream above which the health report marks the
+	// stream_backlog component degraded (disabled when 0)
+	StreamLengthWarnThreshold int
+
+	// Consumer group lag monitor of worker streams (disabled when the interval is 0)
+	StreamLagPollInterval  time.Duration
+	StreamLagWarnThreshold int
+
+	// Pace of operator replays of worker stream ranges
+	ReplayMaxMessagesPerSecond int
+
+	// Recovery of worker stream entries left unacknowledged by a crashed
+	// worker (disabled when the interval is 0)
+	StaleClaimInterval  time.Duration
+	StaleMessageMinIdle time.Duration
+	// Deliveries after which a stale entry is dead-lettered instead of re-added
+	StaleMessageMaxDeliveries int
+
+	// Channel stats gauge export interval
+	ChannelStatsInterval time.Duration
+
+	// Local channel subscriber distribution sample interval
+	ChannelStatsSampleInterval time.Duration
+
+	// How long GET /channels reuses its snapshot of local channels
+	ChannelListCacheTTL time.Duration
+
+	// Outbound stream (worker -> gateway)
+	OutboundStreamBlock time.Duration
+
+	// Consumer group on worker streams and the outbound stream
+	ConsumerGroupName string
 
 	// Message limits
 	MaxTextLength int
+	// Content types clients may set with content_type on publish
+	AllowedContentTypes []string
+	// Limits of the meta object clients may attach on publish
+	MaxMetaKeys     int
+	MaxMetaValueLen int // Bytes
+	// Reject a user's message that repeats their last text in the channel
+	// within this window (0 = disabled)
+	DuplicateTextWindow time.Duration
+	// Reject a user's message that repeats any of their messages in the
+	// channel since the last BloomResetInterval. A local Bloom filter sized
+	// for BloomFilterCapacity messages at BloomFalsePositiveRate saves the
+	// Redis lookup of new messages (0 = disabled).
+	BloomFilterCapacity    int
+	BloomFalsePositiveRate float64
+	BloomResetInterval     time.Duration
+	// Publishes a user may make per RateLimitWindowSeconds (0 = unlimited).
+	// RateLimiterBackend "local" counts them per gateway instance, "redis"
+	// across all instances sharing Redis.
+	PublishRateLimit       int
+	RateLimitWindowSeconds int
+	RateLimiterBackend     string
+	// File of blocked phrases, one per line; empty disables the content filter
+	ContentFilterFile string
+
+	// Channel limits
+	MaxSubscribersPerChannel  int
+	PresenceCacheTTL          time.Duration // How long a channel's subscriber count is reused for the limit
+	MaxSubscriptionsPerClient int
+	ChannelMetadataMaxSize    int // Bytes of JSON accepted by PATCH /channels/{channel}/metadata
+	// Regexes a channel name must match to be subscribed; empty keeps the
+	// built-in chat, chat:* and user:* channels
+	ChannelPatterns []string
+	// Channel namespaces, from a JSON array; with StrictNamespaceMode,
+	// channels in namespaces not listed here are rejected
+	Namespaces          []NamespaceConfig
+	namespacesErr       error
+	StrictNamespaceMode bool
+
+	// echo:{clientId} channels publish messages straight back to their
+	// subscribers, bypassing workers and Redis, for connectivity checks
+	EchoEnabled bool
+
+	// Published messages kept per channel in its channel:history: list,
+	// which expires HistoryTTL after the last publish (0 = never)
+	HistoryRetain int
+	HistoryTTL    time.Duration
+	// Messages kept per user in their user:history: list for moderation,
+	// expiring like channel history (0 = disabled)
+	UserHistoryRetain int
+	// Channels whose decoded history is cached locally (0 = no cache)
+	HistoryCacheSize int
+
+	// Missed message replay on resubscribe, for channels matching a path.Match pattern
+	RecoverChannels     []string
+	RecoverHistoryLimit int
+
+	// Outbound messages delivered to clients are appended as newline-delimited
+	// JSON to files in ArchivePath, starting a new file every
+	// ArchiveRotateInterval, to keep them beyond the stream MaxLen
+	ArchiveEnabled        bool
+	ArchivePath           string
+	ArchiveRotateInterval time.Duration
+
+	// Connection limits
+	MaxConnectionsPerIP int
+	MaxConnections      int     // Load shedding is disabled when 0
+	LoadShedThreshold   float64 // Fraction of MaxConnections that starts load shedding
+	// Clients without subscriptions that neither subscribe nor publish for
+	// this long are disconnected with a reconnect code (0 = never)
+	MaxIdleConnectionTime time.Duration
+
+	// Reconnect backoff communicated to clients on planned disconnects
+	ReconnectPolicy ReconnectPolicy
 
 	// WebSocket
-	WriteTimeout      time.Duration
-	PingInterval      time.Duration
-	PongTimeout       time.Duration
-	MessageSizeLimit  int
-	ReadBufferSize    int
-	WriteBufferSize   int
-	AllowedOrigins    []string
+	WriteTimeout     time.Duration
+	PingInterval     time.Duration
+	PongTimeout      time.Duration
+	MessageSizeLimit int
+	ReadBufferSize   int
+	WriteBufferSize  int
+	// How long each client's writer collects messages before writing them
+	// in one frame (at most 16 per frame); 0 writes every message at once
+	WriteFlushInterval time.Duration
+	AllowedOrigins     []string
+	// Client IP networks allowed to open WebSocket connections; empty allows all
+	AllowedIPCIDRs []string
+	// Reverse proxy networks whose X-Forwarded-For / X-Real-IP headers are
+	// trusted; empty uses the connection's remote address only
+	TrustedProxyCIDRs []string
+	// Serve the WebSocket port over TLS when both files are set. The minimum
+	// version ("1.2" or "1.3") and TLS 1.2 cipher suites default to crypto/tls.
+	TLSCertFile     string
+	TLSKeyFile      string
+	TLSMinVersion   string
+	TLSCipherSuites []string
+
+	// Application-level pings measuring client round-trip time
+	AppPingEnabled  bool
+	AppPingInterval time.Duration
+
+	// WebSocket compression, decided per upgrade request by CompressionPolicy
+	CompressionLevel   int
+	CompressionMinSize int
+	CompressionPolicy  CompressionPolicy
+
+	// HTTP fallback transports for networks that block WebSocket upgrades.
+	// Centrifuge replaced SockJS with its own HTTP-streaming/SSE emulation,
+	// which centrifuge-js uses as its fallback; these are served under SockJSURL.
+	SockJSEnabled bool
+	SockJSURL     string
+
+	// OpenTelemetry tracing, exported over OTLP gRPC
+	OTELEnabled     bool
+	OTELEndpoint    string
+	OTELServiceName string
 }
 
 func Load() *Config {
-	return &Config{
+	cfg := &Config{
+		// Instance
+		InstanceID: getEnv("GATEWAY_INSTANCE_ID", ""), // empty = generated on startup
+
 		// Server ports
 		WebSocketPort: getEnvInt("WEBSOCKET_PORT", 8000),
 		HTTPPort:      getEnvInt("HTTP_PORT", 3000),
 		MetricsPort:   getEnvInt("METRICS_PORT", 2112),
+		GRPCPort:      getEnvInt("GRPC_PORT", 9090),
+
+		// Readiness probe
+		ReadinessGracePeriod: getEnvDuration("READINESS_GRACE_PERIOD", 10*time.Second),
+
+		// Health stream
+		HealthStreamInterval:   getEnvDuration("HEALTH_STREAM_INTERVAL", 5*time.Second),
+		HealthStreamMaxClients: getEnvInt("HEALTH_STREAM_MAX_CLIENTS", 5),
+		HealthWarnThreshold:    getEnvDuration("HEALTH_WARN_THRESHOLD", 100*time.Millisecond),
+
+		// Metrics stream
+		MetricsStreamInterval:   getEnvDuration("METRICS_STREAM_INTERVAL", time.Second),
+		MetricsStreamMaxClients: getEnvInt("METRICS_STREAM_MAX_CLIENTS", 5),
+
+		// Graceful shutdown
+		ShutdownTimeoutWS:      getEnvDuration("SHUTDOWN_TIMEOUT_WS", 30*time.Second),
+		ShutdownTimeoutHTTP:    getEnvDuration("SHUTDOWN_TIMEOUT_HTTP", 10*time.Second),
+		ShutdownTimeoutMetrics: getEnvDuration("SHUTDOWN_TIMEOUT_METRICS", 5*time.Second),
 
 		// Redis
 		RedisURL:         getEnv("REDIS_URL", "redis://localhost:6379"),
@@ -52,24 +341,487 @@ func Load() *Config {
 		RedisMaxRetries:  getEnvInt("REDIS_MAX_RETRIES", 3),
 		RedisDialTimeout: getEnvDuration("REDIS_DIAL_TIMEOUT", 5*time.Second),
 
+		RedisClusterAddrs: getEnvList("REDIS_CLUSTER_ADDRS", nil),
+		RedisProtocol:     getEnvInt("REDIS_PROTOCOL", 2),
+
+		RedisMaxPublishRetries: getEnvInt("REDIS_MAX_PUBLISH_RETRIES", 3),
+		DeadLetterEnabled:      getEnvBool("DEAD_LETTER_ENABLED", true),
+		PublishContextTimeout:  getEnvDuration("PUBLISH_CONTEXT_TIMEOUT", 5*time.Second),
+
+		// Client publish queue
+		ClientQueueDepth:         getEnvInt("CLIENT_QUEUE_DEPTH", 64), // 0 = disabled
+		ClientQueueFlushInterval: getEnvDuration("CLIENT_QUEUE_FLUSH_INTERVAL", 500*time.Millisecond),
+
 		// JWT
 		TokenHMACSecret: getEnv("CENTRIFUGO_TOKEN_HMAC_SECRET_KEY", ""),
 
+		// Admin API
+		AdminSecret: getEnv("ADMIN_SECRET", ""),
+
+		// Message signing
+		MessageSigningKey: getEnv("MESSAGE_SIGNING_KEY", ""),
+
+		// Presence webhook
+		WebhookURL:       getEnv("WEBHOOK_URL", ""), // empty = disabled
+		WebhookSecret:    getEnv("WEBHOOK_SECRET", ""),
+		WebhookWorkers:   getEnvInt("WEBHOOK_WORKERS", 4),
+		WebhookQueueSize: getEnvInt("WEBHOOK_QUEUE_SIZE", 1000),
+
+		// Presence
+		SuppressJoinLeaveForReconnect: getEnvBool("SUPPRESS_JOIN_LEAVE_FOR_RECONNECT", false),
+
+		// CORS
+		HTTPAllowedOrigins: getEnvList("HTTP_ALLOWED_ORIGINS", nil), // empty = CORS disabled
+		HTTPAllowedMethods: getEnvList("HTTP_ALLOWED_METHODS", []string{"GET", "POST"}),
+		HTTPMaxBodySize:    int64(getEnvInt("HTTP_MAX_BODY_SIZE", 65536)),
+
+		// HTTP/2
+		H2Enabled: getEnvBool("H2_ENABLED", false),
+
+		// Channels
+		PrivateChannelPrefix: getEnv("PRIVATE_CHANNEL_PREFIX", "private:"),
+
 		// Routing
-		RouteCacheTTL: getEnvDuration("ROUTE_CACHE_TTL", 30*time.Second),
+		RouteCacheTTL:   getEnvDuration("ROUTE_CACHE_TTL", 30*time.Second),
+		ChannelRouteTTL: getEnvDuration("CHANNEL_ROUTE_TTL", 24*time.Hour),
+		Region:          getEnv("GATEWAY_REGION", ""), // empty = no region affinity
+
+		WorkerSelectionStrategy:       getEnv("WORKER_SELECTION_STRATEGY", "round-robin"),
+		ChannelCountReconcileInterval: getEnvDuration("CHANNEL_COUNT_RECONCILE_INTERVAL", 5*time.Minute),
+
+		KeyspaceNotificationsEnabled: getEnvBool("KEYSPACE_NOTIFICATIONS_ENABLED", false),
+
+		// Stream message schema
+		StreamSchemaVersion: getEnvInt("STREAM_SCHEMA_VERSION", 1),
+		StreamMaxLen:        getEnvInt("STREAM_MAX_LEN", 0), // 0 = unlimited
+
+		// Stream backlog monitor
+		StreamBacklogCheckInterval: getEnvDuration("STREAM_BACKLOG_CHECK_INTERVAL", 10*time.Second),
+		WorkerCooldownDuration:     getEnvDuration("WORKER_COOLDOWN_DURATION", time.Minute),
+
+		// Worker heartbeat monitor
+		WorkerHeartbeatTimeout: getEnvDuration("WORKER_HEARTBEAT_TIMEOUT", 0),
+
+		// Worker stream length health check
+		StreamLengthWarnThreshold: getEnvInt("STREAM_LENGTH_WARN_THRESHOLD", 10000),
+
+		// Worker stream lag monitor
+		StreamLagPollInterval:  getEnvDuration("STREAM_LAG_POLL_INTERVAL", 10*time.Second),
+		StreamLagWarnThreshold: getEnvInt("STREAM_LAG_WARN_THRESHOLD", 1000),
+
+		// Worker stream replay
+		ReplayMaxMessagesPerSecond: getEnvInt("REPLAY_MAX_MESSAGES_PER_SECOND", 100),
+
+		// Stale worker stream entry recovery
+		StaleClaimInterval:  getEnvDuration("STALE_CLAIM_INTERVAL", time.Minute), // 0 = no recovery
+		StaleMessageMinIdle: getEnvDuration("STALE_MESSAGE_MIN_IDLE", 5*time.Minute),
+
+		StaleMessageMaxDeliveries: getEnvInt("STALE_MESSAGE_MAX_DELIVERIES", 5),
+
+		// Channel stats
+		ChannelStatsInterval: getEnvDuration("CHANNEL_STATS_INTERVAL", 30*time.Second), // 0 = no gauge export
+
+		ChannelStatsSampleInterval: getEnvDuration("CHANNEL_STATS_SAMPLE_INTERVAL", 30*time.Second), // 0 = no sampling
+
+		ChannelListCacheTTL: getEnvDuration("CHANNEL_LIST_CACHE_TTL", 5*time.Second), // 0 = no caching
+
+		// Outbound stream
+		OutboundStreamBlock: time.Duration(getEnvInt("OUTBOUND_STREAM_BLOCK_MS", 1000)) * time.Millisecond,
+
+		// Consumer group
+		ConsumerGroupName: getEnv("CONSUMER_GROUP_NAME", "gw-consumer"),
 
 		// Message limits
-		MaxTextLength: getEnvInt("MAX_TEXT_LENGTH", 5000),
+		MaxTextLength:       getEnvInt("MAX_TEXT_LENGTH", 5000),
+		AllowedContentTypes: getEnvList("ALLOWED_CONTENT_TYPES", []string{"text/plain", "application/json", "application/x-reaction"}),
+		MaxMetaKeys:         getEnvInt("MAX_META_KEYS", 10),
+		MaxMetaValueLen:     getEnvInt("MAX_META_VALUE_LEN", 256),
+		ContentFilterFile:   getEnv("CONTENT_FILTER_FILE", ""),
+		DuplicateTextWindow: getEnvDuration("DUPLICATE_TEXT_WINDOW", 0), // 0 = disabled
+
+		// Duplicate message detection
+		BloomFilterCapacity:    getEnvInt("BLOOM_FILTER_CAPACITY", 0), // 0 = disabled
+		BloomFalsePositiveRate: getEnvFloat("BLOOM_FALSE_POSITIVE_RATE", 0.01),
+		BloomResetInterval:     getEnvDuration("BLOOM_RESET_INTERVAL", time.Minute),
+
+		// Publish rate limit
+		PublishRateLimit:       getEnvInt("PUBLISH_RATE_LIMIT", 0), // 0 = unlimited
+		RateLimitWindowSeconds: getEnvInt("RATE_LIMIT_WINDOW_SECONDS", 1),
+		RateLimiterBackend:     getEnv("RATE_LIMITER_BACKEND", "local"),
+
+		// Channel limits
+		MaxSubscribersPerChannel:  getEnvInt("MAX_SUBSCRIBERS_PER_CHANNEL", 0), // 0 = unlimited
+		PresenceCacheTTL:          getEnvDuration("PRESENCE_CACHE_TTL", 500*time.Millisecond),
+		MaxSubscriptionsPerClient: getEnvInt("MAX_SUBSCRIPTIONS_PER_CLIENT", 100), // 0 = unlimited
+		ChannelMetadataMaxSize:    getEnvInt("CHANNEL_METADATA_MAX_SIZE", 4096),
+		ChannelPatterns:           getEnvList("CHANNEL_PATTERNS", nil),
+		EchoEnabled:               getEnvBool("ECHO_ENABLED", false),
+		StrictNamespaceMode:       getEnvBool("STRICT_NAMESPACE_MODE", false),
+
+		// Channel history
+		HistoryRetain: getEnvInt("HISTORY_RETAIN", 100),
+		HistoryTTL:    getEnvDuration("HISTORY_TTL", 24*time.Hour),
+
+		UserHistoryRetain: getEnvInt("USER_HISTORY_RETAIN", 50), // 0 = disabled
+
+		HistoryCacheSize: getEnvInt("HISTORY_CACHE_SIZE", 100),
+
+		// Message recovery
+		RecoverChannels:     getEnvList("RECOVER_CHANNELS", nil), // empty = recovery disabled
+		RecoverHistoryLimit: getEnvInt("RECOVER_HISTORY_LIMIT", 100),
+
+		// Message archive
+		ArchiveEnabled:        getEnvBool("ARCHIVE_ENABLED", false),
+		ArchivePath:           getEnv("ARCHIVE_PATH", "./archive"),
+		ArchiveRotateInterval: getEnvDuration("ARCHIVE_ROTATE_INTERVAL", time.Hour),
+
+		// Connection limits
+		MaxConnectionsPerIP:   getEnvInt("MAX_CONNECTIONS_PER_IP", 100), // 0 = unlimited
+		MaxConnections:        getEnvInt("MAX_CONNECTIONS", 0),          // 0 = no load shedding
+		LoadShedThreshold:     getEnvFloat("LOAD_SHED_THRESHOLD", 0.9),
+		MaxIdleConnectionTime: getEnvDuration("MAX_IDLE_CONNECTION_TIME", 0), // 0 = disabled
+
+		// Reconnect policy
+		ReconnectPolicy: ReconnectPolicy{
+			InitialDelay: time.Duration(getEnvInt("RECONNECT_INITIAL_DELAY_MS", 500)) * time.Millisecond,
+			MaxDelay:     time.Duration(getEnvInt("RECONNECT_MAX_DELAY_MS", 20000)) * time.Millisecond,
+			Multiplier:   getEnvFloat("RECONNECT_MULTIPLIER", 2),
+		},
 
 		// WebSocket
-		WriteTimeout:     getEnvDuration("WS_WRITE_TIMEOUT", time.Second),
-		PingInterval:     getEnvDuration("WS_PING_INTERVAL", 25*time.Second),
-		PongTimeout:      getEnvDuration("WS_PONG_TIMEOUT", 10*time.Second),
-		MessageSizeLimit: getEnvInt("WS_MESSAGE_SIZE_LIMIT", 65536),
-		ReadBufferSize:   getEnvInt("WS_READ_BUFFER_SIZE", 4096),
-		WriteBufferSize:  getEnvInt("WS_WRITE_BUFFER_SIZE", 4096),
-		AllowedOrigins:   []string{}, // empty = allow all
+		WriteTimeout:       getEnvDuration("WS_WRITE_TIMEOUT", time.Second),
+		PingInterval:       getEnvDuration("WS_PING_INTERVAL", 25*time.Second),
+		PongTimeout:        getEnvDuration("WS_PONG_TIMEOUT", 10*time.Second),
+		MessageSizeLimit:   getEnvInt("WS_MESSAGE_SIZE_LIMIT", 65536),
+		ReadBufferSize:     getEnvInt("WS_READ_BUFFER_SIZE", 4096),
+		WriteBufferSize:    getEnvInt("WS_WRITE_BUFFER_SIZE", 4096),
+		WriteFlushInterval: getEnvDuration("WS_WRITE_FLUSH_INTERVAL", 0),
+		AllowedOrigins:     []string{}, // empty = allow all
+		AllowedIPCIDRs:     getEnvList("ALLOWED_IP_CIDRS", nil),
+		TrustedProxyCIDRs:  getEnvList("TRUSTED_PROXY_CIDRS", nil),
+
+		TLSCertFile:     getEnv("WS_TLS_CERT_FILE", ""), // empty = plain WebSocket
+		TLSKeyFile:      getEnv("WS_TLS_KEY_FILE", ""),
+		TLSMinVersion:   getEnv("WS_TLS_MIN_VERSION", ""),
+		TLSCipherSuites: getEnvList("WS_TLS_CIPHER_SUITES", nil),
+
+		// Application-level ping
+		AppPingEnabled:  getEnvBool("APP_PING_ENABLED", false),
+		AppPingInterval: getEnvDuration("APP_PING_INTERVAL", 30*time.Second),
+
+		// WebSocket compression
+		CompressionLevel:   getEnvInt("WS_COMPRESSION_LEVEL", 4),
+		CompressionMinSize: getEnvInt("WS_COMPRESSION_MIN_SIZE", 1024),
+
+		// HTTP fallback transports
+		SockJSEnabled: getEnvBool("SOCKJS_ENABLED", false),
+		SockJSURL:     getEnv("SOCKJS_URL", "/connection/sockjs"),
+
+		// OpenTelemetry
+		OTELEnabled:     getEnvBool("OTEL_ENABLED", false),
+		OTELEndpoint:    getEnv("OTEL_ENDPOINT", "localhost:4317"),
+		OTELServiceName: getEnv("OTEL_SERVICE_NAME", "realtime-message-gateway"),
+	}
+	cfg.CompressionPolicy = DefaultCompressionPolicy(cfg.CompressionLevel, cfg.CompressionMinSize)
+	cfg.Namespaces, cfg.namespacesErr = parseNamespaces(os.Getenv("CHANNEL_NAMESPACES"))
+
+	return cfg
+}
+
+// Warning is a validation problem that does not prevent startup
+type Warning struct {
+	msg string
+}
+
+func (w *Warning) Error() string {
+	return w.msg
+}
+
+// IsWarning reports whether err is a validation Warning rather than a hard error
+func IsWarning(err error) bool {
+	var w *Warning
+	return errors.As(err, &w)
+}
+
+// Validate checks the configuration for mistakes that defaults cannot fix.
+// Hard errors should abort startup; use IsWarning to tell warnings apart.
+func (c *Config) Validate() []error {
+	var errs []error
+
+	if c.RedisURL == "" && len(c.RedisClusterAddrs) == 0 {
+		errs = append(errs, errors.New("REDIS_URL must not be empty"))
+	}
+	if c.WebSocketPort == c.HTTPPort {
+		errs = append(errs, fmt.Errorf("WEBSOCKET_PORT and HTTP_PORT must differ (both %d)", c.WebSocketPort))
+	}
+	if c.PongTimeout >= c.PingInterval {
+		errs = append(errs, fmt.Errorf("WS_PONG_TIMEOUT (%s) must be less than WS_PING_INTERVAL (%s)", c.PongTimeout, c.PingInterval))
+	}
+	if c.AppPingEnabled && c.AppPingInterval <= 0 {
+		errs = append(errs, fmt.Errorf("APP_PING_INTERVAL must be positive, got %s", c.AppPingInterval))
+	}
+	if c.MaxTextLength <= 0 {
+		errs = append(errs, fmt.Errorf("MAX_TEXT_LENGTH must be positive, got %d", c.MaxTextLength))
+	}
+	if len(c.AllowedContentTypes) == 0 {
+		errs = append(errs, errors.New("ALLOWED_CONTENT_TYPES must not be empty"))
+	}
+	if c.MaxMetaKeys < 0 {
+		errs = append(errs, fmt.Errorf("MAX_META_KEYS must not be negative, got %d", c.MaxMetaKeys))
 	}
+	if c.MaxMetaValueLen <= 0 {
+		errs = append(errs, fmt.Errorf("MAX_META_VALUE_LEN must be positive, got %d", c.MaxMetaValueLen))
+	}
+	if c.DuplicateTextWindow < 0 {
+		errs = append(errs, fmt.Errorf("DUPLICATE_TEXT_WINDOW must not be negative, got %s", c.DuplicateTextWindow))
+	}
+	if c.BloomFilterCapacity < 0 {
+		errs = append(errs, fmt.Errorf("BLOOM_FILTER_CAPACITY must not be negative, got %d", c.BloomFilterCapacity))
+	}
+	if c.BloomFilterCapacity > 0 && (c.BloomFalsePositiveRate <= 0 || c.BloomFalsePositiveRate >= 1) {
+		errs = append(errs, fmt.Errorf("BLOOM_FALSE_POSITIVE_RATE must be between 0 and 1, got %g", c.BloomFalsePositiveRate))
+	}
+	if c.BloomFilterCapacity > 0 && c.BloomResetInterval <= 0 {
+		errs = append(errs, fmt.Errorf("BLOOM_RESET_INTERVAL must be positive, got %s", c.BloomResetInterval))
+	}
+	if c.PublishRateLimit < 0 {
+		errs = append(errs, fmt.Errorf("PUBLISH_RATE_LIMIT must not be negative, got %d", c.PublishRateLimit))
+	}
+	if c.PublishRateLimit > 0 && c.RateLimitWindowSeconds <= 0 {
+		errs = append(errs, fmt.Errorf("RATE_LIMIT_WINDOW_SECONDS must be positive, got %d", c.RateLimitWindowSeconds))
+	}
+	if c.RateLimiterBackend != "local" && c.RateLimiterBackend != "redis" {
+		errs = append(errs, fmt.Errorf("RATE_LIMITER_BACKEND must be local or redis, got %q", c.RateLimiterBackend))
+	}
+	if c.RedisPoolSize < 0 {
+		errs = append(errs, fmt.Errorf("REDIS_POOL_SIZE must not be negative, got %d", c.RedisPoolSize))
+	}
+	if c.RedisMinIdle < 0 {
+		errs = append(errs, fmt.Errorf("REDIS_MIN_IDLE must not be negative, got %d", c.RedisMinIdle))
+	}
+	if c.RedisProtocol != 2 && c.RedisProtocol != 3 {
+		errs = append(errs, fmt.Errorf("REDIS_PROTOCOL must be 2 or 3, got %d", c.RedisProtocol))
+	}
+	if c.CompressionLevel < MinCompressionLevel || c.CompressionLevel > MaxCompressionLevel {
+		errs = append(errs, fmt.Errorf("WS_COMPRESSION_LEVEL must be between %d and %d, got %d", MinCompressionLevel, MaxCompressionLevel, c.CompressionLevel))
+	}
+	if c.CompressionMinSize < 0 {
+		errs = append(errs, fmt.Errorf("WS_COMPRESSION_MIN_SIZE must not be negative, got %d", c.CompressionMinSize))
+	}
+	if c.MaxIdleConnectionTime < 0 {
+		errs = append(errs, fmt.Errorf("MAX_IDLE_CONNECTION_TIME must not be negative, got %s", c.MaxIdleConnectionTime))
+	}
+	if c.WriteFlushInterval < 0 {
+		errs = append(errs, fmt.Errorf("WS_WRITE_FLUSH_INTERVAL must not be negative, got %s", c.WriteFlushInterval))
+	}
+	if c.MaxConnections < 0 {
+		errs = append(errs, fmt.Errorf("MAX_CONNECTIONS must not be negative, got %d", c.MaxConnections))
+	}
+	// Load shedding stops below 80% of MaxConnections, so it must start above that
+	if c.MaxConnections > 0 && (c.LoadShedThreshold <= 0.8 || c.LoadShedThreshold > 1) {
+		errs = append(errs, fmt.Errorf("LOAD_SHED_THRESHOLD must be above 0.8 and at most 1, got %g", c.LoadShedThreshold))
+	}
+	if c.ReconnectPolicy.InitialDelay <= 0 {
+		errs = append(errs, fmt.Errorf("RECONNECT_INITIAL_DELAY_MS must be positive, got %s", c.ReconnectPolicy.InitialDelay))
+	}
+	if c.ReconnectPolicy.MaxDelay < c.ReconnectPolicy.InitialDelay {
+		errs = append(errs, fmt.Errorf("RECONNECT_MAX_DELAY_MS (%s) must not be less than RECONNECT_INITIAL_DELAY_MS (%s)", c.ReconnectPolicy.MaxDelay, c.ReconnectPolicy.InitialDelay))
+	}
+	if c.ReconnectPolicy.Multiplier < 1 {
+		errs = append(errs, fmt.Errorf("RECONNECT_MULTIPLIER must be at least 1, got %g", c.ReconnectPolicy.Multiplier))
+	}
+	if c.ReadinessGracePeriod < 0 {
+		errs = append(errs, fmt.Errorf("READINESS_GRACE_PERIOD must not be negative, got %s", c.ReadinessGracePeriod))
+	}
+	if c.HealthStreamInterval <= 0 {
+		errs = append(errs, fmt.Errorf("HEALTH_STREAM_INTERVAL must be positive, got %s", c.HealthStreamInterval))
+	}
+	if c.HealthStreamMaxClients <= 0 {
+		errs = append(errs, fmt.Errorf("HEALTH_STREAM_MAX_CLIENTS must be positive, got %d", c.HealthStreamMaxClients))
+	}
+	if c.HealthWarnThreshold < 0 {
+		errs = append(errs, fmt.Errorf("HEALTH_WARN_THRESHOLD must not be negative, got %s", c.HealthWarnThreshold))
+	}
+	if c.MetricsStreamInterval <= 0 {
+		errs = append(errs, fmt.Errorf("METRICS_STREAM_INTERVAL must be positive, got %s", c.MetricsStreamInterval))
+	}
+	if c.MetricsStreamMaxClients <= 0 {
+		errs = append(errs, fmt.Errorf("METRICS_STREAM_MAX_CLIENTS must be positive, got %d", c.MetricsStreamMaxClients))
+	}
+	if c.ShutdownTimeoutWS <= 0 {
+		errs = append(errs, fmt.Errorf("SHUTDOWN_TIMEOUT_WS must be positive, got %s", c.ShutdownTimeoutWS))
+	}
+	if c.ShutdownTimeoutHTTP <= 0 {
+		errs = append(errs, fmt.Errorf("SHUTDOWN_TIMEOUT_HTTP must be positive, got %s", c.ShutdownTimeoutHTTP))
+	}
+	if c.ShutdownTimeoutMetrics <= 0 {
+		errs = append(errs, fmt.Errorf("SHUTDOWN_TIMEOUT_METRICS must be positive, got %s", c.ShutdownTimeoutMetrics))
+	}
+	if c.ChannelListCacheTTL < 0 {
+		errs = append(errs, fmt.Errorf("CHANNEL_LIST_CACHE_TTL must not be negative, got %s", c.ChannelListCacheTTL))
+	}
+	if c.ChannelMetadataMaxSize <= 0 {
+		errs = append(errs, fmt.Errorf("CHANNEL_METADATA_MAX_SIZE must be positive, got %d", c.ChannelMetadataMaxSize))
+	}
+	for _, cidr := range c.AllowedIPCIDRs {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			errs = append(errs, fmt.Errorf("ALLOWED_IP_CIDRS entry %q is invalid: %w", cidr, err))
+		}
+	}
+	for _, cidr := range c.TrustedProxyCIDRs {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			errs = append(errs, fmt.Errorf("TRUSTED_PROXY_CIDRS entry %q is invalid: %w", cidr, err))
+		}
+	}
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		errs = append(errs, errors.New("WS_TLS_CERT_FILE and WS_TLS_KEY_FILE must be set together"))
+	}
+	if _, err := c.WebSocketTLSConfig(); err != nil {
+		errs = append(errs, err)
+	}
+	for _, pattern := range c.ChannelPatterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			errs = append(errs, fmt.Errorf("CHANNEL_PATTERNS pattern %q is invalid: %w", pattern, err))
+		}
+	}
+	if c.namespacesErr != nil {
+		errs = append(errs, fmt.Errorf("CHANNEL_NAMESPACES is invalid: %w", c.namespacesErr))
+	}
+	seenNamespaces := make(map[string]bool, len(c.Namespaces))
+	for _, ns := range c.Namespaces {
+		switch {
+		case ns.Name == "" || strings.Contains(ns.Name, ":"):
+			errs = append(errs, fmt.Errorf("CHANNEL_NAMESPACES name must be non-empty without ':', got %q", ns.Name))
+		case seenNamespaces[ns.Name]:
+			errs = append(errs, fmt.Errorf("CHANNEL_NAMESPACES namespace %q is listed twice", ns.Name))
+		}
+		seenNamespaces[ns.Name] = true
+		if ns.MaxSubscribers < 0 {
+			errs = append(errs, fmt.Errorf("CHANNEL_NAMESPACES namespace %q maxSubscribers must not be negative, got %d", ns.Name, ns.MaxSubscribers))
+		}
+	}
+	for _, pattern := range c.RecoverChannels {
+		if _, err := path.Match(pattern, ""); err != nil {
+			errs = append(errs, fmt.Errorf("RECOVER_CHANNELS pattern %q is invalid: %w", pattern, err))
+		}
+	}
+	if c.HistoryRetain <= 0 {
+		errs = append(errs, fmt.Errorf("HISTORY_RETAIN must be positive, got %d", c.HistoryRetain))
+	}
+	if c.HistoryTTL < 0 {
+		errs = append(errs, fmt.Errorf("HISTORY_TTL must not be negative, got %s", c.HistoryTTL))
+	}
+	if c.UserHistoryRetain < 0 {
+		errs = append(errs, fmt.Errorf("USER_HISTORY_RETAIN must not be negative, got %d", c.UserHistoryRetain))
+	}
+	if c.HistoryCacheSize < 0 {
+		errs = append(errs, fmt.Errorf("HISTORY_CACHE_SIZE must not be negative, got %d", c.HistoryCacheSize))
+	}
+	if len(c.RecoverChannels) > 0 && c.RecoverHistoryLimit <= 0 {
+		errs = append(errs, fmt.Errorf("RECOVER_HISTORY_LIMIT must be positive, got %d", c.RecoverHistoryLimit))
+	}
+	if c.ArchiveEnabled {
+		if c.ArchivePath == "" {
+			errs = append(errs, errors.New("ARCHIVE_PATH must not be empty when ARCHIVE_ENABLED is set"))
+		}
+		if c.ArchiveRotateInterval <= 0 {
+			errs = append(errs, fmt.Errorf("ARCHIVE_ROTATE_INTERVAL must be positive, got %s", c.ArchiveRotateInterval))
+		}
+	}
+	if c.WebhookURL != "" {
+		if u, err := url.Parse(c.WebhookURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("WEBHOOK_URL must be an http or https URL, got %q", c.WebhookURL))
+		}
+		if c.WebhookWorkers <= 0 {
+			errs = append(errs, fmt.Errorf("WEBHOOK_WORKERS must be positive, got %d", c.WebhookWorkers))
+		}
+		if c.WebhookQueueSize <= 0 {
+			errs = append(errs, fmt.Errorf("WEBHOOK_QUEUE_SIZE must be positive, got %d", c.WebhookQueueSize))
+		}
+	}
+	if c.OTELEnabled && c.OTELEndpoint == "" {
+		errs = append(errs, errors.New("OTEL_ENDPOINT must not be empty when OTEL_ENABLED is set"))
+	}
+	if c.StreamMaxLen < 0 {
+		errs = append(errs, fmt.Errorf("STREAM_MAX_LEN must not be negative, got %d", c.StreamMaxLen))
+	}
+	if c.ClientQueueDepth < 0 {
+		errs = append(errs, fmt.Errorf("CLIENT_QUEUE_DEPTH must not be negative, got %d", c.ClientQueueDepth))
+	}
+	if c.ClientQueueDepth > 0 && c.ClientQueueFlushInterval <= 0 {
+		errs = append(errs, fmt.Errorf("CLIENT_QUEUE_FLUSH_INTERVAL must be positive, got %s", c.ClientQueueFlushInterval))
+	}
+	if c.StreamMaxLen > 0 && c.StreamBacklogCheckInterval <= 0 {
+		errs = append(errs, fmt.Errorf("STREAM_BACKLOG_CHECK_INTERVAL must be positive, got %s", c.StreamBacklogCheckInterval))
+	}
+	if c.StreamMaxLen > 0 && c.WorkerCooldownDuration <= 0 {
+		errs = append(errs, fmt.Errorf("WORKER_COOLDOWN_DURATION must be positive, got %s", c.WorkerCooldownDuration))
+	}
+	if c.HTTPMaxBodySize <= 0 {
+		errs = append(errs, fmt.Errorf("HTTP_MAX_BODY_SIZE must be positive, got %d", c.HTTPMaxBodySize))
+	}
+	if c.PresenceCacheTTL < 0 {
+		errs = append(errs, fmt.Errorf("PRESENCE_CACHE_TTL must not be negative, got %s", c.PresenceCacheTTL))
+	}
+	if c.WorkerHeartbeatTimeout < 0 {
+		errs = append(errs, fmt.Errorf("WORKER_HEARTBEAT_TIMEOUT must not be negative, got %s", c.WorkerHeartbeatTimeout))
+	}
+	if c.StreamLengthWarnThreshold < 0 {
+		errs = append(errs, fmt.Errorf("STREAM_LENGTH_WARN_THRESHOLD must not be negative, got %d", c.StreamLengthWarnThreshold))
+	}
+	if c.StreamLagPollInterval < 0 {
+		errs = append(errs, fmt.Errorf("STREAM_LAG_POLL_INTERVAL must not be negative, got %s", c.StreamLagPollInterval))
+	}
+	if c.StreamLagPollInterval > 0 && c.StreamLagWarnThreshold <= 0 {
+		errs = append(errs, fmt.Errorf("STREAM_LAG_WARN_THRESHOLD must be positive, got %d", c.StreamLagWarnThreshold))
+	}
+	if c.ReplayMaxMessagesPerSecond <= 0 {
+		errs = append(errs, fmt.Errorf("REPLAY_MAX_MESSAGES_PER_SECOND must be positive, got %d", c.ReplayMaxMessagesPerSecond))
+	}
+	if c.PublishContextTimeout <= 0 {
+		errs = append(errs, fmt.Errorf("PUBLISH_CONTEXT_TIMEOUT must be positive, got %s", c.PublishContextTimeout))
+	}
+	if c.ChannelRouteTTL < 0 {
+		errs = append(errs, fmt.Errorf("CHANNEL_ROUTE_TTL must not be negative, got %s", c.ChannelRouteTTL))
+	}
+	if c.WorkerSelectionStrategy != "round-robin" && c.WorkerSelectionStrategy != "least-channels" {
+		errs = append(errs, fmt.Errorf("WORKER_SELECTION_STRATEGY must be round-robin or least-channels, got %q", c.WorkerSelectionStrategy))
+	}
+	if c.ChannelCountReconcileInterval < 0 {
+		errs = append(errs, fmt.Errorf("CHANNEL_COUNT_RECONCILE_INTERVAL must not be negative, got %s", c.ChannelCountReconcileInterval))
+	}
+	if c.StaleClaimInterval < 0 {
+		errs = append(errs, fmt.Errorf("STALE_CLAIM_INTERVAL must not be negative, got %s", c.StaleClaimInterval))
+	}
+	if c.StaleClaimInterval > 0 && c.StaleMessageMinIdle <= 0 {
+		errs = append(errs, fmt.Errorf("STALE_MESSAGE_MIN_IDLE must be positive, got %s", c.StaleMessageMinIdle))
+	}
+	if c.StaleMessageMaxDeliveries <= 0 {
+		errs = append(errs, fmt.Errorf("STALE_MESSAGE_MAX_DELIVERIES must be positive, got %d", c.StaleMessageMaxDeliveries))
+	}
+	if c.ConsumerGroupName == "" {
+		errs = append(errs, errors.New("CONSUMER_GROUP_NAME must not be empty"))
+	}
+	if c.StreamSchemaVersion < 1 {
+		errs = append(errs, fmt.Errorf("STREAM_SCHEMA_VERSION must be at least 1, got %d", c.StreamSchemaVersion))
+	}
+	if c.TokenHMACSecret == "" {
+		errs = append(errs, &Warning{msg: "CENTRIFUGO_TOKEN_HMAC_SECRET_KEY not set, authentication disabled"})
+	}
+	if slices.Contains(c.HTTPAllowedOrigins, "*") {
+		errs = append(errs, &Warning{msg: "HTTP_ALLOWED_ORIGINS contains *, any website can call the HTTP API"})
+	}
+	if c.AdminSecret == "" {
+		errs = append(errs, &Warning{msg: "ADMIN_SECRET not set, gRPC admin API and signed HTTP admin endpoints reject all calls"})
+	}
+	if c.WebhookURL != "" && c.WebhookSecret == "" {
+		errs = append(errs, &Warning{msg: "WEBHOOK_SECRET not set, presence webhooks are sent unsigned"})
+	}
+	if c.KeyspaceNotificationsEnabled && len(c.RedisClusterAddrs) > 0 {
+		errs = append(errs, &Warning{msg: "KEYSPACE_NOTIFICATIONS_ENABLED with Redis Cluster only receives events from one node, routes on other nodes still expire after ROUTE_CACHE_TTL"})
+	}
+
+	return errs
 }
 
 func getEnv(key, defaultValue string) string {
@@ -88,6 +840,53 @@ func getEnvInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
+func getEnvFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if floatVal, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatVal
+		}
+	}
+	return defaultValue
+}
+
+func getEnvBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolVal, err := strconv.ParseBool(value); err == nil {
+			return boolVal
+		}
+	}
+	return defaultValue
+}
+
+// getEnvList splits a comma-separated env var, dropping empty items
+func getEnvList(key string, defaultValue []string) []string {
+	var list []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	if len(list) == 0 {
+		return defaultValue
+	}
+	return list
+}
+
+// parseNamespaces decodes the CHANNEL_NAMESPACES JSON array; empty means
+// no namespaces. Unknown fields are rejected to catch misspelled options.
+func parseNamespaces(value string) ([]NamespaceConfig, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+	dec := json.NewDecoder(strings.NewReader(value))
+	dec.DisallowUnknownFields()
+	var namespaces []NamespaceConfig
+	if err := dec.Decode(&namespaces); err != nil {
+		return nil, err
+	}
+	return namespaces, nil
+}
+
 func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
 	if value := os.Getenv(key); value != "" {
 		if duration, err := time.ParseDuration(value); err == nil {
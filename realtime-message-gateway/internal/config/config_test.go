@@ -0,0 +1,307 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// validConfig returns a config that passes validation without warnings
+func validConfig() *Config {
+	return &Config{
+		WebSocketPort:   8000,
+		HTTPPort:        3000,
+		RedisURL:        "redis://localhost:6379",
+		RedisPoolSize:   10,
+		RedisMinIdle:    2,
+		RedisProtocol:   2,
+		TokenHMACSecret: "secret",
+		AdminSecret:     "admin-secret",
+		MaxTextLength:   5000,
+		PingInterval:    25 * time.Second,
+		PongTimeout:     10 * time.Second,
+
+		AllowedContentTypes: []string{"text/plain"},
+		MaxMetaKeys:         10,
+		MaxMetaValueLen:     256,
+
+		RateLimitWindowSeconds: 1,
+		RateLimiterBackend:     "local",
+
+		HealthStreamInterval:   5 * time.Second,
+		HealthStreamMaxClients: 5,
+
+		MetricsStreamInterval:   time.Second,
+		MetricsStreamMaxClients: 5,
+
+		ShutdownTimeoutWS:      30 * time.Second,
+		ShutdownTimeoutHTTP:    10 * time.Second,
+		ShutdownTimeoutMetrics: 5 * time.Second,
+
+		StreamSchemaVersion: 1,
+		ConsumerGroupName:   "gw-consumer",
+
+		StreamBacklogCheckInterval: 10 * time.Second,
+		WorkerCooldownDuration:     time.Minute,
+		ReplayMaxMessagesPerSecond: 100,
+		PublishContextTimeout:      5 * time.Second,
+		HTTPMaxBodySize:            65536,
+		WorkerSelectionStrategy:    "round-robin",
+		HistoryRetain:              100,
+		StaleMessageMaxDeliveries:  5,
+
+		ReconnectPolicy: ReconnectPolicy{
+			InitialDelay: 500 * time.Millisecond,
+			MaxDelay:     20 * time.Second,
+			Multiplier:   2,
+		},
+
+		CompressionLevel:   4,
+		CompressionMinSize: 1024,
+
+		ChannelMetadataMaxSize: 4096,
+		RecoverHistoryLimit:    100,
+
+		ClientQueueDepth:         64,
+		ClientQueueFlushInterval: 500 * time.Millisecond,
+
+		WebhookWorkers:   4,
+		WebhookQueueSize: 1000,
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name         string
+		modify       func(c *Config)
+		wantErrors   int
+		wantWarnings int
+	}{
+		{"valid config", func(c *Config) {}, 0, 0},
+		{"empty redis url", func(c *Config) { c.RedisURL = "" }, 1, 0},
+		{"empty redis url with cluster addrs", func(c *Config) {
+			c.RedisURL = ""
+			c.RedisClusterAddrs = []string{"redis-0:6379"}
+		}, 0, 0},
+		{"same websocket and http port", func(c *Config) { c.HTTPPort = c.WebSocketPort }, 1, 0},
+		{"pong timeout equals ping interval", func(c *Config) { c.PongTimeout = c.PingInterval }, 1, 0},
+		{"zero app ping interval", func(c *Config) { c.AppPingEnabled = true; c.AppPingInterval = 0 }, 1, 0},
+		{"zero app ping interval with app ping disabled", func(c *Config) { c.AppPingInterval = 0 }, 0, 0},
+		{"pong timeout exceeds ping interval", func(c *Config) { c.PongTimeout = 30 * time.Second }, 1, 0},
+		{"zero max text length", func(c *Config) { c.MaxTextLength = 0 }, 1, 0},
+		{"negative max text length", func(c *Config) { c.MaxTextLength = -1 }, 1, 0},
+		{"no allowed content types", func(c *Config) { c.AllowedContentTypes = nil }, 1, 0},
+		{"negative channel list cache ttl", func(c *Config) { c.ChannelListCacheTTL = -time.Second }, 1, 0},
+		{"negative max meta keys", func(c *Config) { c.MaxMetaKeys = -1 }, 1, 0},
+		{"zero max meta keys", func(c *Config) { c.MaxMetaKeys = 0 }, 0, 0},
+		{"zero max meta value length", func(c *Config) { c.MaxMetaValueLen = 0 }, 1, 0},
+		{"negative pool size", func(c *Config) { c.RedisPoolSize = -1 }, 1, 0},
+		{"negative min idle", func(c *Config) { c.RedisMinIdle = -1 }, 1, 0},
+		{"compression level too low", func(c *Config) { c.CompressionLevel = -3 }, 1, 0},
+		{"compression level too high", func(c *Config) { c.CompressionLevel = 10 }, 1, 0},
+		{"negative compression min size", func(c *Config) { c.CompressionMinSize = -1 }, 1, 0},
+		{"negative max idle connection time", func(c *Config) { c.MaxIdleConnectionTime = -time.Second }, 1, 0},
+		{"negative write flush interval", func(c *Config) { c.WriteFlushInterval = -time.Millisecond }, 1, 0},
+		{"valid namespaces", func(c *Config) {
+			c.Namespaces = []NamespaceConfig{{Name: "chat", MaxSubscribers: 100}, {Name: "user", Protected: true}}
+		}, 0, 0},
+		{"unparsable namespaces", func(c *Config) { _, c.namespacesErr = parseNamespaces("{") }, 1, 0},
+		{"namespace without name", func(c *Config) { c.Namespaces = []NamespaceConfig{{}} }, 1, 0},
+		{"namespace name with colon", func(c *Config) { c.Namespaces = []NamespaceConfig{{Name: "chat:room"}} }, 1, 0},
+		{"duplicate namespace", func(c *Config) { c.Namespaces = []NamespaceConfig{{Name: "chat"}, {Name: "chat"}} }, 1, 0},
+		{"negative namespace max subscribers", func(c *Config) { c.Namespaces = []NamespaceConfig{{Name: "chat", MaxSubscribers: -1}} }, 1, 0},
+		{"zero backlog check interval with stream max len", func(c *Config) {
+			c.StreamMaxLen = 1000
+			c.StreamBacklogCheckInterval = 0
+		}, 1, 0},
+		{"zero worker cooldown with stream max len", func(c *Config) {
+			c.StreamMaxLen = 1000
+			c.WorkerCooldownDuration = 0
+		}, 1, 0},
+		{"negative max connections", func(c *Config) { c.MaxConnections = -1 }, 1, 0},
+		{"load shed threshold at resume level", func(c *Config) { c.MaxConnections = 1000; c.LoadShedThreshold = 0.8 }, 1, 0},
+		{"load shed threshold above one", func(c *Config) { c.MaxConnections = 1000; c.LoadShedThreshold = 1.5 }, 1, 0},
+		{"load shed threshold ignored without max connections", func(c *Config) { c.LoadShedThreshold = 0 }, 0, 0},
+		{"zero reconnect initial delay", func(c *Config) { c.ReconnectPolicy.InitialDelay = 0 }, 1, 0},
+		{"reconnect max delay below initial", func(c *Config) { c.ReconnectPolicy.MaxDelay = 100 * time.Millisecond }, 1, 0},
+		{"reconnect multiplier below one", func(c *Config) { c.ReconnectPolicy.Multiplier = 0.5 }, 1, 0},
+		{"negative readiness grace period", func(c *Config) { c.ReadinessGracePeriod = -time.Second }, 1, 0},
+		{"zero health stream interval", func(c *Config) { c.HealthStreamInterval = 0 }, 1, 0},
+		{"zero metrics stream interval", func(c *Config) { c.MetricsStreamInterval = 0 }, 1, 0},
+		{"zero metrics stream clients", func(c *Config) { c.MetricsStreamMaxClients = 0 }, 1, 0},
+		{"zero health stream clients", func(c *Config) { c.HealthStreamMaxClients = 0 }, 1, 0},
+		{"negative health warn threshold", func(c *Config) { c.HealthWarnThreshold = -time.Second }, 1, 0},
+		{"zero ws shutdown timeout", func(c *Config) { c.ShutdownTimeoutWS = 0 }, 1, 0},
+		{"negative metrics shutdown timeout", func(c *Config) { c.ShutdownTimeoutMetrics = -time.Second }, 1, 0},
+		{"zero channel metadata size", func(c *Config) { c.ChannelMetadataMaxSize = 0 }, 1, 0},
+		{"valid channel patterns", func(c *Config) { c.ChannelPatterns = []string{"^chat$", "^chat:room-[a-z0-9-]+$"} }, 0, 0},
+		{"valid ip cidrs", func(c *Config) { c.AllowedIPCIDRs = []string{"10.0.0.0/8", "2001:db8::/32"} }, 0, 0},
+		{"invalid ip cidrs", func(c *Config) { c.AllowedIPCIDRs = []string{"10.0.0.1", "10.0.0.0/33", "192.168.0.0/16"} }, 2, 0},
+		{"valid trusted proxy cidrs", func(c *Config) { c.TrustedProxyCIDRs = []string{"10.0.0.0/8", "fd00::/8"} }, 0, 0},
+		{"invalid trusted proxy cidrs", func(c *Config) { c.TrustedProxyCIDRs = []string{"10.0.0.1", "10.0.0.0/8"} }, 1, 0},
+		{"invalid channel pattern", func(c *Config) { c.ChannelPatterns = []string{"^chat:(room"} }, 1, 0},
+		{"each invalid channel pattern reported", func(c *Config) { c.ChannelPatterns = []string{"^chat$", "[a-", "user:*+"} }, 2, 0},
+		{"invalid recover channel pattern", func(c *Config) { c.RecoverChannels = []string{"chat:[a"} }, 1, 0},
+		{"zero recover history limit", func(c *Config) {
+			c.RecoverChannels = []string{"chat:*"}
+			c.RecoverHistoryLimit = 0
+		}, 1, 0},
+		{"zero recover history limit with recovery disabled", func(c *Config) { c.RecoverHistoryLimit = 0 }, 0, 0},
+		{"webhook with secret", func(c *Config) { c.WebhookURL, c.WebhookSecret = "https://app.example.com/hooks", "s" }, 0, 0},
+		{"webhook without secret", func(c *Config) { c.WebhookURL = "https://app.example.com/hooks" }, 0, 1},
+		{"webhook url without scheme", func(c *Config) { c.WebhookURL, c.WebhookSecret = "app.example.com/hooks", "s" }, 1, 0},
+		{"webhook with zero workers and queue", func(c *Config) {
+			c.WebhookURL, c.WebhookSecret = "https://app.example.com/hooks", "s"
+			c.WebhookWorkers, c.WebhookQueueSize = 0, 0
+		}, 2, 0},
+		{"zero webhook workers while disabled", func(c *Config) { c.WebhookWorkers = 0 }, 0, 0},
+		{"negative stream length warn threshold", func(c *Config) { c.StreamLengthWarnThreshold = -1 }, 1, 0},
+		{"negative stream lag poll interval", func(c *Config) { c.StreamLagPollInterval = -time.Second }, 1, 0},
+		{"zero stream lag warn threshold", func(c *Config) { c.StreamLagPollInterval = 10 * time.Second; c.StreamLagWarnThreshold = 0 }, 1, 0},
+		{"zero stream lag warn threshold with monitor disabled", func(c *Config) { c.StreamLagWarnThreshold = 0 }, 0, 0},
+		{"zero replay rate", func(c *Config) { c.ReplayMaxMessagesPerSecond = 0 }, 1, 0},
+		{"zero http max body size", func(c *Config) { c.HTTPMaxBodySize = 0 }, 1, 0},
+		{"negative presence cache ttl", func(c *Config) { c.PresenceCacheTTL = -time.Second }, 1, 0},
+		{"negative worker heartbeat timeout", func(c *Config) { c.WorkerHeartbeatTimeout = -time.Second }, 1, 0},
+		{"zero publish context timeout", func(c *Config) { c.PublishContextTimeout = 0 }, 1, 0},
+		{"least-channels selection strategy", func(c *Config) { c.WorkerSelectionStrategy = "least-channels" }, 0, 0},
+		{"unknown selection strategy", func(c *Config) { c.WorkerSelectionStrategy = "random" }, 1, 0},
+		{"zero history retain", func(c *Config) { c.HistoryRetain = 0 }, 1, 0},
+		{"negative history ttl", func(c *Config) { c.HistoryTTL = -time.Second }, 1, 0},
+		{"negative channel route ttl", func(c *Config) { c.ChannelRouteTTL = -time.Second }, 1, 0},
+		{"negative channel count reconcile interval", func(c *Config) { c.ChannelCountReconcileInterval = -time.Second }, 1, 0},
+		{"negative stale claim interval", func(c *Config) { c.StaleClaimInterval = -time.Second }, 1, 0},
+		{"zero stale message min idle", func(c *Config) { c.StaleClaimInterval = time.Minute; c.StaleMessageMinIdle = 0 }, 1, 0},
+		{"zero stale message min idle with recovery disabled", func(c *Config) { c.StaleMessageMinIdle = 0 }, 0, 0},
+		{"zero stale message max deliveries", func(c *Config) { c.StaleMessageMaxDeliveries = 0 }, 1, 0},
+		{"otel without endpoint", func(c *Config) { c.OTELEnabled, c.OTELEndpoint = true, "" }, 1, 0},
+		{"otel endpoint while disabled", func(c *Config) { c.OTELEndpoint = "" }, 0, 0},
+		{"negative stream max len", func(c *Config) { c.StreamMaxLen = -1 }, 1, 0},
+		{"negative client queue depth", func(c *Config) { c.ClientQueueDepth = -1 }, 1, 0},
+		{"zero client queue flush interval", func(c *Config) { c.ClientQueueFlushInterval = 0 }, 1, 0},
+		{"zero flush interval with queue disabled", func(c *Config) {
+			c.ClientQueueDepth = 0
+			c.ClientQueueFlushInterval = 0
+		}, 0, 0},
+		{"zero schema version", func(c *Config) { c.StreamSchemaVersion = 0 }, 1, 0},
+		{"empty consumer group", func(c *Config) { c.ConsumerGroupName = "" }, 1, 0},
+		{"empty hmac secret", func(c *Config) { c.TokenHMACSecret = "" }, 0, 1},
+		{"wildcard http origin", func(c *Config) { c.HTTPAllowedOrigins = []string{"*"} }, 0, 1},
+		{"empty admin secret", func(c *Config) { c.AdminSecret = "" }, 0, 1},
+		{"keyspace notifications with cluster", func(c *Config) {
+			c.KeyspaceNotificationsEnabled = true
+			c.RedisClusterAddrs = []string{"redis-0:6379"}
+		}, 0, 1},
+		{"negative duplicate text window", func(c *Config) { c.DuplicateTextWindow = -time.Second }, 1, 0},
+		{"negative bloom filter capacity", func(c *Config) { c.BloomFilterCapacity = -1 }, 1, 0},
+		{"bloom false positive rate of 1", func(c *Config) {
+			c.BloomFilterCapacity, c.BloomFalsePositiveRate = 1000, 1
+			c.BloomResetInterval = time.Minute
+		}, 1, 0},
+		{"zero bloom reset interval", func(c *Config) {
+			c.BloomFilterCapacity, c.BloomFalsePositiveRate = 1000, 0.01
+			c.BloomResetInterval = 0
+		}, 1, 0},
+		{"zero bloom reset interval without filter", func(c *Config) { c.BloomResetInterval = 0 }, 0, 0},
+		{"negative publish rate limit", func(c *Config) { c.PublishRateLimit = -1 }, 1, 0},
+		{"zero rate limit window", func(c *Config) { c.PublishRateLimit = 10; c.RateLimitWindowSeconds = 0 }, 1, 0},
+		{"zero rate limit window without limit", func(c *Config) { c.RateLimitWindowSeconds = 0 }, 0, 0},
+		{"redis rate limiter", func(c *Config) { c.RateLimiterBackend = "redis" }, 0, 0},
+		{"unknown rate limiter backend", func(c *Config) { c.RateLimiterBackend = "memcached" }, 1, 0},
+		{"RESP3", func(c *Config) { c.RedisProtocol = 3 }, 0, 0},
+		{"unknown Redis protocol", func(c *Config) { c.RedisProtocol = 4 }, 1, 0},
+		{"negative user history retain", func(c *Config) { c.UserHistoryRetain = -1 }, 1, 0},
+		{"negative history cache size", func(c *Config) { c.HistoryCacheSize = -1 }, 1, 0},
+		{"archive without path", func(c *Config) { c.ArchiveEnabled = true; c.ArchiveRotateInterval = time.Hour }, 1, 0},
+		{"archive without rotation", func(c *Config) { c.ArchiveEnabled = true; c.ArchivePath = "archive" }, 1, 0},
+		{"archive disabled with zero rotation", func(c *Config) { c.ArchiveRotateInterval = 0 }, 0, 0},
+		{"TLS cert without key", func(c *Config) { c.TLSCertFile = "cert.pem" }, 1, 0},
+		{"TLS cert and key", func(c *Config) {
+			c.TLSCertFile = "cert.pem"
+			c.TLSKeyFile = "key.pem"
+			c.TLSMinVersion = "1.3"
+		}, 0, 0},
+		{"unknown TLS min version", func(c *Config) { c.TLSMinVersion = "1.1" }, 1, 0},
+		{"unknown TLS cipher suite", func(c *Config) { c.TLSCipherSuites = []string{"TLS_FOO"} }, 1, 0},
+		{"keyspace notifications single node", func(c *Config) { c.KeyspaceNotificationsEnabled = true }, 0, 0},
+		{"multiple problems", func(c *Config) {
+			c.RedisURL = ""
+			c.MaxTextLength = 0
+			c.TokenHMACSecret = ""
+		}, 2, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			tt.modify(cfg)
+
+			var gotErrors, gotWarnings int
+			for _, err := range cfg.Validate() {
+				if IsWarning(err) {
+					gotWarnings++
+				} else {
+					gotErrors++
+				}
+			}
+			if gotErrors != tt.wantErrors || gotWarnings != tt.wantWarnings {
+				t.Errorf("Validate() = %d errors, %d warnings, want %d errors, %d warnings",
+					gotErrors, gotWarnings, tt.wantErrors, tt.wantWarnings)
+			}
+		})
+	}
+}
+
+func TestGetEnvList(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  []string
+	}{
+		{"unset", "", []string{"default"}},
+		{"only separators", " , ,", []string{"default"}},
+		{"single", "redis-0:6379", []string{"redis-0:6379"}},
+		{"multiple", "redis-0:6379,redis-1:6379", []string{"redis-0:6379", "redis-1:6379"}},
+		{"spaces and empty items", " redis-0:6379 ,, redis-1:6379,", []string{"redis-0:6379", "redis-1:6379"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TEST_ENV_LIST", tt.value)
+			if got := getEnvList("TEST_ENV_LIST", []string{"default"}); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("getEnvList() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseNamespaces(t *testing.T) {
+	off := false
+	tests := []struct {
+		name    string
+		value   string
+		want    []NamespaceConfig
+		wantErr bool
+	}{
+		{"unset", "", nil, false},
+		{"empty array", "[]", []NamespaceConfig{}, false},
+		{"all fields", `[{"name":"chat","protected":true,"pushJoinLeave":false,"maxSubscribers":50}]`,
+			[]NamespaceConfig{{Name: "chat", Protected: true, PushJoinLeave: &off, MaxSubscribers: 50}}, false},
+		{"defaults", `[{"name":"chat"},{"name":"user"}]`, []NamespaceConfig{{Name: "chat"}, {Name: "user"}}, false},
+		{"invalid JSON", `[{"name":`, nil, true},
+		{"not an array", `{"name":"chat"}`, nil, true},
+		{"unknown field", `[{"name":"chat","maxSubscriber":5}]`, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseNamespaces(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseNamespaces() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseNamespaces() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
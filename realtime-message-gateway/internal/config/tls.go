@@ -0,0 +1,49 @@
+package config
+
+import (
+	"crypto/tls"
+	"fmt"
+)
+
+// tlsVersions maps WS_TLS_MIN_VERSION values to crypto/tls versions
+var tlsVersions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// WebSocketTLSConfig returns the TLS settings of the WebSocket server from
+// TLSMinVersion and TLSCipherSuites. Unset fields keep the crypto/tls
+// defaults. Cipher suites are the IANA names of tls.CipherSuites; they only
+// apply to TLS 1.2, as TLS 1.3 suites are not configurable.
+func (c *Config) WebSocketTLSConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{}
+
+	if c.TLSMinVersion != "" {
+		version, ok := tlsVersions[c.TLSMinVersion]
+		if !ok {
+			return nil, fmt.Errorf("WS_TLS_MIN_VERSION must be 1.2 or 1.3, got %q", c.TLSMinVersion)
+		}
+		tlsConfig.MinVersion = version
+	}
+
+	for _, name := range c.TLSCipherSuites {
+		id, ok := cipherSuiteID(name)
+		if !ok {
+			return nil, fmt.Errorf("WS_TLS_CIPHER_SUITES entry %q is not a supported cipher suite", name)
+		}
+		tlsConfig.CipherSuites = append(tlsConfig.CipherSuites, id)
+	}
+
+	return tlsConfig, nil
+}
+
+// cipherSuiteID returns the ID of the secure cipher suite called name.
+// Suites of tls.InsecureCipherSuites are not accepted.
+func cipherSuiteID(name string) (uint16, bool) {
+	for _, suite := range tls.CipherSuites() {
+		if suite.Name == name {
+			return suite.ID, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,87 @@
+package config
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestWebSocketTLSConfig(t *testing.T) {
+	tests := []struct {
+		name             string
+		minVersion       string
+		cipherSuites     []string
+		wantMinVersion   uint16
+		wantCipherSuites []uint16
+		wantErr          bool
+	}{
+		{name: "defaults"},
+		{name: "TLS 1.2", minVersion: "1.2", wantMinVersion: tls.VersionTLS12},
+		{name: "TLS 1.3", minVersion: "1.3", wantMinVersion: tls.VersionTLS13},
+		{name: "unknown version", minVersion: "1.1", wantErr: true},
+		{
+			name:             "cipher suites",
+			cipherSuites:     []string{"TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384", "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"},
+			wantCipherSuites: []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384, tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256},
+		},
+		{name: "unknown cipher suite", cipherSuites: []string{"TLS_FOO"}, wantErr: true},
+		{name: "insecure cipher suite", cipherSuites: []string{"TLS_RSA_WITH_RC4_128_SHA"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{TLSMinVersion: tt.minVersion, TLSCipherSuites: tt.cipherSuites}
+			got, err := cfg.WebSocketTLSConfig()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("WebSocketTLSConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got.MinVersion != tt.wantMinVersion || !reflect.DeepEqual(got.CipherSuites, tt.wantCipherSuites) {
+				t.Errorf("WebSocketTLSConfig() = MinVersion %x, CipherSuites %v, want %x, %v",
+					got.MinVersion, got.CipherSuites, tt.wantMinVersion, tt.wantCipherSuites)
+			}
+		})
+	}
+}
+
+func TestWebSocketTLSConfigRejectsOldClients(t *testing.T) {
+	cfg := &Config{TLSMinVersion: "1.2"}
+	tlsConfig, err := cfg.WebSocketTLSConfig()
+	if err != nil {
+		t.Fatalf("WebSocketTLSConfig() error = %v", err)
+	}
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.TLS = tlsConfig
+	server.StartTLS()
+	defer server.Close()
+
+	tests := []struct {
+		name       string
+		maxVersion uint16
+		wantErr    bool
+	}{
+		{"TLS 1.1", tls.VersionTLS11, true},
+		{"TLS 1.2", tls.VersionTLS12, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := server.Client()
+			transport := client.Transport.(*http.Transport).Clone()
+			transport.TLSClientConfig.MinVersion = tls.VersionTLS10
+			transport.TLSClientConfig.MaxVersion = tt.maxVersion
+			client.Transport = transport
+
+			resp, err := client.Get(server.URL)
+			if err == nil {
+				resp.Body.Close()
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Get() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
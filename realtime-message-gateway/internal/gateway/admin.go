@@ -0,0 +1,143 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+
+	"github.com/centrifugal/centrifuge"
+
+	"realtime-message-gateway/internal/routing"
+)
+
+// WorkerLoad describes an active worker, its stream backlog and the number
+// of channels routed to it
+type WorkerLoad struct {
+	WorkerID      string `json:"workerId"`
+	LastHeartbeat int64  `json:"lastHeartbeat"` // unix milliseconds
+	StreamLength  int64  `json:"streamLength"`  // across all priority streams
+	Channels      int64  `json:"channels"`
+}
+
+var (
+	// ErrNoChannelHistory is returned when the history of a channel names
+	// no worker to recover its route from
+	ErrNoChannelHistory = errors.New("channel history names no worker")
+	// ErrChannelRouteExists is returned when recovering the route of a
+	// channel that has one
+	ErrChannelRouteExists = errors.New("channel already has a route")
+)
+
+// DisconnectUser disconnects all connections of userID on this gateway
+func (g *Gateway) DisconnectUser(userID string) error {
+	return g.node.Disconnect(userID, centrifuge.WithCustomDisconnect(centrifuge.DisconnectForceNoReconnect))
+}
+
+// TrimWorkerStreams trims each priority stream of workerID to maxLen entries
+// and returns the number of entries removed. Used to drop the backlog a
+// worker accumulated while it was down; see redis.Client.XTrim for approx
+func (g *Gateway) TrimWorkerStreams(ctx context.Context, workerID string, maxLen int64, approx bool) (int64, error) {
+	var trimmed int64
+	for _, streamKey := range routing.GetWorkerStreamKeys(workerID) {
+		n, err := g.redis.XTrim(ctx, streamKey, maxLen, approx)
+		if err != nil {
+			return trimmed, err
+		}
+		trimmed += n
+	}
+	return trimmed, nil
+}
+
+// MigrateWorkerChannels moves all channels routed to workerID to other
+// active workers; see routing.Router.MigrateWorkerChannels
+func (g *Gateway) MigrateWorkerChannels(ctx context.Context, workerID string) (routing.MigrationResult, error) {
+	return g.router.MigrateWorkerChannels(ctx, workerID)
+}
+
+// DeleteChannelRoute removes the route of channel so it is assigned a
+// worker again; see routing.Router.DeleteChannelRoute
+func (g *Gateway) DeleteChannelRoute(ctx context.Context, channel string) (bool, error) {
+	return g.router.DeleteChannelRoute(ctx, channel)
+}
+
+// RecoverRouteFromHistory routes channel back to the worker that handled
+// its most recent message in the channel history, so a channel whose
+// route expired or was deleted returns to the worker holding its state
+// instead of a round-robin pick. Returns the worker ID, ErrNoChannelHistory
+// when no history message names a worker, ErrChannelRouteExists when the
+// channel has a route, and routing.ErrWorkerNotActive when the worker is
+// no longer active.
+func (g *Gateway) RecoverRouteFromHistory(ctx context.Context, channel string) (string, error) {
+	entries, err := g.loadHistory(ctx, channel)
+	if err != nil {
+		return "", err
+	}
+
+	// Newest first
+	var workerID string
+	for _, entry := range entries {
+		if entry.msg.WorkerID != "" {
+			workerID = entry.msg.WorkerID
+			break
+		}
+	}
+	if workerID == "" {
+		return "", ErrNoChannelHistory
+	}
+
+	restored, err := g.router.RestoreChannelRoute(ctx, channel, workerID)
+	if err != nil {
+		return "", err
+	}
+	if !restored {
+		return "", ErrChannelRouteExists
+	}
+	return workerID, nil
+}
+
+// WorkerLoad returns all active workers with their last heartbeat, stream
+// length and channel count
+func (g *Gateway) WorkerLoad(ctx context.Context) ([]WorkerLoad, error) {
+	workers, err := g.redis.ZRangeWithScores(ctx, routing.ActiveWorkersKey, 0, -1)
+	if err != nil {
+		return nil, err
+	}
+	channels, err := g.router.WorkerChannelCounts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	loads := make([]WorkerLoad, 0, len(workers))
+	for _, w := range workers {
+		workerID, _ := w.Member.(string)
+		var length int64
+		for _, streamKey := range routing.GetWorkerStreamKeys(workerID) {
+			n, err := g.redis.XLen(ctx, streamKey)
+			if err != nil {
+				return nil, err
+			}
+			length += n
+		}
+		loads = append(loads, WorkerLoad{
+			WorkerID:      workerID,
+			LastHeartbeat: int64(w.Score),
+			StreamLength:  length,
+			Channels:      channels[workerID],
+		})
+	}
+	return loads, nil
+}
+
+// ChannelHistory returns up to limit of the most recent messages published
+// to channel, newest first, from the channel's history list
+func (g *Gateway) ChannelHistory(ctx context.Context, channel string, limit int) ([]StreamMessage, error) {
+	history, err := g.loadHistory(ctx, channel)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := make([]StreamMessage, 0, min(len(history), limit))
+	for _, entry := range history[:min(len(history), limit)] {
+		messages = append(messages, entry.msg)
+	}
+	return messages, nil
+}
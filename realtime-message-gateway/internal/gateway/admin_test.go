@@ -0,0 +1,144 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+
+	"realtime-message-gateway/internal/routing"
+)
+
+func TestChannelHistory(t *testing.T) {
+	gw := NewTestGateway(t)
+	gw.config.HistoryRetain = 3
+	gw.config.HistoryTTL = time.Hour
+	ctx := context.Background()
+
+	for i, channel := range []string{"chat:a", "chat:a", "chat:b", "chat:a", "chat:a", "chat:a"} {
+		payload, _ := json.Marshal(StreamMessage{
+			SchemaVersion: 1,
+			ID:            fmt.Sprintf("m%d", i+1),
+			Type:          EventTypeMessage,
+			Channel:       channel,
+		})
+		addTestHistory(t, gw, channel, fmt.Sprintf("%d-0", i+1), payload)
+	}
+
+	tests := []struct {
+		name    string
+		channel string
+		limit   int
+		want    []string
+	}{
+		{"newest first", "chat:a", 2, []string{"m6", "m5"}},
+		{"trimmed to retain", "chat:a", 10, []string{"m6", "m5", "m4"}},
+		{"other channel", "chat:b", 10, []string{"m3"}},
+		{"no history", "chat:c", 10, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messages, err := gw.ChannelHistory(ctx, tt.channel, tt.limit)
+			if err != nil {
+				t.Fatalf("ChannelHistory() error = %v", err)
+			}
+			var ids []string
+			for _, msg := range messages {
+				ids = append(ids, msg.ID)
+			}
+			if !slices.Equal(ids, tt.want) {
+				t.Errorf("ChannelHistory() IDs = %v, want %v", ids, tt.want)
+			}
+		})
+	}
+
+	opt, err := goredis.ParseURL(gw.config.RedisURL)
+	if err != nil {
+		t.Fatalf("ParseURL() error = %v", err)
+	}
+	rdb := goredis.NewClient(opt)
+	defer rdb.Close()
+	if ttl := rdb.TTL(ctx, ChannelHistoryPrefix+"chat:a").Val(); ttl <= 59*time.Minute {
+		t.Errorf("history TTL = %s, want about 1h", ttl)
+	}
+}
+
+func TestTrimWorkerStreams(t *testing.T) {
+	gw := NewTestGateway(t)
+	ctx := context.Background()
+
+	for i := 0; i < 5; i++ {
+		for _, streamKey := range routing.GetWorkerStreamKeys("worker-0") {
+			if _, err := gw.redis.XAdd(ctx, streamKey, map[string]interface{}{"payload": "{}"}); err != nil {
+				t.Fatalf("XAdd() error = %v", err)
+			}
+		}
+	}
+
+	trimmed, err := gw.TrimWorkerStreams(ctx, "worker-0", 2, false)
+	if err != nil {
+		t.Fatalf("TrimWorkerStreams() error = %v", err)
+	}
+	if trimmed != 6 {
+		t.Errorf("TrimWorkerStreams() = %d, want 6", trimmed)
+	}
+	for _, streamKey := range routing.GetWorkerStreamKeys("worker-0") {
+		if n, _ := gw.redis.XLen(ctx, streamKey); n != 2 {
+			t.Errorf("XLen(%s) = %d, want 2", streamKey, n)
+		}
+	}
+}
+
+func TestRecoverRouteFromHistory(t *testing.T) {
+	gw := NewTestGateway(t, WithWorkers([]string{"worker-0", "worker-1"}))
+	ctx := context.Background()
+	client := connectTestClient(t, gw)
+
+	if err := publishAndWait(gw, client, "chat", `{"text":"hello"}`); err != nil {
+		t.Fatalf("publish error = %v", err)
+	}
+	workerID, err := gw.router.GetWorkerForChannel(ctx, "chat")
+	if err != nil {
+		t.Fatalf("GetWorkerForChannel() error = %v", err)
+	}
+
+	if _, err := gw.RecoverRouteFromHistory(ctx, "chat:empty"); !errors.Is(err, ErrNoChannelHistory) {
+		t.Errorf("RecoverRouteFromHistory() without history error = %v, want ErrNoChannelHistory", err)
+	}
+	if _, err := gw.RecoverRouteFromHistory(ctx, "chat"); !errors.Is(err, ErrChannelRouteExists) {
+		t.Errorf("RecoverRouteFromHistory() with route error = %v, want ErrChannelRouteExists", err)
+	}
+
+	// The worker goes offline and the route is dropped
+	if _, err := gw.DeleteChannelRoute(ctx, "chat"); err != nil {
+		t.Fatalf("DeleteChannelRoute() error = %v", err)
+	}
+	if err := gw.redis.ZRem(ctx, routing.ActiveWorkersKey, workerID); err != nil {
+		t.Fatalf("ZRem() error = %v", err)
+	}
+	if _, err := gw.RecoverRouteFromHistory(ctx, "chat"); !errors.Is(err, routing.ErrWorkerNotActive) {
+		t.Errorf("RecoverRouteFromHistory() with offline worker error = %v, want ErrWorkerNotActive", err)
+	}
+
+	// It comes back: the channel returns to it rather than a round-robin pick
+	if _, err := gw.redis.ZAddNX(ctx, routing.ActiveWorkersKey, goredis.Z{Score: float64(time.Now().UnixMilli()), Member: workerID}); err != nil {
+		t.Fatalf("ZAddNX() error = %v", err)
+	}
+	got, err := gw.RecoverRouteFromHistory(ctx, "chat")
+	if err != nil || got != workerID {
+		t.Fatalf("RecoverRouteFromHistory() = %q, %v, want %q", got, err, workerID)
+	}
+	if routed, _ := gw.redis.Get(ctx, routing.ChannelRoutePrefix+"chat"); routed != workerID {
+		t.Errorf("route = %q, want %q", routed, workerID)
+	}
+	counts, err := gw.router.WorkerChannelCounts(ctx)
+	if err != nil || counts[workerID] != 1 {
+		t.Errorf("WorkerChannelCounts() = %v, %v, want %s: 1", counts, err, workerID)
+	}
+}
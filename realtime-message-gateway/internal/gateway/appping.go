@@ -0,0 +1,90 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/centrifugal/centrifuge"
+)
+
+// Application-level ping message types. Centrifuge answers transport pings
+// itself without exposing the round trip, so the gateway sends its own ping
+// as an async message and the client echoes ts back with centrifuge.send().
+const (
+	appPingType = "ping"
+	appPongType = "pong"
+)
+
+// appPingMessage is both the ping sent to clients and the pong they send
+// back; ts is the send time in Unix milliseconds. The round trip is taken
+// from the ping the gateway sent, so a pong only counts when its ts is the
+// one of the connection's latest ping, and only once.
+type appPingMessage struct {
+	Type string `json:"type"`
+	TS   int64  `json:"ts"`
+}
+
+// appPinger sends an application-level ping to every local connection each
+// AppPingInterval
+func (g *Gateway) appPinger(ctx context.Context) {
+	defer g.wg.Done()
+
+	ticker := time.NewTicker(g.config.AppPingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			g.sendAppPings(time.Now())
+		}
+	}
+}
+
+// sendAppPings sends one ping stamped with now to all local connections,
+// replacing the ping each awaits a pong for
+func (g *Gateway) sendAppPings(now time.Time) {
+	ts := now.UnixMilli()
+	data, err := json.Marshal(appPingMessage{Type: appPingType, TS: ts})
+	if err != nil {
+		slog.Error("failed to marshal app ping", "error", err)
+		return
+	}
+
+	g.connectionsMu.RLock()
+	metas := make([]*connectionMeta, 0, len(g.connections))
+	for _, meta := range g.connections {
+		if meta.client != nil {
+			metas = append(metas, meta)
+		}
+	}
+	g.connectionsMu.RUnlock()
+
+	for _, meta := range metas {
+		meta.appPingTS.Store(ts)
+		if err := meta.client.Send(data); err != nil {
+			slog.Debug("failed to send app ping", "clientId", meta.clientID, "error", err)
+		}
+	}
+}
+
+// handleMessage records the round trip of pong messages answering the
+// pending ping of their connection; other async client messages are ignored
+func (g *Gateway) handleMessage(client *centrifuge.Client, e centrifuge.MessageEvent) {
+	var msg appPingMessage
+	if err := json.Unmarshal(e.Data, &msg); err != nil || msg.Type != appPongType {
+		return
+	}
+
+	g.connectionsMu.RLock()
+	meta, ok := g.connections[client.ID()]
+	g.connectionsMu.RUnlock()
+	if !ok || msg.TS <= 0 || !meta.appPingTS.CompareAndSwap(msg.TS, 0) {
+		slog.Debug("ignoring pong that answers no pending ping", "clientId", client.ID(), "ts", msg.TS)
+		return
+	}
+	g.metrics.WSPingRTT.Observe(time.Since(time.UnixMilli(msg.TS)).Seconds())
+}
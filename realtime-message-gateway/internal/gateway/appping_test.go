@@ -0,0 +1,107 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/centrifugal/centrifuge"
+	"github.com/centrifugal/protocol"
+	dto "github.com/prometheus/client_model/go"
+
+	"realtime-message-gateway/internal/metrics"
+)
+
+// pingRTTCount returns the number of round trips observed so far
+func pingRTTCount(t *testing.T) uint64 {
+	t.Helper()
+	var m dto.Metric
+	if err := metrics.Default.WSPingRTT.Write(&m); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	return m.GetHistogram().GetSampleCount()
+}
+
+func TestHandleMessagePong(t *testing.T) {
+	gw := NewTestGateway(t)
+	client := connectTestClient(t, gw)
+	meta := gw.connections[client.ID()]
+	ts := time.Now().Add(-50 * time.Millisecond).UnixMilli()
+
+	tests := []struct {
+		name     string
+		pending  int64
+		data     string
+		wantRTTs uint64
+	}{
+		{"pong", ts, fmt.Sprintf(`{"type":"pong","ts":%d}`, ts), 1},
+		{"second pong for the ping", 0, fmt.Sprintf(`{"type":"pong","ts":%d}`, ts), 0},
+		{"pong for another ping", ts, fmt.Sprintf(`{"type":"pong","ts":%d}`, ts-1), 0},
+		{"pong without a ping", 0, fmt.Sprintf(`{"type":"pong","ts":%d}`, time.Now().Add(-time.Hour).UnixMilli()), 0},
+		{"ping echoed as ping", ts, fmt.Sprintf(`{"type":"ping","ts":%d}`, ts), 0},
+		{"other message", ts, `{"type":"typing"}`, 0},
+		{"invalid json", ts, `pong`, 0},
+		{"missing ts", ts, `{"type":"pong"}`, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.pending != 0 {
+				meta.appPingTS.Store(tt.pending)
+			}
+			before := pingRTTCount(t)
+			gw.handleMessage(client, centrifuge.MessageEvent{Data: []byte(tt.data)})
+			if got := pingRTTCount(t) - before; got != tt.wantRTTs {
+				t.Errorf("round trips recorded = %d, want %d", got, tt.wantRTTs)
+			}
+		})
+	}
+}
+
+func TestAppPingRoundTrip(t *testing.T) {
+	gw := NewTestGateway(t)
+	gw.config.AppPingEnabled = true
+
+	transport := &testTransport{}
+	client, closeFn, err := centrifuge.NewClient(context.Background(), gw.node, transport)
+	if err != nil {
+		t.Fatalf("centrifuge.NewClient() error = %v", err)
+	}
+	defer closeFn()
+	client.HandleCommand(&protocol.Command{Id: 1, Connect: &protocol.ConnectRequest{}}, 0)
+	subscribeReplyFor(t, transport, 1)
+
+	sent := time.Now()
+	gw.sendAppPings(sent)
+
+	deadline := time.Now().Add(time.Second)
+	for !transportContains(transport, []byte(`"type":"ping"`)) {
+		if time.Now().After(deadline) {
+			t.Fatal("no app ping written to the client")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	before := pingRTTCount(t)
+	pong := fmt.Sprintf(`{"type":"pong","ts":%d}`, sent.UnixMilli())
+	client.HandleCommand(&protocol.Command{Send: &protocol.SendRequest{Data: []byte(pong)}}, 0)
+
+	if got := pingRTTCount(t) - before; got != 1 {
+		t.Errorf("round trips recorded = %d, want 1", got)
+	}
+}
+
+// transportContains reports whether any message written to transport
+// contains sub
+func transportContains(transport *testTransport, sub []byte) bool {
+	transport.mu.Lock()
+	defer transport.mu.Unlock()
+	for _, msg := range transport.messages {
+		if bytes.Contains(msg, sub) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,136 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Archiver stores messages delivered to clients beyond the MaxLen of the
+// streams they passed through
+type Archiver interface {
+	Archive(ctx context.Context, entries []StreamMessage) error
+}
+
+var (
+	_ Archiver = NopArchiver{}
+	_ Archiver = (*FileArchiver)(nil)
+)
+
+// NopArchiver discards messages; used when ARCHIVE_ENABLED is not set
+type NopArchiver struct{}
+
+// Archive does nothing
+func (NopArchiver) Archive(context.Context, []StreamMessage) error {
+	return nil
+}
+
+// FileArchiver appends messages as newline-delimited JSON to files in a
+// directory, starting a new file for every rotation interval. Files are
+// named after the UTC start of their interval, e.g.
+// messages-20260102T150000Z.ndjson, so they sort chronologically.
+type FileArchiver struct {
+	dir            string
+	rotateInterval time.Duration
+	now            func() time.Time
+
+	mu        sync.Mutex
+	file      *os.File
+	fileStart time.Time
+}
+
+// NewFileArchiver creates a FileArchiver writing to dir, which is created
+// if missing
+func NewFileArchiver(dir string, rotateInterval time.Duration) (*FileArchiver, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("create archive directory: %w", err)
+	}
+	return &FileArchiver{dir: dir, rotateInterval: rotateInterval, now: time.Now}, nil
+}
+
+// Archive appends entries, one JSON object per line, to the file of the
+// current interval
+func (a *FileArchiver) Archive(_ context.Context, entries []StreamMessage) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, msg := range entries {
+		if err := enc.Encode(msg); err != nil {
+			return fmt.Errorf("encode archived message %s: %w", msg.ID, err)
+		}
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err := a.rotate(a.now()); err != nil {
+		return err
+	}
+	if _, err := a.file.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("write archive: %w", err)
+	}
+	return nil
+}
+
+// rotate makes a.file the file of the interval containing now, closing the
+// file of an earlier interval. Callers hold a.mu.
+func (a *FileArchiver) rotate(now time.Time) error {
+	start := now.UTC().Truncate(a.rotateInterval)
+	if a.file != nil && start.Equal(a.fileStart) {
+		return nil
+	}
+	if a.file != nil {
+		if err := a.file.Close(); err != nil {
+			return fmt.Errorf("close archive: %w", err)
+		}
+		a.file = nil
+	}
+
+	name := filepath.Join(a.dir, "messages-"+start.Format("20060102T150405Z")+".ndjson")
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o640)
+	if err != nil {
+		return fmt.Errorf("open archive: %w", err)
+	}
+	a.file = f
+	a.fileStart = start
+	return nil
+}
+
+// Close closes the current archive file
+func (a *FileArchiver) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.file == nil {
+		return nil
+	}
+	err := a.file.Close()
+	a.file = nil
+	return err
+}
+
+// archiveOutbound archives the payloads of outbound entries delivered to
+// clients. Payloads that are not a StreamMessage are skipped; failures are
+// logged, as the entries are already acknowledged.
+func (g *Gateway) archiveOutbound(ctx context.Context, payloads []string) {
+	entries := make([]StreamMessage, 0, len(payloads))
+	for _, payload := range payloads {
+		var msg StreamMessage
+		if json.Unmarshal([]byte(payload), &msg) != nil || msg.ID == "" {
+			continue
+		}
+		entries = append(entries, msg)
+	}
+	if len(entries) == 0 {
+		return
+	}
+	if err := g.archiver.Archive(ctx, entries); err != nil {
+		slog.WarnContext(ctx, "failed to archive outbound messages", "count", len(entries), "error", err)
+	}
+}
@@ -0,0 +1,146 @@
+package gateway
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"realtime-message-gateway/internal/routing"
+)
+
+// readArchive returns the IDs of the messages in archive file name of dir
+func readArchive(t *testing.T, dir, name string) []string {
+	t.Helper()
+
+	f, err := os.Open(filepath.Join(dir, name))
+	if err != nil {
+		t.Fatalf("open archive: %v", err)
+	}
+	defer f.Close()
+
+	var ids []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var msg StreamMessage
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			t.Fatalf("archive line %q: %v", scanner.Text(), err)
+		}
+		ids = append(ids, msg.ID)
+	}
+	return ids
+}
+
+func TestFileArchiver(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "archive")
+	archiver, err := NewFileArchiver(dir, time.Hour)
+	if err != nil {
+		t.Fatalf("NewFileArchiver() error = %v", err)
+	}
+	ctx := context.Background()
+
+	now := time.Date(2026, 1, 2, 15, 30, 0, 0, time.UTC)
+	archiver.now = func() time.Time { return now }
+	if err := archiver.Archive(ctx, []StreamMessage{{ID: "a"}, {ID: "b"}}); err != nil {
+		t.Fatalf("Archive() error = %v", err)
+	}
+	if err := archiver.Archive(ctx, []StreamMessage{{ID: "c"}}); err != nil {
+		t.Fatalf("Archive() error = %v", err)
+	}
+	now = now.Add(45 * time.Minute)
+	if err := archiver.Archive(ctx, []StreamMessage{{ID: "d"}}); err != nil {
+		t.Fatalf("Archive() after rotation error = %v", err)
+	}
+	if err := archiver.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	tests := []struct {
+		file string
+		want []string
+	}{
+		{"messages-20260102T150000Z.ndjson", []string{"a", "b", "c"}},
+		{"messages-20260102T160000Z.ndjson", []string{"d"}},
+	}
+	for _, tt := range tests {
+		if got := readArchive(t, dir, tt.file); !slices.Equal(got, tt.want) {
+			t.Errorf("%s = %v, want %v", tt.file, got, tt.want)
+		}
+	}
+
+	// A restarted gateway appends to the file of the current interval
+	reopened, err := NewFileArchiver(dir, time.Hour)
+	if err != nil {
+		t.Fatalf("NewFileArchiver() error = %v", err)
+	}
+	reopened.now = func() time.Time { return now }
+	if err := reopened.Archive(ctx, []StreamMessage{{ID: "e"}}); err != nil {
+		t.Fatalf("Archive() error = %v", err)
+	}
+	reopened.Close()
+	if got, want := readArchive(t, dir, tests[1].file), []string{"d", "e"}; !slices.Equal(got, want) {
+		t.Errorf("%s after reopen = %v, want %v", tests[1].file, got, want)
+	}
+}
+
+// recordingArchiver records the IDs of archived messages
+type recordingArchiver struct {
+	mu  sync.Mutex
+	ids []string
+}
+
+func (a *recordingArchiver) Archive(_ context.Context, entries []StreamMessage) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, msg := range entries {
+		a.ids = append(a.ids, msg.ID)
+	}
+	return nil
+}
+
+func (a *recordingArchiver) archived() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return slices.Clone(a.ids)
+}
+
+func TestOutboundConsumerArchivesDeliveredEntries(t *testing.T) {
+	archiver := &recordingArchiver{}
+	gw := NewTestGateway(t, WithGatewayOptions(WithArchiver(archiver)))
+	ctx := context.Background()
+	streamKey := routing.GetGatewayStreamKey(gw.InstanceID())
+
+	// The archived entry comes last, so once it is archived all were handled
+	entries := []map[string]interface{}{
+		// No target: acknowledged without delivery
+		{"payload": `{"id":"undeliverable"}`},
+		// Not a StreamMessage: delivered but not archived
+		{"channel": "chat", "payload": `{"text":"raw"}`},
+		{"channel": "chat", "payload": `{"id":"delivered","channel":"chat","text":"hi"}`},
+	}
+	for _, values := range entries {
+		if _, err := gw.redis.XAdd(ctx, streamKey, values); err != nil {
+			t.Fatalf("XAdd() error = %v", err)
+		}
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if len(archiver.archived()) > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("delivered outbound entry was not archived")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if got := archiver.archived(); !slices.Equal(got, []string{"delivered"}) {
+		t.Errorf("archived = %v, want [delivered]", got)
+	}
+}
@@ -0,0 +1,173 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"realtime-message-gateway/internal/redis"
+	"realtime-message-gateway/internal/routing"
+)
+
+// MaxBatchSize is the maximum number of messages in a single PublishBatch call
+const MaxBatchSize = 50
+
+var (
+	// ErrInvalidBatch is wrapped by all PublishBatch validation errors
+	ErrInvalidBatch = errors.New("invalid batch")
+	// ErrEmptyBatch is returned when PublishBatch is called without messages
+	ErrEmptyBatch = fmt.Errorf("%w: no messages", ErrInvalidBatch)
+	// ErrBatchTooLarge is returned when a batch exceeds MaxBatchSize
+	ErrBatchTooLarge = fmt.Errorf("%w: more than %d messages", ErrInvalidBatch, MaxBatchSize)
+	// ErrPartialBatch is returned when some messages in a batch failed to publish
+	ErrPartialBatch = errors.New("some batch messages failed to publish")
+)
+
+// BatchMessage is a single message in a PublishBatch request
+type BatchMessage struct {
+	Text     string `json:"text"`
+	UserID   string `json:"userId,omitempty"`
+	UserName string `json:"userName,omitempty"`
+}
+
+// PublishBatch writes msgs to the channel's worker stream in one pipelined
+// round trip and broadcasts them to channel subscribers. Each message must
+// be allowed to its userId by the channel rules of client publishes; a
+// batch has no subscription, so channels that need one are forbidden. The
+// returned IDs match msgs by index; failed messages have an empty ID and
+// the error wraps ErrPartialBatch.
+func (g *Gateway) PublishBatch(ctx context.Context, channel string, msgs []BatchMessage) ([]string, error) {
+	if len(msgs) == 0 {
+		return nil, ErrEmptyBatch
+	}
+	if len(msgs) > MaxBatchSize {
+		return nil, ErrBatchTooLarge
+	}
+	texts := make([]string, len(msgs))
+	for i, msg := range msgs {
+		text, err := g.sanitizer.Sanitize(msg.Text)
+		if errors.Is(err, ErrTextTooLong) {
+			return nil, ErrMessageTooLarge.Wrap(fmt.Errorf("%w: message %d: %w", ErrInvalidBatch, i, err))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: message %d: %w", ErrInvalidBatch, i, err)
+		}
+		if strings.TrimSpace(text) == "" {
+			return nil, fmt.Errorf("%w: message %d: text is required", ErrInvalidBatch, i)
+		}
+		if g.contentFiltered(text) {
+			return nil, ErrMessageRejected.Wrap(fmt.Errorf("%w: message %d", ErrInvalidBatch, i))
+		}
+		texts[i] = text
+	}
+	for i, msg := range msgs {
+		if err := g.authorizeChannelPublish(channel, msg.UserID, false); err != nil {
+			return nil, fmt.Errorf("message %d: %w", i, err)
+		}
+	}
+
+	g.metrics.BatchPublishSize.Observe(float64(len(msgs)))
+
+	workerID, err := g.router.GetWorkerForChannel(ctx, channel)
+	if err != nil {
+		return nil, ErrWorkerUnavailable.Wrap(err)
+	}
+	streamKey := routing.GetWorkerStreamKey(workerID, routing.PriorityNormal)
+	publishedAt := time.Now().UTC()
+	timestamp := publishedAt.Format(time.RFC3339Nano)
+
+	messageIDs := make([]string, len(msgs))
+	raws := make([][]byte, len(msgs))
+	payloads := make([][]byte, len(msgs))
+	entries := make([]redis.StreamEntry, len(msgs))
+	for i, msg := range msgs {
+		text := texts[i]
+		userName := msg.UserName
+		if userName == "" {
+			userName = "Anonymous"
+		}
+
+		raw, err := json.Marshal(map[string]string{"text": text})
+		if err != nil {
+			return nil, err
+		}
+
+		message := StreamMessage{
+			SchemaVersion: g.config.StreamSchemaVersion,
+			ID:            uuid.New().String(),
+			Type:          EventTypeMessage,
+			Channel:       channel,
+			WorkerID:      workerID,
+			UserID:        msg.UserID,
+			UserName:      userName,
+			Text:          strings.TrimSpace(text),
+			ContentType:   ContentTypeText,
+			Timestamp:     timestamp,
+			Raw:           string(raw),
+			GatewayID:     g.instanceID,
+			Priority:      routing.PriorityNormal,
+		}
+		g.signMessage(&message)
+		payload, err := json.Marshal(message)
+		if err != nil {
+			return nil, err
+		}
+
+		messageIDs[i] = message.ID
+		raws[i] = raw
+		payloads[i] = payload
+		// The userId of a batch message comes from the request body, so it
+		// is not recorded in the user's moderation history
+		entries[i] = redis.StreamEntry{
+			Stream: streamKey,
+			Values: map[string]interface{}{"payload": string(payload)},
+			Push:   g.historyPush(ctx, channel, "", g.historyIDs.next(publishedAt), payload),
+			Expire: g.router.RouteExpire(channel),
+		}
+	}
+
+	g.prepareWorkerStreams(ctx, workerID)
+	_, errs := g.redis.XAddPipeline(ctx, entries)
+
+	failed := 0
+	for i, err := range errs {
+		if err != nil {
+			failed++
+			messageIDs[i] = ""
+			g.metrics.PublishTotal.WithLabelValues("error", "redis_error").Inc()
+			slog.ErrorContext(ctx, "failed to write batch message to stream", "streamKey", streamKey, "index", i, "error", err)
+			g.deadLetter(ctx, streamKey, payloads[i], err, g.config.RedisMaxPublishRetries)
+			continue
+		}
+
+		g.metrics.PublishTotal.WithLabelValues("success", "").Inc()
+		g.router.RecordChannelMessage(channel)
+		if _, err := g.node.Publish(channel, raws[i]); err != nil {
+			slog.WarnContext(ctx, "failed to broadcast batch message", "channel", channel, "messageId", messageIDs[i], "error", err)
+		}
+	}
+
+	if published := len(msgs) - failed; published > 0 {
+		g.historyCache.remove(channel)
+		g.incrChannelStat(ctx, channel, statsFieldMessages, int64(published))
+	}
+
+	slog.InfoContext(ctx, "batch published",
+		"channel", channel,
+		"streamKey", streamKey,
+		"workerId", workerID,
+		"size", len(msgs),
+		"failed", failed,
+	)
+
+	if failed > 0 {
+		return messageIDs, fmt.Errorf("%w: %d of %d", ErrPartialBatch, failed, len(msgs))
+	}
+	return messageIDs, nil
+}
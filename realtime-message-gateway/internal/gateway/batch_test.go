@@ -0,0 +1,82 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"realtime-message-gateway/internal/config"
+)
+
+func TestPublishBatchValidation(t *testing.T) {
+	gw := &Gateway{config: &config.Config{MaxTextLength: 10}, sanitizer: NewDefaultSanitizer(10)}
+
+	tooMany := make([]BatchMessage, MaxBatchSize+1)
+	for i := range tooMany {
+		tooMany[i] = BatchMessage{Text: "hi"}
+	}
+
+	tests := []struct {
+		name string
+		msgs []BatchMessage
+	}{
+		{"empty batch", nil},
+		{"too many messages", tooMany},
+		{"missing text", []BatchMessage{{Text: "hi"}, {Text: "  "}}},
+		{"text too long", []BatchMessage{{Text: strings.Repeat("a", 11)}}},
+		{"only control characters", []BatchMessage{{Text: "\x00\x1b"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := gw.PublishBatch(context.Background(), "chat", tt.msgs)
+			if !errors.Is(err, ErrInvalidBatch) {
+				t.Errorf("PublishBatch() error = %v, want %v", err, ErrInvalidBatch)
+			}
+			if tooLarge := errors.Is(err, ErrMessageTooLarge); tooLarge != (tt.name == "text too long") {
+				t.Errorf("errors.Is(err, ErrMessageTooLarge) = %v for %v", tooLarge, err)
+			}
+		})
+	}
+}
+
+func TestPublishBatchAuthorization(t *testing.T) {
+	gw := NewTestGateway(t)
+	gw.config.PrivateChannelPrefix = "private:"
+	gw.config.StrictNamespaceMode = true
+	gw.config.Namespaces = []config.NamespaceConfig{
+		{Name: "chat"}, {Name: "user"}, {Name: "private"}, {Name: "news", Protected: true},
+	}
+
+	tests := []struct {
+		name    string
+		channel string
+		msgs    []BatchMessage
+		wantErr error
+	}{
+		{"allowed channel", "chat:general", []BatchMessage{{Text: "hi", UserID: "alice"}}, nil},
+		{"own user channel", "user:alice", []BatchMessage{{Text: "hi", UserID: "alice"}}, nil},
+		{"other user's channel", "user:alice", []BatchMessage{{Text: "hi", UserID: "alice"}, {Text: "hi", UserID: "bob"}}, ErrPermissionDenied},
+		{"private channel", "private:x", []BatchMessage{{Text: "hi"}}, ErrPermissionDenied},
+		{"protected namespace", "news:today", []BatchMessage{{Text: "hi"}}, ErrPermissionDenied},
+		{"room channel", Room{ID: "r1"}.Channel(), []BatchMessage{{Text: "hi"}}, ErrPermissionDenied},
+		{"unnormalized channel", "chat:Room 1", []BatchMessage{{Text: "hi"}}, ErrPermissionDenied},
+		{"unknown namespace", "sports:x", []BatchMessage{{Text: "hi"}}, ErrChannelNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ids, err := gw.PublishBatch(context.Background(), tt.channel, tt.msgs)
+			if tt.wantErr == nil {
+				if err != nil || len(ids) != len(tt.msgs) {
+					t.Errorf("PublishBatch() = %v, %v, want %d IDs", ids, err, len(tt.msgs))
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) || ids != nil {
+				t.Errorf("PublishBatch() = %v, %v, want %v", ids, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"realtime-message-gateway/internal/routing"
+)
+
+// serverUserName is the user name of messages without a client sender
+const serverUserName = "System"
+
+// ErrEmptyServerMessage is returned for a server message without text
+var ErrEmptyServerMessage = errors.New("text is required")
+
+// ServerMessage is a message the application backend publishes through the
+// admin API rather than a connected client
+type ServerMessage struct {
+	Text     string `json:"text"`
+	UserName string `json:"userName,omitempty"` // default serverUserName
+}
+
+// BroadcastToChannel publishes msg as JSON to the subscribers of channel
+// connected to this gateway and forwards it to the other registered
+// gateways for theirs. It does not go through a worker stream, so no worker
+// sees or processes the message.
+func (g *Gateway) BroadcastToChannel(ctx context.Context, channel string, msg StreamMessage) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	if _, err := g.node.Publish(channel, data); err != nil {
+		return fmt.Errorf("broadcast to channel %q: %w", channel, err)
+	}
+	g.forwardToGateways(ctx, channel, data)
+	slog.InfoContext(ctx, "server message broadcast", "channel", channel, "messageId", msg.ID)
+	return nil
+}
+
+// PublishServerMessage sanitizes m and broadcasts it to channel as a
+// StreamMessage with BroadcastToChannel. Returns the broadcast message.
+func (g *Gateway) PublishServerMessage(ctx context.Context, channel string, m ServerMessage) (StreamMessage, error) {
+	text, err := g.sanitizer.Sanitize(m.Text)
+	if errors.Is(err, ErrTextTooLong) {
+		return StreamMessage{}, ErrMessageTooLarge.Wrap(err)
+	}
+	if err != nil {
+		return StreamMessage{}, err
+	}
+	if strings.TrimSpace(text) == "" {
+		return StreamMessage{}, ErrEmptyServerMessage
+	}
+	userName := m.UserName
+	if userName == "" {
+		userName = serverUserName
+	}
+
+	raw, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return StreamMessage{}, err
+	}
+	msg := StreamMessage{
+		SchemaVersion: g.config.StreamSchemaVersion,
+		ID:            uuid.New().String(),
+		Type:          EventTypeMessage,
+		Channel:       channel,
+		UserName:      userName,
+		Text:          strings.TrimSpace(text),
+		ContentType:   ContentTypeText,
+		Timestamp:     time.Now().UTC().Format(time.RFC3339Nano),
+		Raw:           string(raw),
+		GatewayID:     g.instanceID,
+		Priority:      routing.PriorityNormal,
+	}
+	g.signMessage(&msg)
+
+	if err := g.BroadcastToChannel(ctx, channel, msg); err != nil {
+		return StreamMessage{}, err
+	}
+	return msg, nil
+}
@@ -0,0 +1,64 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/centrifugal/centrifuge"
+)
+
+func TestPublishServerMessage(t *testing.T) {
+	gw := NewTestGateway(t)
+	gw.config.MessageSigningKey = "secret"
+	server := httptest.NewServer(gw.WebsocketHandler(centrifuge.WebsocketConfig{}))
+	defer server.Close()
+	ctx := context.Background()
+
+	bob := dialTestClient(t, server, "Bob")
+	bob.command(2, "subscribe", map[string]string{"channel": "chat"})
+
+	sent, err := gw.PublishServerMessage(ctx, "chat", ServerMessage{Text: " maintenance at noon "})
+	if err != nil {
+		t.Fatalf("PublishServerMessage() error = %v", err)
+	}
+
+	push := bob.await(func(r wsReply) bool {
+		return r.Push != nil && r.Push.Channel == "chat" && r.Push.Pub != nil
+	})
+	var got StreamMessage
+	if err := json.Unmarshal(push.Push.Pub.Data, &got); err != nil {
+		t.Fatalf("publication data %s: %v", push.Push.Pub.Data, err)
+	}
+	if got.ID != sent.ID || got.Text != "maintenance at noon" || got.UserName != serverUserName || got.Type != EventTypeMessage {
+		t.Errorf("publication = %+v, want the server message %+v", got, sent)
+	}
+	if !VerifySignature(got, "secret") {
+		t.Error("server message signature does not verify")
+	}
+
+	// Workers never see the message
+	if types := presenceEventTypes(t, gw, "chat"); slices.Contains(types, EventTypeMessage) {
+		t.Errorf("worker stream events = %v, want no message", types)
+	}
+
+	tests := []struct {
+		name    string
+		text    string
+		wantErr error
+	}{
+		{"empty text", "  ", ErrEmptyServerMessage},
+		{"text too long", strings.Repeat("a", gw.config.MaxTextLength+1), ErrMessageTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := gw.PublishServerMessage(ctx, "chat", ServerMessage{Text: tt.text}); !errors.Is(err, tt.wantErr) {
+				t.Errorf("PublishServerMessage() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package gateway
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+// Page sizes of the HTTP channel list endpoint
+const (
+	DefaultChannelListLimit = 100
+	MaxChannelListLimit     = 500
+)
+
+// ChannelSummary is a channel with subscribers on this gateway.
+// LastMessageAt is when a message was last published to the channel through
+// this gateway, absent if none was.
+type ChannelSummary struct {
+	Name            string     `json:"name"`
+	SubscriberCount int        `json:"subscriberCount"`
+	LastMessageAt   *time.Time `json:"lastMessageAt,omitempty"`
+}
+
+// ListChannels returns a page of the local channels whose name starts with
+// prefix and that have at least minSubscribers subscribers, sorted by name,
+// and the total number of matching channels. The channel snapshot is reused
+// for ChannelListCacheTTL.
+func (g *Gateway) ListChannels(prefix string, minSubscribers, offset, limit int) ([]ChannelSummary, int) {
+	var matching []ChannelSummary
+	for _, summary := range g.channelSummaries() {
+		if strings.HasPrefix(summary.Name, prefix) && summary.SubscriberCount >= minSubscribers {
+			matching = append(matching, summary)
+		}
+	}
+	return page(matching, offset, limit), len(matching)
+}
+
+// channelSummaries returns all local channels with subscribers, from the
+// cache while it is fresh
+func (g *Gateway) channelSummaries() []ChannelSummary {
+	g.channelListMu.Lock()
+	defer g.channelListMu.Unlock()
+
+	if g.channelList != nil && time.Since(g.channelListAt) < g.config.ChannelListCacheTTL {
+		return g.channelList
+	}
+
+	hub := g.node.Hub()
+	summaries := []ChannelSummary{}
+	for _, channel := range hub.Channels() {
+		n := hub.NumSubscribers(channel)
+		if n == 0 {
+			continue
+		}
+		summary := ChannelSummary{Name: channel, SubscriberCount: n}
+		if at, ok := g.router.LastChannelMessage(channel); ok {
+			summary.LastMessageAt = &at
+		}
+		summaries = append(summaries, summary)
+	}
+	slices.SortFunc(summaries, func(a, b ChannelSummary) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	g.channelList = summaries
+	g.channelListAt = time.Now()
+	return summaries
+}
@@ -0,0 +1,92 @@
+package gateway
+
+import (
+	"testing"
+	"time"
+)
+
+// waitSubscribers waits until channel has n local subscribers
+func waitSubscribers(t *testing.T, gw *Gateway, channel string, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for gw.node.Hub().NumSubscribers(channel) < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("%s has %d subscribers, want %d", channel, gw.node.Hub().NumSubscribers(channel), n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestListChannels(t *testing.T) {
+	gw := NewTestGateway(t)
+	subscriptions := map[string]int{"chat": 1, "chat:a": 2, "chat:b": 3, "chat:c": 1}
+	for channel, n := range subscriptions {
+		for i := 0; i < n; i++ {
+			subscribeTestClient(connectTestClient(t, gw), 2, channel)
+		}
+		waitSubscribers(t, gw, channel, n)
+	}
+	gw.router.RecordChannelMessage("chat:b")
+
+	tests := []struct {
+		name           string
+		prefix         string
+		minSubscribers int
+		offset, limit  int
+		want           []string
+		wantTotal      int
+	}{
+		{"all", "", 1, 0, 0, []string{"chat", "chat:a", "chat:b", "chat:c"}, 4},
+		{"prefix", "chat:", 1, 0, 0, []string{"chat:a", "chat:b", "chat:c"}, 3},
+		{"min subscribers", "", 2, 0, 0, []string{"chat:a", "chat:b"}, 2},
+		{"first page", "", 1, 0, 3, []string{"chat", "chat:a", "chat:b"}, 4},
+		{"second page", "", 1, 3, 3, []string{"chat:c"}, 4},
+		{"offset past end", "", 1, 10, 3, []string{}, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, total := gw.ListChannels(tt.prefix, tt.minSubscribers, tt.offset, tt.limit)
+			if total != tt.wantTotal {
+				t.Errorf("total = %d, want %d", total, tt.wantTotal)
+			}
+			names := make([]string, len(got))
+			for i, summary := range got {
+				names[i] = summary.Name
+				if want := subscriptions[summary.Name]; summary.SubscriberCount != want {
+					t.Errorf("%s SubscriberCount = %d, want %d", summary.Name, summary.SubscriberCount, want)
+				}
+				if (summary.LastMessageAt != nil) != (summary.Name == "chat:b") {
+					t.Errorf("%s LastMessageAt = %v", summary.Name, summary.LastMessageAt)
+				}
+			}
+			if len(names) != len(tt.want) {
+				t.Fatalf("ListChannels() = %v, want %v", names, tt.want)
+			}
+			for i := range names {
+				if names[i] != tt.want[i] {
+					t.Fatalf("ListChannels() = %v, want %v", names, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestListChannelsCache(t *testing.T) {
+	gw := NewTestGateway(t)
+	gw.config.ChannelListCacheTTL = time.Minute
+
+	if _, total := gw.ListChannels("", 1, 0, 0); total != 0 {
+		t.Fatalf("total = %d, want 0", total)
+	}
+	subscribeTestClient(connectTestClient(t, gw), 2, "chat")
+	waitSubscribers(t, gw, "chat", 1)
+
+	if _, total := gw.ListChannels("", 1, 0, 0); total != 0 {
+		t.Errorf("total within cache TTL = %d, want cached 0", total)
+	}
+	gw.config.ChannelListCacheTTL = 0
+	if _, total := gw.ListChannels("", 1, 0, 0); total != 1 {
+		t.Errorf("total after cache TTL = %d, want 1", total)
+	}
+}
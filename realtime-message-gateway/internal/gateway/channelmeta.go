@@ -0,0 +1,53 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"realtime-message-gateway/internal/redis"
+)
+
+// ChannelMetaPrefix is the key prefix of channel metadata strings
+const ChannelMetaPrefix = "channel:meta:"
+
+var (
+	// ErrInvalidMetadata is returned when channel metadata is not valid JSON
+	ErrInvalidMetadata = errors.New("channel metadata must be valid JSON")
+	// ErrMetadataTooLarge is returned when channel metadata exceeds
+	// ChannelMetadataMaxSize
+	ErrMetadataTooLarge = errors.New("channel metadata too large")
+)
+
+// SetChannelMetadata replaces the metadata of channel with the JSON document
+// data. A positive ttl expires the metadata; otherwise it is kept until
+// deleted.
+func (g *Gateway) SetChannelMetadata(ctx context.Context, channel string, data []byte, ttl time.Duration) error {
+	if len(data) > g.config.ChannelMetadataMaxSize {
+		return ErrMetadataTooLarge
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, data); err != nil {
+		return ErrInvalidMetadata
+	}
+	return g.redis.Set(ctx, ChannelMetaPrefix+channel, compact.String(), max(ttl, 0))
+}
+
+// ChannelMetadata returns the metadata of channel, or nil if it has none
+func (g *Gateway) ChannelMetadata(ctx context.Context, channel string) (json.RawMessage, error) {
+	data, err := g.redis.Get(ctx, ChannelMetaPrefix+channel)
+	if redis.IsNil(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return json.RawMessage(data), nil
+}
+
+// DeleteChannelMetadata removes the metadata of channel
+func (g *Gateway) DeleteChannelMetadata(ctx context.Context, channel string) error {
+	return g.redis.Del(ctx, ChannelMetaPrefix+channel)
+}
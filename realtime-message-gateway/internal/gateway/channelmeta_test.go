@@ -0,0 +1,100 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/centrifugal/centrifuge"
+	"github.com/centrifugal/protocol"
+)
+
+func TestChannelMetadataLifecycle(t *testing.T) {
+	gw := NewTestGateway(t)
+	ctx := context.Background()
+
+	metadata, err := gw.ChannelMetadata(ctx, "chat:a")
+	if err != nil || metadata != nil {
+		t.Fatalf("ChannelMetadata() before set = %s, %v, want nil, nil", metadata, err)
+	}
+
+	if err := gw.SetChannelMetadata(ctx, "chat:a", []byte(`{ "topic": "go" }`), 0); err != nil {
+		t.Fatalf("SetChannelMetadata() error = %v", err)
+	}
+	metadata, err = gw.ChannelMetadata(ctx, "chat:a")
+	if err != nil || string(metadata) != `{"topic":"go"}` {
+		t.Errorf("ChannelMetadata() = %s, %v, want compacted JSON", metadata, err)
+	}
+
+	if err := gw.SetChannelMetadata(ctx, "chat:a", []byte(`["replaced"]`), time.Minute); err != nil {
+		t.Fatalf("SetChannelMetadata() replace error = %v", err)
+	}
+	metadata, _ = gw.ChannelMetadata(ctx, "chat:a")
+	if string(metadata) != `["replaced"]` {
+		t.Errorf("ChannelMetadata() after replace = %s", metadata)
+	}
+
+	if err := gw.DeleteChannelMetadata(ctx, "chat:a"); err != nil {
+		t.Fatalf("DeleteChannelMetadata() error = %v", err)
+	}
+	metadata, err = gw.ChannelMetadata(ctx, "chat:a")
+	if err != nil || metadata != nil {
+		t.Errorf("ChannelMetadata() after delete = %s, %v, want nil, nil", metadata, err)
+	}
+}
+
+func TestSetChannelMetadataValidation(t *testing.T) {
+	gw := NewTestGateway(t)
+	limit := gw.config.ChannelMetadataMaxSize
+
+	tests := []struct {
+		name    string
+		data    string
+		wantErr error
+	}{
+		{"invalid JSON", `{"topic":`, ErrInvalidMetadata},
+		{"empty body", ``, ErrInvalidMetadata},
+		{"too large", `"` + strings.Repeat("a", limit) + `"`, ErrMetadataTooLarge},
+		{"at limit", `"` + strings.Repeat("a", limit-2) + `"`, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := gw.SetChannelMetadata(context.Background(), "chat:a", []byte(tt.data), 0)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("SetChannelMetadata() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSubscribeReplyIncludesChannelMetadata(t *testing.T) {
+	gw := NewTestGateway(t)
+	if err := gw.SetChannelMetadata(context.Background(), "chat:a", []byte(`{"topic":"go"}`), 0); err != nil {
+		t.Fatalf("SetChannelMetadata() error = %v", err)
+	}
+
+	transport := &testTransport{}
+	client, closeFn, err := centrifuge.NewClient(context.Background(), gw.node, transport)
+	if err != nil {
+		t.Fatalf("centrifuge.NewClient() error = %v", err)
+	}
+	t.Cleanup(func() { closeFn() })
+	client.HandleCommand(&protocol.Command{Id: 1, Connect: &protocol.ConnectRequest{}}, 0)
+	subscribeTestClient(client, 2, "chat:a")
+
+	transport.mu.Lock()
+	defer transport.mu.Unlock()
+	for _, msg := range transport.messages {
+		if bytes.Contains(msg, []byte(`"id":2`)) {
+			if !bytes.Contains(msg, []byte(`"data":{"topic":"go"}`)) {
+				t.Errorf("subscribe reply = %s, want channel metadata in data", msg)
+			}
+			return
+		}
+	}
+	t.Error("no subscribe reply written")
+}
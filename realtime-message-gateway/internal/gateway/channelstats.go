@@ -0,0 +1,157 @@
+package gateway
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"realtime-message-gateway/internal/redis"
+)
+
+const (
+	// ChannelStatsPrefix is the key prefix of per-channel stats hashes
+	ChannelStatsPrefix = "channel:stats:"
+
+	// channelStatsTTL expires a stats hash this long after its last write
+	channelStatsTTL = 30 * 24 * time.Hour
+)
+
+// Channel stats hash fields
+const (
+	statsFieldMessages    = "messages"
+	statsFieldSubscribers = "subscribers"
+)
+
+// ChannelStats holds the counters of a channel:stats:{channel} hash
+type ChannelStats struct {
+	Channel     string `json:"channel"`
+	Messages    int64  `json:"messages"`
+	Subscribers int64  `json:"subscribers"`
+}
+
+// ChannelStats returns the stats of channel; unknown channels have zero counts
+func (g *Gateway) ChannelStats(ctx context.Context, channel string) (ChannelStats, error) {
+	values, err := g.redis.HGetAll(ctx, ChannelStatsPrefix+channel)
+	if err != nil {
+		return ChannelStats{}, err
+	}
+	return newChannelStats(channel, values), nil
+}
+
+// channelSubscribers reads only the subscribers field of the stats of
+// channel; unknown channels have zero subscribers
+func (g *Gateway) channelSubscribers(ctx context.Context, channel string) (int64, error) {
+	value, err := g.redis.HGet(ctx, ChannelStatsPrefix+channel, statsFieldSubscribers)
+	if redis.IsNil(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	subscribers, _ := strconv.ParseInt(value, 10, 64)
+	return subscribers, nil
+}
+
+// ChannelMessageRate returns the messages per second published to channel
+// through this gateway over the last windowSeconds seconds
+func (g *Gateway) ChannelMessageRate(channel string, windowSeconds int) float64 {
+	return g.router.GetChannelMessageRate(channel, windowSeconds)
+}
+
+// newChannelStats converts hash values into ChannelStats, treating missing
+// or malformed fields as zero
+func newChannelStats(channel string, values map[string]string) ChannelStats {
+	messages, _ := strconv.ParseInt(values[statsFieldMessages], 10, 64)
+	subscribers, _ := strconv.ParseInt(values[statsFieldSubscribers], 10, 64)
+	return ChannelStats{
+		Channel:     channel,
+		Messages:    messages,
+		Subscribers: subscribers,
+	}
+}
+
+// incrChannelStat adjusts a channel stats field and refreshes the hash TTL.
+// Failures are logged only; stats must never fail the request.
+func (g *Gateway) incrChannelStat(ctx context.Context, channel, field string, delta int64) {
+	if _, err := g.redis.HIncrByExpire(ctx, ChannelStatsPrefix+channel, field, delta, channelStatsTTL); err != nil {
+		slog.WarnContext(ctx, "failed to update channel stats", "channel", channel, "field", field, "error", err)
+		return
+	}
+	g.statsChannels.Store(channel, struct{}{})
+}
+
+// channelStatsExporter periodically copies the stats of channels this
+// gateway has written to into Prometheus gauges
+func (g *Gateway) channelStatsExporter(ctx context.Context) {
+	defer g.wg.Done()
+
+	ticker := time.NewTicker(g.config.ChannelStatsInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		g.statsChannels.Range(func(key, _ interface{}) bool {
+			channel := key.(string)
+			stats, err := g.ChannelStats(ctx, channel)
+			if err != nil {
+				slog.Warn("failed to read channel stats", "channel", channel, "error", err)
+				return ctx.Err() == nil
+			}
+
+			// The hash expired: stop exporting the channel
+			if stats.Messages == 0 && stats.Subscribers == 0 {
+				g.statsChannels.Delete(channel)
+				g.metrics.ChannelMessages.DeleteLabelValues(channel)
+				g.metrics.ChannelSubscribers.DeleteLabelValues(channel)
+				return true
+			}
+
+			g.metrics.ChannelMessages.WithLabelValues(channel).Set(float64(stats.Messages))
+			g.metrics.ChannelSubscribers.WithLabelValues(channel).Set(float64(stats.Subscribers))
+			return true
+		})
+	}
+}
+
+// channelSubscriberSampler periodically samples the local subscriber count
+// distribution across channels
+func (g *Gateway) channelSubscriberSampler(ctx context.Context) {
+	defer g.wg.Done()
+
+	ticker := time.NewTicker(g.config.ChannelStatsSampleInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			g.sampleChannelSubscribers()
+		}
+	}
+}
+
+// sampleChannelSubscribers observes the subscriber count of every local
+// channel with subscribers and returns the number of such channels
+func (g *Gateway) sampleChannelSubscribers() int {
+	hub := g.node.Hub()
+
+	active := 0
+	for _, channel := range hub.Channels() {
+		n := hub.NumSubscribers(channel)
+		if n == 0 {
+			continue
+		}
+		active++
+		g.metrics.ChannelSubscriberCount.Observe(float64(n))
+	}
+
+	g.metrics.ActiveChannelsTotal.Set(float64(active))
+	return active
+}
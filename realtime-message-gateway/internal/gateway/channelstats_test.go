@@ -0,0 +1,62 @@
+package gateway
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"realtime-message-gateway/internal/metrics"
+)
+
+func TestNewChannelStats(t *testing.T) {
+	tests := []struct {
+		name   string
+		values map[string]string
+		want   ChannelStats
+	}{
+		{"missing hash", map[string]string{}, ChannelStats{Channel: "chat"}},
+		{"both fields", map[string]string{"messages": "42", "subscribers": "3"}, ChannelStats{Channel: "chat", Messages: 42, Subscribers: 3}},
+		{"only messages", map[string]string{"messages": "7"}, ChannelStats{Channel: "chat", Messages: 7}},
+		{"negative subscribers", map[string]string{"subscribers": "-1"}, ChannelStats{Channel: "chat", Subscribers: -1}},
+		{"malformed field", map[string]string{"messages": "abc", "subscribers": "2"}, ChannelStats{Channel: "chat", Subscribers: 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := newChannelStats("chat", tt.values); got != tt.want {
+				t.Errorf("newChannelStats() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSampleChannelSubscribers(t *testing.T) {
+	gw := NewTestGateway(t)
+	for _, id := range []string{"1", "2"} {
+		if _, err := gw.CreateRoom(context.Background(), Room{ID: id}); err != nil {
+			t.Fatalf("CreateRoom() error = %v", err)
+		}
+	}
+
+	channels := []string{"chat", "chat:room-1", "chat:room-2"}
+	for i, channel := range channels {
+		client := connectTestClient(t, gw)
+		subscribeTestClient(client, uint32(i+2), channel)
+	}
+	// A second subscriber on an existing channel adds no active channel
+	subscribeTestClient(connectTestClient(t, gw), 2, "chat")
+
+	deadline := time.Now().Add(2 * time.Second)
+	for gw.node.Hub().NumChannels() < len(channels) && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if got := gw.sampleChannelSubscribers(); got != len(channels) {
+		t.Errorf("sampleChannelSubscribers() = %d, want %d", got, len(channels))
+	}
+	if got := testutil.ToFloat64(metrics.Default.ActiveChannelsTotal); got != float64(len(channels)) {
+		t.Errorf("active_channels_total = %v, want %d", got, len(channels))
+	}
+}
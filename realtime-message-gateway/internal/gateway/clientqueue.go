@@ -0,0 +1,188 @@
+package gateway
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"realtime-message-gateway/internal/redis"
+	"realtime-message-gateway/internal/requestid"
+)
+
+// queuedMessage is a stream write waiting for Redis to come back
+type queuedMessage struct {
+	id           string // StreamMessage ID
+	requestID    string
+	channel      string
+	userID       string
+	streamKey    string
+	payload      []byte
+	history      *redis.ListPush  // written with the stream entry
+	routeExpire  *redis.KeyExpire // route TTL reset with the stream entry
+	traceContext string           // W3C traceparent of the publish span
+	receivedAt   time.Time        // when the client published, for E2ELatency
+}
+
+// clientQueue is a bounded FIFO ring buffer of a client's pending stream
+// writes. When full, the oldest message is dropped.
+type clientQueue struct {
+	mu   sync.Mutex
+	buf  []queuedMessage
+	head int
+	size int
+}
+
+// newClientQueue creates a clientQueue holding up to depth messages
+func newClientQueue(depth int) *clientQueue {
+	return &clientQueue{buf: make([]queuedMessage, depth)}
+}
+
+// push appends msg, dropping the oldest message if the queue is full.
+// Returns true if a message was dropped.
+func (q *clientQueue) push(msg queuedMessage) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	dropped := false
+	if q.size == len(q.buf) {
+		q.buf[q.head] = queuedMessage{}
+		q.head = (q.head + 1) % len(q.buf)
+		q.size--
+		dropped = true
+	}
+	q.buf[(q.head+q.size)%len(q.buf)] = msg
+	q.size++
+	return dropped
+}
+
+// peek returns the oldest message without removing it
+func (q *clientQueue) peek() (queuedMessage, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.size == 0 {
+		return queuedMessage{}, false
+	}
+	return q.buf[q.head], true
+}
+
+// pop removes the oldest message if it is still the one with id.
+// A concurrent overflow may have dropped it after peek.
+func (q *clientQueue) pop(id string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.size == 0 || q.buf[q.head].id != id {
+		return
+	}
+	q.buf[q.head] = queuedMessage{}
+	q.head = (q.head + 1) % len(q.buf)
+	q.size--
+}
+
+// len returns the number of queued messages
+func (q *clientQueue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.size
+}
+
+// drain discards all queued messages and returns how many there were
+func (q *clientQueue) drain() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	n := q.size
+	clear(q.buf)
+	q.head = 0
+	q.size = 0
+	return n
+}
+
+// clientQueueFor returns the publish queue of a local client, or nil if
+// queueing is disabled or the client is gone
+func (g *Gateway) clientQueueFor(clientID string) *clientQueue {
+	g.connectionsMu.RLock()
+	defer g.connectionsMu.RUnlock()
+
+	if meta, ok := g.connections[clientID]; ok {
+		return meta.queue
+	}
+	return nil
+}
+
+// enqueue stores a failed stream write on the client's queue
+func (g *Gateway) enqueue(ctx context.Context, queue *clientQueue, msg queuedMessage) {
+	if queue.push(msg) {
+		g.metrics.ClientQueueOverflowTotal.Inc()
+		slog.WarnContext(ctx, "client queue full, dropped oldest message", "streamKey", msg.streamKey)
+	}
+}
+
+// clientQueueFlusher periodically retries queued stream writes of all
+// local clients
+func (g *Gateway) clientQueueFlusher(ctx context.Context) {
+	defer g.wg.Done()
+
+	ticker := time.NewTicker(g.config.ClientQueueFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		// The health checker flushes once Redis is reachable again
+		if g.RedisDegraded() {
+			continue
+		}
+		g.flushClientQueues(ctx)
+	}
+}
+
+// flushClientQueues retries queued stream writes of all local clients. A
+// message is sent before it is popped, so flushes run one at a time.
+func (g *Gateway) flushClientQueues(ctx context.Context) {
+	g.queueFlushMu.Lock()
+	defer g.queueFlushMu.Unlock()
+
+	g.connectionsMu.RLock()
+	queues := make([]*clientQueue, 0, len(g.connections))
+	for _, meta := range g.connections {
+		if meta.queue != nil && meta.queue.len() > 0 {
+			queues = append(queues, meta.queue)
+		}
+	}
+	g.connectionsMu.RUnlock()
+
+	for _, queue := range queues {
+		g.flushClientQueue(ctx, queue)
+	}
+}
+
+// flushClientQueue writes queued messages in order, stopping at the first
+// failure so the rest are retried on the next tick
+func (g *Gateway) flushClientQueue(ctx context.Context, queue *clientQueue) {
+	for {
+		msg, ok := queue.peek()
+		if !ok {
+			return
+		}
+
+		msgCtx := requestid.WithID(ctx, msg.requestID)
+		_, errs := g.redis.XAddPipeline(msgCtx, []redis.StreamEntry{{Stream: msg.streamKey, Values: streamEntry(msg.payload, msg.traceContext), Push: msg.history, Expire: msg.routeExpire}})
+		if err := errs[0]; err != nil {
+			slog.DebugContext(msgCtx, "client queue flush failed", "streamKey", msg.streamKey, "error", err)
+			return
+		}
+		queue.pop(msg.id)
+		g.metrics.E2ELatency.Observe(time.Since(msg.receivedAt).Seconds())
+		g.incrChannelStat(msgCtx, msg.channel, statsFieldMessages, 1)
+		g.historyCache.remove(msg.channel)
+
+		slog.InfoContext(msgCtx, "queued message published", "messageId", msg.id, "streamKey", msg.streamKey)
+	}
+}
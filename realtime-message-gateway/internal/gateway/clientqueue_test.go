@@ -0,0 +1,93 @@
+package gateway
+
+import (
+	"fmt"
+	"testing"
+)
+
+// queueIDs drains q via peek/pop and returns the message IDs in order
+func queueIDs(q *clientQueue) []string {
+	var ids []string
+	for {
+		msg, ok := q.peek()
+		if !ok {
+			return ids
+		}
+		ids = append(ids, msg.id)
+		q.pop(msg.id)
+	}
+}
+
+func TestClientQueue(t *testing.T) {
+	tests := []struct {
+		name        string
+		depth       int
+		pushes      int
+		wantDropped int
+		wantIDs     []string
+	}{
+		{"empty", 3, 0, 0, nil},
+		{"below depth", 3, 2, 0, []string{"m0", "m1"}},
+		{"at depth", 3, 3, 0, []string{"m0", "m1", "m2"}},
+		{"overflow drops oldest", 3, 5, 2, []string{"m2", "m3", "m4"}},
+		{"depth one", 1, 3, 2, []string{"m2"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := newClientQueue(tt.depth)
+
+			dropped := 0
+			for i := 0; i < tt.pushes; i++ {
+				if q.push(queuedMessage{id: fmt.Sprintf("m%d", i)}) {
+					dropped++
+				}
+			}
+
+			if dropped != tt.wantDropped {
+				t.Errorf("dropped = %d, want %d", dropped, tt.wantDropped)
+			}
+			if q.len() != len(tt.wantIDs) {
+				t.Errorf("len() = %d, want %d", q.len(), len(tt.wantIDs))
+			}
+			if got := queueIDs(q); fmt.Sprint(got) != fmt.Sprint(tt.wantIDs) {
+				t.Errorf("queued IDs = %v, want %v", got, tt.wantIDs)
+			}
+		})
+	}
+}
+
+func TestClientQueuePopAfterOverflow(t *testing.T) {
+	q := newClientQueue(2)
+	q.push(queuedMessage{id: "m0"})
+	q.push(queuedMessage{id: "m1"})
+
+	// m0 is dropped by an overflow between peek and pop
+	head, _ := q.peek()
+	q.push(queuedMessage{id: "m2"})
+	q.pop(head.id)
+
+	if got := queueIDs(q); fmt.Sprint(got) != "[m1 m2]" {
+		t.Errorf("queued IDs = %v, want [m1 m2]", got)
+	}
+}
+
+func TestClientQueueDrain(t *testing.T) {
+	q := newClientQueue(4)
+	for i := 0; i < 3; i++ {
+		q.push(queuedMessage{id: fmt.Sprintf("m%d", i)})
+	}
+
+	if n := q.drain(); n != 3 {
+		t.Errorf("drain() = %d, want 3", n)
+	}
+	if q.len() != 0 {
+		t.Errorf("len() after drain = %d, want 0", q.len())
+	}
+
+	// The queue is reusable after draining
+	q.push(queuedMessage{id: "m3"})
+	if got := queueIDs(q); fmt.Sprint(got) != "[m3]" {
+		t.Errorf("queued IDs = %v, want [m3]", got)
+	}
+}
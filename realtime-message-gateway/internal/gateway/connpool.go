@@ -0,0 +1,150 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"realtime-message-gateway/internal/redis"
+	"realtime-message-gateway/internal/routing"
+)
+
+// Gateways heartbeat into routing.GatewayRegistryKey every
+// gatewayHeartbeatInterval; members without a heartbeat for
+// gatewayHeartbeatTimeout are removed as crashed
+const (
+	gatewayHeartbeatInterval = 10 * time.Second
+	gatewayHeartbeatTimeout  = 3 * gatewayHeartbeatInterval
+)
+
+// ErrGatewayNotRegistered is returned when forwarding to a gateway that is
+// not in the gateway registry
+var ErrGatewayNotRegistered = errors.New("gateway not registered")
+
+// ConnectionPool spans the client connections of all gateways of a
+// deployment, so a message can be delivered to clients connected to a
+// gateway other than the one that received it
+type ConnectionPool interface {
+	// IsLocalClient reports whether clientID is connected to this gateway
+	IsLocalClient(clientID string) bool
+	// ForwardToGateway has gatewayID publish payload to the subscribers of
+	// channel connected to it
+	ForwardToGateway(ctx context.Context, gatewayID, channel string, payload []byte) error
+}
+
+// RedisConnectionPool forwards messages through the outbound stream of the
+// target gateway (messages:gateway:{instanceID}), which its outboundConsumer
+// publishes to local subscribers like entries pushed by workers
+type RedisConnectionPool struct {
+	redis   *redis.Client
+	isLocal func(clientID string) bool
+}
+
+// NewRedisConnectionPool creates a RedisConnectionPool. isLocal reports
+// whether a client is connected to this gateway.
+func NewRedisConnectionPool(redisClient *redis.Client, isLocal func(clientID string) bool) *RedisConnectionPool {
+	return &RedisConnectionPool{redis: redisClient, isLocal: isLocal}
+}
+
+// IsLocalClient reports whether clientID is connected to this gateway
+func (p *RedisConnectionPool) IsLocalClient(clientID string) bool {
+	return p.isLocal(clientID)
+}
+
+// ForwardToGateway adds payload for channel to the outbound stream of
+// gatewayID. Returns ErrGatewayNotRegistered for gateways missing from the
+// registry, whose stream nobody would read.
+func (p *RedisConnectionPool) ForwardToGateway(ctx context.Context, gatewayID, channel string, payload []byte) error {
+	if _, err := p.redis.ZScore(ctx, routing.GatewayRegistryKey, gatewayID); err != nil {
+		if redis.IsNil(err) {
+			return fmt.Errorf("%w: %s", ErrGatewayNotRegistered, gatewayID)
+		}
+		return err
+	}
+	_, err := p.redis.XAdd(ctx, routing.GetGatewayStreamKey(gatewayID), map[string]interface{}{
+		"channel": channel,
+		"payload": string(payload),
+	})
+	return err
+}
+
+// isLocalClient reports whether clientID is connected to this gateway
+func (g *Gateway) isLocalClient(clientID string) bool {
+	g.connectionsMu.RLock()
+	defer g.connectionsMu.RUnlock()
+	_, ok := g.connections[clientID]
+	return ok
+}
+
+// registerGateway adds this gateway to the gateway registry, keeping the
+// score of an entry left by an earlier run with the same instance ID
+func (g *Gateway) registerGateway(ctx context.Context, now time.Time) error {
+	_, err := g.redis.ZAddNX(ctx, routing.GatewayRegistryKey, redis.Z{Score: float64(now.UnixMilli()), Member: g.instanceID})
+	return err
+}
+
+// gatewayHeartbeat refreshes the heartbeat of this gateway in the gateway
+// registry until ctx is cancelled. Each heartbeat also removes gateways
+// that stopped sending theirs.
+func (g *Gateway) gatewayHeartbeat(ctx context.Context) {
+	defer g.wg.Done()
+
+	ticker := time.NewTicker(gatewayHeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := g.heartbeatRegistry(ctx, time.Now()); err != nil && ctx.Err() == nil {
+			slog.Warn("failed to refresh gateway registry", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// heartbeatRegistry sets the registry score of this gateway to now and
+// removes members whose heartbeat is older than gatewayHeartbeatTimeout.
+// The score is only updated in place (ZADD XX), so a heartbeat never adds
+// a gateway that is missing from the registry: one that has left, or one
+// another gateway removed as stale, stays out until it restarts.
+func (g *Gateway) heartbeatRegistry(ctx context.Context, now time.Time) error {
+	refreshed, err := g.redis.ZAddXX(ctx, routing.GatewayRegistryKey, redis.Z{Score: float64(now.UnixMilli()), Member: g.instanceID})
+	if err != nil {
+		return err
+	}
+	if refreshed == 0 {
+		slog.Warn("gateway missing from registry, not receiving forwarded messages", "instanceId", g.instanceID)
+	}
+
+	removed, err := g.redis.ZRemExpired(ctx, routing.GatewayRegistryKey, gatewayHeartbeatTimeout)
+	if err != nil {
+		return err
+	}
+	if removed > 0 {
+		slog.Warn("removed gateways without heartbeat", "count", removed, "timeout", gatewayHeartbeatTimeout)
+	}
+	return nil
+}
+
+// forwardToGateways forwards payload for channel to every other registered
+// gateway. Failures are logged; local subscribers already have the message.
+func (g *Gateway) forwardToGateways(ctx context.Context, channel string, payload []byte) {
+	gatewayIDs, err := g.redis.ZRange(ctx, routing.GatewayRegistryKey, 0, -1)
+	if err != nil {
+		slog.WarnContext(ctx, "failed to read gateway registry", "channel", channel, "error", err)
+		return
+	}
+	for _, gatewayID := range gatewayIDs {
+		if gatewayID == g.instanceID {
+			continue
+		}
+		if err := g.connPool.ForwardToGateway(ctx, gatewayID, channel, payload); err != nil {
+			slog.WarnContext(ctx, "failed to forward message to gateway", "gatewayId", gatewayID, "channel", channel, "error", err)
+		}
+	}
+}
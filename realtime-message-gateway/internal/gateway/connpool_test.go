@@ -0,0 +1,127 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/centrifugal/centrifuge"
+
+	"realtime-message-gateway/internal/redis"
+	"realtime-message-gateway/internal/routing"
+)
+
+// newPeerGateway runs a second gateway sharing the Redis of gw
+func newPeerGateway(t *testing.T, gw *Gateway) *Gateway {
+	t.Helper()
+
+	cfg := *gw.config
+	peer, err := NewGateway(WithConfig(&cfg), WithRedis(gw.redis))
+	if err != nil {
+		t.Fatalf("NewGateway() error = %v", err)
+	}
+	if err := peer.Run(); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	t.Cleanup(func() { peer.Shutdown(context.Background()) })
+	return peer
+}
+
+func TestBroadcastToChannelForwardsToPeers(t *testing.T) {
+	gw := NewTestGateway(t)
+	peer := newPeerGateway(t, gw)
+	server := httptest.NewServer(peer.WebsocketHandler(centrifuge.WebsocketConfig{}))
+	defer server.Close()
+	ctx := context.Background()
+
+	bob := dialTestClient(t, server, "Bob")
+	bob.command(2, "subscribe", map[string]string{"channel": "chat"})
+
+	sent, err := gw.PublishServerMessage(ctx, "chat", ServerMessage{Text: "hello from the other gateway"})
+	if err != nil {
+		t.Fatalf("PublishServerMessage() error = %v", err)
+	}
+
+	push := bob.await(func(r wsReply) bool {
+		return r.Push != nil && r.Push.Channel == "chat" && r.Push.Pub != nil
+	})
+	var got StreamMessage
+	if err := json.Unmarshal(push.Push.Pub.Data, &got); err != nil {
+		t.Fatalf("publication data %s: %v", push.Push.Pub.Data, err)
+	}
+	if got.ID != sent.ID || got.GatewayID != gw.InstanceID() {
+		t.Errorf("publication = %+v, want %+v", got, sent)
+	}
+}
+
+func TestRedisConnectionPool(t *testing.T) {
+	gw := NewTestGateway(t)
+	ctx := context.Background()
+	client := connectTestClient(t, gw)
+
+	pool := gw.ConnectionPool()
+	if !pool.IsLocalClient(client.ID()) {
+		t.Errorf("IsLocalClient(%q) = false, want true", client.ID())
+	}
+	if pool.IsLocalClient("unknown") {
+		t.Error("IsLocalClient(unknown) = true, want false")
+	}
+
+	err := pool.ForwardToGateway(ctx, "gone", "chat", []byte(`{}`))
+	if !errors.Is(err, ErrGatewayNotRegistered) {
+		t.Errorf("ForwardToGateway() error = %v, want ErrGatewayNotRegistered", err)
+	}
+	if n, _ := gw.redis.XLen(ctx, routing.GetGatewayStreamKey("gone")); n != 0 {
+		t.Errorf("stream of unregistered gateway has %d entries, want 0", n)
+	}
+}
+
+func TestHeartbeatRegistry(t *testing.T) {
+	gw := NewTestGateway(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	stale := float64(now.Add(-gatewayHeartbeatTimeout - time.Second).UnixMilli())
+	if _, err := gw.redis.ZAddNX(ctx, routing.GatewayRegistryKey, redis.Z{Score: stale, Member: "crashed"}); err != nil {
+		t.Fatalf("ZAddNX() error = %v", err)
+	}
+	if err := gw.heartbeatRegistry(ctx, now); err != nil {
+		t.Fatalf("heartbeatRegistry() error = %v", err)
+	}
+	// The heartbeat goroutine of gw may have refreshed the score since
+	if score, err := gw.redis.ZScore(ctx, routing.GatewayRegistryKey, gw.InstanceID()); err != nil || score < float64(now.UnixMilli()) {
+		t.Errorf("ZScore() = %v, %v, want at least %d", score, err, now.UnixMilli())
+	}
+
+	members, err := gw.redis.ZRange(ctx, routing.GatewayRegistryKey, 0, -1)
+	if err != nil {
+		t.Fatalf("ZRange() error = %v", err)
+	}
+	if len(members) != 1 || members[0] != gw.InstanceID() {
+		t.Errorf("registry = %v, want only %s", members, gw.InstanceID())
+	}
+
+	// Removed as stale by another gateway: a heartbeat does not add it again
+	if err := gw.redis.ZRem(ctx, routing.GatewayRegistryKey, gw.InstanceID()); err != nil {
+		t.Fatalf("ZRem() error = %v", err)
+	}
+	if err := gw.heartbeatRegistry(ctx, now.Add(time.Second)); err != nil {
+		t.Fatalf("heartbeatRegistry() error = %v", err)
+	}
+	if members, _ := gw.redis.ZRange(ctx, routing.GatewayRegistryKey, 0, -1); len(members) != 0 {
+		t.Errorf("registry after heartbeat of removed gateway = %v, want empty", members)
+	}
+
+	if err := gw.registerGateway(ctx, now); err != nil {
+		t.Fatalf("registerGateway() error = %v", err)
+	}
+	if err := gw.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	if members, _ := gw.redis.ZRange(ctx, routing.GatewayRegistryKey, 0, -1); len(members) != 0 {
+		t.Errorf("registry after Shutdown = %v, want empty", members)
+	}
+}
@@ -0,0 +1,72 @@
+package gateway
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+)
+
+// ConnectionState is the lifecycle state of a local connection
+type ConnectionState string
+
+const (
+	// StateNew is a connection of a user not seen within the reconnect window
+	StateNew ConnectionState = "new"
+	// StateReconnecting is a connection of a user who disconnected within
+	// the reconnect window
+	StateReconnecting ConnectionState = "reconnecting"
+	// StateActive is a connection with its handlers set up
+	StateActive ConnectionState = "active"
+	// StateDraining is a connection being closed, by the client or on shutdown
+	StateDraining ConnectionState = "draining"
+)
+
+// ErrInvalidTransition is returned by Transition for paths the state
+// machine does not allow
+var ErrInvalidTransition = errors.New("invalid connection state transition")
+
+// connectionTransitions lists the states each state may move to
+var connectionTransitions = map[ConnectionState][]ConnectionState{
+	StateNew:          {StateActive, StateDraining},
+	StateReconnecting: {StateActive, StateDraining},
+	StateActive:       {StateDraining},
+}
+
+// State returns the current connection state
+func (m *connectionMeta) State() ConnectionState {
+	m.stateMu.Lock()
+	defer m.stateMu.Unlock()
+	return m.state
+}
+
+// Transition moves the connection from state from to state to. It fails if
+// the connection is not in state from or the state machine does not allow
+// the transition.
+func (m *connectionMeta) Transition(from, to ConnectionState) error {
+	m.stateMu.Lock()
+	defer m.stateMu.Unlock()
+
+	if m.state != from {
+		return fmt.Errorf("%w: %s to %s, connection is %s", ErrInvalidTransition, from, to, m.state)
+	}
+	if !slices.Contains(connectionTransitions[from], to) {
+		return fmt.Errorf("%w: %s to %s", ErrInvalidTransition, from, to)
+	}
+	m.state = to
+
+	m.metrics.ConnectionStateTransitionsTotal.WithLabelValues(string(from), string(to)).Inc()
+	slog.Debug("connection state changed", "clientId", m.clientID, "userId", m.userID, "from", from, "to", to)
+	return nil
+}
+
+// drain moves the connection to StateDraining from whichever state it is in
+func (m *connectionMeta) drain() {
+	state := m.State()
+	if state == StateDraining {
+		return
+	}
+	if err := m.Transition(state, StateDraining); err != nil {
+		slog.Warn("failed to drain connection", "clientId", m.clientID, "error", err)
+	}
+}
@@ -0,0 +1,72 @@
+package gateway
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"realtime-message-gateway/internal/metrics"
+)
+
+func TestConnectionTransition(t *testing.T) {
+	tests := []struct {
+		name    string
+		state   ConnectionState
+		from    ConnectionState
+		to      ConnectionState
+		wantErr bool
+	}{
+		{"new to active", StateNew, StateNew, StateActive, false},
+		{"reconnecting to active", StateReconnecting, StateReconnecting, StateActive, false},
+		{"new to draining", StateNew, StateNew, StateDraining, false},
+		{"active to draining", StateActive, StateActive, StateDraining, false},
+		{"active to reconnecting", StateActive, StateActive, StateReconnecting, true},
+		{"draining to active", StateDraining, StateDraining, StateActive, true},
+		{"new to reconnecting", StateNew, StateNew, StateReconnecting, true},
+		{"from does not match state", StateNew, StateActive, StateDraining, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			meta := &connectionMeta{clientID: "c1", state: tt.state, metrics: metrics.Default}
+			before := testutil.ToFloat64(metrics.Default.ConnectionStateTransitionsTotal.WithLabelValues(string(tt.from), string(tt.to)))
+
+			err := meta.Transition(tt.from, tt.to)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Transition(%s, %s) error = %v, wantErr %v", tt.from, tt.to, err, tt.wantErr)
+			}
+
+			wantState, wantCount := tt.to, 1.0
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidTransition) {
+					t.Errorf("error = %v, want ErrInvalidTransition", err)
+				}
+				wantState, wantCount = tt.state, 0
+			}
+			if got := meta.State(); got != wantState {
+				t.Errorf("State() = %s, want %s", got, wantState)
+			}
+			if got := testutil.ToFloat64(metrics.Default.ConnectionStateTransitionsTotal.WithLabelValues(string(tt.from), string(tt.to))) - before; got != wantCount {
+				t.Errorf("transitions counter increased by %v, want %v", got, wantCount)
+			}
+		})
+	}
+}
+
+func TestConnectionStateLifecycle(t *testing.T) {
+	gw := NewTestGateway(t)
+	client := connectTestClient(t, gw)
+
+	gw.connectionsMu.RLock()
+	meta := gw.connections[client.ID()]
+	gw.connectionsMu.RUnlock()
+	if got := meta.State(); got != StateActive {
+		t.Fatalf("State() after connect = %s, want %s", got, StateActive)
+	}
+
+	gw.disconnectAll(gw.PlannedDisconnect())
+	if got := meta.State(); got != StateDraining {
+		t.Errorf("State() after disconnectAll = %s, want %s", got, StateDraining)
+	}
+}
@@ -0,0 +1,84 @@
+package gateway
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"realtime-message-gateway/internal/routing"
+)
+
+// unackedMessagesInterval is how often unacknowledged worker stream entries
+// are exported as gauges
+const unackedMessagesInterval = 15 * time.Second
+
+// ensureConsumerGroups creates the consumer group on all streams of workerID
+// so workers can read them with XREADGROUP and acknowledge processed entries.
+// The group starts at the beginning of the stream; creation is remembered
+// per worker, so only the first write to a worker pays for it.
+func (g *Gateway) ensureConsumerGroups(ctx context.Context, workerID string) error {
+	if _, ok := g.consumerGroups.Load(workerID); ok {
+		return nil
+	}
+	for _, streamKey := range routing.GetWorkerStreamKeys(workerID) {
+		if err := g.redis.XGroupCreateMkStream(ctx, streamKey, g.config.ConsumerGroupName, "0"); err != nil {
+			return err
+		}
+	}
+	g.consumerGroups.Store(workerID, struct{}{})
+	return nil
+}
+
+// prepareWorkerStreams ensures the consumer group before writing to the
+// streams of workerID; a failure is logged and does not block the write
+func (g *Gateway) prepareWorkerStreams(ctx context.Context, workerID string) {
+	if err := g.ensureConsumerGroups(ctx, workerID); err != nil {
+		slog.WarnContext(ctx, "failed to create consumer group", "worker", workerID, "group", g.config.ConsumerGroupName, "error", err)
+	}
+}
+
+// unackedMessagesExporter periodically exports unacknowledged entries per
+// active worker
+func (g *Gateway) unackedMessagesExporter(ctx context.Context) {
+	defer g.wg.Done()
+
+	ticker := time.NewTicker(unackedMessagesInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := g.exportUnackedMessages(ctx); err != nil && ctx.Err() == nil {
+			slog.Warn("failed to export unacked messages", "error", err)
+		}
+	}
+}
+
+// exportUnackedMessages sets gateway_stream_unacked_messages for each active
+// worker, summed over its priority streams
+func (g *Gateway) exportUnackedMessages(ctx context.Context) error {
+	workers, err := g.redis.ZRange(ctx, routing.ActiveWorkersKey, 0, -1)
+	if err != nil {
+		return err
+	}
+
+	for _, workerID := range workers {
+		if err := g.ensureConsumerGroups(ctx, workerID); err != nil {
+			return err
+		}
+		var unacked int64
+		for _, streamKey := range routing.GetWorkerStreamKeys(workerID) {
+			n, err := g.redis.XPendingCount(ctx, streamKey, g.config.ConsumerGroupName)
+			if err != nil {
+				return err
+			}
+			unacked += n
+		}
+		g.metrics.StreamUnackedMessages.WithLabelValues(workerID).Set(float64(unacked))
+	}
+	return nil
+}
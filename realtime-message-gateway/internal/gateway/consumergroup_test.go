@@ -0,0 +1,94 @@
+package gateway
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"realtime-message-gateway/internal/metrics"
+	"realtime-message-gateway/internal/routing"
+)
+
+func TestOutboundConsumerAcknowledgesEntries(t *testing.T) {
+	gw := NewTestGateway(t)
+	ctx := context.Background()
+	streamKey := routing.GetGatewayStreamKey(gw.InstanceID())
+	delivered := metrics.Default.OutboundTotal.WithLabelValues("channel", "success")
+	before := testutil.ToFloat64(delivered)
+
+	if _, err := gw.redis.XAdd(ctx, streamKey, map[string]interface{}{"channel": "chat:a", "payload": `{"text":"hi"}`}); err != nil {
+		t.Fatalf("XAdd() error = %v", err)
+	}
+	// Undeliverable entries are acknowledged too: retrying cannot help
+	if _, err := gw.redis.XAdd(ctx, streamKey, map[string]interface{}{"payload": `{}`}); err != nil {
+		t.Fatalf("XAdd() error = %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for testutil.ToFloat64(delivered) == before {
+		if time.Now().After(deadline) {
+			t.Fatal("outbound entry was not delivered")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// The undeliverable entry is read in the same batch or right after
+	for {
+		pending, err := gw.redis.XPendingCount(ctx, streamKey, gw.config.ConsumerGroupName)
+		if err != nil {
+			t.Fatalf("XPendingCount() error = %v", err)
+		}
+		if pending == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d outbound entries left pending, want 0", pending)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestExportUnackedMessages(t *testing.T) {
+	gw := NewTestGateway(t)
+	ctx := context.Background()
+	group := gw.config.ConsumerGroupName
+
+	if err := gw.ensureConsumerGroups(ctx, "worker-0"); err != nil {
+		t.Fatalf("ensureConsumerGroups() error = %v", err)
+	}
+	// Creating the group again is a no-op
+	gw.consumerGroups.Delete("worker-0")
+	if err := gw.ensureConsumerGroups(ctx, "worker-0"); err != nil {
+		t.Fatalf("ensureConsumerGroups() again error = %v", err)
+	}
+
+	for _, priority := range []int{routing.PriorityNormal, routing.PriorityNormal, routing.PriorityHigh} {
+		if _, err := gw.redis.XAdd(ctx, routing.GetWorkerStreamKey("worker-0", priority), map[string]interface{}{"payload": `{}`}); err != nil {
+			t.Fatalf("XAdd() error = %v", err)
+		}
+	}
+
+	// The worker reads everything and acknowledges one normal entry
+	var toAck string
+	for _, streamKey := range routing.GetWorkerStreamKeys("worker-0") {
+		entries, err := gw.redis.XReadGroup(ctx, streamKey, group, "worker-0", ">", 10, 10*time.Millisecond)
+		if err != nil {
+			t.Fatalf("XReadGroup() error = %v", err)
+		}
+		if streamKey == routing.GetWorkerStreamKey("worker-0", routing.PriorityNormal) {
+			toAck = entries[0].ID
+		}
+	}
+	if err := gw.redis.XAck(ctx, routing.GetWorkerStreamKey("worker-0", routing.PriorityNormal), group, toAck); err != nil {
+		t.Fatalf("XAck() error = %v", err)
+	}
+
+	if err := gw.exportUnackedMessages(ctx); err != nil {
+		t.Fatalf("exportUnackedMessages() error = %v", err)
+	}
+	if got := testutil.ToFloat64(metrics.Default.StreamUnackedMessages.WithLabelValues("worker-0")); got != 2 {
+		t.Errorf("gateway_stream_unacked_messages = %v, want 2", got)
+	}
+}
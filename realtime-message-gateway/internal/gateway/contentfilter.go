@@ -0,0 +1,112 @@
+package gateway
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+// ContentFilter rejects message text containing a blocked phrase. Phrases
+// are read from a file, one per line, and matched case-insensitively
+// anywhere in the text; blank lines and lines starting with # are skipped.
+// Reload swaps in a new trie atomically, so matching never waits for it.
+type ContentFilter struct {
+	path string
+	root atomic.Pointer[trieNode]
+}
+
+// trieNode is a node of the byte trie of lowercased blocked phrases
+type trieNode struct {
+	children map[byte]*trieNode
+	end      bool // a phrase ends here
+}
+
+// insert adds phrase below n
+func (n *trieNode) insert(phrase string) {
+	for i := 0; i < len(phrase); i++ {
+		child := n.children[phrase[i]]
+		if child == nil {
+			if n.children == nil {
+				n.children = make(map[byte]*trieNode)
+			}
+			child = &trieNode{}
+			n.children[phrase[i]] = child
+		}
+		n = child
+	}
+	n.end = true
+}
+
+// NewContentFilter creates a ContentFilter with the phrases in the file at path
+func NewContentFilter(path string) (*ContentFilter, error) {
+	f := &ContentFilter{path: path}
+	if err := f.Reload(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Reload reads the blocklist file again. On error the previous phrases
+// stay in use.
+func (f *ContentFilter) Reload() error {
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return fmt.Errorf("read content filter: %w", err)
+	}
+
+	root := &trieNode{}
+	phrases := 0
+	for _, line := range strings.Split(string(data), "\n") {
+		phrase := strings.ToLower(strings.TrimSpace(line))
+		if phrase == "" || strings.HasPrefix(phrase, "#") {
+			continue
+		}
+		root.insert(phrase)
+		phrases++
+	}
+	f.root.Store(root)
+
+	slog.Info("content filter loaded", "path", f.path, "phrases", phrases)
+	return nil
+}
+
+// Match reports whether text contains a blocked phrase. Walking the trie
+// from every byte offset is correct for UTF-8 since a phrase can only
+// match where a character starts.
+func (f *ContentFilter) Match(text string) bool {
+	root := f.root.Load()
+	text = strings.ToLower(text)
+	for i := 0; i < len(text); i++ {
+		n := root
+		for j := i; j < len(text); j++ {
+			n = n.children[text[j]]
+			if n == nil {
+				break
+			}
+			if n.end {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ReloadContentFilter reloads the CONTENT_FILTER_FILE blocklist; it does
+// nothing when no file is configured
+func (g *Gateway) ReloadContentFilter() error {
+	if g.contentFilter == nil {
+		return nil
+	}
+	return g.contentFilter.Reload()
+}
+
+// contentFiltered reports whether the content filter blocks text
+func (g *Gateway) contentFiltered(text string) bool {
+	if g.contentFilter == nil || !g.contentFilter.Match(text) {
+		return false
+	}
+	g.metrics.PublishContentFilteredTotal.Inc()
+	return true
+}
@@ -0,0 +1,116 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"realtime-message-gateway/internal/routing"
+)
+
+// writeBlocklist writes content to a blocklist file in a temporary directory
+func writeBlocklist(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "blocklist.txt")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	return path
+}
+
+func TestContentFilterMatch(t *testing.T) {
+	f, err := NewContentFilter(writeBlocklist(t, "# comment\nbad word\n\n  Spam  \nböse\n"))
+	if err != nil {
+		t.Fatalf("NewContentFilter() error = %v", err)
+	}
+
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{"hello world", false},
+		{"this is a bad word here", true},
+		{"BAD WORD", true},
+		{"bad  word", false},
+		{"badword", false},
+		{"no spamming", true},
+		{"spa", false},
+		{"BÖSE", true},
+		{"# comment", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if got := f.Match(tt.text); got != tt.want {
+			t.Errorf("Match(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestContentFilterReload(t *testing.T) {
+	path := writeBlocklist(t, "spam\n")
+	f, err := NewContentFilter(path)
+	if err != nil {
+		t.Fatalf("NewContentFilter() error = %v", err)
+	}
+
+	if err := os.WriteFile(path, []byte("scam\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if err := f.Reload(); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	if f.Match("spam") || !f.Match("scam") {
+		t.Error("Reload() did not replace the phrases")
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if err := f.Reload(); err == nil {
+		t.Error("Reload() of a missing file succeeded")
+	}
+	if !f.Match("scam") {
+		t.Error("failed Reload() dropped the previous phrases")
+	}
+}
+
+func TestNewContentFilterMissingFile(t *testing.T) {
+	if _, err := NewContentFilter(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Error("NewContentFilter() error = nil, want error for missing file")
+	}
+}
+
+func TestPublishContentFiltered(t *testing.T) {
+	gw := NewTestGateway(t)
+	f, err := NewContentFilter(writeBlocklist(t, "spam\n"))
+	if err != nil {
+		t.Fatalf("NewContentFilter() error = %v", err)
+	}
+	gw.contentFilter = f
+	client := connectTestClient(t, gw)
+
+	if err := publishAndWait(gw, client, "chat", `{"text":"buy SPAM now"}`); err == nil {
+		t.Error("publish of blocked text succeeded")
+	}
+	if err := publishAndWait(gw, client, "chat", `{"text":"hello"}`); err != nil {
+		t.Fatalf("publish error = %v", err)
+	}
+	entries, err := gw.redis.XRange(context.Background(), routing.GetWorkerStreamKey("worker-0", routing.PriorityNormal), "-", "+", 0)
+	if err != nil {
+		t.Fatalf("XRange() error = %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("stream has %d entries, want 1", len(entries))
+	}
+	if payload := entries[0].Payload; !strings.Contains(payload, `"text":"hello"`) {
+		t.Errorf("payload = %s, want the allowed message", payload)
+	}
+
+	if _, err := gw.PublishBatch(context.Background(), "chat", []BatchMessage{{Text: "ok"}, {Text: "spam"}}); !errors.Is(err, ErrMessageRejected) {
+		t.Errorf("PublishBatch() error = %v, want ErrMessageRejected", err)
+	}
+}
@@ -0,0 +1,43 @@
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+)
+
+// Content types of published messages
+const (
+	ContentTypeText     = "text/plain"
+	ContentTypeJSON     = "application/json"
+	ContentTypeReaction = "application/x-reaction"
+)
+
+var (
+	// ErrContentTypeNotAllowed is returned for a content_type missing from
+	// AllowedContentTypes
+	ErrContentTypeNotAllowed = errors.New("content type not allowed")
+	// ErrInvalidJSONContent is returned when application/json text is not
+	// valid JSON
+	ErrInvalidJSONContent = errors.New("text is not valid JSON")
+)
+
+// contentTypeFromData returns the content_type of publish data, defaulting
+// to ContentTypeText, and checks that text is valid for it
+func (g *Gateway) contentTypeFromData(data map[string]interface{}, text string) (string, error) {
+	contentType := ContentTypeText
+	if v, present := data["content_type"]; present {
+		s, ok := v.(string)
+		if !ok || s == "" {
+			return "", ErrContentTypeNotAllowed
+		}
+		contentType = s
+	}
+	if !slices.Contains(g.config.AllowedContentTypes, contentType) {
+		return "", ErrContentTypeNotAllowed
+	}
+	if contentType == ContentTypeJSON && !json.Valid([]byte(text)) {
+		return "", ErrInvalidJSONContent
+	}
+	return contentType, nil
+}
@@ -0,0 +1,42 @@
+package gateway
+
+import (
+	"errors"
+	"testing"
+
+	"realtime-message-gateway/internal/config"
+)
+
+func TestContentTypeFromData(t *testing.T) {
+	gw := &Gateway{config: &config.Config{
+		AllowedContentTypes: []string{ContentTypeText, ContentTypeJSON},
+	}}
+
+	tests := []struct {
+		name    string
+		data    map[string]interface{}
+		text    string
+		want    string
+		wantErr error
+	}{
+		{"default", map[string]interface{}{}, "hi", ContentTypeText, nil},
+		{"text", map[string]interface{}{"content_type": "text/plain"}, "hi", ContentTypeText, nil},
+		{"json", map[string]interface{}{"content_type": "application/json"}, `{"file":"a.png"}`, ContentTypeJSON, nil},
+		{"invalid json", map[string]interface{}{"content_type": "application/json"}, "{oops", "", ErrInvalidJSONContent},
+		{"not allowed", map[string]interface{}{"content_type": ContentTypeReaction}, "+1", "", ErrContentTypeNotAllowed},
+		{"not a string", map[string]interface{}{"content_type": 1.0}, "hi", "", ErrContentTypeNotAllowed},
+		{"empty", map[string]interface{}{"content_type": ""}, "hi", "", ErrContentTypeNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := gw.contentTypeFromData(tt.data, tt.text)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("contentTypeFromData() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("contentTypeFromData() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
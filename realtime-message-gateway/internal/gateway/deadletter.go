@@ -0,0 +1,134 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"realtime-message-gateway/internal/routing"
+)
+
+// ErrDeadLetterNotFound is returned when a dead-letter entry does not exist
+var ErrDeadLetterNotFound = errors.New("dead-letter entry not found")
+
+// DeadLetter is a stream message that could not be written to its worker
+// stream, or that its workers failed to acknowledge too often
+type DeadLetter struct {
+	ID        string `json:"id"`
+	StreamKey string `json:"streamKey"`
+	Payload   string `json:"payload"`
+	Error     string `json:"error"`
+	Attempts  int    `json:"attempts"`
+	FailedAt  string `json:"failedAt"`
+}
+
+// deadLetter records a payload that failed after attempts writes or
+// deliveries
+func (g *Gateway) deadLetter(ctx context.Context, streamKey string, payload []byte, cause error, attempts int) {
+	if !g.config.DeadLetterEnabled {
+		return
+	}
+
+	// The failed write may have been cancelled, the dead-letter write must not
+	_, err := g.redis.XAdd(context.WithoutCancel(ctx), routing.DeadLetterStreamKey, map[string]interface{}{
+		"streamKey": streamKey,
+		"payload":   string(payload),
+		"error":     cause.Error(),
+		"attempts":  attempts,
+		"failedAt":  time.Now().UTC().Format(time.RFC3339Nano),
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to write dead-letter entry", "streamKey", streamKey, "error", err)
+		return
+	}
+
+	g.metrics.DeadLetterMessagesTotal.Inc()
+	slog.WarnContext(ctx, "message dead-lettered", "streamKey", streamKey, "error", cause)
+}
+
+// DeadLetters returns up to limit of the most recent dead-letter entries
+func (g *Gateway) DeadLetters(ctx context.Context, limit int64) ([]DeadLetter, error) {
+	entries, err := g.redis.XRevRangeMessages(ctx, routing.DeadLetterStreamKey, "+", "-", int(limit))
+	if err != nil {
+		return nil, err
+	}
+
+	letters := make([]DeadLetter, 0, len(entries))
+	for _, entry := range entries {
+		letters = append(letters, newDeadLetter(entry.ID, entry.Values))
+	}
+	return letters, nil
+}
+
+// RetryDeadLetter re-enqueues a dead-letter entry to the worker currently
+// assigned to its channel and removes it from the dead-letter stream
+func (g *Gateway) RetryDeadLetter(ctx context.Context, id string) error {
+	entries, err := g.redis.XRangeMessages(ctx, routing.DeadLetterStreamKey, id, id, 1)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return ErrDeadLetterNotFound
+	}
+	letter := newDeadLetter(entries[0].ID, entries[0].Values)
+
+	// Re-route: the original worker may have gone away since the failure
+	var message StreamMessage
+	if err := json.Unmarshal([]byte(letter.Payload), &message); err != nil {
+		return err
+	}
+	if err := MigrateStreamMessage(&message, g.config.StreamSchemaVersion); err != nil {
+		return err
+	}
+	workerID, err := g.router.GetWorkerForChannel(ctx, message.Channel)
+	if err != nil {
+		return err
+	}
+	message.WorkerID = workerID
+
+	payload, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	streamKey := routing.GetWorkerStreamKey(workerID, message.Priority)
+	if _, err := g.redis.RetryXAdd(ctx, streamKey, map[string]interface{}{
+		"payload": string(payload),
+	}, g.config.RedisMaxPublishRetries); err != nil {
+		return err
+	}
+
+	if err := g.redis.XDel(ctx, routing.DeadLetterStreamKey, id); err != nil {
+		return err
+	}
+
+	slog.InfoContext(ctx, "dead-letter entry re-enqueued",
+		"id", id,
+		"messageId", message.ID,
+		"streamKey", streamKey,
+		"channel", message.Channel,
+	)
+	return nil
+}
+
+// newDeadLetter converts stream entry values into a DeadLetter
+func newDeadLetter(id string, values map[string]interface{}) DeadLetter {
+	streamKey, _ := values["streamKey"].(string)
+	payload, _ := values["payload"].(string)
+	errMsg, _ := values["error"].(string)
+	failedAt, _ := values["failedAt"].(string)
+	attemptsStr, _ := values["attempts"].(string)
+	attempts, _ := strconv.Atoi(attemptsStr)
+
+	return DeadLetter{
+		ID:        id,
+		StreamKey: streamKey,
+		Payload:   payload,
+		Error:     errMsg,
+		Attempts:  attempts,
+		FailedAt:  failedAt,
+	}
+}
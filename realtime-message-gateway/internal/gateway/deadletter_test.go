@@ -0,0 +1,28 @@
+package gateway
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewDeadLetter(t *testing.T) {
+	got := newDeadLetter("1700000000000-0", map[string]interface{}{
+		"streamKey": "messages:worker:worker-0:normal",
+		"payload":   `{"id":"msg-1"}`,
+		"error":     "i/o timeout",
+		"attempts":  "3",
+		"failedAt":  "2024-01-01T00:00:00Z",
+	})
+
+	want := DeadLetter{
+		ID:        "1700000000000-0",
+		StreamKey: "messages:worker:worker-0:normal",
+		Payload:   `{"id":"msg-1"}`,
+		Error:     "i/o timeout",
+		Attempts:  3,
+		FailedAt:  "2024-01-01T00:00:00Z",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("newDeadLetter() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,141 @@
+package gateway
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"log/slog"
+	"math"
+	"sync"
+	"time"
+)
+
+// DedupKeyPrefix prefixes the Redis keys of messages recorded by the
+// duplicate message detector
+const DedupKeyPrefix = "dedup:"
+
+// bloomFilter is a set of message digests that answers "probably seen" or
+// "definitely not seen". Holding at most the capacity it was sized for,
+// it reports unseen digests as seen at about its false positive rate.
+type bloomFilter struct {
+	mu     sync.Mutex
+	bits   []uint64
+	hashes uint64
+}
+
+// newBloomFilter sizes a filter for capacity digests at fpRate
+func newBloomFilter(capacity int, fpRate float64) *bloomFilter {
+	n := float64(capacity)
+	m := math.Ceil(-n * math.Log(fpRate) / (math.Ln2 * math.Ln2))
+	k := math.Max(1, math.Round(m/n*math.Ln2))
+	return &bloomFilter{
+		bits:   make([]uint64, (uint64(m)+63)/64),
+		hashes: uint64(k),
+	}
+}
+
+// positions calls fn with the bits of digest, derived from its first two
+// words by double hashing, until fn returns false
+func (f *bloomFilter) positions(digest [sha256.Size]byte, fn func(word int, mask uint64) bool) {
+	h1 := binary.BigEndian.Uint64(digest[0:8])
+	h2 := binary.BigEndian.Uint64(digest[8:16]) | 1
+	m := uint64(len(f.bits)) * 64
+	for i := range f.hashes {
+		bit := (h1 + i*h2) % m
+		if !fn(int(bit/64), 1<<(bit%64)) {
+			return
+		}
+	}
+}
+
+// add inserts digest
+func (f *bloomFilter) add(digest [sha256.Size]byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.positions(digest, func(word int, mask uint64) bool {
+		f.bits[word] |= mask
+		return true
+	})
+}
+
+// test reports whether digest was probably added since the last reset
+func (f *bloomFilter) test(digest [sha256.Size]byte) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	found := true
+	f.positions(digest, func(word int, mask uint64) bool {
+		found = f.bits[word]&mask != 0
+		return found
+	})
+	return found
+}
+
+// reset removes all digests
+func (f *bloomFilter) reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	clear(f.bits)
+}
+
+// messageDigest identifies a message by its user, channel and text, each
+// prefixed with its length so that no two messages share the input
+func messageDigest(userID, channel, text string) [sha256.Size]byte {
+	h := sha256.New()
+	for _, field := range []string{userID, channel, text} {
+		h.Write(binary.BigEndian.AppendUint32(nil, uint32(len(field))))
+		h.Write([]byte(field))
+	}
+	return [sha256.Size]byte(h.Sum(nil))
+}
+
+// isDuplicateMessage reports whether userID published text to channel
+// through this gateway since the last reset of the Bloom filter. Messages
+// the filter has not seen need no Redis lookup; the filter's positives are
+// confirmed by the message's key in Redis, so false positives do not
+// reject messages. Messages are let through when Redis fails.
+func (g *Gateway) isDuplicateMessage(ctx context.Context, userID, channel, text string) bool {
+	digest := messageDigest(userID, channel, text)
+	if !g.dedupFilter.test(digest) {
+		return false
+	}
+	g.metrics.DedupBloomPositivesTotal.Inc()
+
+	exists, err := g.redis.KeysExist(ctx, []string{DedupKeyPrefix + hex.EncodeToString(digest[:])})
+	if err != nil {
+		slog.WarnContext(ctx, "failed to check duplicate message", "channel", channel, "error", err)
+		return false
+	}
+	return exists[0]
+}
+
+// recordPublished adds a published message to the Bloom filter and its key
+// to Redis, expiring with the filter's reset interval
+func (g *Gateway) recordPublished(ctx context.Context, userID, channel, text string) {
+	digest := messageDigest(userID, channel, text)
+	g.dedupFilter.add(digest)
+	if err := g.redis.Set(ctx, DedupKeyPrefix+hex.EncodeToString(digest[:]), 1, g.config.BloomResetInterval); err != nil {
+		slog.WarnContext(ctx, "failed to record published message", "channel", channel, "error", err)
+	}
+}
+
+// dedupFilterReset periodically clears the Bloom filter, whose false
+// positive rate grows past its capacity
+func (g *Gateway) dedupFilterReset(ctx context.Context) {
+	defer g.wg.Done()
+
+	ticker := time.NewTicker(g.config.BloomResetInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			g.dedupFilter.reset()
+		}
+	}
+}
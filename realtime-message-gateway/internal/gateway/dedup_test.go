@@ -0,0 +1,103 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/centrifugal/centrifuge"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"realtime-message-gateway/internal/metrics"
+)
+
+func TestBloomFilter(t *testing.T) {
+	const capacity = 1000
+	f := newBloomFilter(capacity, 0.01)
+
+	for i := range capacity {
+		f.add(messageDigest("u1", "chat", fmt.Sprint(i)))
+	}
+	for i := range capacity {
+		if !f.test(messageDigest("u1", "chat", fmt.Sprint(i))) {
+			t.Fatalf("test(%d) = false after add", i)
+		}
+	}
+
+	positives := 0
+	for i := capacity; i < 11*capacity; i++ {
+		if f.test(messageDigest("u1", "chat", fmt.Sprint(i))) {
+			positives++
+		}
+	}
+	if rate := float64(positives) / (10 * capacity); rate > 0.02 {
+		t.Errorf("false positive rate = %g, want about 0.01", rate)
+	}
+
+	f.reset()
+	if f.test(messageDigest("u1", "chat", "0")) {
+		t.Error("test(0) = true after reset")
+	}
+}
+
+func TestMessageDigest(t *testing.T) {
+	// Bytes moved between fields change the digest
+	if messageDigest("u1", "chat", "hi") == messageDigest("u1c", "hat", "hi") {
+		t.Error("messageDigest() equal for different field splits")
+	}
+}
+
+func TestIsDuplicateMessage(t *testing.T) {
+	gw := NewTestGateway(t)
+	gw.config.BloomResetInterval = time.Minute
+	gw.dedupFilter = newBloomFilter(100, 0.01)
+	ctx := context.Background()
+	positives := testutil.ToFloat64(metrics.Default.DedupBloomPositivesTotal)
+
+	if gw.isDuplicateMessage(ctx, "u1", "chat", "hi") {
+		t.Error("isDuplicateMessage() = true before publishing")
+	}
+	gw.recordPublished(ctx, "u1", "chat", "hi")
+	if !gw.isDuplicateMessage(ctx, "u1", "chat", "hi") {
+		t.Error("isDuplicateMessage() = false after publishing")
+	}
+	if gw.isDuplicateMessage(ctx, "u2", "chat", "hi") {
+		t.Error("isDuplicateMessage() = true for another user")
+	}
+
+	// A filter positive without the key in Redis is a false positive
+	digest := messageDigest("u1", "chat", "bye")
+	gw.dedupFilter.add(digest)
+	if gw.isDuplicateMessage(ctx, "u1", "chat", "bye") {
+		t.Error("isDuplicateMessage() = true for a false positive")
+	}
+	if got := testutil.ToFloat64(metrics.Default.DedupBloomPositivesTotal) - positives; got != 2 {
+		t.Errorf("bloom positives = %g, want 2", got)
+	}
+}
+
+func TestPublishDuplicateMessage(t *testing.T) {
+	gw := NewTestGateway(t)
+	gw.config.BloomResetInterval = time.Minute
+	gw.dedupFilter = newBloomFilter(100, 0.01)
+	client := connectTestClient(t, gw)
+
+	for _, text := range []string{"a", "b"} {
+		if err := publishAndWait(gw, client, "chat", fmt.Sprintf(`{"text":%q}`, text)); err != nil {
+			t.Fatalf("publish of %s error = %v", text, err)
+		}
+	}
+	// Unlike DUPLICATE_TEXT_WINDOW, any earlier text is a duplicate
+	err := publishAndWait(gw, client, "chat", `{"text":"a"}`)
+	var replyErr *centrifuge.Error
+	if !errors.As(err, &replyErr) || replyErr.Code != uint32(ErrCodeDuplicateMessage) {
+		t.Errorf("duplicate publish error = %v, want code %d", err, ErrCodeDuplicateMessage)
+	}
+
+	gw.dedupFilter.reset()
+	if err := publishAndWait(gw, client, "chat", `{"text":"a"}`); err != nil {
+		t.Errorf("publish after reset error = %v", err)
+	}
+}
@@ -0,0 +1,65 @@
+package gateway
+
+import (
+	"context"
+	"time"
+)
+
+// lastText is the text of the last message a user published to a channel
+type lastText struct {
+	text string
+	at   time.Time
+}
+
+// lastTextKey identifies the last message of a user in a channel
+func lastTextKey(userID, channel string) string {
+	return userID + "\x00" + channel
+}
+
+// isDuplicateText reports whether text is the last message userID
+// published to channel, less than DuplicateTextWindow before now
+func (g *Gateway) isDuplicateText(userID, channel, text string, now time.Time) bool {
+	value, ok := g.lastTexts.Load(lastTextKey(userID, channel))
+	if !ok {
+		return false
+	}
+	last := value.(*lastText)
+	return last.text == text && now.Sub(last.at) < g.config.DuplicateTextWindow
+}
+
+// recordLastText makes text, published at, the last message of userID in
+// channel. It runs only once the message is published, so that a client
+// can retry a failed publish and rejected duplicates do not extend the
+// window.
+func (g *Gateway) recordLastText(userID, channel, text string, at time.Time) {
+	g.lastTexts.Store(lastTextKey(userID, channel), &lastText{text: text, at: at})
+}
+
+// lastTextCleanup periodically removes last messages older than twice
+// DuplicateTextWindow, which can no longer match a publish
+func (g *Gateway) lastTextCleanup(ctx context.Context) {
+	defer g.wg.Done()
+
+	ticker := time.NewTicker(g.config.DuplicateTextWindow)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			g.expireLastTexts(now)
+		}
+	}
+}
+
+// expireLastTexts removes last messages older than twice DuplicateTextWindow
+func (g *Gateway) expireLastTexts(now time.Time) {
+	cutoff := now.Add(-2 * g.config.DuplicateTextWindow)
+	g.lastTexts.Range(func(key, value any) bool {
+		if value.(*lastText).at.Before(cutoff) {
+			g.lastTexts.CompareAndDelete(key, value)
+		}
+		return true
+	})
+}
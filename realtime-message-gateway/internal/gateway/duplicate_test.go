@@ -0,0 +1,105 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/centrifugal/centrifuge"
+
+	"realtime-message-gateway/internal/redis"
+	"realtime-message-gateway/internal/routing"
+)
+
+func TestIsDuplicateText(t *testing.T) {
+	const window = time.Second
+	start := time.Now()
+
+	tests := []struct {
+		name      string
+		userID    string
+		channel   string
+		text      string
+		after     time.Duration // since start
+		published bool          // recorded unless a duplicate
+		want      bool
+	}{
+		{"first message", "u1", "chat", "hi", 0, true, false},
+		{"repeat inside window", "u1", "chat", "hi", window - time.Nanosecond, true, true},
+		{"repeat at window end", "u1", "chat", "hi", window, true, false},
+		{"repeat after a new message", "u1", "chat", "hi", window + time.Millisecond, true, true},
+		{"failed publish", "u1", "chat", "hello", window + 2*time.Millisecond, false, false},
+		{"retry of failed publish", "u1", "chat", "hello", window + 3*time.Millisecond, true, false},
+		{"other channel", "u1", "chat:other", "hello", window + 4*time.Millisecond, true, false},
+		{"other user", "u2", "chat", "hello", window + 5*time.Millisecond, true, false},
+	}
+
+	gw := NewTestGateway(t)
+	gw.config.DuplicateTextWindow = window
+	for _, tt := range tests {
+		at := start.Add(tt.after)
+		got := gw.isDuplicateText(tt.userID, tt.channel, tt.text, at)
+		if got != tt.want {
+			t.Errorf("%s: isDuplicateText() = %v, want %v", tt.name, got, tt.want)
+		}
+		if !got && tt.published {
+			gw.recordLastText(tt.userID, tt.channel, tt.text, at)
+		}
+	}
+}
+
+func TestExpireLastTexts(t *testing.T) {
+	gw := NewTestGateway(t)
+	gw.config.DuplicateTextWindow = time.Second
+	now := time.Now()
+	gw.recordLastText("u1", "chat", "old", now.Add(-3*time.Second))
+	gw.recordLastText("u2", "chat", "recent", now.Add(-time.Second))
+
+	gw.expireLastTexts(now)
+
+	if _, ok := gw.lastTexts.Load(lastTextKey("u1", "chat")); ok {
+		t.Error("entry older than twice the window was kept")
+	}
+	if _, ok := gw.lastTexts.Load(lastTextKey("u2", "chat")); !ok {
+		t.Error("entry within twice the window was removed")
+	}
+}
+
+func TestPublishDuplicateText(t *testing.T) {
+	gw := NewTestGateway(t)
+	gw.config.DuplicateTextWindow = time.Minute
+	client := connectTestClient(t, gw)
+
+	if err := publishAndWait(gw, client, "chat", `{"text":"hello"}`); err != nil {
+		t.Fatalf("first publish error = %v", err)
+	}
+	err := publishAndWait(gw, client, "chat", `{"text":"hello"}`)
+	var replyErr *centrifuge.Error
+	if !errors.As(err, &replyErr) || replyErr.Code != uint32(ErrCodeDuplicateMessage) {
+		t.Errorf("duplicate publish error = %v, want code %d", err, ErrCodeDuplicateMessage)
+	}
+	if err := publishAndWait(gw, client, "chat", `{"text":"hello again"}`); err != nil {
+		t.Errorf("publish of new text error = %v", err)
+	}
+}
+
+func TestPublishDuplicateTextAfterFailure(t *testing.T) {
+	gw := NewTestGateway(t, WithWorkers(nil))
+	gw.config.DuplicateTextWindow = time.Minute
+	client := connectTestClient(t, gw)
+
+	err := publishAndWait(gw, client, "chat", `{"text":"hello"}`)
+	var replyErr *centrifuge.Error
+	if !errors.As(err, &replyErr) || replyErr.Code != uint32(ErrCodeWorkerUnavailable) {
+		t.Fatalf("publish without workers error = %v, want code %d", err, ErrCodeWorkerUnavailable)
+	}
+
+	// The retry of a failed publish is not a duplicate
+	if _, err := gw.redis.ZAddNX(context.Background(), routing.ActiveWorkersKey, redis.Z{Score: 1, Member: "worker-0"}); err != nil {
+		t.Fatalf("ZAddNX() error = %v", err)
+	}
+	if err := publishAndWait(gw, client, "chat", `{"text":"hello"}`); err != nil {
+		t.Errorf("retry of failed publish error = %v", err)
+	}
+}
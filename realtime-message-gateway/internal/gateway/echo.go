@@ -0,0 +1,54 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/centrifugal/centrifuge"
+)
+
+// echoChannelPrefix starts the names of echo channels, which clients use
+// to check connectivity end to end without involving workers
+const echoChannelPrefix = "echo:"
+
+// isEchoChannel reports whether channel is an echo channel and ECHO_ENABLED
+// is set
+func (g *Gateway) isEchoChannel(channel string) bool {
+	return g.config.EchoEnabled && strings.HasPrefix(channel, echoChannelPrefix)
+}
+
+// ownEchoChannel reports whether channel is the echo channel of client,
+// echo:{clientId}. Echo publishes skip the worker path and its checks, so
+// a client may only subscribe and publish to its own.
+func ownEchoChannel(client *centrifuge.Client, channel string) bool {
+	return channel == echoChannelPrefix+client.ID()
+}
+
+// handleEchoPublish publishes data back to the subscribers of the client's
+// echo channel as is, after the rate limit and a MAX_TEXT_LENGTH size cap.
+// Nothing else is validated, routed or written to Redis.
+func (g *Gateway) handleEchoPublish(ctx context.Context, client *centrifuge.Client, channel string, data []byte, cb centrifuge.PublishCallback) {
+	if !ownEchoChannel(client, channel) {
+		g.metrics.PublishTotal.WithLabelValues("rejected", "invalid_channel").Inc()
+		cb(centrifuge.PublishReply{}, clientError(ErrPermissionDenied.Wrap(fmt.Errorf("echo channel %q of another client", channel))))
+		return
+	}
+	if len(data) > g.config.MaxTextLength {
+		g.metrics.PublishTotal.WithLabelValues("rejected", "message_too_large").Inc()
+		cb(centrifuge.PublishReply{}, clientError(ErrMessageTooLarge.Wrap(fmt.Errorf("echo data of %d bytes exceeds %d", len(data), g.config.MaxTextLength))))
+		return
+	}
+
+	result, err := g.node.Publish(channel, data)
+	if err != nil {
+		g.metrics.PublishTotal.WithLabelValues("error", "echo_failed").Inc()
+		slog.ErrorContext(ctx, "failed to echo message", "channel", channel, "error", err)
+		cb(centrifuge.PublishReply{}, centrifuge.ErrorInternal)
+		return
+	}
+	g.metrics.PublishTotal.WithLabelValues("success", "echo").Inc()
+	// Already published: Centrifuge must not broadcast the data again
+	cb(centrifuge.PublishReply{Result: &result}, nil)
+}
@@ -0,0 +1,113 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/centrifugal/centrifuge"
+	"golang.org/x/net/websocket"
+
+	"realtime-message-gateway/internal/config"
+	"realtime-message-gateway/internal/middleware"
+	"realtime-message-gateway/internal/routing"
+)
+
+func TestEchoChannel(t *testing.T) {
+	gw := NewTestGateway(t)
+	gw.config.EchoEnabled = true
+	server := httptest.NewServer(gw.WebsocketHandler(centrifuge.WebsocketConfig{}))
+	defer server.Close()
+	ctx := context.Background()
+
+	alice := dialTestClient(t, server, "Alice")
+	channel := "echo:" + alice.clientID
+	alice.command(2, "subscribe", map[string]string{"channel": channel})
+
+	for i := range 100 {
+		// Echo data is published as is, whatever its shape
+		data := fmt.Sprintf(`{"seq":%d}`, i)
+		frame, _ := json.Marshal(map[string]interface{}{
+			"id":      i + 3,
+			"publish": map[string]interface{}{"channel": channel, "data": json.RawMessage(data)},
+		})
+		if err := websocket.Message.Send(alice.conn, string(frame)); err != nil {
+			t.Fatalf("send publish: %v", err)
+		}
+		alice.await(func(r wsReply) bool {
+			return r.Push != nil && r.Push.Pub != nil && string(r.Push.Pub.Data) == data
+		})
+	}
+
+	// Neither the subscription nor the messages reached a worker
+	if n, _ := gw.redis.XLen(ctx, routing.GetWorkerStreamKey("worker-0", routing.PriorityNormal)); n != 0 {
+		t.Errorf("worker stream has %d entries, want 0", n)
+	}
+	if route, _ := gw.redis.Get(ctx, routing.ChannelRoutePrefix+channel); route != "" {
+		t.Errorf("echo channel routed to %q, want no route", route)
+	}
+}
+
+func TestEchoChannelRejected(t *testing.T) {
+	gw := NewTestGateway(t)
+	gw.config.EchoEnabled = true
+	gw.config.MaxTextLength = 16
+	alice := connectTestClient(t, gw)
+	bob := connectTestClient(t, gw)
+	own := "echo:" + alice.ID()
+
+	var subscribeErr error
+	gw.handleSubscribe(context.Background(), alice, centrifuge.SubscribeEvent{Channel: "echo:" + bob.ID()}, func(_ centrifuge.SubscribeReply, err error) {
+		subscribeErr = err
+	})
+
+	tests := []struct {
+		name    string
+		err     error
+		wantErr uint32
+	}{
+		{"subscribe to another client's channel", subscribeErr, uint32(ErrCodePermissionDenied)},
+		{"publish to another client's channel", publishAndWait(gw, alice, "echo:"+bob.ID(), `{}`), uint32(ErrCodePermissionDenied)},
+		{"data over MAX_TEXT_LENGTH", publishAndWait(gw, alice, own, `{"text":"0123456789abcdef"}`), uint32(ErrCodeMessageTooLarge)},
+	}
+	for _, tt := range tests {
+		var replyErr *centrifuge.Error
+		if !errors.As(tt.err, &replyErr) || replyErr.Code != tt.wantErr {
+			t.Errorf("%s: error = %v, want code %d", tt.name, tt.err, tt.wantErr)
+		}
+	}
+
+	// Echo publishes count against the publish rate limit
+	gw.publishLimiter = middleware.NewLocalRateLimiter(1, time.Minute)
+	if err := publishAndWait(gw, alice, own, `{}`); err != nil {
+		t.Fatalf("first echo publish error = %v", err)
+	}
+	var replyErr *centrifuge.Error
+	if err := publishAndWait(gw, alice, own, `{}`); !errors.As(err, &replyErr) || replyErr.Code != uint32(ErrCodeRateLimited) {
+		t.Errorf("echo publish over the rate limit error = %v, want code %d", err, ErrCodeRateLimited)
+	}
+}
+
+func TestIsEchoChannel(t *testing.T) {
+	gw := &Gateway{config: &config.Config{EchoEnabled: true}, router: routing.NewRouter(nil, 0)}
+	t.Cleanup(func() { gw.router.Close() })
+
+	tests := []struct {
+		channel string
+		want    bool
+	}{
+		{"echo:alice", true},
+		{"echo:", true},
+		{"echo", false},
+		{"chat:echo:alice", false},
+	}
+	for _, tt := range tests {
+		if got := gw.isEchoChannel(tt.channel); got != tt.want {
+			t.Errorf("isEchoChannel(%q) = %v, want %v", tt.channel, got, tt.want)
+		}
+	}
+}
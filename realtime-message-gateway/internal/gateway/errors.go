@@ -0,0 +1,147 @@
+package gateway
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/centrifugal/centrifuge"
+)
+
+// ErrorCode is a code of the errors and disconnects sent to clients
+type ErrorCode uint32
+
+// User-facing error codes. Clients branch on them, so a code must never be
+// reused or changed once released. 102, 103 and 111 are Centrifuge's own
+// codes; disconnect codes are in Centrifuge's 4000-4499 range, so clients
+// reconnect after them.
+const (
+	// ErrCodeChannelNotFound is sent for channels that must exist first,
+	// such as rooms that were not created
+	ErrCodeChannelNotFound ErrorCode = 102
+	// ErrCodePermissionDenied is sent when a client may not use a channel
+	ErrCodePermissionDenied ErrorCode = 103
+	// ErrCodeRateLimited is sent when a client exceeded a rate or connection
+	// limit; temporary
+	ErrCodeRateLimited ErrorCode = 111
+	// ErrCodePlannedDisconnect is the disconnect code on shutdown and
+	// rebalancing, with the reconnect policy as reason
+	ErrCodePlannedDisconnect ErrorCode = 4000
+	// ErrCodeChannelFull is sent when a channel reached
+	// MaxSubscribersPerChannel
+	ErrCodeChannelFull ErrorCode = 4030
+	// ErrCodeIPLimit is the disconnect code when an IP exceeds
+	// MaxConnectionsPerIP
+	ErrCodeIPLimit ErrorCode = 4031
+	// ErrCodeOverloaded is the disconnect code of new connections while the
+	// gateway sheds load
+	ErrCodeOverloaded ErrorCode = 4034
+	// ErrCodeTooManySubscriptions is sent when a client reached
+	// MaxSubscriptionsPerClient
+	ErrCodeTooManySubscriptions ErrorCode = 4035
+	// ErrCodeMessageTooLarge is sent when message text exceeds MaxTextLength
+	ErrCodeMessageTooLarge ErrorCode = 4036
+	// ErrCodeWorkerUnavailable is sent when no worker can take a channel;
+	// temporary
+	ErrCodeWorkerUnavailable ErrorCode = 4037
+	// ErrCodeMessageRejected is sent when the content filter blocks message
+	// text
+	ErrCodeMessageRejected ErrorCode = 4038
+	// ErrCodeDuplicateMessage is sent when a user publishes the same text to
+	// a channel again within DuplicateTextWindow or BloomResetInterval
+	ErrCodeDuplicateMessage ErrorCode = 4039
+	// ErrCodeIdle is the disconnect code of clients without subscriptions
+	// idle for MaxIdleConnectionTime
+	ErrCodeIdle ErrorCode = 4040
+)
+
+// NewClientError returns a Centrifuge error replied to clients with code
+// and msg
+func NewClientError(code ErrorCode, msg string) *centrifuge.Error {
+	return &centrifuge.Error{Code: uint32(code), Message: msg}
+}
+
+// GatewayError is an error with a code that is sent to clients. Code is the
+// Centrifuge error code of the reply; Message is safe to show to clients,
+// while Cause carries the details for logs. errors.Is matches any
+// GatewayError with the same code, so a wrapped sentinel still matches.
+type GatewayError struct {
+	Code    ErrorCode
+	Message string
+	Cause   error
+}
+
+var (
+	// ErrChannelNotFound is returned for channels that must exist first, such
+	// as rooms that were not created
+	ErrChannelNotFound = &GatewayError{Code: ErrCodeChannelNotFound, Message: "channel not found"}
+	// ErrPermissionDenied is returned when a client may not use a channel
+	ErrPermissionDenied = &GatewayError{Code: ErrCodePermissionDenied, Message: "permission denied"}
+	// ErrRateLimited is returned when a client exceeded a rate or connection limit
+	ErrRateLimited = &GatewayError{Code: ErrCodeRateLimited, Message: "rate limited"}
+	// ErrMessageTooLarge is returned when message text exceeds MaxTextLength
+	ErrMessageTooLarge = &GatewayError{Code: ErrCodeMessageTooLarge, Message: "message too large"}
+	// ErrWorkerUnavailable is returned when no worker can take a channel
+	ErrWorkerUnavailable = &GatewayError{Code: ErrCodeWorkerUnavailable, Message: "worker unavailable"}
+	// ErrMessageRejected is returned when the content filter blocks message text
+	ErrMessageRejected = &GatewayError{Code: ErrCodeMessageRejected, Message: "message rejected"}
+	// ErrDuplicateMessage is returned when a user repeats a message within
+	// DuplicateTextWindow or BloomResetInterval
+	ErrDuplicateMessage = &GatewayError{Code: ErrCodeDuplicateMessage, Message: "duplicate message"}
+)
+
+func (e *GatewayError) Error() string {
+	if e.Cause == nil {
+		return e.Message
+	}
+	return e.Message + ": " + e.Cause.Error()
+}
+
+func (e *GatewayError) Unwrap() error {
+	return e.Cause
+}
+
+// Is reports whether target is a GatewayError with the same code
+func (e *GatewayError) Is(target error) bool {
+	t, ok := target.(*GatewayError)
+	return ok && t.Code == e.Code
+}
+
+// Wrap returns a copy of e with cause attached
+func (e *GatewayError) Wrap(cause error) *GatewayError {
+	return &GatewayError{Code: e.Code, Message: e.Message, Cause: cause}
+}
+
+// HTTPStatus returns the HTTP status code matching e
+func (e *GatewayError) HTTPStatus() int {
+	switch e.Code {
+	case ErrCodeChannelNotFound:
+		return http.StatusNotFound
+	case ErrCodePermissionDenied:
+		return http.StatusForbidden
+	case ErrCodeRateLimited:
+		return http.StatusTooManyRequests
+	case ErrCodeMessageTooLarge:
+		return http.StatusRequestEntityTooLarge
+	case ErrCodeWorkerUnavailable:
+		return http.StatusServiceUnavailable
+	case ErrCodeMessageRejected:
+		return http.StatusUnprocessableEntity
+	case ErrCodeDuplicateMessage:
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// clientError converts err to the error replied to a Centrifuge client.
+// Only the code and message are sent; errors that are not a GatewayError
+// become ErrorInternal.
+func clientError(err error) *centrifuge.Error {
+	var gwErr *GatewayError
+	if !errors.As(err, &gwErr) {
+		return centrifuge.ErrorInternal
+	}
+	reply := NewClientError(gwErr.Code, gwErr.Message)
+	reply.Temporary = gwErr.Code == ErrCodeRateLimited || gwErr.Code == ErrCodeWorkerUnavailable
+	return reply
+}
@@ -0,0 +1,122 @@
+package gateway
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/centrifugal/centrifuge"
+)
+
+func TestGatewayErrorMatching(t *testing.T) {
+	cause := errors.New("no active workers")
+	err := fmt.Errorf("publish: %w", ErrWorkerUnavailable.Wrap(cause))
+
+	if !errors.Is(err, ErrWorkerUnavailable) {
+		t.Error("errors.Is(err, ErrWorkerUnavailable) = false, want true")
+	}
+	if errors.Is(err, ErrPermissionDenied) {
+		t.Error("errors.Is(err, ErrPermissionDenied) = true, want false")
+	}
+	if !errors.Is(err, cause) {
+		t.Error("errors.Is(err, cause) = false, want true")
+	}
+
+	var gwErr *GatewayError
+	if !errors.As(err, &gwErr) {
+		t.Fatal("errors.As(err, *GatewayError) = false, want true")
+	}
+	if gwErr.Cause != cause {
+		t.Errorf("Cause = %v, want %v", gwErr.Cause, cause)
+	}
+	if got, want := err.Error(), "publish: worker unavailable: no active workers"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+	if ErrWorkerUnavailable.Cause != nil {
+		t.Error("Wrap() modified the sentinel")
+	}
+}
+
+func TestGatewayErrorMapping(t *testing.T) {
+	tests := []struct {
+		name          string
+		err           error
+		wantStatus    int
+		wantCode      uint32
+		wantTemporary bool
+	}{
+		{"channel not found", ErrChannelNotFound.Wrap(ErrRoomNotFound), http.StatusNotFound, 102, false},
+		{"permission denied", ErrPermissionDenied, http.StatusForbidden, 103, false},
+		{"rate limited", ErrRateLimited, http.StatusTooManyRequests, 111, true},
+		{"message too large", ErrMessageTooLarge.Wrap(ErrTextTooLong), http.StatusRequestEntityTooLarge, 4036, false},
+		{"worker unavailable", ErrWorkerUnavailable, http.StatusServiceUnavailable, 4037, true},
+		{"message rejected", ErrMessageRejected, http.StatusUnprocessableEntity, 4038, false},
+		{"unknown code", &GatewayError{Code: 1, Message: "other"}, http.StatusInternalServerError, 1, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gwErr *GatewayError
+			if !errors.As(tt.err, &gwErr) {
+				t.Fatalf("errors.As(%v) = false", tt.err)
+			}
+			if got := gwErr.HTTPStatus(); got != tt.wantStatus {
+				t.Errorf("HTTPStatus() = %d, want %d", got, tt.wantStatus)
+			}
+
+			reply := clientError(tt.err)
+			if reply.Code != tt.wantCode || reply.Temporary != tt.wantTemporary {
+				t.Errorf("clientError() = %d (temporary %v), want %d (temporary %v)", reply.Code, reply.Temporary, tt.wantCode, tt.wantTemporary)
+			}
+			if reply.Message != gwErr.Message {
+				t.Errorf("clientError() message = %q, want %q without the cause", reply.Message, gwErr.Message)
+			}
+		})
+	}
+}
+
+func TestClientErrorInternal(t *testing.T) {
+	if got := clientError(errors.New("redis down")); got != centrifuge.ErrorInternal {
+		t.Errorf("clientError() = %v, want ErrorInternal", got)
+	}
+}
+
+func TestErrorCodesUnique(t *testing.T) {
+	// Parse the source so codes added later are checked without listing them
+	file, err := parser.ParseFile(token.NewFileSet(), "errors.go", nil, 0)
+	if err != nil {
+		t.Fatalf("ParseFile() error = %v", err)
+	}
+
+	seen := make(map[string]string)
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.CONST {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			value := spec.(*ast.ValueSpec)
+			for i, name := range value.Names {
+				if !strings.HasPrefix(name.Name, "ErrCode") {
+					continue
+				}
+				lit, ok := value.Values[i].(*ast.BasicLit)
+				if !ok {
+					t.Fatalf("%s is not an integer literal", name.Name)
+				}
+				if other, ok := seen[lit.Value]; ok {
+					t.Errorf("%s and %s share code %s", other, name.Name, lit.Value)
+				}
+				seen[lit.Value] = name.Name
+			}
+		}
+	}
+	if len(seen) == 0 {
+		t.Fatal("no ErrCode constants found")
+	}
+}
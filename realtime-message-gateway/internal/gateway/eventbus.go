@@ -0,0 +1,128 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+
+	"realtime-message-gateway/internal/metrics"
+)
+
+// eventBusQueueSize is how many events each handler of the gateway's bus
+// buffers before further events are dropped
+const eventBusQueueSize = 1024
+
+// ErrEventBusClosed is returned when publishing to a closed AsyncEventBus
+var ErrEventBusClosed = errors.New("event bus closed")
+
+// GatewayEvent is a presence event of a client in a channel, routed to the
+// worker in Message.WorkerID
+type GatewayEvent struct {
+	Message StreamMessage
+	// W3C trace context of the subscribe or unsubscribe that caused the
+	// event, written to the worker stream entry
+	TraceContext string
+}
+
+// EventHandler handles a GatewayEvent
+type EventHandler func(ctx context.Context, event GatewayEvent)
+
+// EventBus delivers gateway events to the handlers registered for them, so
+// the subscribe and unsubscribe paths do not call each consumer directly
+type EventBus interface {
+	Publish(ctx context.Context, event GatewayEvent) error
+}
+
+var _ EventBus = (*AsyncEventBus)(nil)
+
+// eventHandler is a handler registered on an AsyncEventBus with its queue
+type eventHandler struct {
+	name    string
+	handler EventHandler
+	queue   chan GatewayEvent
+}
+
+// AsyncEventBus runs each registered handler in its own goroutine, reading
+// a bounded queue. A handler sees events in publish order. Publish never
+// waits: an event is dropped for a handler whose queue is full and counted
+// in gateway_event_bus_dropped_total, so a slow handler delays neither the
+// publisher nor other handlers.
+type AsyncEventBus struct {
+	ctx       context.Context
+	queueSize int
+	metrics   *metrics.Metrics
+
+	mu       sync.RWMutex
+	closed   bool
+	handlers []*eventHandler
+	wg       sync.WaitGroup
+}
+
+// NewAsyncEventBus creates an AsyncEventBus whose handlers are called with
+// ctx and buffer up to queueSize events each
+func NewAsyncEventBus(ctx context.Context, queueSize int, m *metrics.Metrics) *AsyncEventBus {
+	return &AsyncEventBus{ctx: ctx, queueSize: queueSize, metrics: m}
+}
+
+// Register adds handler for every event published from now on, called once
+// Start runs it. name identifies the handler in logs and metrics.
+func (b *AsyncEventBus) Register(name string, handler EventHandler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = append(b.handlers, &eventHandler{
+		name:    name,
+		handler: handler,
+		queue:   make(chan GatewayEvent, b.queueSize),
+	})
+}
+
+// Start starts a goroutine per registered handler; events published before
+// wait in the queues
+func (b *AsyncEventBus) Start() {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, h := range b.handlers {
+		b.wg.Add(1)
+		go func() {
+			defer b.wg.Done()
+			for event := range h.queue {
+				h.handler(b.ctx, event)
+			}
+			slog.Debug("event handler stopped", "handler", h.name)
+		}()
+	}
+}
+
+// Publish queues event for every handler with room in its queue. Returns
+// ErrEventBusClosed after Close.
+func (b *AsyncEventBus) Publish(ctx context.Context, event GatewayEvent) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.closed {
+		return ErrEventBusClosed
+	}
+	for _, h := range b.handlers {
+		select {
+		case h.queue <- event:
+		default:
+			b.metrics.EventBusDroppedTotal.WithLabelValues(h.name).Inc()
+			slog.WarnContext(ctx, "event handler queue full, dropping event", "handler", h.name, "eventType", event.Message.Type)
+		}
+	}
+	return nil
+}
+
+// Close stops accepting events and returns once the started handlers have
+// handled the events already queued
+func (b *AsyncEventBus) Close() {
+	b.mu.Lock()
+	if !b.closed {
+		b.closed = true
+		for _, h := range b.handlers {
+			close(h.queue)
+		}
+	}
+	b.mu.Unlock()
+	b.wg.Wait()
+}
@@ -0,0 +1,75 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"realtime-message-gateway/internal/metrics"
+)
+
+func TestAsyncEventBus(t *testing.T) {
+	bus := NewAsyncEventBus(context.Background(), 10, metrics.Default)
+
+	var mu sync.Mutex
+	got := map[string][]string{}
+	for _, name := range []string{"a", "b"} {
+		bus.Register(name, func(_ context.Context, event GatewayEvent) {
+			mu.Lock()
+			got[name] = append(got[name], event.Message.ID)
+			mu.Unlock()
+		})
+	}
+
+	bus.Start()
+
+	want := []string{"1", "2", "3"}
+	for _, id := range want {
+		if err := bus.Publish(context.Background(), GatewayEvent{Message: StreamMessage{ID: id}}); err != nil {
+			t.Fatalf("Publish() error = %v", err)
+		}
+	}
+	bus.Close()
+
+	// Close waited for the queued events
+	for _, name := range []string{"a", "b"} {
+		if !slices.Equal(got[name], want) {
+			t.Errorf("handler %s got %v, want %v", name, got[name], want)
+		}
+	}
+	if err := bus.Publish(context.Background(), GatewayEvent{}); !errors.Is(err, ErrEventBusClosed) {
+		t.Errorf("Publish() after Close error = %v, want ErrEventBusClosed", err)
+	}
+}
+
+func TestAsyncEventBusFullQueue(t *testing.T) {
+	bus := NewAsyncEventBus(context.Background(), 1, metrics.Default)
+	started, release := make(chan struct{}, 2), make(chan struct{})
+	bus.Register("slow", func(context.Context, GatewayEvent) {
+		started <- struct{}{}
+		<-release
+	})
+	bus.Start()
+	defer bus.Close()
+	defer close(release)
+	dropped := testutil.ToFloat64(metrics.Default.EventBusDroppedTotal.WithLabelValues("slow"))
+
+	// The handler holds the first event, the queue the second, and the
+	// third is dropped without waiting
+	if err := bus.Publish(context.Background(), GatewayEvent{}); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	<-started
+	for range 2 {
+		if err := bus.Publish(context.Background(), GatewayEvent{}); err != nil {
+			t.Fatalf("Publish() error = %v", err)
+		}
+	}
+	if got := testutil.ToFloat64(metrics.Default.EventBusDroppedTotal.WithLabelValues("slow")) - dropped; got != 1 {
+		t.Errorf("dropped events = %g, want 1", got)
+	}
+}
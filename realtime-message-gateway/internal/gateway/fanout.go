@@ -0,0 +1,192 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/centrifugal/centrifuge"
+	"github.com/google/uuid"
+
+	"realtime-message-gateway/internal/redis"
+	"realtime-message-gateway/internal/routing"
+	"realtime-message-gateway/internal/tracing"
+)
+
+// MaxFanOutChannels is the maximum number of channels in the channels field
+// of a publish
+const MaxFanOutChannels = 10
+
+// ErrInvalidFanOut is wrapped by all errors about the channels field of a publish
+var ErrInvalidFanOut = errors.New("invalid channels")
+
+// fanOutChannels returns the channels listed in the channels field of
+// publish data, without the publish channel itself and duplicates. The
+// client must be allowed to publish to every listed channel, as checked by
+// authorizePublish; one channel that is not allowed rejects the publish.
+func (g *Gateway) fanOutChannels(client *centrifuge.Client, data map[string]interface{}, channel string) ([]string, error) {
+	v, present := data["channels"]
+	if !present {
+		return nil, nil
+	}
+	list, ok := v.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: must be an array of channel names", ErrInvalidFanOut)
+	}
+	if len(list) > MaxFanOutChannels {
+		return nil, fmt.Errorf("%w: more than %d channels", ErrInvalidFanOut, MaxFanOutChannels)
+	}
+
+	var channels []string
+	for _, item := range list {
+		name, ok := item.(string)
+		if !ok {
+			return nil, fmt.Errorf("%w: channel %v not a string", ErrInvalidFanOut, item)
+		}
+		if err := g.authorizePublish(client, name); err != nil {
+			return nil, fmt.Errorf("%w: channel %q: %w", ErrInvalidFanOut, name, err)
+		}
+		if name != channel && !slices.Contains(channels, name) {
+			channels = append(channels, name)
+		}
+	}
+	return channels, nil
+}
+
+// handleFanOutPublish writes a client publication to the worker streams of
+// all channels and broadcasts it to them, without the channels field that
+// lists the recipients
+func (g *Gateway) handleFanOutPublish(ctx context.Context, client *centrifuge.Client, channels []string, data map[string]interface{}, text, contentType string, meta map[string]string, priority int, cb centrifuge.PublishCallback) {
+	delete(data, "channels")
+	raw, err := json.Marshal(data)
+	if err != nil {
+		g.metrics.PublishTotal.WithLabelValues("error", "marshal_error").Inc()
+		slog.ErrorContext(ctx, "failed to marshal raw data", "error", err)
+		cb(centrifuge.PublishReply{}, centrifuge.ErrorInternal)
+		return
+	}
+
+	messageIDs, err := g.publishFanOut(ctx, client, channels, text, contentType, meta, raw, priority)
+	if err != nil {
+		g.metrics.PublishTotal.WithLabelValues("error", "fan_out_failed").Inc()
+		slog.ErrorContext(ctx, "fan-out publish failed", "channels", channels, "error", err)
+		cb(centrifuge.PublishReply{}, clientError(err))
+		return
+	}
+
+	for i, channel := range channels {
+		if _, err := g.node.Publish(channel, raw); err != nil {
+			slog.WarnContext(ctx, "failed to broadcast fan-out message", "channel", channel, "messageId", messageIDs[i], "error", err)
+		}
+		g.metrics.PublishTotal.WithLabelValues("success", "").Inc()
+		g.incrChannelStat(ctx, channel, statsFieldMessages, 1)
+		g.router.RecordChannelMessage(channel)
+	}
+	// Duplicates are detected on the publish channel
+	if g.config.DuplicateTextWindow > 0 {
+		g.recordLastText(client.UserID(), channels[0], text, time.Now())
+	}
+	if g.dedupFilter != nil {
+		g.recordPublished(ctx, client.UserID(), channels[0], text)
+	}
+	g.metrics.WebSocketMessagesTotal.WithLabelValues("inbound").Inc()
+
+	slog.InfoContext(ctx, "fan-out message published", "channels", channels, "messageIds", messageIDs)
+	// Already broadcast above; Centrifuge would send the client's data with
+	// the channels field
+	cb(centrifuge.PublishReply{Result: &centrifuge.PublishResult{}}, nil)
+}
+
+// publishFanOut writes one stream message per channel to the channels'
+// worker streams in a single pipeline and returns the message IDs. If any
+// write fails, the written entries are deleted again; a worker may already
+// have read them.
+func (g *Gateway) publishFanOut(ctx context.Context, client *centrifuge.Client, channels []string, text, contentType string, meta map[string]string, raw []byte, priority int) ([]string, error) {
+	userName := clientUserName(client)
+	publishedAt := time.Now().UTC()
+	timestamp := publishedAt.Format(time.RFC3339Nano)
+	traceContext := tracing.Inject(ctx)
+
+	messageIDs := make([]string, len(channels))
+	entries := make([]redis.StreamEntry, len(channels))
+	for i, channel := range channels {
+		workerID, err := g.router.GetWorkerForChannel(ctx, channel)
+		if err != nil {
+			g.metrics.BatchChannelPublishTotal.WithLabelValues("error").Inc()
+			return nil, ErrWorkerUnavailable.Wrap(fmt.Errorf("channel %q: %w", channel, err))
+		}
+		g.prepareWorkerStreams(ctx, workerID)
+
+		message := StreamMessage{
+			SchemaVersion: g.config.StreamSchemaVersion,
+			ID:            uuid.New().String(),
+			Type:          EventTypeMessage,
+			Channel:       channel,
+			WorkerID:      workerID,
+			UserID:        client.UserID(),
+			UserName:      userName,
+			Text:          strings.TrimSpace(text),
+			ContentType:   contentType,
+			Meta:          meta,
+			Timestamp:     timestamp,
+			Raw:           string(raw),
+			ClientID:      client.ID(),
+			GatewayID:     g.instanceID,
+			Priority:      priority,
+		}
+		g.signMessage(&message)
+		payload, err := json.Marshal(message)
+		if err != nil {
+			g.metrics.BatchChannelPublishTotal.WithLabelValues("error").Inc()
+			return nil, err
+		}
+
+		messageIDs[i] = message.ID
+		entries[i] = redis.StreamEntry{
+			Stream: routing.GetWorkerStreamKey(workerID, priority),
+			Values: streamEntry(payload, traceContext),
+			Push:   g.historyPush(ctx, channel, client.UserID(), g.historyIDs.next(publishedAt), payload),
+			Expire: g.router.RouteExpire(channel),
+		}
+	}
+
+	ids, errs := g.redis.XAddPipeline(ctx, entries)
+	if err := errors.Join(errs...); err != nil {
+		g.rollbackFanOut(ctx, entries, ids)
+		g.metrics.BatchChannelPublishTotal.WithLabelValues("rolled_back").Inc()
+		return nil, err
+	}
+
+	for _, channel := range channels {
+		g.historyCache.remove(channel)
+	}
+	g.metrics.BatchChannelPublishTotal.WithLabelValues("success").Inc()
+	return messageIDs, nil
+}
+
+// rollbackFanOut deletes the entries of a fan-out publish that were
+// written, and their history records, also when ctx was cancelled during
+// the publish
+func (g *Gateway) rollbackFanOut(ctx context.Context, entries []redis.StreamEntry, ids []string) {
+	ctx = context.WithoutCancel(ctx)
+	for i, id := range ids {
+		if id == "" {
+			continue
+		}
+		if err := g.redis.XDel(ctx, entries[i].Stream, id); err != nil {
+			slog.ErrorContext(ctx, "failed to roll back fan-out entry", "streamKey", entries[i].Stream, "entryId", id, "error", err)
+		}
+		if push := entries[i].Push; push != nil {
+			for _, list := range push.Lists {
+				if err := g.redis.LRem(ctx, list.Key, 1, push.Value); err != nil {
+					slog.ErrorContext(ctx, "failed to roll back fan-out history", "key", list.Key, "error", err)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/centrifugal/centrifuge"
+	"github.com/centrifugal/protocol"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"realtime-message-gateway/internal/metrics"
+	"realtime-message-gateway/internal/routing"
+)
+
+func TestFanOutChannels(t *testing.T) {
+	gw := NewTestGateway(t)
+	gw.config.PrivateChannelPrefix = "private:"
+	client := connectTestClient(t, gw)
+	own := "user:" + client.UserID()
+
+	tests := []struct {
+		name    string
+		data    string
+		want    []string
+		wantErr bool
+	}{
+		{"no channels field", `{"text":"hi"}`, nil, false},
+		{"channels", `{"channels":["chat:b","` + own + `"]}`, []string{"chat:b", own}, false},
+		{"duplicates and publish channel dropped", `{"channels":["chat:a","chat:b","chat:b"]}`, []string{"chat:b"}, false},
+		{"not an array", `{"channels":"chat:b"}`, nil, true},
+		{"not a string", `{"channels":["chat:b",1]}`, nil, true},
+		{"invalid channel", `{"channels":["random"]}`, nil, true},
+		{"user channel of another user", `{"channels":["chat:b","user:u2"]}`, nil, true},
+		{"private channel", `{"channels":["private:x"]}`, nil, true},
+		{"room not joined", `{"channels":["chat:room-x"]}`, nil, true},
+		{"too many channels", `{"channels":["chat:1","chat:2","chat:3","chat:4","chat:5","chat:6","chat:7","chat:8","chat:9","chat:10","chat:11"]}`, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var data map[string]interface{}
+			if err := json.Unmarshal([]byte(tt.data), &data); err != nil {
+				t.Fatalf("json.Unmarshal() error = %v", err)
+			}
+			got, err := gw.fanOutChannels(client, data, "chat:a")
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidFanOut) {
+					t.Errorf("fanOutChannels() error = %v, want %v", err, ErrInvalidFanOut)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("fanOutChannels() error = %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("fanOutChannels() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFanOutPublishAuthorization(t *testing.T) {
+	gw := NewTestGateway(t)
+	alice := connectTestClient(t, gw)
+	transport := &testTransport{}
+	bob, closeFn, err := centrifuge.NewClient(context.Background(), gw.node, transport)
+	if err != nil {
+		t.Fatalf("centrifuge.NewClient() error = %v", err)
+	}
+	t.Cleanup(func() { closeFn() })
+	bob.HandleCommand(&protocol.Command{Id: 1, Connect: &protocol.ConnectRequest{}}, 0)
+	subscribeTestClient(bob, 2, "chat:b")
+	streamKey := routing.GetWorkerStreamKey("worker-0", routing.PriorityNormal)
+	before, _ := gw.redis.XLen(context.Background(), streamKey) // bob's join
+
+	// Another user's channel rejects the whole publish
+	err = publishAndWait(gw, alice, "chat:a", `{"text":"hi","channels":["chat:b","user:`+bob.UserID()+`"]}`)
+	var replyErr *centrifuge.Error
+	if !errors.As(err, &replyErr) || replyErr.Code != uint32(ErrCodePermissionDenied) {
+		t.Errorf("publish to another user's channel error = %v, want code %d", err, ErrCodePermissionDenied)
+	}
+	if n, _ := gw.redis.XLen(context.Background(), streamKey); n != before {
+		t.Errorf("worker stream has %d entries after a rejected fan-out, want %d", n, before)
+	}
+
+	// Recipients see the message without the list of channels
+	if err := publishAndWait(gw, alice, "chat:a", `{"text":"hi","channels":["chat:b"]}`); err != nil {
+		t.Fatalf("publish error = %v", err)
+	}
+	transport.mu.Lock()
+	defer transport.mu.Unlock()
+	var pushed bool
+	for _, msg := range transport.messages {
+		if strings.Contains(string(msg), `"pub"`) {
+			pushed = true
+			if strings.Contains(string(msg), "channels") {
+				t.Errorf("broadcast %s contains the channels field", msg)
+			}
+		}
+	}
+	if !pushed {
+		t.Error("fan-out message not broadcast to chat:b")
+	}
+}
+
+// publishAndWait calls handlePublish and returns the error passed to its callback
+func publishAndWait(gw *Gateway, client *centrifuge.Client, channel, data string) error {
+	var replyErr error
+	gw.handlePublish(context.Background(), client, centrifuge.PublishEvent{Channel: channel, Data: []byte(data)}, func(_ centrifuge.PublishReply, err error) {
+		replyErr = err
+	})
+	return replyErr
+}
+
+// routeChannels pins channels to workers by index
+func routeChannels(t *testing.T, gw *Gateway, channels, workers []string) {
+	t.Helper()
+	for i, channel := range channels {
+		if err := gw.redis.Set(context.Background(), routing.ChannelRoutePrefix+channel, workers[i], 0); err != nil {
+			t.Fatalf("Set() error = %v", err)
+		}
+	}
+}
+
+func TestFanOutPublishDifferentWorkers(t *testing.T) {
+	workers := []string{"worker-0", "worker-1", "worker-2"}
+	channels := []string{"chat:a", "chat:b", "chat:c"}
+	gw := NewTestGateway(t, WithWorkers(workers))
+	routeChannels(t, gw, channels, workers)
+	client := connectTestClient(t, gw)
+	before := testutil.ToFloat64(metrics.Default.BatchChannelPublishTotal.WithLabelValues("success"))
+
+	if err := publishAndWait(gw, client, "chat:a", `{"text":"hello","channels":["chat:b","chat:c"]}`); err != nil {
+		t.Fatalf("publish error = %v", err)
+	}
+
+	ctx := context.Background()
+	for i, workerID := range workers {
+		entries, err := gw.redis.XRange(ctx, routing.GetWorkerStreamKey(workerID, routing.PriorityNormal), "-", "+", 0)
+		if err != nil {
+			t.Fatalf("XRange() error = %v", err)
+		}
+		if len(entries) != 1 {
+			t.Fatalf("%s stream has %d entries, want 1", workerID, len(entries))
+		}
+		var msg StreamMessage
+		if err := json.Unmarshal([]byte(entries[0].Payload), &msg); err != nil {
+			t.Fatalf("json.Unmarshal() error = %v", err)
+		}
+		if msg.Channel != channels[i] || msg.WorkerID != workerID || msg.Text != "hello" {
+			t.Errorf("%s message = %s/%s/%q, want %s/%s/%q", workerID, msg.Channel, msg.WorkerID, msg.Text, channels[i], workerID, "hello")
+		}
+	}
+	if got := testutil.ToFloat64(metrics.Default.BatchChannelPublishTotal.WithLabelValues("success")) - before; got != 1 {
+		t.Errorf("successful fan-out publishes increased by %v, want 1", got)
+	}
+}
+
+func TestFanOutPublishRollback(t *testing.T) {
+	workers := []string{"worker-0", "worker-1", "worker-2"}
+	gw := NewTestGateway(t, WithWorkers(workers))
+	routeChannels(t, gw, []string{"chat:a", "chat:b", "chat:c"}, workers)
+	client := connectTestClient(t, gw)
+	before := testutil.ToFloat64(metrics.Default.BatchChannelPublishTotal.WithLabelValues("rolled_back"))
+
+	// XADD to a string key fails with WRONGTYPE
+	ctx := context.Background()
+	if err := gw.redis.Set(ctx, routing.GetWorkerStreamKey("worker-2", routing.PriorityNormal), "not a stream", 0); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	err := publishAndWait(gw, client, "chat:a", `{"text":"hello","channels":["chat:b","chat:c"]}`)
+	if err == nil || !strings.Contains(err.Error(), "internal") {
+		t.Fatalf("publish error = %v, want internal error", err)
+	}
+
+	for _, workerID := range workers[:2] {
+		if n, err := gw.redis.XLen(ctx, routing.GetWorkerStreamKey(workerID, routing.PriorityNormal)); err != nil || n != 0 {
+			t.Errorf("%s stream has %d entries after rollback (error %v), want 0", workerID, n, err)
+		}
+	}
+	if got := testutil.ToFloat64(metrics.Default.BatchChannelPublishTotal.WithLabelValues("rolled_back")) - before; got != 1 {
+		t.Errorf("rolled back fan-out publishes increased by %v, want 1", got)
+	}
+}
@@ -0,0 +1,149 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"realtime-message-gateway/internal/routing"
+)
+
+// Health report component names
+const (
+	ComponentRedis         = "redis"
+	ComponentCentrifuge    = "centrifuge"
+	ComponentRouting       = "routing"
+	ComponentStreamBacklog = "stream_backlog"
+)
+
+// healthCheckTimeout bounds the Redis calls of a health report
+const healthCheckTimeout = 2 * time.Second
+
+// ErrRedisUnavailable is the error of the redis component when Redis does
+// not answer; the cause is logged instead of reported
+var ErrRedisUnavailable = errors.New("redis unavailable")
+
+// HealthStatus is the health of the gateway and its components
+type HealthStatus struct {
+	Healthy bool `json:"healthy"`
+	// Degraded is set while Redis is down but publishes are queued locally;
+	// the gateway still serves clients
+	Degraded   bool                       `json:"degraded,omitempty"`
+	Components map[string]ComponentStatus `json:"components"`
+	Timestamp  time.Time                  `json:"timestamp"`
+}
+
+// ComponentStatus is the health of one component. Value is the
+// component's measurement: Redis ping latency in seconds, 1 while the
+// Centrifuge node runs, the route cache hit ratio, or the longest worker
+// stream in entries. A degraded component is healthy but needs attention.
+type ComponentStatus struct {
+	Healthy  bool    `json:"healthy"`
+	Degraded bool    `json:"degraded,omitempty"`
+	Value    float64 `json:"value"`
+	Error    string  `json:"error,omitempty"`
+	// Entries across the priority streams of each active worker, only set
+	// for stream_backlog
+	StreamLength map[string]int64 `json:"streamLength,omitempty"`
+}
+
+// HealthReport checks the redis, centrifuge and routing components and
+// exports the results as the gateway_health_component_status gauges. It
+// costs one Redis round trip, so it can back unauthenticated health checks.
+// The gateway is healthy when all components are.
+func (g *Gateway) HealthReport(ctx context.Context) HealthStatus {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	return g.healthReport(map[string]ComponentStatus{
+		ComponentRedis:      g.redisHealthStatus(ctx),
+		ComponentCentrifuge: g.centrifugeHealthStatus(),
+		ComponentRouting:    {Healthy: true, Value: g.router.CacheHitRatio()},
+	})
+}
+
+// DetailedHealthReport is HealthReport with the stream_backlog component,
+// which reads the length of every worker stream and names the workers
+func (g *Gateway) DetailedHealthReport(ctx context.Context) HealthStatus {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	return g.healthReport(map[string]ComponentStatus{
+		ComponentRedis:         g.redisHealthStatus(ctx),
+		ComponentCentrifuge:    g.centrifugeHealthStatus(),
+		ComponentRouting:       {Healthy: true, Value: g.router.CacheHitRatio()},
+		ComponentStreamBacklog: g.streamBacklogHealthStatus(ctx),
+	})
+}
+
+// healthReport exports components as gauges and sums them up in a report
+func (g *Gateway) healthReport(components map[string]ComponentStatus) HealthStatus {
+	report := HealthStatus{
+		Healthy:    true,
+		Components: components,
+		Timestamp:  time.Now().UTC(),
+	}
+	for name, status := range report.Components {
+		value := 0.0
+		if status.Healthy {
+			value = 1
+		}
+		g.metrics.HealthComponentStatus.WithLabelValues(name).Set(value)
+		report.Healthy = report.Healthy && status.Healthy
+	}
+	report.Degraded = !report.Healthy && report.Components[ComponentCentrifuge].Healthy &&
+		g.config.ClientQueueDepth > 0 && g.RedisDegraded()
+	return report
+}
+
+// redisHealthStatus pings Redis and reports the latency
+func (g *Gateway) redisHealthStatus(ctx context.Context) ComponentStatus {
+	start := time.Now()
+	if err := g.redis.Ping(ctx); err != nil {
+		slog.WarnContext(ctx, "health check failed to ping Redis", "error", err)
+		return ComponentStatus{Error: ErrRedisUnavailable.Error()}
+	}
+	return ComponentStatus{Healthy: true, Value: time.Since(start).Seconds()}
+}
+
+// centrifugeHealthStatus reports whether the Centrifuge node is running
+func (g *Gateway) centrifugeHealthStatus() ComponentStatus {
+	if !g.running.Load() {
+		return ComponentStatus{Error: ErrNodeNotRunning.Error()}
+	}
+	return ComponentStatus{Healthy: true, Value: 1}
+}
+
+// streamBacklogHealthStatus reports the longest stream of the active
+// workers and the stream length of each worker. It is degraded when a
+// stream is longer than StreamLengthWarnThreshold.
+func (g *Gateway) streamBacklogHealthStatus(ctx context.Context) ComponentStatus {
+	workers, err := g.redis.ZRange(ctx, routing.ActiveWorkersKey, 0, -1)
+	if err != nil {
+		slog.WarnContext(ctx, "health check failed to list workers", "error", err)
+		return ComponentStatus{Error: ErrRedisUnavailable.Error()}
+	}
+
+	lengths := make(map[string]int64, len(workers))
+	var longest int64
+	for _, workerID := range workers {
+		for _, streamKey := range routing.GetWorkerStreamKeys(workerID) {
+			n, err := g.redis.XLen(ctx, streamKey)
+			if err != nil {
+				slog.WarnContext(ctx, "health check failed to read stream length", "stream", streamKey, "error", err)
+				return ComponentStatus{Error: ErrRedisUnavailable.Error()}
+			}
+			lengths[workerID] += n
+			longest = max(longest, n)
+		}
+	}
+
+	threshold := int64(g.config.StreamLengthWarnThreshold)
+	return ComponentStatus{
+		Healthy:      true,
+		Degraded:     threshold > 0 && longest > threshold,
+		Value:        float64(longest),
+		StreamLength: lengths,
+	}
+}
@@ -0,0 +1,113 @@
+package gateway
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"realtime-message-gateway/internal/metrics"
+	"realtime-message-gateway/internal/routing"
+)
+
+func TestHealthReport(t *testing.T) {
+	gw := NewTestGateway(t, WithWorkers([]string{"worker-0", "worker-1"}))
+
+	report := gw.HealthReport(context.Background())
+	if !report.Healthy || report.Degraded {
+		t.Fatalf("HealthReport() = %+v, want healthy", report)
+	}
+	if _, ok := report.Components[ComponentStreamBacklog]; ok {
+		t.Error("HealthReport() reports the worker streams")
+	}
+	if got := report.Components[ComponentCentrifuge].Value; got != 1 {
+		t.Errorf("centrifuge value = %v, want 1", got)
+	}
+	if report.Timestamp.IsZero() {
+		t.Error("Timestamp not set")
+	}
+	for _, name := range []string{ComponentRedis, ComponentCentrifuge, ComponentRouting} {
+		if got := testutil.ToFloat64(metrics.Default.HealthComponentStatus.WithLabelValues(name)); got != 1 {
+			t.Errorf("health_component_status{component=%q} = %v, want 1", name, got)
+		}
+	}
+}
+
+func TestDetailedHealthReport(t *testing.T) {
+	gw := NewTestGateway(t, WithWorkers([]string{"worker-0", "worker-1"}))
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		gw.redis.XAdd(ctx, routing.GetWorkerStreamKey("worker-1", routing.PriorityHigh), streamEntry([]byte(`{}`), ""))
+	}
+
+	report := gw.DetailedHealthReport(ctx)
+	if !report.Healthy || report.Degraded {
+		t.Fatalf("DetailedHealthReport() = %+v, want healthy", report)
+	}
+	backlog := report.Components[ComponentStreamBacklog]
+	if backlog.Value != 3 || backlog.Degraded {
+		t.Errorf("stream_backlog = %+v, want value 3 and not degraded", backlog)
+	}
+	if want := map[string]int64{"worker-0": 0, "worker-1": 3}; !reflect.DeepEqual(backlog.StreamLength, want) {
+		t.Errorf("stream_backlog streamLength = %v, want %v", backlog.StreamLength, want)
+	}
+	if got := testutil.ToFloat64(metrics.Default.HealthComponentStatus.WithLabelValues(ComponentStreamBacklog)); got != 1 {
+		t.Errorf("health_component_status{component=\"stream_backlog\"} = %v, want 1", got)
+	}
+}
+
+func TestHealthReportStreamLengthWarnThreshold(t *testing.T) {
+	gw := NewTestGateway(t, WithWorkers([]string{"worker-0"}))
+	gw.config.StreamLengthWarnThreshold = 2
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		gw.redis.XAdd(ctx, routing.GetWorkerStreamKey("worker-0", routing.PriorityNormal), streamEntry([]byte(`{}`), ""))
+	}
+
+	report := gw.DetailedHealthReport(ctx)
+	backlog := report.Components[ComponentStreamBacklog]
+	if !backlog.Healthy || !backlog.Degraded {
+		t.Errorf("stream_backlog = %+v, want healthy and degraded", backlog)
+	}
+	if !report.Healthy {
+		t.Error("a degraded component made the report unhealthy")
+	}
+}
+
+func TestHealthReportRedisDown(t *testing.T) {
+	tests := []struct {
+		name         string
+		queueDepth   int
+		wantDegraded bool
+	}{
+		{"queueing disabled", 0, false},
+		{"queueing enabled", 16, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gw := NewTestGateway(t)
+			gw.config.ClientQueueDepth = tt.queueDepth
+			gw.redis.Close()
+			gw.redisHealth.check(context.Background())
+
+			report := gw.HealthReport(context.Background())
+			if report.Healthy {
+				t.Fatal("HealthReport().Healthy = true with Redis down")
+			}
+			if report.Degraded != tt.wantDegraded {
+				t.Errorf("Degraded = %v, want %v", report.Degraded, tt.wantDegraded)
+			}
+			if status := report.Components[ComponentRedis]; status.Healthy || status.Error != ErrRedisUnavailable.Error() {
+				t.Errorf("redis component = %+v, want unhealthy with %q", status, ErrRedisUnavailable)
+			}
+			if !report.Components[ComponentCentrifuge].Healthy {
+				t.Error("centrifuge component unhealthy while the node runs")
+			}
+			if got := testutil.ToFloat64(metrics.Default.HealthComponentStatus.WithLabelValues(ComponentRedis)); got != 0 {
+				t.Errorf("health_component_status{component=\"redis\"} = %v, want 0", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package gateway
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// History page sizes of the HTTP history endpoint
+const (
+	DefaultHistoryLimit = 50
+	MaxHistoryLimit     = 200
+)
+
+// HistoryDirection orders a history page by history ID
+type HistoryDirection string
+
+const (
+	HistoryAsc  HistoryDirection = "asc"  // oldest first
+	HistoryDesc HistoryDirection = "desc" // newest first
+)
+
+// ErrInvalidCursor is returned for a history cursor that is not a history ID
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// HistoryPage is a page of channel messages. NextCursor is the history ID of
+// the last message while more messages follow and empty otherwise.
+type HistoryPage struct {
+	Messages   []StreamMessage `json:"messages"`
+	NextCursor string          `json:"nextCursor,omitempty"`
+}
+
+// historyEntry is a channel message with its history ID
+type historyEntry struct {
+	id  string
+	msg StreamMessage
+}
+
+// ChannelHistoryPage returns up to limit messages of channel after cursor,
+// a history ID returned as NextCursor, in direction; an empty cursor starts
+// at the oldest or newest message. Messages come from the channel's history
+// list, so at most HistoryRetain are available. Messages published through
+// different gateways can have equal history IDs; a page extends past limit
+// rather than split those across pages.
+func (g *Gateway) ChannelHistoryPage(ctx context.Context, channel string, direction HistoryDirection, cursor string, limit int) (HistoryPage, error) {
+	if cursor != "" {
+		if _, _, ok := parseStreamID(cursor); !ok {
+			return HistoryPage{}, fmt.Errorf("%w: %q", ErrInvalidCursor, cursor)
+		}
+	}
+
+	history, err := g.loadHistory(ctx, channel)
+	if err != nil {
+		return HistoryPage{}, err
+	}
+
+	found := make([]historyEntry, 0, len(history))
+	for _, entry := range history {
+		if cursor == "" ||
+			(direction == HistoryAsc && compareStreamIDs(entry.id, cursor) > 0) ||
+			(direction == HistoryDesc && compareStreamIDs(entry.id, cursor) < 0) {
+			found = append(found, entry)
+		}
+	}
+	slices.SortStableFunc(found, func(a, b historyEntry) int {
+		if direction == HistoryDesc {
+			return compareStreamIDs(b.id, a.id)
+		}
+		return compareStreamIDs(a.id, b.id)
+	})
+
+	n := min(len(found), limit)
+	for n > 0 && n < len(found) && found[n].id == found[n-1].id {
+		n++
+	}
+	page := HistoryPage{Messages: make([]StreamMessage, 0, n)}
+	for _, entry := range found[:n] {
+		page.Messages = append(page.Messages, entry.msg)
+	}
+	// Continue after the last entry of the page while any remain
+	if n < len(found) {
+		page.NextCursor = found[n-1].id
+	}
+	return page, nil
+}
+
+// parseStreamID splits a stream entry ID of the form ms-seq
+func parseStreamID(id string) (ms, seq uint64, ok bool) {
+	msPart, seqPart, found := strings.Cut(id, "-")
+	if !found {
+		return 0, 0, false
+	}
+	ms, err := strconv.ParseUint(msPart, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	seq, err = strconv.ParseUint(seqPart, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	return ms, seq, true
+}
+
+// compareStreamIDs orders stream entry IDs like Redis does
+func compareStreamIDs(a, b string) int {
+	aMS, aSeq, _ := parseStreamID(a)
+	bMS, bSeq, _ := parseStreamID(b)
+	return cmp.Or(cmp.Compare(aMS, bMS), cmp.Compare(aSeq, bSeq))
+}
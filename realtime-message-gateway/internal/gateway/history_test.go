@@ -0,0 +1,122 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+
+	goredis "github.com/redis/go-redis/v9"
+
+	"realtime-message-gateway/internal/routing"
+)
+
+// addTestEntry adds a message to the worker-0 stream of priority with the
+// given entry ID, which the gateway's redis.Client cannot set
+func addTestEntry(t *testing.T, gw *Gateway, entryID, id, channel string, priority int) {
+	t.Helper()
+	opt, err := goredis.ParseURL(gw.config.RedisURL)
+	if err != nil {
+		t.Fatalf("ParseURL() error = %v", err)
+	}
+	rdb := goredis.NewClient(opt)
+	defer rdb.Close()
+
+	payload, _ := json.Marshal(StreamMessage{SchemaVersion: 1, ID: id, Type: EventTypeMessage, Channel: channel, Priority: priority})
+	err = rdb.XAdd(context.Background(), &goredis.XAddArgs{
+		Stream: routing.GetWorkerStreamKey("worker-0", priority),
+		ID:     entryID,
+		Values: streamEntry(payload, ""),
+	}).Err()
+	if err != nil {
+		t.Fatalf("XAdd() error = %v", err)
+	}
+}
+
+func TestChannelHistoryPage(t *testing.T) {
+	gw := NewTestGateway(t)
+	ctx := context.Background()
+	add := func(historyID, id, channel string, priority int) {
+		payload, _ := json.Marshal(StreamMessage{SchemaVersion: 1, ID: id, Type: EventTypeMessage, Channel: channel, Priority: priority})
+		addTestHistory(t, gw, channel, historyID, payload)
+	}
+
+	add("1000-0", "m1", "chat:a", routing.PriorityNormal)
+	add("2000-0", "m2", "chat:a", routing.PriorityHigh)
+	add("3000-0", "m3", "chat:b", routing.PriorityNormal)
+	add("4000-0", "m4", "chat:a", routing.PriorityNormal)
+	add("5000-0", "m5", "chat:a", routing.PriorityHigh)
+	add("5000-0", "m6", "chat:a", routing.PriorityNormal) // same history ID as m5, from another gateway
+	add("6000-0", "m7", "chat:a", routing.PriorityHigh)
+
+	tests := []struct {
+		name       string
+		direction  HistoryDirection
+		cursor     string
+		limit      int
+		want       []string
+		wantCursor string
+	}{
+		{"asc first page", HistoryAsc, "", 2, []string{"m1", "m2"}, "2000-0"},
+		{"asc next page", HistoryAsc, "2000-0", 2, []string{"m4", "m6", "m5"}, "5000-0"},
+		{"asc last page", HistoryAsc, "5000-0", 2, []string{"m7"}, ""},
+		{"asc past end", HistoryAsc, "6000-0", 2, []string{}, ""},
+		{"desc first page", HistoryDesc, "", 2, []string{"m7", "m6", "m5"}, "5000-0"},
+		{"desc next page", HistoryDesc, "5000-0", 2, []string{"m4", "m2"}, "2000-0"},
+		{"desc full last page", HistoryDesc, "5000-0", 3, []string{"m4", "m2", "m1"}, ""},
+		{"desc all", HistoryDesc, "", 10, []string{"m7", "m6", "m5", "m4", "m2", "m1"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := gw.ChannelHistoryPage(ctx, "chat:a", tt.direction, tt.cursor, tt.limit)
+			if err != nil {
+				t.Fatalf("ChannelHistoryPage() error = %v", err)
+			}
+			ids := []string{}
+			for _, msg := range page.Messages {
+				ids = append(ids, msg.ID)
+			}
+			// Entries with equal IDs have no defined order
+			if !slices.Equal(ids, tt.want) && !slices.Equal(swapTied(ids), tt.want) {
+				t.Errorf("ChannelHistoryPage() IDs = %v, want %v", ids, tt.want)
+			}
+			if page.NextCursor != tt.wantCursor {
+				t.Errorf("NextCursor = %q, want %q", page.NextCursor, tt.wantCursor)
+			}
+		})
+	}
+
+	if _, err := gw.ChannelHistoryPage(ctx, "chat:a", HistoryAsc, "oops", 2); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("ChannelHistoryPage() error = %v, want ErrInvalidCursor", err)
+	}
+}
+
+// swapTied swaps m5 and m6, the messages with equal entry IDs
+func swapTied(ids []string) []string {
+	swapped := slices.Clone(ids)
+	i, j := slices.Index(swapped, "m5"), slices.Index(swapped, "m6")
+	if i >= 0 && j >= 0 {
+		swapped[i], swapped[j] = swapped[j], swapped[i]
+	}
+	return swapped
+}
+
+func TestCompareStreamIDs(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1-0", "1-0", 0},
+		{"1-1", "1-0", 1},
+		{"9-0", "10-0", -1},
+		{"10-5", "9-99", 1},
+	}
+
+	for _, tt := range tests {
+		if got := compareStreamIDs(tt.a, tt.b); got != tt.want {
+			t.Errorf("compareStreamIDs(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
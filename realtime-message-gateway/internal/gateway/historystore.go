@@ -0,0 +1,186 @@
+package gateway
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+
+	"realtime-message-gateway/internal/redis"
+)
+
+// ChannelHistoryPrefix is the key prefix of the per-channel lists of
+// published messages, newest first
+const ChannelHistoryPrefix = "channel:history:"
+
+// historyCacheTTL bounds the age of locally cached channel history lists.
+// Entries are dropped when this gateway records a message to the channel;
+// messages other gateways publish show up after at most historyCacheTTL.
+const historyCacheTTL = time.Second
+
+// historyRecord is an element of a channel history list: a published
+// StreamMessage and the ID of its worker stream entry, used as cursor
+type historyRecord struct {
+	ID      string          `json:"id"`
+	Message json.RawMessage `json:"message"`
+}
+
+// historyIDGen generates increasing history IDs in the ms-seq form of
+// stream entry IDs, the way Redis numbers the entries of one stream
+type historyIDGen struct {
+	mu     sync.Mutex
+	lastMS int64
+	seq    uint64
+}
+
+// next returns the history ID of a message published at t
+func (h *historyIDGen) next(t time.Time) string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if ms := t.UnixMilli(); ms > h.lastMS {
+		h.lastMS, h.seq = ms, 0
+	} else {
+		h.seq++
+	}
+	return strconv.FormatInt(h.lastMS, 10) + "-" + strconv.FormatUint(h.seq, 10)
+}
+
+// historyPush returns the push of the message payload to the history list
+// of channel, capped at HistoryRetain messages, and to the history list of
+// userID. userID is set only for messages published by an authenticated
+// client, so moderators never see messages attributed from request bodies.
+// The push is written in the transaction of the message's stream entry,
+// before its entry ID is known, so history entries are identified by a
+// historyID from g.historyIDs instead. Returns nil when the record does not
+// encode, which is logged.
+func (g *Gateway) historyPush(ctx context.Context, channel, userID, historyID string, payload []byte) *redis.ListPush {
+	record, err := json.Marshal(historyRecord{ID: historyID, Message: payload})
+	if err != nil {
+		slog.WarnContext(ctx, "failed to encode history record", "channel", channel, "error", err)
+		return nil
+	}
+	lists := []redis.CappedList{{Key: ChannelHistoryPrefix + channel, MaxLen: int64(g.config.HistoryRetain)}}
+	if userList, ok := g.userHistoryList(userID); ok && userID != "" {
+		lists = append(lists, userList)
+	}
+	return &redis.ListPush{Value: record, Lists: lists, TTL: g.config.HistoryTTL}
+}
+
+// loadHistory returns the messages in the history list of channel, newest
+// first, migrated to the configured schema version. Records that do not
+// decode are skipped.
+func (g *Gateway) loadHistory(ctx context.Context, channel string) ([]historyEntry, error) {
+	if entries, ok := g.historyCache.get(channel, time.Now()); ok {
+		g.metrics.HistoryCacheHits.Inc()
+		return entries, nil
+	}
+	g.metrics.HistoryCacheMisses.Inc()
+
+	records, err := g.redis.LRange(ctx, ChannelHistoryPrefix+channel, 0, -1)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]historyEntry, 0, len(records))
+	for _, raw := range records {
+		var record historyRecord
+		var msg StreamMessage
+		if json.Unmarshal([]byte(raw), &record) != nil || json.Unmarshal(record.Message, &msg) != nil {
+			continue
+		}
+		if err := MigrateStreamMessage(&msg, g.config.StreamSchemaVersion); err != nil {
+			return nil, err
+		}
+		entries = append(entries, historyEntry{id: record.ID, msg: msg})
+	}
+
+	g.historyCache.add(channel, entries, time.Now())
+	return entries, nil
+}
+
+// historyCacheItem is a cached history list
+type historyCacheItem struct {
+	channel   string
+	entries   []historyEntry
+	expiresAt time.Time
+}
+
+// historyCache is a least recently used cache of decoded history lists of
+// up to size channels (HISTORY_CACHE_SIZE), so hot channels are not read
+// and decoded from Redis by every joiner replaying missed messages. A size
+// of 0 caches nothing.
+type historyCache struct {
+	mu    sync.Mutex
+	size  int
+	ttl   time.Duration
+	order *list.List               // front is most recently used
+	items map[string]*list.Element // Value is *historyCacheItem
+}
+
+// newHistoryCache creates a new historyCache
+func newHistoryCache(size int, ttl time.Duration) *historyCache {
+	return &historyCache{
+		size:  size,
+		ttl:   ttl,
+		order: list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+// get returns the cached entries of channel if they have not expired.
+// Callers must not modify the returned slice.
+func (c *historyCache) get(channel string, now time.Time) ([]historyEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[channel]
+	if !ok {
+		return nil, false
+	}
+	item := elem.Value.(*historyCacheItem)
+	if now.After(item.expiresAt) {
+		c.order.Remove(elem)
+		delete(c.items, channel)
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return item.entries, true
+}
+
+// add caches entries of channel, evicting the least recently used channel
+// when the cache is full
+func (c *historyCache) add(channel string, entries []historyEntry, now time.Time) {
+	if c.size <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	item := &historyCacheItem{channel: channel, entries: entries, expiresAt: now.Add(c.ttl)}
+	if elem, ok := c.items[channel]; ok {
+		elem.Value = item
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.items[channel] = c.order.PushFront(item)
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*historyCacheItem).channel)
+	}
+}
+
+// remove drops the cached entries of channel
+func (c *historyCache) remove(channel string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.items[channel]; ok {
+		c.order.Remove(elem)
+		delete(c.items, channel)
+	}
+}
@@ -0,0 +1,124 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"realtime-message-gateway/internal/metrics"
+	"realtime-message-gateway/internal/redis"
+	"realtime-message-gateway/internal/routing"
+)
+
+// addTestHistory writes payload to the history of channel with the given
+// history ID, in the transaction of a worker-0 stream entry as a publish
+func addTestHistory(t *testing.T, gw *Gateway, channel, historyID string, payload []byte) {
+	t.Helper()
+
+	ctx := context.Background()
+	entry := redis.StreamEntry{
+		Stream: routing.GetWorkerStreamKey("worker-0", routing.PriorityNormal),
+		Values: streamEntry(payload, ""),
+		Push:   gw.historyPush(ctx, channel, "", historyID, payload),
+	}
+	if _, errs := gw.redis.XAddPipeline(ctx, []redis.StreamEntry{entry}); errs[0] != nil {
+		t.Fatalf("XAddPipeline() error = %v", errs[0])
+	}
+	gw.historyCache.remove(channel)
+}
+
+func TestHistoryIDGen(t *testing.T) {
+	var gen historyIDGen
+	start := time.UnixMilli(1000)
+
+	tests := []struct {
+		at   time.Time
+		want string
+	}{
+		{start, "1000-0"},
+		{start, "1000-1"},
+		{start.Add(time.Millisecond), "1001-0"},
+		// A clock going back keeps the IDs increasing
+		{start, "1001-1"},
+		{start.Add(time.Second), "2000-0"},
+	}
+	for _, tt := range tests {
+		if got := gen.next(tt.at); got != tt.want {
+			t.Errorf("next(%d) = %s, want %s", tt.at.UnixMilli(), got, tt.want)
+		}
+	}
+}
+
+func TestHistoryCache(t *testing.T) {
+	cache := newHistoryCache(2, time.Second)
+	now := time.Now()
+	entries := []historyEntry{{id: "1-0", msg: StreamMessage{ID: "m1"}}}
+
+	cache.add("a", entries, now)
+	cache.add("b", entries, now)
+	if _, ok := cache.get("a", now); !ok {
+		t.Fatal("get(a) missed, want hit")
+	}
+
+	// b is now the least recently used channel
+	cache.add("c", entries, now)
+	if _, ok := cache.get("b", now); ok {
+		t.Error("get(b) hit after eviction, want miss")
+	}
+	if got, ok := cache.get("a", now); !ok || len(got) != 1 || got[0].msg.ID != "m1" {
+		t.Errorf("get(a) = %v, %v, want cached entries", got, ok)
+	}
+
+	if _, ok := cache.get("c", now.Add(2*time.Second)); ok {
+		t.Error("get(c) hit after ttl, want miss")
+	}
+
+	cache.remove("a")
+	if _, ok := cache.get("a", now); ok {
+		t.Error("get(a) hit after remove, want miss")
+	}
+}
+
+func TestHistoryCacheDisabled(t *testing.T) {
+	cache := newHistoryCache(0, time.Second)
+	now := time.Now()
+
+	cache.add("a", []historyEntry{{id: "1-0"}}, now)
+	if _, ok := cache.get("a", now); ok {
+		t.Error("get(a) hit with size 0, want miss")
+	}
+}
+
+func TestLoadHistoryCacheMetrics(t *testing.T) {
+	gw := NewTestGateway(t)
+	ctx := context.Background()
+	hits := testutil.ToFloat64(metrics.Default.HistoryCacheHits)
+	misses := testutil.ToFloat64(metrics.Default.HistoryCacheMisses)
+
+	record := func(id string) {
+		payload, _ := json.Marshal(StreamMessage{SchemaVersion: 1, ID: id, Channel: "chat"})
+		addTestHistory(t, gw, "chat", id+"-0", payload)
+	}
+	load := func() {
+		t.Helper()
+		if _, err := gw.loadHistory(ctx, "chat"); err != nil {
+			t.Fatalf("loadHistory() error = %v", err)
+		}
+	}
+
+	record("1")
+	load() // miss
+	load() // hit
+	record("2")
+	load() // miss: publishing invalidates the channel
+
+	if got := testutil.ToFloat64(metrics.Default.HistoryCacheHits) - hits; got != 1 {
+		t.Errorf("history cache hits = %g, want 1", got)
+	}
+	if got := testutil.ToFloat64(metrics.Default.HistoryCacheMisses) - misses; got != 2 {
+		t.Errorf("history cache misses = %g, want 2", got)
+	}
+}
@@ -0,0 +1,41 @@
+package gateway
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/centrifugal/centrifuge"
+)
+
+// DisconnectIdle is issued to clients idle for MaxIdleConnectionTime. It is
+// in the reconnect range, so clients that need the connection come back.
+var DisconnectIdle = centrifuge.Disconnect{
+	Code:   uint32(ErrCodeIdle),
+	Reason: "idle connection",
+}
+
+// startIdleTimer disconnects the client of meta once it has neither
+// subscribed nor published for MaxIdleConnectionTime and has no
+// subscriptions. Subscribers that only read are not idle. It does nothing
+// when the limit is 0.
+func (g *Gateway) startIdleTimer(meta *connectionMeta) {
+	if g.config.MaxIdleConnectionTime <= 0 {
+		return
+	}
+	meta.idleTimer = time.AfterFunc(g.config.MaxIdleConnectionTime, func() {
+		if len(meta.client.Channels()) > 0 {
+			g.resetIdleTimer(meta)
+			return
+		}
+		g.metrics.IdleDisconnectTotal.Inc()
+		slog.Info("disconnecting idle client", "clientId", meta.clientID, "userId", meta.userID, "idleFor", g.config.MaxIdleConnectionTime)
+		meta.client.Disconnect(DisconnectIdle)
+	})
+}
+
+// resetIdleTimer restarts the idle timer of meta on client activity
+func (g *Gateway) resetIdleTimer(meta *connectionMeta) {
+	if meta.idleTimer != nil {
+		meta.idleTimer.Reset(g.config.MaxIdleConnectionTime)
+	}
+}
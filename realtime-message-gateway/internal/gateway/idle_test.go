@@ -0,0 +1,74 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/centrifugal/centrifuge"
+	"github.com/centrifugal/protocol"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"realtime-message-gateway/internal/metrics"
+)
+
+func TestIdleConnectionDisconnected(t *testing.T) {
+	const idle = 100 * time.Millisecond
+	gw := NewTestGateway(t)
+	gw.config.MaxIdleConnectionTime = idle
+	before := testutil.ToFloat64(metrics.Default.IdleDisconnectTotal)
+
+	connect := func() (*centrifuge.Client, *testTransport) {
+		transport := &testTransport{}
+		client, closeFn, err := centrifuge.NewClient(context.Background(), gw.node, transport)
+		if err != nil {
+			t.Fatalf("centrifuge.NewClient() error = %v", err)
+		}
+		t.Cleanup(func() { closeFn() })
+		client.HandleCommand(&protocol.Command{Id: 1, Connect: &protocol.ConnectRequest{}}, 0)
+		return client, transport
+	}
+	closed := func(transport *testTransport) bool {
+		transport.mu.Lock()
+		defer transport.mu.Unlock()
+		return transport.closed
+	}
+
+	_, idleTransport := connect()
+	active, activeTransport := connect()
+	start := time.Now()
+
+	// Each subscription restarts the idle timer of the active client
+	for i := range 5 {
+		time.Sleep(idle / 2)
+		subscribeTestClient(active, uint32(i+2), fmt.Sprintf("chat:idle-%d", i))
+	}
+
+	if !closed(idleTransport) {
+		t.Errorf("idle client still connected after %s", time.Since(start))
+	}
+	idleTransport.mu.Lock()
+	if code := idleTransport.disconnect.Code; code != uint32(ErrCodeIdle) {
+		t.Errorf("idle disconnect code = %d, want reconnectable %d", code, ErrCodeIdle)
+	}
+	idleTransport.mu.Unlock()
+	if closed(activeTransport) {
+		t.Error("active client disconnected while subscribing")
+	}
+	if got := testutil.ToFloat64(metrics.Default.IdleDisconnectTotal) - before; got != 1 {
+		t.Errorf("gateway_idle_disconnect_total increased by %v, want 1", got)
+	}
+
+	// Once it stops, the active client only reads its subscriptions and is
+	// not idle
+	time.Sleep(3 * idle)
+	if closed(activeTransport) {
+		t.Error("read-only subscriber disconnected as idle")
+	}
+	gw.connectionsMu.RLock()
+	defer gw.connectionsMu.RUnlock()
+	if n := len(gw.connections); n != 1 {
+		t.Errorf("%d connections tracked after idle disconnect, want 1", n)
+	}
+}
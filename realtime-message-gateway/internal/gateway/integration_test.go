@@ -0,0 +1,217 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/centrifugal/centrifuge"
+	"golang.org/x/net/websocket"
+
+	"realtime-message-gateway/internal/routing"
+)
+
+// wsTestClient speaks the Centrifuge JSON protocol over a real WebSocket
+// connection. centrifuge-go is not a dependency, so commands and replies
+// are plain JSON frames.
+type wsTestClient struct {
+	t    testing.TB
+	conn *websocket.Conn
+	// Client ID from the connect reply
+	clientID string
+}
+
+// wsReply is a reply or push frame of the Centrifuge JSON protocol
+type wsReply struct {
+	ID      uint32            `json:"id"`
+	Error   *centrifuge.Error `json:"error"`
+	Connect *struct {
+		Client string `json:"client"`
+	} `json:"connect"`
+	Push *struct {
+		Channel string `json:"channel"`
+		Pub     *struct {
+			Data json.RawMessage `json:"data"`
+		} `json:"pub"`
+	} `json:"push"`
+}
+
+// dialTestClient opens a WebSocket connection to server and connects as name
+func dialTestClient(t testing.TB, server *httptest.Server, name string) *wsTestClient {
+	t.Helper()
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/connection/websocket"
+	conn, err := websocket.Dial(url, "", server.URL)
+	if err != nil {
+		t.Fatalf("websocket.Dial() error = %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	c := &wsTestClient{t: t, conn: conn}
+	c.clientID = c.command(1, "connect", map[string]interface{}{"data": map[string]string{"name": name}}).Connect.Client
+	return c
+}
+
+// command sends a command with id and waits for its successful reply
+func (c *wsTestClient) command(id uint32, method string, params interface{}) wsReply {
+	c.t.Helper()
+
+	frame, err := json.Marshal(map[string]interface{}{"id": id, method: params})
+	if err != nil {
+		c.t.Fatalf("marshal %s command: %v", method, err)
+	}
+	if err := websocket.Message.Send(c.conn, string(frame)); err != nil {
+		c.t.Fatalf("send %s command: %v", method, err)
+	}
+	reply := c.await(func(r wsReply) bool { return r.ID == id })
+	if reply.Error != nil {
+		c.t.Fatalf("%s reply error = %v", method, reply.Error)
+	}
+	return reply
+}
+
+// await reads frames until one matches, failing the test after a second
+func (c *wsTestClient) await(match func(wsReply) bool) wsReply {
+	c.t.Helper()
+
+	c.conn.SetReadDeadline(time.Now().Add(time.Second))
+	for {
+		var frame []byte
+		if err := websocket.Message.Receive(c.conn, &frame); err != nil {
+			c.t.Fatalf("read frame: %v", err)
+		}
+		// Centrifuge batches replies into one frame, newline-delimited
+		for _, line := range bytes.Split(frame, []byte("\n")) {
+			var reply wsReply
+			if len(line) == 0 || json.Unmarshal(line, &reply) != nil {
+				continue
+			}
+			if match(reply) {
+				return reply
+			}
+		}
+	}
+}
+
+func TestPublishSubscribeIntegration(t *testing.T) {
+	gw := NewTestGateway(t)
+	server := httptest.NewServer(gw.WebsocketHandler(centrifuge.WebsocketConfig{}))
+	defer server.Close()
+
+	alice := dialTestClient(t, server, "Alice")
+	bob := dialTestClient(t, server, "Bob")
+	alice.command(2, "subscribe", map[string]string{"channel": "chat"})
+	bob.command(2, "subscribe", map[string]string{"channel": "chat"})
+
+	alice.command(3, "publish", map[string]interface{}{
+		"channel": "chat",
+		"data":    map[string]string{"text": "hello bob"},
+	})
+
+	push := bob.await(func(r wsReply) bool {
+		return r.Push != nil && r.Push.Channel == "chat" && r.Push.Pub != nil
+	})
+	var data struct {
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(push.Push.Pub.Data, &data); err != nil || data.Text != "hello bob" {
+		t.Errorf("publication data = %s, want text %q", push.Push.Pub.Data, "hello bob")
+	}
+
+	streamKey := routing.GetWorkerStreamKey("worker-0", routing.PriorityNormal)
+	entries, err := gw.redis.XRange(context.Background(), streamKey, "-", "+", 0)
+	if err != nil {
+		t.Fatalf("XRange() error = %v", err)
+	}
+	// The stream also holds both join events
+	var messages []StreamMessage
+	for _, entry := range entries {
+		var message StreamMessage
+		if err := json.Unmarshal([]byte(entry.Payload), &message); err != nil {
+			t.Fatalf("unmarshal stream message: %v", err)
+		}
+		if message.Type == EventTypeMessage {
+			messages = append(messages, message)
+		}
+	}
+	if len(messages) != 1 {
+		t.Fatalf("stream %s has %d messages, want 1", streamKey, len(messages))
+	}
+	if m := messages[0]; m.Channel != "chat" || m.Text != "hello bob" || m.UserName != "Alice" {
+		t.Errorf("stream message = %+v, want Alice's message to chat", m)
+	}
+}
+
+// BenchmarkWriteFlushInterval measures the throughput and publish-to-read
+// latency of one subscriber with WS_WRITE_FLUSH_INTERVAL off and on. Up to
+// 64 messages are in flight so the writer has messages to collect.
+func BenchmarkWriteFlushInterval(b *testing.B) {
+	// Connection and presence logs would dominate the output
+	logger := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	b.Cleanup(func() { slog.SetDefault(logger) })
+
+	for _, interval := range []time.Duration{0, time.Millisecond, 5 * time.Millisecond} {
+		for _, size := range []int{50, 500, 5000} {
+			b.Run(fmt.Sprintf("interval=%s/size=%d", interval, size), func(b *testing.B) {
+				benchmarkWriteFlushInterval(b, interval, size)
+			})
+		}
+	}
+}
+
+func benchmarkWriteFlushInterval(b *testing.B, interval time.Duration, size int) {
+	gw := NewTestGateway(b)
+	gw.config.WriteFlushInterval = interval
+	server := httptest.NewServer(gw.WebsocketHandler(centrifuge.WebsocketConfig{}))
+	defer server.Close()
+
+	client := dialTestClient(b, server, "Bench")
+	client.command(2, "subscribe", map[string]string{"channel": "chat:bench"})
+	client.conn.SetReadDeadline(time.Time{})
+
+	data, _ := json.Marshal(strings.Repeat("x", size))
+	inFlight := make(chan time.Time, 64)
+	var latency time.Duration
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for received := 0; received < b.N; {
+			var frame []byte
+			if err := websocket.Message.Receive(client.conn, &frame); err != nil {
+				b.Errorf("read frame: %v", err)
+				return
+			}
+			for _, line := range bytes.Split(frame, []byte("\n")) {
+				var reply wsReply
+				if json.Unmarshal(line, &reply) != nil || reply.Push == nil || reply.Push.Pub == nil {
+					continue
+				}
+				latency += time.Since(<-inFlight)
+				received++
+			}
+		}
+	}()
+
+	b.SetBytes(int64(size))
+	b.ResetTimer()
+	for range b.N {
+		select {
+		case inFlight <- time.Now():
+		case <-done:
+			b.FailNow()
+		}
+		if _, err := gw.node.Publish("chat:bench", data); err != nil {
+			b.Fatalf("Publish() error = %v", err)
+		}
+	}
+	<-done
+	b.ReportMetric(float64(latency.Microseconds())/float64(b.N), "us/latency")
+}
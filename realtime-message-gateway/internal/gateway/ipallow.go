@@ -0,0 +1,42 @@
+package gateway
+
+import (
+	"fmt"
+	"net"
+)
+
+// parseCIDRs parses the ALLOWED_IP_CIDRS or TRUSTED_PROXY_CIDRS networks
+func parseCIDRs(cidrs []string) ([]*net.IPNet, error) {
+	if len(cidrs) == 0 {
+		return nil, nil
+	}
+	nets := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("ip cidr %q: %w", cidr, err)
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets, nil
+}
+
+// IPAllowed reports whether a client IP, as returned by ClientIP, is in
+// ALLOWED_IP_CIDRS. All IPs are allowed when no networks are configured;
+// otherwise IPs that do not parse are rejected. IPv4-mapped IPv6 addresses
+// match IPv4 networks.
+func (g *Gateway) IPAllowed(ip string) bool {
+	if len(g.allowedNets) == 0 {
+		return true
+	}
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, ipNet := range g.allowedNets {
+		if ipNet.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,55 @@
+package gateway
+
+import "testing"
+
+func TestIPAllowed(t *testing.T) {
+	nets, err := parseCIDRs([]string{"10.1.2.0/24", "192.168.0.7/32", "2001:db8:abcd::/48"})
+	if err != nil {
+		t.Fatalf("parseCIDRs() error = %v", err)
+	}
+	gw := &Gateway{allowedNets: nets}
+
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"10.1.2.0", true},
+		{"10.1.2.255", true},
+		{"10.1.1.255", false},
+		{"10.1.3.0", false},
+		{"192.168.0.7", true},
+		{"192.168.0.8", false},
+		{"::ffff:10.1.2.3", true},
+		{"2001:db8:abcd::1", true},
+		{"2001:db8:abcd:ffff:ffff:ffff:ffff:ffff", true},
+		{"2001:db8:abce::", false},
+		{"::1", false},
+		{"not-an-ip", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			if got := gw.IPAllowed(tt.ip); got != tt.want {
+				t.Errorf("IPAllowed(%q) = %v, want %v", tt.ip, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIPAllowedWithoutCIDRs(t *testing.T) {
+	gw := &Gateway{}
+	for _, ip := range []string{"10.1.2.3", "2001:db8::1", "not-an-ip"} {
+		if !gw.IPAllowed(ip) {
+			t.Errorf("IPAllowed(%q) = false without ALLOWED_IP_CIDRS, want true", ip)
+		}
+	}
+}
+
+func TestParseCIDRsInvalid(t *testing.T) {
+	for _, cidr := range []string{"10.1.2.3", "10.0.0.0/33", "2001:db8::/129", "example.com/24"} {
+		if _, err := parseCIDRs([]string{"10.0.0.0/8", cidr}); err == nil {
+			t.Errorf("parseCIDRs(%q) error = nil, want error", cidr)
+		}
+	}
+}
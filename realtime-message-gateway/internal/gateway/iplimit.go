@@ -0,0 +1,223 @@
+package gateway
+
+import (
+	"context"
+	"hash/fnv"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/centrifugal/centrifuge"
+)
+
+// DisconnectIPLimit is issued when an IP exceeds MaxConnectionsPerIP
+var DisconnectIPLimit = centrifuge.Disconnect{
+	Code:   uint32(ErrCodeIPLimit),
+	Reason: "connection limit per ip",
+}
+
+// ipLimiterShards is the number of independently locked shards
+const ipLimiterShards = 32
+
+// clientIPKey is the context key for the client IP
+type clientIPKey struct{}
+
+// WithClientIP stores the client IP, as returned by ClientIP, in the
+// request context so connection handlers can read it
+func (g *Gateway) WithClientIP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), clientIPKey{}, g.ClientIP(r))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// ClientIP returns the client IP of a request. Forwarding headers are only
+// read when the connection comes from a TRUSTED_PROXY_CIDRS network: the
+// client IP is then the rightmost X-Forwarded-For address that is not a
+// trusted proxy, or X-Real-IP without X-Forwarded-For. Otherwise, and when
+// every forwarded address is a trusted proxy, the connection's remote
+// address is used.
+func (g *Gateway) ClientIP(r *http.Request) string {
+	remote, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		remote = r.RemoteAddr
+	}
+	if !g.trustedProxy(remote) {
+		return remote
+	}
+	if xff := r.Header.Values("X-Forwarded-For"); len(xff) > 0 {
+		// Proxies append to the list, so only the entries right of the last
+		// untrusted address were added by trusted proxies
+		hops := strings.Split(strings.Join(xff, ","), ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			if ip := strings.TrimSpace(hops[i]); ip != "" && !g.trustedProxy(ip) {
+				return ip
+			}
+		}
+		return remote
+	}
+	if ip := strings.TrimSpace(r.Header.Get("X-Real-IP")); ip != "" {
+		return ip
+	}
+	return remote
+}
+
+// trustedProxy reports whether ip is in TRUSTED_PROXY_CIDRS
+func (g *Gateway) trustedProxy(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, ipNet := range g.trustedProxies {
+		if ipNet.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIPFromContext returns the client IP stored by WithClientIP
+func clientIPFromContext(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIPKey{}).(string)
+	return ip
+}
+
+// ipCIDR returns the /24 network of an IPv4 address (/48 for IPv6),
+// used as a low-cardinality metric label
+func ipCIDR(ip string) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return "unknown"
+	}
+	if v4 := parsed.To4(); v4 != nil {
+		return (&net.IPNet{IP: v4.Mask(net.CIDRMask(24, 32)), Mask: net.CIDRMask(24, 32)}).String()
+	}
+	return (&net.IPNet{IP: parsed.Mask(net.CIDRMask(48, 128)), Mask: net.CIDRMask(48, 128)}).String()
+}
+
+// ipEntry tracks live connections from a single IP
+type ipEntry struct {
+	count    int64 // atomic
+	lastSeen int64 // atomic, unix nanos
+}
+
+// ipShard is a locked subset of the IP map
+type ipShard struct {
+	mu      sync.RWMutex
+	entries map[string]*ipEntry
+}
+
+// ipLimiter counts live connections per IP in a sharded map. Entries with
+// no connections are evicted once idle for longer than ttl.
+type ipLimiter struct {
+	limit  int64
+	ttl    time.Duration
+	shards [ipLimiterShards]*ipShard
+}
+
+// newIPLimiter creates a new ipLimiter
+func newIPLimiter(limit int, ttl time.Duration) *ipLimiter {
+	l := &ipLimiter{
+		limit: int64(limit),
+		ttl:   ttl,
+	}
+	for i := range l.shards {
+		l.shards[i] = &ipShard{entries: make(map[string]*ipEntry)}
+	}
+	return l
+}
+
+// shard returns the shard owning ip
+func (l *ipLimiter) shard(ip string) *ipShard {
+	h := fnv.New32a()
+	h.Write([]byte(ip))
+	return l.shards[h.Sum32()%ipLimiterShards]
+}
+
+// entry returns the entry for ip, creating it if needed
+func (l *ipLimiter) entry(ip string) *ipEntry {
+	s := l.shard(ip)
+
+	s.mu.RLock()
+	e, ok := s.entries[ip]
+	s.mu.RUnlock()
+	if ok {
+		return e
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok = s.entries[ip]; !ok {
+		e = &ipEntry{}
+		s.entries[ip] = e
+	}
+	return e
+}
+
+// acquire reserves a connection slot for ip, returning false when the limit is reached
+func (l *ipLimiter) acquire(ip string) bool {
+	if l.limit <= 0 || ip == "" {
+		return true
+	}
+
+	e := l.entry(ip)
+	atomic.StoreInt64(&e.lastSeen, time.Now().UnixNano())
+	if atomic.AddInt64(&e.count, 1) > l.limit {
+		atomic.AddInt64(&e.count, -1)
+		return false
+	}
+	return true
+}
+
+// release frees a connection slot previously acquired for ip
+func (l *ipLimiter) release(ip string) {
+	if l.limit <= 0 || ip == "" {
+		return
+	}
+
+	s := l.shard(ip)
+	s.mu.RLock()
+	e, ok := s.entries[ip]
+	s.mu.RUnlock()
+	if !ok {
+		return
+	}
+	atomic.StoreInt64(&e.lastSeen, time.Now().UnixNano())
+	if atomic.AddInt64(&e.count, -1) < 0 {
+		atomic.StoreInt64(&e.count, 0)
+	}
+}
+
+// evict removes entries with no connections that have been idle longer than ttl
+func (l *ipLimiter) evict(now time.Time) {
+	cutoff := now.Add(-l.ttl).UnixNano()
+	for _, s := range l.shards {
+		s.mu.Lock()
+		for ip, e := range s.entries {
+			if atomic.LoadInt64(&e.count) <= 0 && atomic.LoadInt64(&e.lastSeen) < cutoff {
+				delete(s.entries, ip)
+			}
+		}
+		s.mu.Unlock()
+	}
+}
+
+// cleanupIPLimiter periodically evicts idle IP entries
+func (g *Gateway) cleanupIPLimiter(ctx context.Context) {
+	defer g.wg.Done()
+
+	ticker := time.NewTicker(g.ipLimiter.ttl)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			g.ipLimiter.evict(now)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package gateway
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestClientIP(t *testing.T) {
+	trusted, err := parseCIDRs([]string{"10.0.0.0/8"})
+	if err != nil {
+		t.Fatalf("parseCIDRs() error = %v", err)
+	}
+	gw := &Gateway{trustedProxies: trusted}
+
+	tests := []struct {
+		name       string
+		xff        string
+		realIP     string
+		remoteAddr string
+		want       string
+	}{
+		{"forwarded single", "203.0.113.7", "", "10.0.0.1:1234", "203.0.113.7"},
+		{"forwarded through proxies", "203.0.113.7, 10.0.0.2, 10.0.0.3", "", "10.0.0.1:1234", "203.0.113.7"},
+		{"forwarded with spaces", "  203.0.113.7  ,10.0.0.2", "", "10.0.0.1:1234", "203.0.113.7"},
+		{"spoofed leftmost entry", "192.0.2.1, 203.0.113.7, 10.0.0.2", "", "10.0.0.1:1234", "203.0.113.7"},
+		{"only trusted proxies", "10.0.0.3, 10.0.0.2", "", "10.0.0.1:1234", "10.0.0.1"},
+		{"real ip", "", "198.51.100.4", "10.0.0.1:1234", "198.51.100.4"},
+		{"forwarded wins over real ip", "203.0.113.7", "198.51.100.4", "10.0.0.1:1234", "203.0.113.7"},
+		{"untrusted remote ignores forwarded", "203.0.113.7", "", "192.0.2.9:5678", "192.0.2.9"},
+		{"untrusted remote ignores real ip", "", "198.51.100.4", "192.0.2.9:5678", "192.0.2.9"},
+		{"remote addr", "", "", "192.0.2.9:5678", "192.0.2.9"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/connection/websocket", nil)
+			r.RemoteAddr = tt.remoteAddr
+			if tt.xff != "" {
+				r.Header.Set("X-Forwarded-For", tt.xff)
+			}
+			if tt.realIP != "" {
+				r.Header.Set("X-Real-IP", tt.realIP)
+			}
+			if got := gw.ClientIP(r); got != tt.want {
+				t.Errorf("ClientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientIPWithoutTrustedProxies(t *testing.T) {
+	gw := &Gateway{}
+	r := httptest.NewRequest("GET", "/connection/websocket", nil)
+	r.RemoteAddr = "10.0.0.1:1234"
+	r.Header.Set("X-Forwarded-For", "203.0.113.7")
+	r.Header.Set("X-Real-IP", "198.51.100.4")
+	if got := gw.ClientIP(r); got != "10.0.0.1" {
+		t.Errorf("ClientIP() = %q without TRUSTED_PROXY_CIDRS, want the remote address", got)
+	}
+}
+
+func TestIPCIDR(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want string
+	}{
+		{"203.0.113.7", "203.0.113.0/24"},
+		{"2001:db8:1234:5678::1", "2001:db8:1234::/48"},
+		{"not-an-ip", "unknown"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			if got := ipCIDR(tt.ip); got != tt.want {
+				t.Errorf("ipCIDR(%q) = %q, want %q", tt.ip, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIPLimiter(t *testing.T) {
+	l := newIPLimiter(2, time.Minute)
+
+	if !l.acquire("203.0.113.7") || !l.acquire("203.0.113.7") {
+		t.Fatal("acquire() under limit = false, want true")
+	}
+	if l.acquire("203.0.113.7") {
+		t.Error("acquire() over limit = true, want false")
+	}
+	if !l.acquire("198.51.100.4") {
+		t.Error("acquire() for other IP = false, want true")
+	}
+
+	l.release("203.0.113.7")
+	if !l.acquire("203.0.113.7") {
+		t.Error("acquire() after release = false, want true")
+	}
+}
+
+func TestIPLimiterUnlimited(t *testing.T) {
+	l := newIPLimiter(0, time.Minute)
+	for i := 0; i < 10; i++ {
+		if !l.acquire("203.0.113.7") {
+			t.Fatal("acquire() with no limit = false, want true")
+		}
+	}
+}
+
+func TestIPLimiterEvict(t *testing.T) {
+	l := newIPLimiter(1, time.Minute)
+	l.acquire("203.0.113.7")
+	l.acquire("198.51.100.4")
+	l.release("198.51.100.4")
+
+	l.evict(time.Now().Add(2 * time.Minute))
+
+	if _, ok := l.shard("203.0.113.7").entries["203.0.113.7"]; !ok {
+		t.Error("evict() removed IP with live connections")
+	}
+	if _, ok := l.shard("198.51.100.4").entries["198.51.100.4"]; ok {
+		t.Error("evict() kept idle IP with no connections")
+	}
+}
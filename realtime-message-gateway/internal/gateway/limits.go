@@ -0,0 +1,115 @@
+package gateway
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrorChannelFull is returned when a channel reached MaxSubscribersPerChannel
+var ErrorChannelFull = NewClientError(ErrCodeChannelFull, "channel full")
+
+// ErrorTooManySubscriptions is returned when a client reached
+// MaxSubscriptionsPerClient
+var ErrorTooManySubscriptions = NewClientError(ErrCodeTooManySubscriptions, "too many subscriptions")
+
+// subscriberCountEntry holds a cached subscriber count; expiresAt never
+// changes after the entry is stored
+type subscriberCountEntry struct {
+	count     atomic.Int32
+	expiresAt time.Time
+}
+
+// subscriberCountCache caches per-channel subscriber counts for
+// PresenceCacheTTL so bursts of subscribes don't each enumerate the
+// channel's presence. Lookups of a busy channel only load from a sync.Map
+// and do not contend on a lock.
+type subscriberCountCache struct {
+	ttl     time.Duration
+	entries sync.Map // map[string]*subscriberCountEntry
+}
+
+// newSubscriberCountCache creates a new subscriberCountCache
+func newSubscriberCountCache(ttl time.Duration) *subscriberCountCache {
+	return &subscriberCountCache{ttl: ttl}
+}
+
+// get returns the cached count for channel if it has not expired
+func (c *subscriberCountCache) get(channel string, now time.Time) (int, bool) {
+	v, ok := c.entries.Load(channel)
+	if !ok {
+		return 0, false
+	}
+	entry := v.(*subscriberCountEntry)
+	if now.After(entry.expiresAt) {
+		c.entries.CompareAndDelete(channel, entry)
+		return 0, false
+	}
+	return int(entry.count.Load()), true
+}
+
+// set stores a fresh count for channel
+func (c *subscriberCountCache) set(channel string, count int, now time.Time) {
+	entry := &subscriberCountEntry{expiresAt: now.Add(c.ttl)}
+	entry.count.Store(int32(count))
+	c.entries.Store(channel, entry)
+}
+
+// incr bumps a cached count after admitting a subscriber so that a burst
+// within the TTL window cannot overshoot the limit
+func (c *subscriberCountCache) incr(channel string) {
+	if v, ok := c.entries.Load(channel); ok {
+		v.(*subscriberCountEntry).count.Add(1)
+	}
+}
+
+// invalidate drops the cached count of channel after a subscriber left, so
+// the freed slot is available before the entry expires
+func (c *subscriberCountCache) invalidate(channel string) {
+	c.entries.Delete(channel)
+}
+
+// isChannelFull checks if channel reached limit subscribers; limit <= 0
+// means unlimited
+func (g *Gateway) isChannelFull(channel string, limit int) (bool, error) {
+	if limit <= 0 {
+		return false, nil
+	}
+
+	now := time.Now()
+	count, ok := g.subscriberCounts.get(channel, now)
+	if !ok {
+		result, err := g.node.Presence(channel)
+		if err != nil {
+			return false, err
+		}
+		count = len(result.Presence)
+		g.subscriberCounts.set(channel, count, now)
+	}
+
+	return count >= limit, nil
+}
+
+// acquireSubscription counts a new subscription of clientID, returning false
+// without counting it if the client reached MaxSubscriptionsPerClient
+func (g *Gateway) acquireSubscription(clientID string) bool {
+	limit := g.config.MaxSubscriptionsPerClient
+	if limit <= 0 {
+		return true
+	}
+
+	v, _ := g.subscriptionCount.LoadOrStore(clientID, new(atomic.Int32))
+	count := v.(*atomic.Int32)
+	if count.Add(1) > int32(limit) {
+		count.Add(-1)
+		return false
+	}
+	return true
+}
+
+// releaseSubscription uncounts a subscription of clientID
+func (g *Gateway) releaseSubscription(clientID string) {
+	if v, ok := g.subscriptionCount.Load(clientID); ok {
+		v.(*atomic.Int32).Add(-1)
+	}
+}
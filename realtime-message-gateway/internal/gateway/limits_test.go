@@ -0,0 +1,128 @@
+package gateway
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/centrifugal/centrifuge"
+	"github.com/centrifugal/protocol"
+
+	"realtime-message-gateway/internal/config"
+)
+
+func TestSubscriberCountCache(t *testing.T) {
+	cache := newSubscriberCountCache(250 * time.Millisecond)
+	now := time.Now()
+
+	if _, ok := cache.get("chat", now); ok {
+		t.Fatal("get() on empty cache returned a count")
+	}
+
+	cache.set("chat", 5, now)
+	if count, ok := cache.get("chat", now.Add(100*time.Millisecond)); !ok || count != 5 {
+		t.Errorf("get() = %d, %v, want 5, true", count, ok)
+	}
+
+	cache.incr("chat")
+	if count, _ := cache.get("chat", now); count != 6 {
+		t.Errorf("get() after incr = %d, want 6", count)
+	}
+
+	if _, ok := cache.get("chat", now.Add(time.Second)); ok {
+		t.Error("get() after TTL returned a count")
+	}
+
+	cache.incr("chat:room-abc")
+	if _, ok := cache.get("chat:room-abc", now); ok {
+		t.Error("incr() on missing entry created a count")
+	}
+
+	cache.set("chat", 5, now)
+	cache.invalidate("chat")
+	if _, ok := cache.get("chat", now); ok {
+		t.Error("get() after invalidate() returned a count")
+	}
+}
+
+func TestIsChannelFullUnlimited(t *testing.T) {
+	gw := &Gateway{config: &config.Config{MaxSubscribersPerChannel: 0}}
+
+	full, err := gw.isChannelFull("chat", gw.config.MaxSubscribersPerChannel)
+	if err != nil || full {
+		t.Errorf("isChannelFull() = %v, %v, want false, nil", full, err)
+	}
+}
+
+func TestMaxSubscriptionsPerClient(t *testing.T) {
+	gw := NewTestGateway(t)
+	gw.config.MaxSubscriptionsPerClient = 2
+	client := connectTestClient(t, gw)
+
+	subscribeTestClient(client, 2, "chat:a")
+	subscribeTestClient(client, 3, "chat:b")
+	subscribeTestClient(client, 4, "chat:c")
+
+	if got := len(client.Channels()); got != 2 {
+		t.Fatalf("subscribed to %d channels, want 2", got)
+	}
+	if client.IsSubscribed("chat:c") {
+		t.Error("subscription beyond the limit was accepted")
+	}
+
+	client.HandleCommand(&protocol.Command{
+		Id:          5,
+		Unsubscribe: &protocol.UnsubscribeRequest{Channel: "chat:a"},
+	}, 0)
+
+	subscribeTestClient(client, 6, "chat:c")
+	if !client.IsSubscribed("chat:c") {
+		t.Error("subscription after unsubscribe was rejected")
+	}
+}
+
+// BenchmarkIsChannelFullConcurrent checks the subscriber limit of one
+// channel with 1000 subscribers from 1000 goroutines per iteration, with
+// and without the subscriber count cache
+func BenchmarkIsChannelFullConcurrent(b *testing.B) {
+	const subscribers = 1000
+
+	node, err := centrifuge.New(centrifuge.Config{LogLevel: centrifuge.LogLevelNone})
+	if err != nil {
+		b.Fatalf("centrifuge.New() error = %v", err)
+	}
+	presence, err := centrifuge.NewMemoryPresenceManager(node, centrifuge.MemoryPresenceManagerConfig{})
+	if err != nil {
+		b.Fatalf("NewMemoryPresenceManager() error = %v", err)
+	}
+	node.SetPresenceManager(presence)
+	for i := 0; i < subscribers; i++ {
+		clientID := fmt.Sprintf("client-%d", i)
+		if err := presence.AddPresence("chat", clientID, &centrifuge.ClientInfo{ClientID: clientID}); err != nil {
+			b.Fatalf("AddPresence() error = %v", err)
+		}
+	}
+
+	for _, ttl := range []time.Duration{0, 500 * time.Millisecond} {
+		b.Run(fmt.Sprintf("ttl=%s", ttl), func(b *testing.B) {
+			gw := &Gateway{node: node, subscriberCounts: newSubscriberCountCache(ttl)}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				var wg sync.WaitGroup
+				for g := 0; g < subscribers; g++ {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						if _, err := gw.isChannelFull("chat", 2*subscribers); err != nil {
+							b.Errorf("isChannelFull() error = %v", err)
+						}
+					}()
+				}
+				wg.Wait()
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package gateway
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/centrifugal/centrifuge"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+const (
+	// loadShedResumeRatio is the fraction of MaxConnections below which a
+	// shedding gateway accepts connections again
+	loadShedResumeRatio = 0.8
+
+	// loadShedCheckInterval is how often the LoadShedder reads the gauge
+	loadShedCheckInterval = time.Second
+)
+
+// DisconnectOverloaded is issued to new connections while load shedding.
+// It is in the reconnect range so clients retry, possibly via another gateway.
+var DisconnectOverloaded = centrifuge.Disconnect{
+	Code:   uint32(ErrCodeOverloaded),
+	Reason: "gateway overloaded",
+}
+
+// LoadShedder rejects new connections when the connection count exceeds
+// MaxConnections * LoadShedThreshold, and accepts them again once it drops
+// below loadShedResumeRatio of MaxConnections
+type LoadShedder struct {
+	shedding    atomic.Bool
+	shedAt      float64
+	resumeAt    float64
+	connections func() float64
+}
+
+// NewLoadShedder creates a LoadShedder reading the current connection count
+// from connections
+func NewLoadShedder(maxConnections int, threshold float64, connections func() float64) *LoadShedder {
+	return &LoadShedder{
+		shedAt:      float64(maxConnections) * threshold,
+		resumeAt:    float64(maxConnections) * loadShedResumeRatio,
+		connections: connections,
+	}
+}
+
+// Shedding reports whether new connections should be rejected
+func (s *LoadShedder) Shedding() bool {
+	return s.shedding.Load()
+}
+
+// Run updates the shedding state every interval until ctx is cancelled
+func (s *LoadShedder) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.update()
+		}
+	}
+}
+
+// update toggles shedding with hysteresis between resumeAt and shedAt
+func (s *LoadShedder) update() {
+	count := s.connections()
+	switch {
+	case count > s.shedAt && !s.shedding.Load():
+		s.shedding.Store(true)
+		slog.Warn("load shedding started", "connections", count, "threshold", s.shedAt)
+	case count < s.resumeAt && s.shedding.Load():
+		s.shedding.Store(false)
+		slog.Info("load shedding stopped", "connections", count, "resumeAt", s.resumeAt)
+	}
+}
+
+// gaugeValue returns the current value of a Prometheus gauge
+func gaugeValue(g prometheus.Gauge) float64 {
+	var m dto.Metric
+	if err := g.Write(&m); err != nil {
+		return 0
+	}
+	return m.GetGauge().GetValue()
+}
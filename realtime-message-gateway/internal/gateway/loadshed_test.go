@@ -0,0 +1,42 @@
+package gateway
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestLoadShedderHysteresis(t *testing.T) {
+	var count float64
+	s := NewLoadShedder(100, 0.9, func() float64 { return count })
+
+	steps := []struct {
+		connections float64
+		want        bool
+	}{
+		{50, false},
+		{90, false}, // at threshold, not above it
+		{91, true},
+		{85, true}, // between resume and shed levels: keep shedding
+		{80, true},
+		{79, false},
+		{85, false}, // below shed level again: keep accepting
+	}
+
+	for _, step := range steps {
+		count = step.connections
+		s.update()
+		if got := s.Shedding(); got != step.want {
+			t.Errorf("connections=%v: Shedding() = %v, want %v", step.connections, got, step.want)
+		}
+	}
+}
+
+func TestGaugeValue(t *testing.T) {
+	g := prometheus.NewGauge(prometheus.GaugeOpts{Name: "test_gauge"})
+	g.Set(42)
+
+	if got := gaugeValue(g); got != 42 {
+		t.Errorf("gaugeValue() = %v, want 42", got)
+	}
+}
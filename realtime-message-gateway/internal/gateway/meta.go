@@ -0,0 +1,55 @@
+package gateway
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidMeta is wrapped by all errors about the meta field of a publish
+var ErrInvalidMeta = errors.New("invalid meta")
+
+// metaFromData returns the meta object of publish data, such as a reply-to
+// or thread ID. Keys must be ASCII alphanumeric and values strings of at
+// most MaxMetaValueLen bytes; at most MaxMetaKeys keys are accepted.
+func (g *Gateway) metaFromData(data map[string]interface{}) (map[string]string, error) {
+	v, present := data["meta"]
+	if !present {
+		return nil, nil
+	}
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: must be an object", ErrInvalidMeta)
+	}
+	if len(obj) > g.config.MaxMetaKeys {
+		return nil, fmt.Errorf("%w: more than %d keys", ErrInvalidMeta, g.config.MaxMetaKeys)
+	}
+
+	meta := make(map[string]string, len(obj))
+	for key, value := range obj {
+		if !isAlphanumeric(key) {
+			return nil, fmt.Errorf("%w: key %q is not alphanumeric", ErrInvalidMeta, key)
+		}
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("%w: value of %q is not a string", ErrInvalidMeta, key)
+		}
+		if len(s) > g.config.MaxMetaValueLen {
+			return nil, fmt.Errorf("%w: value of %q exceeds %d bytes", ErrInvalidMeta, key, g.config.MaxMetaValueLen)
+		}
+		meta[key] = s
+	}
+	return meta, nil
+}
+
+// isAlphanumeric reports whether s is a non-empty string of ASCII letters and digits
+func isAlphanumeric(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range []byte(s) {
+		if (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && (c < '0' || c > '9') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,80 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"maps"
+	"strings"
+	"testing"
+
+	"realtime-message-gateway/internal/config"
+	"realtime-message-gateway/internal/routing"
+)
+
+func TestMetaFromData(t *testing.T) {
+	gw := &Gateway{config: &config.Config{MaxMetaKeys: 3, MaxMetaValueLen: 8}}
+
+	tests := []struct {
+		name    string
+		data    string
+		want    map[string]string
+		wantErr bool
+	}{
+		{"no meta field", `{"text":"hi"}`, nil, false},
+		{"meta", `{"meta":{"replyTo":"m1","threadId":"t1"}}`, map[string]string{"replyTo": "m1", "threadId": "t1"}, false},
+		{"empty object", `{"meta":{}}`, map[string]string{}, false},
+		{"value at limit", `{"meta":{"k":"12345678"}}`, map[string]string{"k": "12345678"}, false},
+		{"not an object", `{"meta":"m1"}`, nil, true},
+		{"too many keys", `{"meta":{"a":"1","b":"2","c":"3","d":"4"}}`, nil, true},
+		{"key not alphanumeric", `{"meta":{"reply-to":"m1"}}`, nil, true},
+		{"empty key", `{"meta":{"":"m1"}}`, nil, true},
+		{"value not a string", `{"meta":{"ts":1700000000}}`, nil, true},
+		{"value too long", `{"meta":{"k":"123456789"}}`, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var data map[string]interface{}
+			if err := json.Unmarshal([]byte(tt.data), &data); err != nil {
+				t.Fatalf("json.Unmarshal() error = %v", err)
+			}
+			got, err := gw.metaFromData(data)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidMeta) {
+					t.Errorf("metaFromData() error = %v, want %v", err, ErrInvalidMeta)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("metaFromData() error = %v", err)
+			}
+			if !maps.Equal(got, tt.want) || (got == nil) != (tt.want == nil) {
+				t.Errorf("metaFromData() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPublishMeta(t *testing.T) {
+	gw := NewTestGateway(t)
+	client := connectTestClient(t, gw)
+
+	if err := publishAndWait(gw, client, "chat", `{"text":"hi","meta":{"bad-key":"x"}}`); err == nil {
+		t.Error("publish with invalid meta succeeded")
+	}
+	if err := publishAndWait(gw, client, "chat", `{"text":"hi","meta":{"replyTo":"m1"}}`); err != nil {
+		t.Fatalf("publish error = %v", err)
+	}
+
+	entries, err := gw.redis.XRange(context.Background(), routing.GetWorkerStreamKey("worker-0", routing.PriorityNormal), "-", "+", 0)
+	if err != nil {
+		t.Fatalf("XRange() error = %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("stream has %d entries, want 1", len(entries))
+	}
+	if payload := entries[0].Payload; !strings.Contains(payload, `"meta":{"replyTo":"m1"}`) {
+		t.Errorf("payload = %s, want meta", payload)
+	}
+}
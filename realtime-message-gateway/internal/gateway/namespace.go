@@ -0,0 +1,24 @@
+package gateway
+
+import (
+	"strings"
+
+	"realtime-message-gateway/internal/config"
+)
+
+// channelNamespace returns the namespace of channel, the part of its name
+// before the first colon, and the namespace's CHANNEL_NAMESPACES entry.
+// Channels without a colon have no namespace; known is false for them and
+// for namespaces without an entry.
+func (g *Gateway) channelNamespace(channel string) (name string, ns config.NamespaceConfig, known bool) {
+	name, _, found := strings.Cut(channel, ":")
+	if !found {
+		return "", config.NamespaceConfig{}, false
+	}
+	for _, ns := range g.config.Namespaces {
+		if ns.Name == name {
+			return name, ns, true
+		}
+	}
+	return name, config.NamespaceConfig{}, false
+}
@@ -0,0 +1,161 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/centrifugal/centrifuge"
+	"github.com/centrifugal/protocol"
+
+	"realtime-message-gateway/internal/config"
+)
+
+func TestChannelNamespace(t *testing.T) {
+	gw := &Gateway{config: &config.Config{Namespaces: []config.NamespaceConfig{{Name: "chat", MaxSubscribers: 5}}}}
+
+	tests := []struct {
+		channel   string
+		wantName  string
+		wantKnown bool
+	}{
+		{"chat:room-1", "chat", true},
+		{"chat:team:general", "chat", true},
+		{"chat", "", false},
+		{"news:today", "news", false},
+		{":x", "", false},
+	}
+	for _, tt := range tests {
+		name, ns, known := gw.channelNamespace(tt.channel)
+		if name != tt.wantName || known != tt.wantKnown {
+			t.Errorf("channelNamespace(%q) = %q, %v, want %q, %v", tt.channel, name, known, tt.wantName, tt.wantKnown)
+		}
+		if known && ns.MaxSubscribers != 5 {
+			t.Errorf("channelNamespace(%q) config = %+v, want the chat namespace", tt.channel, ns)
+		}
+	}
+}
+
+func TestSubscribeNamespaces(t *testing.T) {
+	const secret = "secret"
+	gw := NewTestGateway(t, WithTokenSecret(secret))
+	gw.config.PrivateChannelPrefix = "private:"
+	off := false
+	gw.config.Namespaces = []config.NamespaceConfig{
+		{Name: "chat", MaxSubscribers: 2, PushJoinLeave: &off},
+		{Name: "user", Protected: true},
+		{Name: "private"},
+	}
+
+	connect := func() (*centrifuge.Client, *testTransport) {
+		transport := &testTransport{}
+		client, closeFn, err := centrifuge.NewClient(context.Background(), gw.node, transport)
+		if err != nil {
+			t.Fatalf("centrifuge.NewClient() error = %v", err)
+		}
+		t.Cleanup(func() { closeFn() })
+		client.HandleCommand(&protocol.Command{Id: 1, Connect: &protocol.ConnectRequest{}}, 0)
+		return client, transport
+	}
+	subscribeWithToken := func(client *centrifuge.Client, id uint32, channel, token string) {
+		client.HandleCommand(&protocol.Command{
+			Id:        id,
+			Subscribe: &protocol.SubscribeRequest{Channel: channel, Token: token},
+		}, 0)
+	}
+	subscribers := func(channel string) int {
+		return gw.node.Hub().NumSubscribers(channel)
+	}
+
+	// MaxSubscribers of the namespace replaces MAX_SUBSCRIBERS_PER_CHANNEL
+	alice, aliceTransport := connect()
+	bob, _ := connect()
+	carol, _ := connect()
+	subscribeTestClient(alice, 2, "chat:quiet")
+	subscribeTestClient(bob, 2, "chat:quiet")
+	subscribeTestClient(carol, 2, "chat:quiet")
+	if n := subscribers("chat:quiet"); n != 2 {
+		t.Errorf("chat:quiet subscribers = %d, want the namespace limit 2", n)
+	}
+
+	// Protected namespaces need a subscription token
+	own := "user:" + alice.UserID()
+	subscribeTestClient(alice, 3, own)
+	if n := subscribers(own); n != 0 {
+		t.Errorf("%s subscribers without token = %d, want 0", own, n)
+	}
+	subscribeWithToken(alice, 4, own, SignSubscriptionToken(secret, alice.ID(), own))
+	if n := subscribers(own); n != 1 {
+		t.Errorf("%s subscribers with token = %d, want 1", own, n)
+	}
+
+	// Joins are pushed unless the namespace turns PushJoinLeave off
+	subscribeWithToken(alice, 5, "private:loud", SignSubscriptionToken(secret, alice.ID(), "private:loud"))
+	subscribeWithToken(bob, 3, "private:loud", SignSubscriptionToken(secret, bob.ID(), "private:loud"))
+	pushed := func(push string) bool {
+		aliceTransport.mu.Lock()
+		defer aliceTransport.mu.Unlock()
+		for _, msg := range aliceTransport.messages {
+			if bytes.Contains(msg, []byte(push)) {
+				return true
+			}
+		}
+		return false
+	}
+	for deadline := time.Now().Add(time.Second); !pushed(`"channel":"private:loud","join"`); time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("join to private:loud not pushed")
+		}
+	}
+	if pushed(`"channel":"chat:quiet","join"`) {
+		t.Error("join to chat:quiet pushed, want none with PushJoinLeave off")
+	}
+
+	// Strict mode rejects namespaces missing from CHANNEL_NAMESPACES
+	gw.config.StrictNamespaceMode = true
+	subscribeWithToken(alice, 6, "private:other", SignSubscriptionToken(secret, alice.ID(), "private:other"))
+	if n := subscribers("private:other"); n != 1 {
+		t.Errorf("private:other subscribers = %d, want 1 in a listed namespace", n)
+	}
+	gw.config.Namespaces = gw.config.Namespaces[:2]
+	subscribeWithToken(alice, 7, "private:strict", SignSubscriptionToken(secret, alice.ID(), "private:strict"))
+	if n := subscribers("private:strict"); n != 0 {
+		t.Errorf("private:strict subscribers = %d, want 0 in an unlisted namespace", n)
+	}
+	subscribeTestClient(alice, 8, "chat")
+	if n := subscribers("chat"); n != 1 {
+		t.Errorf("chat subscribers = %d, want 1 for a channel without namespace", n)
+	}
+}
+
+func TestPublishNamespaces(t *testing.T) {
+	const secret = "secret"
+	gw := NewTestGateway(t, WithTokenSecret(secret))
+	gw.config.Namespaces = []config.NamespaceConfig{{Name: "user", Protected: true}}
+	client := connectTestClient(t, gw)
+	own := "user:" + client.UserID()
+
+	// Protected namespaces take publishes only from their subscribers
+	var replyErr *centrifuge.Error
+	if err := publishAndWait(gw, client, own, `{"text":"hi"}`); !errors.As(err, &replyErr) || replyErr.Code != uint32(ErrCodePermissionDenied) {
+		t.Errorf("publish to %s without subscription error = %v, want code %d", own, err, ErrCodePermissionDenied)
+	}
+	client.HandleCommand(&protocol.Command{
+		Id:        2,
+		Subscribe: &protocol.SubscribeRequest{Channel: own, Token: SignSubscriptionToken(secret, client.ID(), own)},
+	}, 0)
+	if err := publishAndWait(gw, client, own, `{"text":"hi"}`); err != nil {
+		t.Errorf("publish to %s after subscribing error = %v", own, err)
+	}
+
+	// Strict mode rejects namespaces missing from CHANNEL_NAMESPACES
+	if err := publishAndWait(gw, client, "chat:go", `{"text":"hi"}`); err != nil {
+		t.Errorf("publish to chat:go error = %v", err)
+	}
+	gw.config.StrictNamespaceMode = true
+	if err := publishAndWait(gw, client, "chat:go", `{"text":"hi"}`); !errors.As(err, &replyErr) || replyErr.Code != uint32(ErrCodeChannelNotFound) {
+		t.Errorf("publish to chat:go in strict mode error = %v, want code %d", err, ErrCodeChannelNotFound)
+	}
+}
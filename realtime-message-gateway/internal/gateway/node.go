@@ -3,33 +3,66 @@ package gateway
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"math"
+	"net"
+	"regexp"
+	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/centrifugal/centrifuge"
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 
 	"realtime-message-gateway/internal/config"
 	"realtime-message-gateway/internal/metrics"
+	"realtime-message-gateway/internal/middleware"
 	"realtime-message-gateway/internal/redis"
+	"realtime-message-gateway/internal/requestid"
 	"realtime-message-gateway/internal/routing"
+	"realtime-message-gateway/internal/tracing"
 )
 
 // connectionMeta stores metadata about a connection for metrics
 type connectionMeta struct {
 	connectTime time.Time
+	clientID    string
 	userID      string
+	client      *centrifuge.Client
+	queue       *clientQueue // nil when ClientQueueDepth is 0
+	metrics     *metrics.Metrics
+	idleTimer   *time.Timer  // nil when MaxIdleConnectionTime is 0
+	appPingTS   atomic.Int64 // ts of the app ping awaiting a pong, 0 for none
+
+	// Lifecycle state, changed only through Transition
+	stateMu sync.Mutex
+	state   ConnectionState
 }
 
 // Gateway wraps Centrifuge node with business logic
 type Gateway struct {
-	node   *centrifuge.Node
-	config *config.Config
-	redis  *redis.Client
-	router *routing.Router
+	node       *centrifuge.Node
+	config     *config.Config
+	redis      *redis.Client
+	router     *routing.Router
+	ownsRouter bool // router was created by NewGateway, not WithRouter
+	metrics    *metrics.Metrics
+	instanceID string
+
+	// Background goroutine lifecycle
+	ctx       context.Context
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+	startedAt time.Time
+	running   atomic.Bool // node is running, between Run and Shutdown
 
 	// Connection tracking for reconnection detection
 	connectionsMu   sync.RWMutex
@@ -37,6 +70,86 @@ type Gateway struct {
 	recentUsersMu   sync.RWMutex
 	recentUsers     map[string]time.Time // userID -> last disconnect time
 	reconnectWindow time.Duration        // Time window to consider as reconnect
+
+	// Serializes flushClientQueues between the flusher and the Redis
+	// health checker, which would otherwise send queued messages twice
+	queueFlushMu sync.Mutex
+
+	// Per-channel subscriber counts for MaxSubscribersPerChannel
+	subscriberCounts *subscriberCountCache
+
+	// Leave events deferred by SuppressJoinLeaveForReconnect. Timers
+	// publish under a read lock of pendingLeavesMu and not once
+	// pendingLeavesClosed is set on shutdown.
+	pendingLeaves       sync.Map // pendingLeaveKey -> *pendingLeave
+	pendingLeavesMu     sync.RWMutex
+	pendingLeavesClosed bool
+
+	// Last message text per user and channel for DuplicateTextWindow
+	lastTexts sync.Map // lastTextKey -> *lastText
+
+	// Messages published since the last BloomResetInterval; nil when
+	// duplicate message detection is disabled
+	dedupFilter *bloomFilter
+
+	// Counts publishes per user for PublishRateLimit; nil when unlimited
+	publishLimiter middleware.RateLimiter
+
+	// Decoded channel history lists of hot channels
+	historyCache *historyCache
+
+	// Per-client subscription counts for MaxSubscriptionsPerClient
+	subscriptionCount sync.Map // clientID -> *atomic.Int32
+
+	// Live connections per client IP for MaxConnectionsPerIP
+	ipLimiter *ipLimiter
+
+	// Rejects new connections near MaxConnections; nil when disabled
+	loadShedder *LoadShedder
+
+	// Tracks Redis reachability; publishes are queued locally while degraded
+	redisHealth *RedisHealthChecker
+
+	// Cleans message text before it is routed to workers
+	sanitizer Sanitizer
+
+	// Blocks text with CONTENT_FILTER_FILE phrases; nil when disabled
+	contentFilter *ContentFilter
+
+	// Posts presence events to WEBHOOK_URL; nil when disabled
+	webhook *webhookDispatcher
+
+	// Compiled CHANNEL_PATTERNS; nil falls back to the built-in channels
+	channelPatterns []*regexp.Regexp
+
+	// Parsed ALLOWED_IP_CIDRS; nil allows all client IPs
+	allowedNets []*net.IPNet
+	// Parsed TRUSTED_PROXY_CIDRS; nil ignores forwarding headers
+	trustedProxies []*net.IPNet
+
+	// IDs of history list entries
+	historyIDs historyIDGen
+
+	// Snapshot of local channels for ListChannels
+	channelListMu sync.Mutex
+	channelList   []ChannelSummary
+	channelListAt time.Time
+
+	// Channels whose stats hash this gateway wrote to, exported as gauges
+	statsChannels sync.Map // channel -> struct{}
+
+	// Workers whose streams have the consumer group
+	consumerGroups sync.Map // workerID -> struct{}
+
+	// Forwards broadcasts to the other registered gateways
+	connPool ConnectionPool
+
+	// Delivers presence events written to worker streams to the audit log
+	// and webhook
+	events *AsyncEventBus
+
+	// Keeps delivered outbound messages; NopArchiver unless ARCHIVE_ENABLED
+	archiver Archiver
 }
 
 // EventType defines the type of stream event
@@ -48,20 +161,35 @@ const (
 	EventTypeLeave   EventType = "leave"
 )
 
-// StreamMessage matches the TypeScript worker message format
+// StreamMessage matches the TypeScript worker message format.
+// See SCHEMA.md before changing fields.
 type StreamMessage struct {
-	ID        string    `json:"id"`
-	Type      EventType `json:"type"`
-	Channel   string    `json:"channel"`
-	WorkerID  string    `json:"workerId"`
-	UserID    string    `json:"userId"`
-	UserName  string    `json:"userName"`
-	Text      string    `json:"text,omitempty"`
-	Timestamp string    `json:"timestamp"`
-	Raw       string    `json:"raw,omitempty"`
-	ClientID  string    `json:"clientId"`
+	SchemaVersion int               `json:"schemaVersion"`
+	ID            string            `json:"id"`
+	Type          EventType         `json:"type"`
+	Channel       string            `json:"channel"`
+	WorkerID      string            `json:"workerId"`
+	UserID        string            `json:"userId"`
+	UserName      string            `json:"userName"`
+	Text          string            `json:"text,omitempty"`
+	ContentType   string            `json:"contentType,omitempty"` // messages only; absent means ContentTypeText
+	Meta          map[string]string `json:"meta,omitempty"`        // messages only; client metadata such as a reply-to ID
+	Timestamp     string            `json:"timestamp"`
+	Raw           string            `json:"raw,omitempty"`
+	ClientID      string            `json:"clientId"`
+	GatewayID     string            `json:"gatewayId"`
+	Priority      int               `json:"priority"`            // routing.PriorityLow..PriorityHigh
+	Replay        bool              `json:"replay,omitempty"`    // re-added by an operator replay of a stream range
+	Retried       bool              `json:"retried,omitempty"`   // re-added after sitting unacknowledged for StaleMessageMinIdle
+	Signature     string            `json:"signature,omitempty"` // messages only, with MessageSigningKey; see SignMessage
 }
 
+// Presence page sizes of the HTTP presence endpoint
+const (
+	DefaultPresenceLimit = 100
+	MaxPresenceLimit     = 500
+)
+
 // PresenceInfo represents a user in a channel
 type PresenceInfo struct {
 	UserID   string `json:"userId"`
@@ -69,8 +197,19 @@ type PresenceInfo struct {
 	ClientID string `json:"clientId"`
 }
 
-// NewGateway creates a new Gateway instance
-func NewGateway(cfg *config.Config, redisClient *redis.Client) (*Gateway, error) {
+// NewGateway creates a new Gateway instance configured by opts, usually
+// WithConfig and WithRedis followed by overrides of single settings. It
+// returns ErrNoRedis without a Redis client.
+func NewGateway(opts ...Option) (*Gateway, error) {
+	o := &gatewayOptions{reconnectWindow: defaultReconnectWindow}
+	for _, opt := range opts {
+		opt(o)
+	}
+	cfg, redisClient := &o.cfg, o.redis
+	if redisClient == nil {
+		return nil, ErrNoRedis
+	}
+
 	node, err := centrifuge.New(centrifuge.Config{
 		LogLevel:   centrifuge.LogLevelInfo,
 		LogHandler: logHandler,
@@ -79,16 +218,130 @@ func NewGateway(cfg *config.Config, redisClient *redis.Client) (*Gateway, error)
 		return nil, err
 	}
 
+	channelPatterns, err := compileChannelPatterns(cfg.ChannelPatterns)
+	if err != nil {
+		return nil, err
+	}
+	allowedNets, err := parseCIDRs(cfg.AllowedIPCIDRs)
+	if err != nil {
+		return nil, err
+	}
+	trustedProxies, err := parseCIDRs(cfg.TrustedProxyCIDRs)
+	if err != nil {
+		return nil, err
+	}
+
+	instanceID := cfg.InstanceID
+	if instanceID == "" {
+		instanceID = uuid.New().String()
+	}
+
+	gatewayMetrics := metrics.Default
+	if o.metricsRegistry != nil {
+		gatewayMetrics = metrics.NewMetrics(o.metricsRegistry)
+	}
+
+	router := o.router
+	if router == nil {
+		routerOpts := []routing.RouterOption{
+			routing.WithSelectionStrategy(routing.SelectionStrategy(cfg.WorkerSelectionStrategy)),
+			routing.WithChannelNameSanitizer(routing.NormalizeChannelName),
+			routing.WithMetrics(gatewayMetrics),
+		}
+		if cfg.ChannelRouteTTL > 0 {
+			routerOpts = append(routerOpts, routing.WithRouteTTL(cfg.ChannelRouteTTL))
+		}
+		if cfg.ChannelCountReconcileInterval > 0 {
+			routerOpts = append(routerOpts, routing.WithChannelCountReconcile(cfg.ChannelCountReconcileInterval))
+		}
+		// Pin new channels to workers in this gateway's region when configured
+		if cfg.Region != "" {
+			region := cfg.Region
+			routerOpts = append(routerOpts, routing.WithRegionAffinity(func(string) string {
+				return region
+			}))
+		}
+		router = routing.NewRouter(redisClient, cfg.RouteCacheTTL, routerOpts...)
+	}
+
+	sanitizer := o.sanitizer
+	if sanitizer == nil {
+		sanitizer = NewDefaultSanitizer(cfg.MaxTextLength)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
 	gw := &Gateway{
-		node:            node,
-		config:          cfg,
-		redis:           redisClient,
-		router:          routing.NewRouter(redisClient, cfg.RouteCacheTTL),
-		connections:     make(map[string]*connectionMeta),
-		recentUsers:     make(map[string]time.Time),
-		reconnectWindow: 60 * time.Second, // Consider reconnect if within 60 seconds
+		node:             node,
+		config:           cfg,
+		redis:            redisClient,
+		router:           router,
+		ownsRouter:       o.router == nil,
+		metrics:          gatewayMetrics,
+		instanceID:       instanceID,
+		ctx:              ctx,
+		cancel:           cancel,
+		startedAt:        time.Now(),
+		connections:      make(map[string]*connectionMeta),
+		recentUsers:      make(map[string]time.Time),
+		subscriberCounts: newSubscriberCountCache(cfg.PresenceCacheTTL),
+		historyCache:     newHistoryCache(cfg.HistoryCacheSize, historyCacheTTL),
+		ipLimiter:        newIPLimiter(cfg.MaxConnectionsPerIP, 5*time.Minute),
+		publishLimiter:   newPublishLimiter(cfg, redisClient),
+		sanitizer:        sanitizer,
+		channelPatterns:  channelPatterns,
+		allowedNets:      allowedNets,
+		trustedProxies:   trustedProxies,
+		reconnectWindow:  o.reconnectWindow,
+	}
+
+	if cfg.ContentFilterFile != "" {
+		contentFilter, err := NewContentFilter(cfg.ContentFilterFile)
+		if err != nil {
+			return nil, err
+		}
+		gw.contentFilter = contentFilter
 	}
 
+	switch {
+	case o.archiver != nil:
+		gw.archiver = o.archiver
+	case cfg.ArchiveEnabled:
+		archiver, err := NewFileArchiver(cfg.ArchivePath, cfg.ArchiveRotateInterval)
+		if err != nil {
+			return nil, err
+		}
+		gw.archiver = archiver
+	default:
+		gw.archiver = NopArchiver{}
+	}
+
+	if cfg.WebhookURL != "" {
+		gw.webhook = newWebhookDispatcher(cfg.WebhookURL, cfg.WebhookSecret, cfg.WebhookQueueSize, gw.metrics)
+	}
+
+	if cfg.BloomFilterCapacity > 0 {
+		gw.dedupFilter = newBloomFilter(cfg.BloomFilterCapacity, cfg.BloomFalsePositiveRate)
+	}
+
+	if cfg.MaxConnections > 0 {
+		gw.loadShedder = NewLoadShedder(cfg.MaxConnections, cfg.LoadShedThreshold, func() float64 {
+			return gaugeValue(gw.metrics.WebSocketConnections)
+		})
+	}
+
+	gw.connPool = NewRedisConnectionPool(redisClient, gw.isLocalClient)
+
+	gw.events = NewAsyncEventBus(ctx, eventBusQueueSize, gw.metrics)
+	gw.events.Register("audit", gw.auditLogHandler)
+	gw.events.Register("webhook", gw.webhookHandler)
+
+	gw.redisHealth = NewRedisHealthChecker(redisClient.Ping, func(ctx context.Context) {
+		if gw.config.ClientQueueDepth > 0 {
+			gw.flushClientQueues(ctx)
+		}
+	}, gw.metrics)
+
 	gw.setupHandlers()
 
 	// Start cleanup goroutine for old user entries
@@ -119,21 +372,178 @@ func (g *Gateway) Node() *centrifuge.Node {
 	return g.node
 }
 
-// Run starts the Centrifuge node
+// InstanceID returns the unique ID of this gateway instance
+func (g *Gateway) InstanceID() string {
+	return g.instanceID
+}
+
+// ConnectionPool returns the pool spanning the connections of all gateways
+func (g *Gateway) ConnectionPool() ConnectionPool {
+	return g.connPool
+}
+
+// workerMonitorInterval is how often workers without heartbeat are removed
+const workerMonitorInterval = 10 * time.Second
+
+// Run starts the Centrifuge node and the background goroutines: outbound
+// stream consumer, gateway registry heartbeat, client queue flusher, IP limiter cleanup, worker heartbeat monitor, stream
+// backlog and lag monitors, channel stats exporter, unacked message
+// exporter, stale message recovery, duplicate text cleanup, channel subscriber sampler, webhook
+// workers, load shedder and Redis health checker
 func (g *Gateway) Run() error {
-	return g.node.Run()
+	if err := g.node.Run(); err != nil {
+		return err
+	}
+	g.running.Store(true)
+	g.events.Start()
+
+	g.wg.Add(1)
+	go g.outboundConsumer(g.ctx)
+
+	if err := g.registerGateway(g.ctx, time.Now()); err != nil {
+		return fmt.Errorf("register gateway: %w", err)
+	}
+	g.wg.Add(1)
+	go g.gatewayHeartbeat(g.ctx)
+
+	if g.config.ClientQueueDepth > 0 {
+		g.wg.Add(1)
+		go g.clientQueueFlusher(g.ctx)
+	}
+
+	g.wg.Add(1)
+	go g.cleanupIPLimiter(g.ctx)
+
+	if g.config.StreamMaxLen > 0 {
+		monitor := routing.NewBacklogMonitor(g.redis, int64(g.config.StreamMaxLen), g.config.WorkerCooldownDuration, g.metrics)
+		g.wg.Add(1)
+		go func() {
+			defer g.wg.Done()
+			monitor.Run(g.ctx, g.config.StreamBacklogCheckInterval)
+		}()
+	}
+
+	if g.config.WorkerHeartbeatTimeout > 0 {
+		monitor := routing.NewWorkerMonitor(g.redis, g.config.WorkerHeartbeatTimeout)
+		g.wg.Add(1)
+		go func() {
+			defer g.wg.Done()
+			monitor.Run(g.ctx, workerMonitorInterval)
+		}()
+	}
+
+	if g.config.StreamLagPollInterval > 0 {
+		monitor := routing.NewStreamLagMonitor(g.redis, g.config.ConsumerGroupName, int64(g.config.StreamLagWarnThreshold), g.metrics)
+		g.wg.Add(1)
+		go func() {
+			defer g.wg.Done()
+			monitor.Run(g.ctx, g.config.StreamLagPollInterval)
+		}()
+	}
+
+	if g.config.KeyspaceNotificationsEnabled {
+		subscriber := routing.NewKeyspaceSubscriber(g.redis, g.router)
+		g.wg.Add(1)
+		go func() {
+			defer g.wg.Done()
+			subscriber.Run(g.ctx)
+		}()
+	}
+
+	if g.config.ChannelStatsInterval > 0 {
+		g.wg.Add(1)
+		go g.channelStatsExporter(g.ctx)
+	}
+
+	g.wg.Add(1)
+	go g.unackedMessagesExporter(g.ctx)
+
+	if g.config.StaleClaimInterval > 0 {
+		g.wg.Add(1)
+		go g.staleMessageRecovery(g.ctx)
+	}
+
+	if g.config.DuplicateTextWindow > 0 {
+		g.wg.Add(1)
+		go g.lastTextCleanup(g.ctx)
+	}
+
+	if g.dedupFilter != nil {
+		g.wg.Add(1)
+		go g.dedupFilterReset(g.ctx)
+	}
+
+	if g.config.AppPingEnabled {
+		g.wg.Add(1)
+		go g.appPinger(g.ctx)
+	}
+
+	if g.webhook != nil {
+		for i := 0; i < g.config.WebhookWorkers; i++ {
+			g.wg.Add(1)
+			go func() {
+				defer g.wg.Done()
+				g.webhook.run(g.ctx)
+			}()
+		}
+	}
+
+	if g.config.ChannelStatsSampleInterval > 0 {
+		g.wg.Add(1)
+		go g.channelSubscriberSampler(g.ctx)
+	}
+
+	if g.loadShedder != nil {
+		g.wg.Add(1)
+		go func() {
+			defer g.wg.Done()
+			g.loadShedder.Run(g.ctx, loadShedCheckInterval)
+		}()
+	}
+
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		g.redisHealth.Run(g.ctx, redisHealthCheckInterval)
+	}()
+
+	return nil
 }
 
-// Shutdown gracefully stops the node
+// Shutdown disconnects clients with the reconnect policy, stops background
+// goroutines and the router NewGateway created, leaves the gateway registry
+// and gracefully stops the node. A router passed with WithRouter is left to
+// its owner.
 func (g *Gateway) Shutdown(ctx context.Context) error {
+	g.running.Store(false)
+	g.disconnectAll(g.PlannedDisconnect())
+	g.flushPendingLeaves(ctx)
+	g.events.Close()
+	g.cancel()
+	g.wg.Wait()
+	if g.ownsRouter {
+		if err := g.router.Close(); err != nil {
+			slog.Warn("failed to close router", "error", err)
+		}
+	}
+	if closer, ok := g.archiver.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			slog.Warn("failed to close message archive", "error", err)
+		}
+	}
+	if err := g.redis.ZRem(ctx, routing.GatewayRegistryKey, g.instanceID); err != nil {
+		slog.Warn("failed to leave gateway registry", "error", err)
+	}
 	return g.node.Shutdown(ctx)
 }
 
-// GetChannelPresence returns the list of users currently subscribed to a channel
-func (g *Gateway) GetChannelPresence(channel string) ([]PresenceInfo, error) {
+// GetChannelPresence returns up to limit users subscribed to a channel,
+// starting at offset in client ID order, and the total number of users.
+// A limit <= 0 returns all users from offset.
+func (g *Gateway) GetChannelPresence(channel string, offset, limit int) ([]PresenceInfo, int, error) {
 	result, err := g.node.Presence(channel)
 	if err != nil {
-		return nil, err
+		return nil, 0, fmt.Errorf("presence of channel %q: %w", channel, err)
 	}
 
 	users := make([]PresenceInfo, 0, len(result.Presence))
@@ -164,7 +574,27 @@ func (g *Gateway) GetChannelPresence(channel string) ([]PresenceInfo, error) {
 		})
 	}
 
-	return users, nil
+	// Presence is a map; sort so pages are stable across requests
+	slices.SortFunc(users, func(a, b PresenceInfo) int {
+		return strings.Compare(a.ClientID, b.ClientID)
+	})
+
+	return page(users, offset, limit), len(users), nil
+}
+
+// page returns the items in [offset, offset+limit), clamped to items
+func page[T any](items []T, offset, limit int) []T {
+	if offset < 0 {
+		offset = 0
+	}
+	if offset >= len(items) {
+		return []T{}
+	}
+	end := len(items)
+	if limit > 0 && offset+limit < end {
+		end = offset + limit
+	}
+	return items[offset:end]
 }
 
 // logHandler converts Centrifuge logs to slog
@@ -196,7 +626,30 @@ func (g *Gateway) setupHandlers() {
 
 // handleConnecting handles authentication before connection is established
 func (g *Gateway) handleConnecting(ctx context.Context, e centrifuge.ConnectEvent) (centrifuge.ConnectReply, error) {
-	metrics.ConnectTotal.WithLabelValues("attempt").Inc()
+	g.metrics.ConnectTotal.WithLabelValues("attempt").Inc()
+
+	// Reuse the HTTP request's ID when the transport went through requestid.Middleware
+	if requestid.FromContext(ctx) == "" {
+		ctx = requestid.New(ctx)
+	}
+
+	// Protect existing connections while overloaded
+	if g.loadShedder != nil && g.loadShedder.Shedding() {
+		g.metrics.ConnectTotal.WithLabelValues("rejected").Inc()
+		g.metrics.LoadShedTotal.Inc()
+		slog.WarnContext(ctx, "connection rejected", "reason", "load_shed")
+		return centrifuge.ConnectReply{}, DisconnectOverloaded
+	}
+
+	// Enforce per-IP connection limit
+	ip := clientIPFromContext(ctx)
+	if !g.ipLimiter.acquire(ip) {
+		err := ErrRateLimited.Wrap(fmt.Errorf("ip %s reached %d connections", ip, g.config.MaxConnectionsPerIP))
+		g.metrics.ConnectTotal.WithLabelValues("rejected").Inc()
+		g.metrics.ConnectIPLimitTotal.WithLabelValues(ipCIDR(ip)).Inc()
+		slog.WarnContext(ctx, "connection rejected", "ip", ip, "reason", "ip_limit", "error", err)
+		return centrifuge.ConnectReply{}, DisconnectIPLimit
+	}
 
 	// Extract user info from connection data
 	var connectData struct {
@@ -219,17 +672,18 @@ func (g *Gateway) handleConnecting(ctx context.Context, e centrifuge.ConnectEven
 		"name": userName,
 	})
 
-	metrics.ConnectTotal.WithLabelValues("success").Inc()
-	metrics.WebSocketConnections.Inc()
+	g.metrics.ConnectTotal.WithLabelValues("success").Inc()
+	g.metrics.WebSocketConnections.Inc()
 
-	slog.Info("client connecting", "userId", userID, "userName", userName)
+	slog.InfoContext(ctx, "client connecting", "userId", userID, "userName", userName)
 
 	return centrifuge.ConnectReply{
 		Credentials: &centrifuge.Credentials{
 			UserID: userID,
 			Info:   info,
 		},
-		Data: []byte(`{"version":"1.0.0"}`),
+		Data:       []byte(`{"version":"1.0.0"}`),
+		WriteDelay: g.config.WriteFlushInterval,
 	}, nil
 }
 
@@ -239,27 +693,38 @@ func (g *Gateway) handleConnect(client *centrifuge.Client) {
 	clientID := client.ID()
 	userID := client.UserID()
 
-	// Check if this is a reconnection
-	isReconnect := false
+	// Users who disconnected within the reconnect window are reconnecting
+	state := StateNew
 	g.recentUsersMu.RLock()
-	if lastDisconnect, ok := g.recentUsers[userID]; ok {
-		if time.Since(lastDisconnect) < g.reconnectWindow {
-			isReconnect = true
-		}
+	if lastDisconnect, ok := g.recentUsers[userID]; ok && time.Since(lastDisconnect) < g.reconnectWindow {
+		state = StateReconnecting
 	}
 	g.recentUsersMu.RUnlock()
 
 	// Track connection metadata
-	g.connectionsMu.Lock()
-	g.connections[clientID] = &connectionMeta{
+	meta := &connectionMeta{
 		connectTime: time.Now(),
+		clientID:    clientID,
 		userID:      userID,
+		client:      client,
+		state:       state,
+		metrics:     g.metrics,
 	}
+	if g.config.ClientQueueDepth > 0 {
+		meta.queue = newClientQueue(g.config.ClientQueueDepth)
+	}
+	g.startIdleTimer(meta)
+	g.connectionsMu.Lock()
+	g.connections[clientID] = meta
 	g.connectionsMu.Unlock()
 
+	if isSockJSTransport(transport.Name()) {
+		g.metrics.SockJSConnections.Inc()
+	}
+
 	// Record reconnection metric
-	if isReconnect {
-		metrics.ReconnectTotal.WithLabelValues("success").Inc()
+	if state == StateReconnecting {
+		g.metrics.ReconnectTotal.WithLabelValues("success").Inc()
 		slog.Info("client reconnected",
 			"clientId", clientID,
 			"userId", userID,
@@ -277,67 +742,249 @@ func (g *Gateway) handleConnect(client *centrifuge.Client) {
 
 	// Subscribe handler
 	client.OnSubscribe(func(e centrifuge.SubscribeEvent, cb centrifuge.SubscribeCallback) {
-		g.handleSubscribe(client, e, cb)
+		g.resetIdleTimer(meta)
+		ctx, cancel := g.eventContext(client.Context())
+		defer cancel()
+		g.handleSubscribe(ctx, client, e, cb)
 	})
 
-
-	// Unsubscribe handler - push leave event
+	// Unsubscribe handler - push leave event, also when the client is
+	// already gone
 	client.OnUnsubscribe(func(e centrifuge.UnsubscribeEvent) {
-		g.handleUnsubscribe(client, e)
+		ctx, cancel := g.eventContext(context.WithoutCancel(client.Context()))
+		defer cancel()
+		g.handleUnsubscribe(ctx, client, e)
 	})
 
 	// Publish handler
 	client.OnPublish(func(e centrifuge.PublishEvent, cb centrifuge.PublishCallback) {
-		g.handlePublish(client, e, cb)
+		g.resetIdleTimer(meta)
+		ctx, cancel := g.eventContext(client.Context())
+		defer cancel()
+		g.handlePublish(ctx, client, e, cb)
 	})
 
+	// Async messages carry application-level pongs
+	if g.config.AppPingEnabled {
+		client.OnMessage(func(e centrifuge.MessageEvent) {
+			g.handleMessage(client, e)
+		})
+	}
+
 	// Disconnect handler
 	client.OnDisconnect(func(e centrifuge.DisconnectEvent) {
-		g.handleDisconnect(client, e)
+		ctx, cancel := g.eventContext(context.WithoutCancel(client.Context()))
+		defer cancel()
+		g.handleDisconnect(ctx, client, e)
 	})
+
+	if err := meta.Transition(state, StateActive); err != nil {
+		slog.Warn("failed to activate connection", "clientId", clientID, "error", err)
+	}
+}
+
+// eventContext returns the context for handling a client event: parent,
+// usually the client's connection context that is cancelled when the client
+// disconnects, bounded by PublishContextTimeout and carrying a new request ID
+func (g *Gateway) eventContext(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithTimeout(parent, g.config.PublishContextTimeout)
+	return requestid.New(ctx), cancel
 }
 
 // handleSubscribe validates channel subscription
-func (g *Gateway) handleSubscribe(client *centrifuge.Client, e centrifuge.SubscribeEvent, cb centrifuge.SubscribeCallback) {
+func (g *Gateway) handleSubscribe(ctx context.Context, client *centrifuge.Client, e centrifuge.SubscribeEvent, cb centrifuge.SubscribeCallback) {
 	channel := e.Channel
+	ctx, span := tracing.Tracer().Start(ctx, "gateway.subscribe",
+		trace.WithAttributes(attribute.String("channel", channel)))
+	defer span.End()
 	userID := client.UserID()
 
-	// Validate channel format
-	if !g.isValidChannel(channel, userID) {
-		metrics.SubscribeTotal.WithLabelValues("rejected", "invalid_channel").Inc()
-		slog.Warn("subscription rejected", "channel", channel, "userId", userID, "reason", "invalid_channel")
-		cb(centrifuge.SubscribeReply{}, centrifuge.ErrorPermissionDenied)
+	// Echo channels belong to a single connection. Private channels require
+	// a subscription token signed by the application backend instead of the
+	// regular channel format validation.
+	if g.isEchoChannel(channel) {
+		if !ownEchoChannel(client, channel) {
+			err := ErrPermissionDenied.Wrap(fmt.Errorf("echo channel %q of another client", channel))
+			g.metrics.SubscribeTotal.WithLabelValues("rejected", "invalid_channel").Inc()
+			slog.WarnContext(ctx, "subscription rejected", "channel", channel, "userId", userID, "reason", "invalid_channel", "error", err)
+			cb(centrifuge.SubscribeReply{}, clientError(err))
+			return
+		}
+	} else if g.isPrivateChannel(channel) {
+		if !verifySubscriptionToken(g.config.TokenHMACSecret, client.ID(), channel, e.Token) {
+			err := ErrPermissionDenied.Wrap(fmt.Errorf("invalid subscription token for client %s", client.ID()))
+			g.metrics.SubscribeTotal.WithLabelValues("rejected", "invalid_token").Inc()
+			slog.WarnContext(ctx, "subscription rejected", "channel", channel, "userId", userID, "reason", "invalid_token", "error", err)
+			cb(centrifuge.SubscribeReply{}, clientError(err))
+			return
+		}
+	} else if !g.isValidChannel(channel, userID) {
+		err := ErrPermissionDenied.Wrap(fmt.Errorf("channel %q not allowed for user %s", channel, userID))
+		g.metrics.SubscribeTotal.WithLabelValues("rejected", "invalid_channel").Inc()
+		slog.WarnContext(ctx, "subscription rejected", "channel", channel, "userId", userID, "reason", "invalid_channel", "error", err)
+		cb(centrifuge.SubscribeReply{}, clientError(err))
+		return
+	}
+
+	// Namespaces from CHANNEL_NAMESPACES may require a subscription token;
+	// with StrictNamespaceMode, namespaces not listed there are unknown
+	namespace, ns, knownNamespace := g.channelNamespace(channel)
+	if namespace != "" && !knownNamespace && g.config.StrictNamespaceMode {
+		err := ErrChannelNotFound.Wrap(fmt.Errorf("namespace %q not configured", namespace))
+		g.metrics.SubscribeTotal.WithLabelValues("rejected", "unknown_namespace").Inc()
+		slog.WarnContext(ctx, "subscription rejected", "channel", channel, "userId", userID, "reason", "unknown_namespace", "error", err)
+		cb(centrifuge.SubscribeReply{}, clientError(err))
+		return
+	}
+	if ns.Protected && !verifySubscriptionToken(g.config.TokenHMACSecret, client.ID(), channel, e.Token) {
+		err := ErrPermissionDenied.Wrap(fmt.Errorf("invalid subscription token for client %s in protected namespace %q", client.ID(), namespace))
+		g.metrics.SubscribeTotal.WithLabelValues("rejected", "invalid_token").Inc()
+		slog.WarnContext(ctx, "subscription rejected", "channel", channel, "userId", userID, "reason", "invalid_token", "error", err)
+		cb(centrifuge.SubscribeReply{}, clientError(err))
+		return
+	}
+
+	// Room channels must be created via the HTTP API first; a namespace and
+	// then a room may override the per-channel subscriber limit
+	limit := g.config.MaxSubscribersPerChannel
+	if ns.MaxSubscribers > 0 {
+		limit = ns.MaxSubscribers
+	}
+	if roomID, ok := roomIDFromChannel(channel); ok {
+		maxSubscribers, err := g.roomMaxSubscribers(ctx, roomID)
+		if errors.Is(err, ErrRoomNotFound) {
+			err = ErrChannelNotFound.Wrap(fmt.Errorf("room %s: %w", roomID, err))
+			g.metrics.SubscribeTotal.WithLabelValues("rejected", "unknown_room").Inc()
+			slog.WarnContext(ctx, "subscription rejected", "channel", channel, "userId", userID, "reason", "unknown_room", "error", err)
+			cb(centrifuge.SubscribeReply{}, clientError(err))
+			return
+		}
+		if err != nil {
+			g.metrics.SubscribeTotal.WithLabelValues("error", "redis_error").Inc()
+			slog.ErrorContext(ctx, "failed to look up room", "channel", channel, "error", err)
+			cb(centrifuge.SubscribeReply{}, centrifuge.ErrorInternal)
+			return
+		}
+		if maxSubscribers > 0 {
+			limit = maxSubscribers
+		}
+	}
+
+	// Clients ask for missed messages with a since timestamp in the
+	// subscribe data; other channels ignore it
+	var since time.Time
+	if g.isRecoverableChannel(channel) {
+		var err error
+		if since, err = recoverSince(e.Data); err != nil {
+			g.metrics.SubscribeTotal.WithLabelValues("rejected", "invalid_since").Inc()
+			slog.WarnContext(ctx, "subscription rejected", "channel", channel, "userId", userID, "reason", "invalid_since")
+			cb(centrifuge.SubscribeReply{}, centrifuge.ErrorBadRequest)
+			return
+		}
+	}
+
+	// Enforce per-channel subscriber limit
+	full, err := g.isChannelFull(channel, limit)
+	if err != nil {
+		slog.WarnContext(ctx, "failed to count channel subscribers", "channel", channel, "error", err)
+	}
+	if full {
+		g.metrics.SubscribeTotal.WithLabelValues("rejected", "channel_full").Inc()
+		g.metrics.SubscribeRejectedChannelFull.WithLabelValues(channel).Inc()
+		slog.WarnContext(ctx, "subscription rejected", "channel", channel, "userId", userID, "reason", "channel_full")
+		cb(centrifuge.SubscribeReply{}, ErrorChannelFull)
+		return
+	}
+
+	// Enforce per-client subscription limit
+	if !g.acquireSubscription(client.ID()) {
+		g.metrics.SubscribeTotal.WithLabelValues("rejected", "too_many_subscriptions").Inc()
+		slog.WarnContext(ctx, "subscription rejected", "channel", channel, "userId", userID, "reason", "too_many_subscriptions")
+		cb(centrifuge.SubscribeReply{}, ErrorTooManySubscriptions)
 		return
 	}
+	g.subscriberCounts.incr(channel)
+
+	// Channel metadata is sent to the client with the subscribe reply;
+	// failing to read it must not fail the subscription
+	metadata, err := g.ChannelMetadata(ctx, channel)
+	if err != nil {
+		slog.WarnContext(ctx, "failed to read channel metadata", "channel", channel, "error", err)
+	}
+
+	// Missed messages are best effort as well
+	var recovered []StreamMessage
+	if !since.IsZero() {
+		recovered, err = g.recoverMessages(ctx, channel, since)
+		if err != nil {
+			slog.WarnContext(ctx, "failed to recover messages", "channel", channel, "error", err)
+		}
+		g.metrics.SubscribeRecoveredMessages.Add(float64(len(recovered)))
+	}
+	data, err := marshalSubscribeReplyData(metadata, recovered)
+	if err != nil {
+		slog.WarnContext(ctx, "failed to marshal subscribe reply data", "channel", channel, "error", err)
+	}
 
-	metrics.SubscribeTotal.WithLabelValues("success", "").Inc()
-	slog.Info("client subscribed", "channel", channel, "userId", userID, "clientId", client.ID())
+	g.metrics.SubscribeTotal.WithLabelValues("success", "").Inc()
+	slog.InfoContext(ctx, "client subscribed", "channel", channel, "userId", userID, "clientId", client.ID(), "recovered", len(recovered))
 
 	cb(centrifuge.SubscribeReply{
 		Options: centrifuge.SubscribeOptions{
 			EmitPresence:  true,
 			EmitJoinLeave: true,
-			PushJoinLeave: true,
+			PushJoinLeave: ns.PushesJoinLeave(),
+			Data:          data,
 		},
 	}, nil)
 
+	// Echo channels have no worker and no stats
+	if g.isEchoChannel(channel) {
+		return
+	}
+	g.incrChannelStat(ctx, channel, statsFieldSubscribers, 1)
+
 	// Push join event to worker stream after successful subscription
-	g.pushPresenceEvent(client, channel, EventTypeJoin)
+	g.pushPresenceEvent(ctx, client, channel, EventTypeJoin, nil)
 }
 
-// handleUnsubscribe pushes leave event to worker stream
-func (g *Gateway) handleUnsubscribe(client *centrifuge.Client, e centrifuge.UnsubscribeEvent) {
-	g.pushPresenceEvent(client, e.Channel, EventTypeLeave)
+// handleUnsubscribe updates subscription counts and channel stats and pushes
+// leave event to worker stream
+func (g *Gateway) handleUnsubscribe(ctx context.Context, client *centrifuge.Client, e centrifuge.UnsubscribeEvent) {
+	g.releaseSubscription(client.ID())
+	g.subscriberCounts.invalidate(e.Channel)
+	if g.isEchoChannel(e.Channel) {
+		return
+	}
+	g.incrChannelStat(ctx, e.Channel, statsFieldSubscribers, -1)
+	g.pushPresenceEvent(ctx, client, e.Channel, EventTypeLeave, e.Disconnect)
 }
 
-// pushPresenceEvent sends a join/leave event to the worker stream
-func (g *Gateway) pushPresenceEvent(client *centrifuge.Client, channel string, eventType EventType) {
-	ctx := context.Background()
+// pushPresenceEvent sends a join/leave event to the worker stream. With
+// SuppressJoinLeaveForReconnect, the leave of a client unsubscribed by a
+// disconnect it reconnects after is deferred for reconnectWindow; a join of
+// the same user and channel within the window drops both events.
+func (g *Gateway) pushPresenceEvent(ctx context.Context, client *centrifuge.Client, channel string, eventType EventType, disconnect *centrifuge.Disconnect) {
+	if g.config.SuppressJoinLeaveForReconnect {
+		switch {
+		case eventType == EventTypeLeave && disconnect != nil && isReconnectable(*disconnect):
+			g.deferLeave(client, channel)
+			return
+		case eventType == EventTypeJoin && g.cancelPendingLeave(client.UserID(), channel):
+			slog.InfoContext(ctx, "presence events suppressed for reconnect", "channel", channel, "userId", client.UserID())
+			return
+		}
+	}
+	g.publishPresenceEvent(ctx, client, channel, eventType)
+}
 
+// publishPresenceEvent routes a join/leave event, writes it to the worker
+// stream and, once written, publishes it on the event bus
+func (g *Gateway) publishPresenceEvent(ctx context.Context, client *centrifuge.Client, channel string, eventType EventType) {
 	// Get worker for this channel
 	workerID, err := g.router.GetWorkerForChannel(ctx, channel)
 	if err != nil {
-		slog.Error("failed to get worker for presence event",
+		slog.ErrorContext(ctx, "failed to get worker for presence event",
 			"channel", channel,
 			"eventType", eventType,
 			"error", err,
@@ -345,10 +992,6 @@ func (g *Gateway) pushPresenceEvent(client *centrifuge.Client, channel string, e
 		return
 	}
 
-	streamKey := routing.GetWorkerStreamKey(workerID)
-	messageID := uuid.New().String()
-	timestamp := time.Now().UTC()
-
 	// Get user name from client info
 	userName := "Anonymous"
 	if info := client.Info(); len(info) > 0 {
@@ -360,79 +1003,194 @@ func (g *Gateway) pushPresenceEvent(client *centrifuge.Client, channel string, e
 		}
 	}
 
-	// Construct presence event
-	event := StreamMessage{
-		ID:        messageID,
-		Type:      eventType,
-		Channel:   channel,
-		WorkerID:  workerID,
-		UserID:    client.UserID(),
-		UserName:  userName,
-		Timestamp: timestamp.Format(time.RFC3339Nano),
-		ClientID:  client.ID(),
+	event := GatewayEvent{
+		Message: StreamMessage{
+			SchemaVersion: g.config.StreamSchemaVersion,
+			ID:            uuid.New().String(),
+			Type:          eventType,
+			Channel:       channel,
+			WorkerID:      workerID,
+			UserID:        client.UserID(),
+			UserName:      userName,
+			Timestamp:     time.Now().UTC().Format(time.RFC3339Nano),
+			ClientID:      client.ID(),
+			GatewayID:     g.instanceID,
+			Priority:      routing.PriorityNormal,
+		},
+		TraceContext: tracing.Inject(ctx),
 	}
-
-	// Marshal event payload
-	payload, err := json.Marshal(event)
-	if err != nil {
-		slog.Error("failed to marshal presence event", "error", err)
+	if !g.writePresenceEvent(ctx, event) {
 		return
 	}
+	if err := g.events.Publish(ctx, event); err != nil {
+		slog.ErrorContext(ctx, "failed to publish presence event",
+			"eventType", eventType,
+			"channel", channel,
+			"error", err,
+		)
+	}
+}
 
-	// Write to worker's stream
-	_, err = g.redis.XAdd(ctx, streamKey, map[string]interface{}{
-		"payload": string(payload),
-	})
+// writePresenceEvent writes a presence event to the worker stream,
+// dead-lettering it on failure, and reports whether it was written
+func (g *Gateway) writePresenceEvent(ctx context.Context, event GatewayEvent) bool {
+	payload, err := json.Marshal(event.Message)
 	if err != nil {
-		slog.Error("failed to write presence event to stream",
+		slog.ErrorContext(ctx, "failed to marshal presence event", "error", err)
+		return false
+	}
+
+	workerID := event.Message.WorkerID
+	streamKey := routing.GetWorkerStreamKey(workerID, routing.PriorityNormal)
+	g.prepareWorkerStreams(ctx, workerID)
+	if _, err := g.redis.RetryXAdd(ctx, streamKey, streamEntry(payload, event.TraceContext), g.config.RedisMaxPublishRetries); err != nil {
+		slog.ErrorContext(ctx, "failed to write presence event to stream",
 			"streamKey", streamKey,
 			"error", err,
 		)
-		return
+		g.deadLetter(ctx, streamKey, payload, err, g.config.RedisMaxPublishRetries)
+		return false
 	}
+	return true
+}
 
-	slog.Info("presence event published",
-		"eventType", eventType,
-		"channel", channel,
-		"userId", client.UserID(),
-		"workerId", workerID,
+// auditLogHandler logs every presence event
+func (g *Gateway) auditLogHandler(ctx context.Context, event GatewayEvent) {
+	slog.InfoContext(ctx, "presence event published",
+		"eventType", event.Message.Type,
+		"channel", event.Message.Channel,
+		"userId", event.Message.UserID,
+		"clientId", event.Message.ClientID,
+		"workerId", event.Message.WorkerID,
 	)
 }
 
-// isValidChannel checks if channel name is valid
+// isPrivateChannel checks if channel requires a subscription token
+func (g *Gateway) isPrivateChannel(channel string) bool {
+	prefix := g.config.PrivateChannelPrefix
+	return prefix != "" && strings.HasPrefix(channel, prefix)
+}
+
+// compileChannelPatterns compiles the CHANNEL_PATTERNS regexes
+func compileChannelPatterns(patterns []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("channel pattern %q: %w", pattern, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+// isValidChannel checks if channel name is valid. Other spellings of a
+// channel would share its route while escaping the namespace and pattern
+// checks made against its name, so only names already in their routed form
+// are valid. User and room channels are checked by exact ID and keep their
+// names as given.
 func (g *Gateway) isValidChannel(channel, userID string) bool {
-	// Global chat channel
-	if channel == "chat" {
-		return true
+	// User-specific channels: user:{userId}, whatever the patterns allow.
+	// Checked as named, so user IDs differing only in case stay apart.
+	owner, isUser := strings.CutPrefix(channel, "user:")
+	if isUser && owner != userID {
+		return false
 	}
+	if _, isRoom := roomIDFromChannel(channel); !isUser && !isRoom && g.router.NormalizeChannel(channel) != channel {
+		return false
+	}
+
+	return g.matchesChannelPatterns(channel)
+}
+
+// authorizePublish reports whether client may publish to channel, with
+// the channel rules of subscribe
+func (g *Gateway) authorizePublish(client *centrifuge.Client, channel string) error {
+	return g.authorizeChannelPublish(channel, client.UserID(), client.IsSubscribed(channel))
+}
 
-	// Room channels: chat:room-xxx
-	if strings.HasPrefix(channel, "chat:") {
-		return true
+// authorizeChannelPublish reports whether userID may publish to channel.
+// Private channels, protected namespaces and rooms need a subscription
+// token or room membership, which publishes do not carry, so the publisher
+// must be subscribed to them.
+func (g *Gateway) authorizeChannelPublish(channel, userID string, subscribed bool) error {
+	namespace, ns, knownNamespace := g.channelNamespace(channel)
+	if namespace != "" && !knownNamespace && g.config.StrictNamespaceMode {
+		return ErrChannelNotFound.Wrap(fmt.Errorf("namespace %q not configured", namespace))
 	}
 
-	// User-specific channels: user:{userId}
-	if strings.HasPrefix(channel, "user:") {
-		expectedUserID := strings.TrimPrefix(channel, "user:")
-		return expectedUserID == userID
+	_, isRoom := roomIDFromChannel(channel)
+	if g.isPrivateChannel(channel) || ns.Protected || isRoom {
+		if !subscribed {
+			return ErrPermissionDenied.Wrap(fmt.Errorf("user %s not subscribed to %q", userID, channel))
+		}
+		return nil
 	}
+	if !g.isValidChannel(channel, userID) {
+		return ErrPermissionDenied.Wrap(fmt.Errorf("channel %q not allowed for user %s", channel, userID))
+	}
+	return nil
+}
 
+// matchesChannelPatterns reports whether channel matches CHANNEL_PATTERNS,
+// or the built-in channels when no patterns are configured
+func (g *Gateway) matchesChannelPatterns(channel string) bool {
+	if len(g.channelPatterns) == 0 {
+		return isBuiltinChannel(channel)
+	}
+	for _, re := range g.channelPatterns {
+		if re.MatchString(channel) {
+			return true
+		}
+	}
 	return false
 }
 
+// isBuiltinChannel matches the channels allowed when CHANNEL_PATTERNS is
+// empty: the global chat, chat:{room} and user:{userId}
+func isBuiltinChannel(channel string) bool {
+	return channel == "chat" || strings.HasPrefix(channel, "chat:") || strings.HasPrefix(channel, "user:")
+}
+
 // handlePublish processes message publication
-func (g *Gateway) handlePublish(client *centrifuge.Client, e centrifuge.PublishEvent, cb centrifuge.PublishCallback) {
-	timer := metrics.NewTimer(metrics.PublishLatency)
+func (g *Gateway) handlePublish(ctx context.Context, client *centrifuge.Client, e centrifuge.PublishEvent, cb centrifuge.PublishCallback) {
+	receivedAt := time.Now()
+	timer := metrics.NewTimer(g.metrics.PublishLatency)
 	defer timer.ObserveDuration()
 
-	ctx := context.Background()
 	channel := e.Channel
 	userID := client.UserID()
+	ctx, span := tracing.Tracer().Start(ctx, "gateway.publish",
+		trace.WithAttributes(attribute.String("channel", channel)))
+	defer span.End()
+
+	if g.publishLimiter != nil && !g.publishAllowed(ctx, userID) {
+		g.metrics.PublishTotal.WithLabelValues("rejected", "rate_limited").Inc()
+		slog.DebugContext(ctx, "publish rejected by rate limit", "channel", channel, "userId", userID)
+		cb(centrifuge.PublishReply{}, clientError(ErrRateLimited))
+		return
+	}
+
+	if g.isEchoChannel(channel) {
+		g.handleEchoPublish(ctx, client, channel, e.Data, cb)
+		return
+	}
+
+	if err := g.authorizePublish(client, channel); err != nil {
+		reason := "permission_denied"
+		if errors.Is(err, ErrChannelNotFound) {
+			reason = "unknown_namespace"
+		}
+		g.metrics.PublishTotal.WithLabelValues("rejected", reason).Inc()
+		slog.WarnContext(ctx, "publish rejected", "channel", channel, "userId", userID, "reason", reason, "error", err)
+		cb(centrifuge.PublishReply{}, clientError(err))
+		return
+	}
 
 	// Parse message data
 	var data map[string]interface{}
 	if err := json.Unmarshal(e.Data, &data); err != nil {
-		metrics.PublishTotal.WithLabelValues("rejected", "invalid_json").Inc()
+		g.metrics.PublishTotal.WithLabelValues("rejected", "invalid_json").Inc()
 		cb(centrifuge.PublishReply{}, centrifuge.ErrorBadRequest)
 		return
 	}
@@ -440,115 +1198,294 @@ func (g *Gateway) handlePublish(client *centrifuge.Client, e centrifuge.PublishE
 	// Extract text
 	text, _ := data["text"].(string)
 	if text == "" {
-		metrics.PublishTotal.WithLabelValues("rejected", "missing_text").Inc()
+		g.metrics.PublishTotal.WithLabelValues("rejected", "missing_text").Inc()
+		cb(centrifuge.PublishReply{}, centrifuge.ErrorBadRequest)
+		return
+	}
+
+	// Sanitize text, which also enforces MaxTextLength by default
+	text, err := g.sanitizer.Sanitize(text)
+	if err != nil {
+		reason, replyErr := "sanitize_failed", centrifuge.ErrorBadRequest
+		if errors.Is(err, ErrTextTooLong) {
+			err = ErrMessageTooLarge.Wrap(err)
+			reason, replyErr = "text_too_long", clientError(err)
+		}
+		g.metrics.PublishTotal.WithLabelValues("rejected", reason).Inc()
+		slog.DebugContext(ctx, "publish rejected by sanitizer", "channel", channel, "error", err)
+		cb(centrifuge.PublishReply{}, replyErr)
+		return
+	}
+	if strings.TrimSpace(text) == "" {
+		g.metrics.PublishTotal.WithLabelValues("rejected", "missing_text").Inc()
+		cb(centrifuge.PublishReply{}, centrifuge.ErrorBadRequest)
+		return
+	}
+	data["text"] = text
+
+	if g.contentFiltered(text) {
+		g.metrics.PublishTotal.WithLabelValues("rejected", "content_filtered").Inc()
+		slog.DebugContext(ctx, "publish rejected by content filter", "channel", channel, "userId", userID)
+		cb(centrifuge.PublishReply{}, clientError(ErrMessageRejected))
+		return
+	}
+
+	contentType, err := g.contentTypeFromData(data, text)
+	if err != nil {
+		reason := "invalid_content_type"
+		if errors.Is(err, ErrInvalidJSONContent) {
+			reason = "invalid_json_content"
+		}
+		g.metrics.PublishTotal.WithLabelValues("rejected", reason).Inc()
+		slog.DebugContext(ctx, "publish rejected", "channel", channel, "error", err)
+		cb(centrifuge.PublishReply{}, centrifuge.ErrorBadRequest)
+		return
+	}
+
+	priority, ok := priorityFromData(data)
+	if !ok {
+		g.metrics.PublishTotal.WithLabelValues("rejected", "invalid_priority").Inc()
 		cb(centrifuge.PublishReply{}, centrifuge.ErrorBadRequest)
 		return
 	}
 
-	// Validate text length
-	if len(text) > g.config.MaxTextLength {
-		metrics.PublishTotal.WithLabelValues("rejected", "text_too_long").Inc()
+	meta, err := g.metaFromData(data)
+	if err != nil {
+		g.metrics.PublishTotal.WithLabelValues("rejected", "invalid_meta").Inc()
+		slog.DebugContext(ctx, "publish rejected", "channel", channel, "error", err)
 		cb(centrifuge.PublishReply{}, centrifuge.ErrorBadRequest)
 		return
 	}
 
+	fanOut, err := g.fanOutChannels(client, data, channel)
+	if err != nil {
+		reply := centrifuge.ErrorBadRequest
+		var gwErr *GatewayError
+		if errors.As(err, &gwErr) {
+			reply = clientError(err)
+		}
+		g.metrics.PublishTotal.WithLabelValues("rejected", "invalid_channels").Inc()
+		slog.DebugContext(ctx, "publish rejected", "channel", channel, "error", err)
+		cb(centrifuge.PublishReply{}, reply)
+		return
+	}
+	if g.config.DuplicateTextWindow > 0 && g.isDuplicateText(userID, channel, text, receivedAt) {
+		g.metrics.PublishTotal.WithLabelValues("rejected", "duplicate_text").Inc()
+		slog.DebugContext(ctx, "publish rejected as duplicate", "channel", channel, "userId", userID)
+		cb(centrifuge.PublishReply{}, clientError(ErrDuplicateMessage))
+		return
+	}
+	if g.dedupFilter != nil && g.isDuplicateMessage(ctx, userID, channel, text) {
+		g.metrics.PublishTotal.WithLabelValues("rejected", "duplicate_message").Inc()
+		slog.DebugContext(ctx, "publish rejected as duplicate message", "channel", channel, "userId", userID)
+		cb(centrifuge.PublishReply{}, clientError(ErrDuplicateMessage))
+		return
+	}
+
+	if len(fanOut) > 0 {
+		g.handleFanOutPublish(ctx, client, append([]string{channel}, fanOut...), data, text, contentType, meta, priority, cb)
+		return
+	}
+
 	// Get worker for this channel
 	workerID, err := g.router.GetWorkerForChannel(ctx, channel)
 	if err != nil {
-		metrics.PublishTotal.WithLabelValues("error", "no_worker").Inc()
-		slog.Error("failed to get worker for channel", "channel", channel, "error", err)
-		cb(centrifuge.PublishReply{}, centrifuge.ErrorInternal)
+		err = ErrWorkerUnavailable.Wrap(fmt.Errorf("channel %q: %w", channel, err))
+		g.metrics.PublishTotal.WithLabelValues("error", "no_worker").Inc()
+		slog.ErrorContext(ctx, "failed to get worker for channel", "channel", channel, "error", err)
+		span.SetStatus(codes.Error, "no worker")
+		cb(centrifuge.PublishReply{}, clientError(err))
 		return
 	}
 
-	streamKey := routing.GetWorkerStreamKey(workerID)
+	streamKey := routing.GetWorkerStreamKey(workerID, priority)
 	messageID := uuid.New().String()
 	timestamp := time.Now().UTC()
+	span.SetAttributes(attribute.String("workerID", workerID), attribute.String("messageID", messageID))
+	traceContext := tracing.Inject(ctx)
 
-	// Get user name from client info
-	userName := "Anonymous"
-	if info := client.Info(); len(info) > 0 {
-		var userInfo struct {
-			Name string `json:"name"`
-		}
-		if json.Unmarshal(info, &userInfo) == nil && userInfo.Name != "" {
-			userName = userInfo.Name
-		}
-	}
+	userName := clientUserName(client)
 
 	// Marshal raw data for storage
 	rawJSON, err := json.Marshal(data)
 	if err != nil {
-		metrics.PublishTotal.WithLabelValues("error", "marshal_error").Inc()
-		slog.Error("failed to marshal raw data", "error", err)
+		g.metrics.PublishTotal.WithLabelValues("error", "marshal_error").Inc()
+		slog.ErrorContext(ctx, "failed to marshal raw data", "error", err)
 		cb(centrifuge.PublishReply{}, centrifuge.ErrorInternal)
 		return
 	}
 
 	// Construct message payload
 	message := StreamMessage{
-		ID:        messageID,
-		Type:      EventTypeMessage,
-		Channel:   channel,
-		WorkerID:  workerID,
-		UserID:    userID,
-		UserName:  userName,
-		Text:      strings.TrimSpace(text),
-		Timestamp: timestamp.Format(time.RFC3339Nano),
-		Raw:       string(rawJSON),
-		ClientID:  client.ID(),
+		SchemaVersion: g.config.StreamSchemaVersion,
+		ID:            messageID,
+		Type:          EventTypeMessage,
+		Channel:       channel,
+		WorkerID:      workerID,
+		UserID:        userID,
+		UserName:      userName,
+		Text:          strings.TrimSpace(text),
+		ContentType:   contentType,
+		Meta:          meta,
+		Timestamp:     timestamp.Format(time.RFC3339Nano),
+		Raw:           string(rawJSON),
+		ClientID:      client.ID(),
+		GatewayID:     g.instanceID,
+		Priority:      priority,
 	}
 
+	g.signMessage(&message)
+
 	// Marshal message payload
 	payload, err := json.Marshal(message)
 	if err != nil {
-		metrics.PublishTotal.WithLabelValues("error", "marshal_error").Inc()
-		slog.Error("failed to marshal message", "error", err)
+		g.metrics.PublishTotal.WithLabelValues("error", "marshal_error").Inc()
+		slog.ErrorContext(ctx, "failed to marshal message", "error", err)
 		cb(centrifuge.PublishReply{}, centrifuge.ErrorInternal)
 		return
 	}
 
-	// Write to worker's stream
-	_, err = g.redis.XAdd(ctx, streamKey, map[string]interface{}{
-		"payload": string(payload),
-	})
-	if err != nil {
-		metrics.PublishTotal.WithLabelValues("error", "redis_error").Inc()
-		slog.Error("failed to write to stream", "streamKey", streamKey, "error", err)
-		cb(centrifuge.PublishReply{}, centrifuge.ErrorInternal)
-		return
+	// Messages behind a non-empty queue wait their turn to keep per-client order
+	queue := g.clientQueueFor(client.ID())
+	queued := queue != nil && queue.len() > 0
+
+	// In degraded mode Redis is known to be down: queue without retrying
+	reason := "redis_error"
+	if !queued && queue != nil && g.RedisDegraded() {
+		queued = true
+		reason = "redis_degraded"
+	}
+
+	// Write to worker's stream, with the history of the message
+	history := g.historyPush(ctx, channel, userID, g.historyIDs.next(timestamp), payload)
+	if !queued {
+		g.prepareWorkerStreams(ctx, workerID)
+		_, err = g.redis.RetryXAddEntry(ctx, redis.StreamEntry{Stream: streamKey, Values: streamEntry(payload, traceContext), Push: history, Expire: g.router.RouteExpire(channel)}, g.config.RedisMaxPublishRetries)
+		if err != nil && queue == nil {
+			g.metrics.PublishTotal.WithLabelValues("error", "redis_error").Inc()
+			slog.ErrorContext(ctx, "failed to write to stream", "streamKey", streamKey, "error", err)
+			span.SetStatus(codes.Error, "stream write failed")
+			g.deadLetter(ctx, streamKey, payload, err, g.config.RedisMaxPublishRetries)
+			cb(centrifuge.PublishReply{}, centrifuge.ErrorInternal)
+			return
+		}
+		if err != nil {
+			slog.WarnContext(ctx, "failed to write to stream, queueing message", "streamKey", streamKey, "error", err)
+			queued = true
+		} else {
+			g.metrics.E2ELatency.Observe(time.Since(receivedAt).Seconds())
+		}
 	}
 
-	metrics.PublishTotal.WithLabelValues("success", "").Inc()
-	metrics.WebSocketMessagesTotal.WithLabelValues("inbound").Inc()
+	if queued {
+		g.enqueue(ctx, queue, queuedMessage{
+			id:           messageID,
+			requestID:    requestid.FromContext(ctx),
+			channel:      channel,
+			userID:       userID,
+			streamKey:    streamKey,
+			payload:      payload,
+			history:      history,
+			routeExpire:  g.router.RouteExpire(channel),
+			traceContext: traceContext,
+			receivedAt:   receivedAt,
+		})
+		g.metrics.PublishTotal.WithLabelValues("queued", reason).Inc()
+	} else {
+		g.metrics.PublishTotal.WithLabelValues("success", "").Inc()
+		g.incrChannelStat(ctx, channel, statsFieldMessages, 1)
+		g.historyCache.remove(channel)
+	}
+	if g.config.DuplicateTextWindow > 0 {
+		g.recordLastText(userID, channel, text, receivedAt)
+	}
+	if g.dedupFilter != nil {
+		g.recordPublished(ctx, userID, channel, text)
+	}
+	g.router.RecordChannelMessage(channel)
+	g.metrics.WebSocketMessagesTotal.WithLabelValues("inbound").Inc()
 
-	slog.Info("message published",
+	slog.InfoContext(ctx, "message published",
 		"messageId", messageID,
 		"streamKey", streamKey,
 		"workerId", workerID,
 		"channel", channel,
+		"queued", queued,
 	)
 
 	// Allow the publication to be broadcast to subscribers
 	cb(centrifuge.PublishReply{}, nil)
 }
 
+// clientUserName returns the name from the client's connection info
+func clientUserName(client *centrifuge.Client) string {
+	if info := client.Info(); len(info) > 0 {
+		var userInfo struct {
+			Name string `json:"name"`
+		}
+		if json.Unmarshal(info, &userInfo) == nil && userInfo.Name != "" {
+			return userInfo.Name
+		}
+	}
+	return "Anonymous"
+}
+
+// priorityFromData returns the priority field of publish data, defaulting
+// to routing.PriorityNormal; ok is false if it is not a valid priority
+func priorityFromData(data map[string]interface{}) (priority int, ok bool) {
+	v, present := data["priority"]
+	if !present {
+		return routing.PriorityNormal, true
+	}
+	f, isNumber := v.(float64)
+	if !isNumber || f != math.Trunc(f) || f < routing.PriorityLow || f > routing.PriorityHigh {
+		return 0, false
+	}
+	return int(f), true
+}
+
+// streamEntry returns the fields of a worker stream entry; traceContext is
+// omitted when empty
+func streamEntry(payload []byte, traceContext string) map[string]interface{} {
+	values := map[string]interface{}{"payload": string(payload)}
+	if traceContext != "" {
+		values[tracing.StreamField] = traceContext
+	}
+	return values
+}
+
 // handleDisconnect cleans up on client disconnect
-func (g *Gateway) handleDisconnect(client *centrifuge.Client, e centrifuge.DisconnectEvent) {
+func (g *Gateway) handleDisconnect(ctx context.Context, client *centrifuge.Client, e centrifuge.DisconnectEvent) {
 	clientID := client.ID()
 	userID := client.UserID()
 
-	metrics.WebSocketConnections.Dec()
+	g.metrics.WebSocketConnections.Dec()
+	g.ipLimiter.release(clientIPFromContext(ctx))
+	if isSockJSTransport(client.Transport().Name()) {
+		g.metrics.SockJSConnections.Dec()
+	}
 
 	// Get connection metadata and calculate duration
 	g.connectionsMu.Lock()
 	meta, ok := g.connections[clientID]
 	if ok {
+		meta.drain()
+		if meta.idleTimer != nil {
+			meta.idleTimer.Stop()
+		}
 		duration := time.Since(meta.connectTime)
-		metrics.ConnectionDuration.Observe(duration.Seconds())
+		g.metrics.ConnectionDuration.Observe(duration.Seconds())
 		delete(g.connections, clientID)
 	}
 	g.connectionsMu.Unlock()
+	g.subscriptionCount.Delete(clientID)
+
+	// Queued messages are not written once their client is gone
+	if ok && meta.queue != nil {
+		if n := meta.queue.drain(); n > 0 {
+			slog.WarnContext(ctx, "discarded queued messages on disconnect", "clientId", clientID, "userId", userID, "count", n)
+		}
+	}
 
 	// Track user's last disconnect time for reconnection detection
 	g.recentUsersMu.Lock()
@@ -556,21 +1493,20 @@ func (g *Gateway) handleDisconnect(client *centrifuge.Client, e centrifuge.Disco
 	g.recentUsersMu.Unlock()
 
 	// Determine if this disconnect is likely to result in a reconnection
-	// Codes < 3000 are typically reconnectable
-	isReconnectable := e.Disconnect.Code < 3000
+	reconnectable := isReconnectable(e.Disconnect)
 
 	// Record disconnect metrics with reason and code
-	metrics.DisconnectTotal.WithLabelValues(
+	g.metrics.DisconnectTotal.WithLabelValues(
 		e.Disconnect.Reason,
 		fmt.Sprintf("%d", e.Disconnect.Code),
-		fmt.Sprintf("%t", isReconnectable),
+		fmt.Sprintf("%t", reconnectable),
 	).Inc()
 
-	slog.Info("client disconnected",
+	slog.InfoContext(ctx, "client disconnected",
 		"clientId", clientID,
 		"userId", userID,
 		"reason", e.Disconnect.Reason,
 		"code", e.Disconnect.Code,
-		"reconnect", isReconnectable,
+		"reconnect", reconnectable,
 	)
 }
@@ -1,11 +1,24 @@
 package gateway
 
 import (
+	"context"
 	"testing"
+	"time"
+
+	"github.com/centrifugal/centrifuge"
+	goredis "github.com/redis/go-redis/v9"
+
+	"realtime-message-gateway/internal/config"
+	"realtime-message-gateway/internal/routing"
+	"realtime-message-gateway/internal/tracing"
 )
 
 func TestIsValidChannel(t *testing.T) {
-	gw := &Gateway{}
+	gw := &Gateway{
+		config: &config.Config{},
+		router: routing.NewRouter(nil, 0, routing.WithChannelNameSanitizer(routing.NormalizeChannelName)),
+	}
+	t.Cleanup(func() { gw.router.Close() })
 
 	tests := []struct {
 		name    string
@@ -18,12 +31,20 @@ func TestIsValidChannel(t *testing.T) {
 		{"room channel", "chat:room-abc", "user-123", true},
 		{"room with numbers", "chat:room-123", "user-123", true},
 		{"own user channel", "user:user-123", "user-123", true},
+		{"own user channel with non-canonical id", "user:Alice_B", "Alice_B", true},
+		{"room channel with non-canonical id", "chat:room-My_Room", "user-123", true},
 
 		// Invalid channels
+		{"room channel spelled differently", "chat:Room 1", "user-123", false},
+		{"namespace spelled differently", "CHAT:abc", "user-123", false},
+		{"user prefix spelled differently", "User:other-user", "user-123", false},
+		{"own user channel with prefix spelled differently", "User:user-123", "user-123", false},
+		{"own user channel spelled differently", "user:USER-123", "user-123", false},
 		{"other user channel", "user:other-user", "user-123", false},
 		{"invalid prefix", "invalid:channel", "user-123", false},
 		{"random channel", "random", "user-123", false},
 		{"empty channel", "", "user-123", false},
+		{"echo channel without ECHO_ENABLED", "echo:abc", "user-123", false},
 	}
 
 	for _, tt := range tests {
@@ -35,3 +56,239 @@ func TestIsValidChannel(t *testing.T) {
 		})
 	}
 }
+
+func TestIsValidChannelPatterns(t *testing.T) {
+	patterns, err := compileChannelPatterns([]string{`^chat$`, `^chat:room-[a-z0-9-]+$`, `^user:[a-z0-9-]+$`, `^team:[0-9]+$`})
+	if err != nil {
+		t.Fatalf("compileChannelPatterns() error = %v", err)
+	}
+	gw := &Gateway{config: &config.Config{}, router: routing.NewRouter(nil, 0), channelPatterns: patterns}
+	t.Cleanup(func() { gw.router.Close() })
+
+	tests := []struct {
+		name    string
+		channel string
+		userID  string
+		want    bool
+	}{
+		{"global chat", "chat", "user-123", true},
+		{"room channel", "chat:room-abc", "user-123", true},
+		{"custom namespace", "team:42", "user-123", true},
+		{"own user channel", "user:user-123", "user-123", true},
+
+		{"room without prefix", "chat:abc", "user-123", false},
+		{"uppercase room", "chat:room-ABC", "user-123", false},
+		{"other user channel", "user:other-user", "user-123", false},
+		{"non-numeric team", "team:abc", "user-123", false},
+		{"empty channel", "", "user-123", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := gw.isValidChannel(tt.channel, tt.userID); got != tt.want {
+				t.Errorf("isValidChannel(%q, %q) = %v, want %v", tt.channel, tt.userID, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompileChannelPatternsInvalid(t *testing.T) {
+	if _, err := compileChannelPatterns([]string{`^chat$`, `^chat:(room`}); err == nil {
+		t.Error("compileChannelPatterns() error = nil, want error for unbalanced group")
+	}
+}
+
+func TestIsPrivateChannel(t *testing.T) {
+	gw := &Gateway{config: &config.Config{PrivateChannelPrefix: "private:"}}
+
+	tests := []struct {
+		name    string
+		channel string
+		want    bool
+	}{
+		{"private channel", "private:room-abc", true},
+		{"room channel", "chat:room-abc", false},
+		{"global chat", "chat", false},
+		{"prefix without separator", "private", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := gw.isPrivateChannel(tt.channel)
+			if got != tt.want {
+				t.Errorf("isPrivateChannel(%q) = %v, want %v", tt.channel, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifySubscriptionToken(t *testing.T) {
+	const secret = "test-secret"
+	token := SignSubscriptionToken(secret, "client-1", "private:room-abc")
+
+	tests := []struct {
+		name     string
+		secret   string
+		clientID string
+		channel  string
+		token    string
+		want     bool
+	}{
+		{"valid token", secret, "client-1", "private:room-abc", token, true},
+		{"other client", secret, "client-2", "private:room-abc", token, false},
+		{"other channel", secret, "client-1", "private:room-xyz", token, false},
+		{"wrong secret", "other-secret", "client-1", "private:room-abc", token, false},
+		{"empty token", secret, "client-1", "private:room-abc", "", false},
+		{"empty secret", "", "client-1", "private:room-abc", SignSubscriptionToken("", "client-1", "private:room-abc"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := verifySubscriptionToken(tt.secret, tt.clientID, tt.channel, tt.token)
+			if got != tt.want {
+				t.Errorf("verifySubscriptionToken() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStreamEntry(t *testing.T) {
+	payload := []byte(`{"id":"m1"}`)
+
+	if got := streamEntry(payload, ""); len(got) != 1 || got["payload"] != string(payload) {
+		t.Errorf("streamEntry() without trace context = %v", got)
+	}
+
+	got := streamEntry(payload, "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	if got[tracing.StreamField] != "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01" {
+		t.Errorf("streamEntry()[%q] = %v", tracing.StreamField, got[tracing.StreamField])
+	}
+}
+
+func TestPriorityFromData(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   map[string]interface{}
+		want   int
+		wantOK bool
+	}{
+		{"absent", map[string]interface{}{"text": "hi"}, routing.PriorityNormal, true},
+		{"low", map[string]interface{}{"priority": 0.0}, routing.PriorityLow, true},
+		{"high", map[string]interface{}{"priority": 2.0}, routing.PriorityHigh, true},
+		{"out of range", map[string]interface{}{"priority": 3.0}, 0, false},
+		{"negative", map[string]interface{}{"priority": -1.0}, 0, false},
+		{"fractional", map[string]interface{}{"priority": 1.5}, 0, false},
+		{"string", map[string]interface{}{"priority": "high"}, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := priorityFromData(tt.data)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("priorityFromData() = %d, %v, want %d, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestPublishRefreshesChannelRoute(t *testing.T) {
+	tests := []struct {
+		name    string
+		publish func(t *testing.T, gw *Gateway, client *centrifuge.Client) error
+	}{
+		{"publish", func(t *testing.T, gw *Gateway, client *centrifuge.Client) error {
+			return publishAndWait(gw, client, "chat", `{"text":"again"}`)
+		}},
+		{"fan-out", func(t *testing.T, gw *Gateway, client *centrifuge.Client) error {
+			return publishAndWait(gw, client, "chat:other", `{"text":"again","channels":["chat"]}`)
+		}},
+		{"batch", func(t *testing.T, gw *Gateway, client *centrifuge.Client) error {
+			_, err := gw.PublishBatch(context.Background(), "chat", []BatchMessage{{Text: "again"}})
+			return err
+		}},
+		{"client queue", func(t *testing.T, gw *Gateway, client *centrifuge.Client) error {
+			queue := newClientQueue(4)
+			gw.connectionsMu.Lock()
+			gw.connections[client.ID()].queue = queue
+			gw.connectionsMu.Unlock()
+
+			// In degraded mode the publish is queued, then flushed on recovery
+			gw.redisHealth.degraded.Store(true)
+			err := publishAndWait(gw, client, "chat", `{"text":"again"}`)
+			gw.redisHealth.degraded.Store(false)
+			if err != nil {
+				return err
+			}
+			gw.flushClientQueues(context.Background())
+			if n := queue.len(); n != 0 {
+				t.Errorf("queue length after flush = %d, want 0", n)
+			}
+			return nil
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gw := NewTestGateway(t, WithGatewayOptions(func(o *gatewayOptions) {
+				o.cfg.ChannelRouteTTL = time.Hour
+			}))
+			client := connectTestClient(t, gw)
+
+			opt, err := goredis.ParseURL(gw.config.RedisURL)
+			if err != nil {
+				t.Fatalf("ParseURL() error = %v", err)
+			}
+			rdb := goredis.NewClient(opt)
+			defer rdb.Close()
+			ctx := context.Background()
+			routeKey := routing.ChannelRoutePrefix + "chat"
+
+			if err := publishAndWait(gw, client, "chat", `{"text":"hi"}`); err != nil {
+				t.Fatalf("publish error = %v", err)
+			}
+			if ttl := rdb.TTL(ctx, routeKey).Val(); ttl <= 59*time.Minute {
+				t.Fatalf("route TTL after first publish = %s, want about 1h", ttl)
+			}
+
+			// Let most of the TTL pass, then publish again
+			if err := rdb.Expire(ctx, routeKey, time.Minute).Err(); err != nil {
+				t.Fatalf("Expire() error = %v", err)
+			}
+			if err := tt.publish(t, gw, client); err != nil {
+				t.Fatalf("publish error = %v", err)
+			}
+			if ttl := rdb.TTL(ctx, routeKey).Val(); ttl <= 59*time.Minute {
+				t.Errorf("route TTL after second publish = %s, want it reset to about 1h", ttl)
+			}
+		})
+	}
+}
+
+func TestPublishCancelledContext(t *testing.T) {
+	gw := NewTestGateway(t)
+	client := connectTestClient(t, gw)
+	streamKey := routing.GetWorkerStreamKey("worker-0", routing.PriorityNormal)
+
+	// Route the channel first so only the stream write sees the cancellation
+	if err := publishAndWait(gw, client, "chat", `{"text":"first"}`); err != nil {
+		t.Fatalf("publish error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var replyErr error
+	gw.handlePublish(ctx, client, centrifuge.PublishEvent{Channel: "chat", Data: []byte(`{"text":"second"}`)}, func(_ centrifuge.PublishReply, err error) {
+		replyErr = err
+	})
+	if replyErr == nil {
+		t.Error("publish with a cancelled context succeeded")
+	}
+
+	length, err := gw.redis.XLen(context.Background(), streamKey)
+	if err != nil {
+		t.Fatalf("XLen() error = %v", err)
+	}
+	if length != 1 {
+		t.Errorf("stream length = %d, want 1: the cancelled write must not be added", length)
+	}
+}
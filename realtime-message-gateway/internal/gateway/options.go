@@ -0,0 +1,97 @@
+package gateway
+
+import (
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"realtime-message-gateway/internal/config"
+	"realtime-message-gateway/internal/redis"
+	"realtime-message-gateway/internal/routing"
+)
+
+// defaultReconnectWindow is how soon after a disconnect a connection of the
+// same user counts as a reconnect
+const defaultReconnectWindow = 60 * time.Second
+
+// gatewayOptions collects the settings applied by Option
+type gatewayOptions struct {
+	cfg             config.Config
+	redis           *redis.Client
+	router          *routing.Router
+	sanitizer       Sanitizer
+	archiver        Archiver
+	metricsRegistry prometheus.Registerer
+	reconnectWindow time.Duration
+}
+
+// Option configures a Gateway created by NewGateway
+type Option func(*gatewayOptions)
+
+// WithConfig sets all settings from cfg, replacing those set by earlier
+// options. The gateway keeps its own copy, so options after WithConfig
+// override single settings without changing cfg.
+func WithConfig(cfg *config.Config) Option {
+	return func(o *gatewayOptions) {
+		o.cfg = *cfg
+	}
+}
+
+// ErrNoRedis is returned by NewGateway when no Redis client is set with
+// WithRedis
+var ErrNoRedis = errors.New("gateway requires a Redis client")
+
+// WithRedis sets the Redis client used for routing, streams and presence.
+// It is required.
+func WithRedis(client *redis.Client) Option {
+	return func(o *gatewayOptions) {
+		o.redis = client
+	}
+}
+
+// WithMaxTextLength sets the maximum published text length
+func WithMaxTextLength(n int) Option {
+	return func(o *gatewayOptions) {
+		o.cfg.MaxTextLength = n
+	}
+}
+
+// WithReconnectWindow sets how soon after a disconnect a connection of the
+// same user counts as a reconnect
+func WithReconnectWindow(d time.Duration) Option {
+	return func(o *gatewayOptions) {
+		o.reconnectWindow = d
+	}
+}
+
+// WithRouter replaces the router NewGateway creates from the config, so
+// its RouteCacheTTL and Region settings are not applied
+func WithRouter(r *routing.Router) Option {
+	return func(o *gatewayOptions) {
+		o.router = r
+	}
+}
+
+// WithSanitizer replaces the DefaultSanitizer applied to published text
+func WithSanitizer(s Sanitizer) Option {
+	return func(o *gatewayOptions) {
+		o.sanitizer = s
+	}
+}
+
+// WithArchiver replaces the archiver of delivered outbound messages that
+// NewGateway creates from ARCHIVE_ENABLED
+func WithArchiver(a Archiver) Option {
+	return func(o *gatewayOptions) {
+		o.archiver = a
+	}
+}
+
+// WithMetricsRegistry registers the gateway's metrics, including those of
+// the router NewGateway creates, with r instead of the global registry
+func WithMetricsRegistry(r prometheus.Registerer) Option {
+	return func(o *gatewayOptions) {
+		o.metricsRegistry = r
+	}
+}
@@ -0,0 +1,103 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"realtime-message-gateway/internal/config"
+	"realtime-message-gateway/internal/metrics"
+	"realtime-message-gateway/internal/routing"
+)
+
+func TestNewGatewayOptions(t *testing.T) {
+	cfg := &config.Config{MaxTextLength: 100, ConsumerGroupName: "group"}
+	router := routing.NewRouter(nil, time.Second)
+	t.Cleanup(func() { router.Close() })
+	redisClient := newTestRedisClient(t)
+
+	gw, err := NewGateway(WithConfig(cfg), WithRedis(redisClient), WithMaxTextLength(10), WithReconnectWindow(time.Second), WithRouter(router))
+	if err != nil {
+		t.Fatalf("NewGateway() error = %v", err)
+	}
+
+	if gw.config.MaxTextLength != 10 || gw.config.ConsumerGroupName != "group" {
+		t.Errorf("config = %+v, want MaxTextLength 10 and the other settings of cfg", *gw.config)
+	}
+	if cfg.MaxTextLength != 100 {
+		t.Errorf("cfg.MaxTextLength = %d, WithMaxTextLength changed the caller's config", cfg.MaxTextLength)
+	}
+	if _, err := gw.sanitizer.Sanitize("longer than ten"); err == nil {
+		t.Error("default sanitizer accepted text longer than WithMaxTextLength")
+	}
+	if gw.reconnectWindow != time.Second {
+		t.Errorf("reconnectWindow = %s, want 1s", gw.reconnectWindow)
+	}
+	if gw.router != router {
+		t.Error("router is not the one passed to WithRouter")
+	}
+
+	gw, err = NewGateway(WithMaxTextLength(10), WithConfig(cfg), WithRedis(redisClient))
+	if err != nil {
+		t.Fatalf("NewGateway() error = %v", err)
+	}
+	if gw.config.MaxTextLength != 100 {
+		t.Errorf("MaxTextLength = %d, want WithConfig to override earlier options", gw.config.MaxTextLength)
+	}
+	if gw.reconnectWindow != defaultReconnectWindow {
+		t.Errorf("reconnectWindow = %s, want default %s", gw.reconnectWindow, defaultReconnectWindow)
+	}
+}
+
+func TestNewGatewayWithoutRedis(t *testing.T) {
+	if _, err := NewGateway(WithConfig(&config.Config{})); !errors.Is(err, ErrNoRedis) {
+		t.Errorf("NewGateway() without WithRedis error = %v, want %v", err, ErrNoRedis)
+	}
+}
+
+func TestShutdownLeavesInjectedRouter(t *testing.T) {
+	router := routing.NewRouter(nil, time.Second)
+	t.Cleanup(func() { router.Close() })
+
+	gw := NewTestGateway(t, WithGatewayOptions(WithRouter(router)))
+	if err := gw.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	select {
+	case <-router.Done():
+		t.Error("Shutdown closed the router passed to WithRouter")
+	default:
+	}
+}
+
+func TestNewGatewayMetricsRegistry(t *testing.T) {
+	// Two gateways in one process, each with its own registry
+	redisClient := newTestRedisClient(t)
+	for range 2 {
+		registry := prometheus.NewRegistry()
+		gw, err := NewGateway(WithConfig(&config.Config{}), WithRedis(redisClient), WithMetricsRegistry(registry))
+		if err != nil {
+			t.Fatalf("NewGateway() error = %v", err)
+		}
+		if gw.metrics == metrics.Default {
+			t.Fatal("gateway uses the default metrics with WithMetricsRegistry")
+		}
+
+		before := testutil.ToFloat64(metrics.Default.LoadShedTotal)
+		gw.metrics.LoadShedTotal.Inc()
+		if got := testutil.ToFloat64(metrics.Default.LoadShedTotal); got != before {
+			t.Errorf("default gateway_loadshed_total = %v, want unchanged %v", got, before)
+		}
+
+		// The router created by NewGateway registers with the same registry
+		for _, name := range []string{"gateway_loadshed_total", "gateway_route_cache_hits_total"} {
+			if n, err := testutil.GatherAndCount(registry, name); err != nil || n != 1 {
+				t.Errorf("GatherAndCount(%s) = %d, %v, want 1", name, n, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"realtime-message-gateway/internal/routing"
+)
+
+// outboundBatchSize is the maximum number of entries read per XREADGROUP call
+const outboundBatchSize = 100
+
+var (
+	// errNoOutboundTarget is returned when an outbound entry has no channel, client or user
+	errNoOutboundTarget = errors.New("outbound entry has no channel, clientId or userId")
+	// errClientNotFound is returned when an outbound target is not connected to this gateway
+	errClientNotFound = errors.New("client not connected to this gateway")
+)
+
+// outboundConsumer reads messages pushed by workers to this instance's stream
+// (messages:gateway:{instanceID}) as a member of the consumer group and
+// delivers them to local clients. Each entry carries a payload and one of
+// channel, clientId or userId.
+//
+// Delivery is at least once: entries are acknowledged once handled, and
+// entries left pending by a previous run or a failed delivery are read
+// again before new ones. Delivered entries are archived once acknowledged.
+func (g *Gateway) outboundConsumer(ctx context.Context) {
+	defer g.wg.Done()
+
+	streamKey := routing.GetGatewayStreamKey(g.instanceID)
+	group := g.config.ConsumerGroupName
+
+	slog.Info("outbound consumer started", "streamKey", streamKey, "group", group)
+
+	// "0" reads this consumer's pending entries, ">" new entries
+	readID := "0"
+	groupReady := false
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("outbound consumer stopped", "streamKey", streamKey)
+			return
+		default:
+		}
+
+		if !groupReady {
+			if err := g.redis.XGroupCreateMkStream(ctx, streamKey, group, "0"); err != nil {
+				if ctx.Err() == nil {
+					slog.Error("failed to create outbound consumer group", "streamKey", streamKey, "error", err)
+					sleepCtx(ctx, time.Second)
+				}
+				continue
+			}
+			groupReady = true
+		}
+
+		entries, err := g.redis.XReadGroup(ctx, streamKey, group, g.instanceID, readID, outboundBatchSize, g.config.OutboundStreamBlock)
+		if err != nil {
+			if ctx.Err() == nil {
+				slog.Error("failed to read outbound stream", "streamKey", streamKey, "error", err)
+				sleepCtx(ctx, time.Second)
+			}
+			continue
+		}
+
+		// Pending entries are exhausted: switch to new entries
+		if readID != ">" && len(entries) == 0 {
+			readID = ">"
+			continue
+		}
+
+		retry := false
+		var delivered []string
+		for _, entry := range entries {
+			if readID != ">" {
+				readID = entry.ID
+			}
+			err := g.deliverOutbound(entry.ID, entry.Values)
+			if err != nil && !isPermanentOutboundError(err) {
+				retry = true
+				continue
+			}
+			if err := g.redis.XAck(ctx, streamKey, group, entry.ID); err != nil {
+				slog.Warn("failed to acknowledge outbound entry", "entryId", entry.ID, "error", err)
+				continue
+			}
+			if err == nil {
+				payload, _ := entry.Values["payload"].(string)
+				delivered = append(delivered, payload)
+			}
+		}
+		g.archiveOutbound(ctx, delivered)
+
+		// Unacknowledged entries stay pending: read them again after a pause
+		if retry {
+			readID = "0"
+			sleepCtx(ctx, time.Second)
+		}
+	}
+}
+
+// isPermanentOutboundError reports whether retrying the delivery of an
+// outbound entry cannot succeed
+func isPermanentOutboundError(err error) bool {
+	return errors.Is(err, errNoOutboundTarget) || errors.Is(err, errClientNotFound)
+}
+
+// sleepCtx waits for d or until ctx is cancelled
+func sleepCtx(ctx context.Context, d time.Duration) {
+	select {
+	case <-ctx.Done():
+	case <-time.After(d):
+	}
+}
+
+// deliverOutbound sends a single outbound entry to its target
+func (g *Gateway) deliverOutbound(entryID string, values map[string]interface{}) error {
+	channel, _ := values["channel"].(string)
+	clientID, _ := values["clientId"].(string)
+	userID, _ := values["userId"].(string)
+	payload, _ := values["payload"].(string)
+
+	var (
+		target string
+		err    error
+	)
+	switch {
+	case channel != "":
+		target = "channel"
+		_, err = g.node.Publish(channel, []byte(payload))
+	case clientID != "":
+		target = "client"
+		err = g.sendToClient(clientID, []byte(payload))
+	case userID != "":
+		target = "user"
+		err = g.sendToUser(userID, []byte(payload))
+	default:
+		target = "unknown"
+		err = errNoOutboundTarget
+	}
+
+	if err != nil {
+		g.metrics.OutboundTotal.WithLabelValues(target, "error").Inc()
+		slog.Warn("failed to deliver outbound message",
+			"entryId", entryID,
+			"target", target,
+			"channel", channel,
+			"clientId", clientID,
+			"userId", userID,
+			"error", err,
+		)
+		return err
+	}
+
+	g.metrics.OutboundTotal.WithLabelValues(target, "success").Inc()
+	g.metrics.WebSocketMessagesTotal.WithLabelValues("outbound").Inc()
+	return nil
+}
+
+// sendToClient sends an async message to a single local connection
+func (g *Gateway) sendToClient(clientID string, data []byte) error {
+	g.connectionsMu.RLock()
+	meta, ok := g.connections[clientID]
+	g.connectionsMu.RUnlock()
+	if !ok || meta.client == nil {
+		return errClientNotFound
+	}
+	return meta.client.Send(data)
+}
+
+// sendToUser sends an async message to all local connections of a user
+func (g *Gateway) sendToUser(userID string, data []byte) error {
+	clients := g.node.Hub().UserConnections(userID)
+	if len(clients) == 0 {
+		return errClientNotFound
+	}
+	for _, client := range clients {
+		if err := client.Send(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
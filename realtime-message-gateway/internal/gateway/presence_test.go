@@ -0,0 +1,90 @@
+package gateway
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestPage(t *testing.T) {
+	users := make([]PresenceInfo, 5)
+	for i := range users {
+		users[i] = PresenceInfo{ClientID: fmt.Sprintf("client-%d", i)}
+	}
+
+	tests := []struct {
+		name          string
+		offset, limit int
+		wantFirst     string
+		wantLen       int
+	}{
+		{"all", 0, 0, "client-0", 5},
+		{"first page", 0, 2, "client-0", 2},
+		{"middle page", 2, 2, "client-2", 2},
+		{"last partial page", 4, 2, "client-4", 1},
+		{"limit larger than total", 0, 500, "client-0", 5},
+		{"offset at end", 5, 2, "", 0},
+		{"offset past end", 10, 2, "", 0},
+		{"negative offset", -1, 2, "client-0", 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := page(users, tt.offset, tt.limit)
+			if got == nil {
+				t.Fatal("page() = nil, want non-nil slice")
+			}
+			if len(got) != tt.wantLen {
+				t.Fatalf("len(page()) = %d, want %d", len(got), tt.wantLen)
+			}
+			if tt.wantLen > 0 && got[0].ClientID != tt.wantFirst {
+				t.Errorf("first ClientID = %q, want %q", got[0].ClientID, tt.wantFirst)
+			}
+		})
+	}
+}
+
+func TestGetChannelPresencePagination(t *testing.T) {
+	gw := NewTestGateway(t)
+
+	const subscribers = 5
+	for i := 0; i < subscribers; i++ {
+		subscribeTestClient(connectTestClient(t, gw), 2, "chat")
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for gw.node.Hub().NumSubscribers("chat") < subscribers && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	var pages [][]PresenceInfo
+	for offset := 0; offset < subscribers; offset += 2 {
+		users, total, err := gw.GetChannelPresence("chat", offset, 2)
+		if err != nil {
+			t.Fatalf("GetChannelPresence() error = %v", err)
+		}
+		if total != subscribers {
+			t.Errorf("total = %d, want %d", total, subscribers)
+		}
+		pages = append(pages, users)
+	}
+
+	// Pages must not overlap and must cover every subscriber in order
+	seen := make(map[string]bool)
+	last := ""
+	for _, page := range pages {
+		for _, u := range page {
+			if seen[u.ClientID] {
+				t.Errorf("client %s returned twice", u.ClientID)
+			}
+			if u.ClientID < last {
+				t.Errorf("client %s out of order after %s", u.ClientID, last)
+			}
+			seen[u.ClientID] = true
+			last = u.ClientID
+		}
+	}
+	if len(seen) != subscribers {
+		t.Errorf("pages covered %d clients, want %d", len(seen), subscribers)
+	}
+}
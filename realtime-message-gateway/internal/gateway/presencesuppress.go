@@ -0,0 +1,83 @@
+package gateway
+
+import (
+	"context"
+	"time"
+
+	"github.com/centrifugal/centrifuge"
+)
+
+// pendingLeave is a leave event held back for reconnectWindow. Whoever
+// removes it from Gateway.pendingLeaves first, the timer or a join of the
+// same user and channel, decides whether it is published.
+type pendingLeave struct {
+	client  *centrifuge.Client
+	channel string
+	timer   *time.Timer
+}
+
+// isReconnectable reports whether clients reconnect after d: Centrifuge's
+// 3000-3499 and 4000-4499 ranges, and codes below 3000 sent by older
+// servers
+func isReconnectable(d centrifuge.Disconnect) bool {
+	return d.Code < 3500 || (d.Code >= 4000 && d.Code < 4500)
+}
+
+// pendingLeaveKey identifies the pending leave of a user in a channel.
+// Reconnects are matched by user ID only, so anonymous clients, whose ID
+// is generated per connection, never match and their leave and join are
+// both published after the window.
+func pendingLeaveKey(userID, channel string) string {
+	return userID + "\x00" + channel
+}
+
+// deferLeave holds back the leave event of client in channel for
+// reconnectWindow and publishes it then, unless cancelPendingLeave takes it
+// first. A newer leave of the same user and channel replaces an older one.
+// Once the gateway stops, leaves are published without delay.
+func (g *Gateway) deferLeave(client *centrifuge.Client, channel string) {
+	g.pendingLeavesMu.RLock()
+	defer g.pendingLeavesMu.RUnlock()
+	if g.pendingLeavesClosed {
+		g.publishPresenceEvent(g.ctx, client, channel, EventTypeLeave)
+		return
+	}
+
+	key := pendingLeaveKey(client.UserID(), channel)
+	leave := &pendingLeave{client: client, channel: channel}
+	g.pendingLeaves.Store(key, leave)
+	leave.timer = time.AfterFunc(g.reconnectWindow, func() {
+		g.pendingLeavesMu.RLock()
+		defer g.pendingLeavesMu.RUnlock()
+		if !g.pendingLeavesClosed && g.pendingLeaves.CompareAndDelete(key, leave) {
+			g.publishPresenceEvent(g.ctx, leave.client, leave.channel, EventTypeLeave)
+		}
+	})
+}
+
+// cancelPendingLeave drops the pending leave of userID in channel and
+// reports whether there was one; the join that reverses it is then not
+// published either
+func (g *Gateway) cancelPendingLeave(userID, channel string) bool {
+	_, ok := g.pendingLeaves.LoadAndDelete(pendingLeaveKey(userID, channel))
+	return ok
+}
+
+// flushPendingLeaves stops the timers of pending leave events, waiting for
+// those already publishing, and publishes the rest now, so they are not
+// lost when the gateway stops and none is published after the event bus
+// closes
+func (g *Gateway) flushPendingLeaves(ctx context.Context) {
+	g.pendingLeavesMu.Lock()
+	g.pendingLeavesClosed = true
+	g.pendingLeavesMu.Unlock()
+
+	g.pendingLeaves.Range(func(key, value any) bool {
+		if g.pendingLeaves.CompareAndDelete(key, value) {
+			leave := value.(*pendingLeave)
+			leave.timer.Stop()
+			g.publishPresenceEvent(ctx, leave.client, leave.channel, EventTypeLeave)
+		}
+		return true
+	})
+}
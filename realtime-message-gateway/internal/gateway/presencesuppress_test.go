@@ -0,0 +1,140 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/centrifugal/centrifuge"
+
+	"realtime-message-gateway/internal/routing"
+)
+
+func TestIsReconnectable(t *testing.T) {
+	tests := []struct {
+		d    centrifuge.Disconnect
+		want bool
+	}{
+		{centrifuge.DisconnectConnectionClosed, true},
+		{centrifuge.DisconnectForceNoReconnect, false},
+		{centrifuge.Disconnect{Code: uint32(ErrCodePlannedDisconnect)}, true},
+		{centrifuge.Disconnect{Code: 4500}, false},
+	}
+
+	for _, tt := range tests {
+		if got := isReconnectable(tt.d); got != tt.want {
+			t.Errorf("isReconnectable(%d) = %v, want %v", tt.d.Code, got, tt.want)
+		}
+	}
+}
+
+func TestSuppressJoinLeaveForReconnect(t *testing.T) {
+	closed := &centrifuge.DisconnectConnectionClosed
+	banned := &centrifuge.DisconnectForceNoReconnect
+
+	type push struct {
+		eventType  EventType
+		disconnect *centrifuge.Disconnect
+	}
+	tests := []struct {
+		name     string
+		suppress bool
+		pushes   []push
+		want     []EventType
+	}{
+		{"reconnect within window", true, []push{{EventTypeLeave, closed}, {EventTypeJoin, nil}}, nil},
+		{"no reconnect", true, []push{{EventTypeLeave, closed}}, []EventType{EventTypeLeave}},
+		{"not reconnectable", true, []push{{EventTypeLeave, banned}, {EventTypeJoin, nil}}, []EventType{EventTypeLeave, EventTypeJoin}},
+		{"unsubscribe without disconnect", true, []push{{EventTypeLeave, nil}}, []EventType{EventTypeLeave}},
+		{"disabled", false, []push{{EventTypeLeave, closed}, {EventTypeJoin, nil}}, []EventType{EventTypeLeave, EventTypeJoin}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gw := NewTestGateway(t)
+			gw.config.SuppressJoinLeaveForReconnect = tt.suppress
+			gw.reconnectWindow = 50 * time.Millisecond
+			client := connectTestClient(t, gw)
+			ctx := context.Background()
+
+			for _, p := range tt.pushes {
+				gw.pushPresenceEvent(ctx, client, "chat", p.eventType, p.disconnect)
+			}
+			time.Sleep(2 * gw.reconnectWindow)
+
+			if got := presenceEventTypes(t, gw, "chat"); !slices.Equal(got, tt.want) {
+				t.Errorf("presence events = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSuppressJoinLeaveAnonymousReconnect(t *testing.T) {
+	gw := NewTestGateway(t)
+	gw.config.SuppressJoinLeaveForReconnect = true
+	gw.reconnectWindow = 50 * time.Millisecond
+	ctx := context.Background()
+
+	// Anonymous clients get a new user ID per connection, so the reconnect
+	// does not match the pending leave
+	first := connectTestClient(t, gw)
+	second := connectTestClient(t, gw)
+	if first.UserID() == second.UserID() {
+		t.Fatalf("anonymous connections share user ID %q", first.UserID())
+	}
+	gw.pushPresenceEvent(ctx, first, "chat", EventTypeLeave, &centrifuge.DisconnectConnectionClosed)
+	gw.pushPresenceEvent(ctx, second, "chat", EventTypeJoin, nil)
+	time.Sleep(2 * gw.reconnectWindow)
+
+	want := []EventType{EventTypeJoin, EventTypeLeave}
+	if got := presenceEventTypes(t, gw, "chat"); !slices.Equal(got, want) {
+		t.Errorf("presence events = %v, want %v", got, want)
+	}
+}
+
+func TestShutdownFlushesPendingLeaves(t *testing.T) {
+	gw := NewTestGateway(t)
+	gw.config.SuppressJoinLeaveForReconnect = true
+	gw.reconnectWindow = time.Hour
+	client := connectTestClient(t, gw)
+
+	gw.pushPresenceEvent(context.Background(), client, "chat", EventTypeLeave, &centrifuge.DisconnectConnectionClosed)
+	// Shutdown flushes the leave and waits for the event bus to write it
+	if err := gw.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+
+	if got := presenceEventTypes(t, gw, "chat"); !slices.Equal(got, []EventType{EventTypeLeave}) {
+		t.Errorf("presence events = %v, want the flushed leave", got)
+	}
+	if gw.cancelPendingLeave(client.UserID(), "chat") {
+		t.Error("leave still pending after flush")
+	}
+}
+
+// presenceEventTypes returns the types of the events in the normal stream
+// of the worker of channel, oldest first
+func presenceEventTypes(t *testing.T, gw *Gateway, channel string) []EventType {
+	t.Helper()
+	ctx := context.Background()
+	workerID, err := gw.router.GetWorkerForChannel(ctx, channel)
+	if err != nil {
+		t.Fatalf("GetWorkerForChannel() error = %v", err)
+	}
+	entries, err := gw.redis.XRange(ctx, routing.GetWorkerStreamKey(workerID, routing.PriorityNormal), "-", "+", 0)
+	if err != nil {
+		t.Fatalf("XRange() error = %v", err)
+	}
+
+	var types []EventType
+	for _, entry := range entries {
+		var msg StreamMessage
+		if err := json.Unmarshal([]byte(entry.Payload), &msg); err != nil {
+			t.Fatalf("Unmarshal() error = %v", err)
+		}
+		types = append(types, msg.Type)
+	}
+	return types
+}
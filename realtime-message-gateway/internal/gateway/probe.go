@@ -0,0 +1,27 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrNodeNotRunning is returned by Probe before Run and after Shutdown
+var ErrNodeNotRunning = errors.New("centrifuge node not running")
+
+// Probe reports whether the gateway can serve requests: the Centrifuge node
+// is running and Redis answers a ping. During the first ReadinessGracePeriod
+// after NewGateway an unreachable Redis does not fail the probe.
+func (g *Gateway) Probe(ctx context.Context) error {
+	if !g.running.Load() {
+		return ErrNodeNotRunning
+	}
+	if err := g.redis.Ping(ctx); err != nil {
+		if time.Since(g.startedAt) < g.config.ReadinessGracePeriod {
+			return nil
+		}
+		return fmt.Errorf("redis ping: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+
+	"realtime-message-gateway/internal/config"
+	"realtime-message-gateway/internal/redis"
+)
+
+func TestProbe(t *testing.T) {
+	mr := miniredis.RunT(t)
+	redisClient, err := redis.NewClient(&config.Config{RedisURL: "redis://" + mr.Addr()})
+	if err != nil {
+		t.Fatalf("redis.NewClient() error = %v", err)
+	}
+	t.Cleanup(func() { redisClient.Close() })
+
+	tests := []struct {
+		name        string
+		running     bool
+		redisDown   bool
+		gracePeriod time.Duration
+		wantErr     bool
+	}{
+		{"ready", true, false, 0, false},
+		{"node not running", false, false, time.Minute, true},
+		{"redis down", true, true, 0, true},
+		{"redis down within grace period", true, true, time.Minute, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gw := &Gateway{
+				config:    &config.Config{ReadinessGracePeriod: tt.gracePeriod},
+				redis:     redisClient,
+				startedAt: time.Now(),
+			}
+			gw.running.Store(tt.running)
+			mr.SetError("")
+			if tt.redisDown {
+				mr.SetError("LOADING Redis is loading the dataset in memory")
+			}
+
+			err := gw.Probe(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Probe() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.running && !errors.Is(err, ErrNodeNotRunning) {
+				t.Errorf("Probe() error = %v, want %v", err, ErrNodeNotRunning)
+			}
+		})
+	}
+}
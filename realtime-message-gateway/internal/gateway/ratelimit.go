@@ -0,0 +1,36 @@
+package gateway
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"realtime-message-gateway/internal/config"
+	"realtime-message-gateway/internal/middleware"
+	"realtime-message-gateway/internal/redis"
+)
+
+// newPublishLimiter creates the limiter of RateLimiterBackend enforcing
+// PublishRateLimit, or returns nil when publishes are unlimited
+func newPublishLimiter(cfg *config.Config, redisClient *redis.Client) middleware.RateLimiter {
+	if cfg.PublishRateLimit <= 0 {
+		return nil
+	}
+	window := time.Duration(cfg.RateLimitWindowSeconds) * time.Second
+	if cfg.RateLimiterBackend == "redis" {
+		return middleware.NewRedisRateLimiter(redisClient, cfg.PublishRateLimit, window)
+	}
+	return middleware.NewLocalRateLimiter(cfg.PublishRateLimit, window)
+}
+
+// publishAllowed reports whether userID is within PublishRateLimit. A
+// failing limiter allows the publish, so a Redis outage does not stop
+// messaging.
+func (g *Gateway) publishAllowed(ctx context.Context, userID string) bool {
+	allowed, err := g.publishLimiter.Allow(ctx, userID)
+	if err != nil {
+		slog.WarnContext(ctx, "publish rate limiter failed", "userId", userID, "error", err)
+		return true
+	}
+	return allowed
+}
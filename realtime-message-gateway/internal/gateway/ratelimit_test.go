@@ -0,0 +1,47 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/centrifugal/centrifuge"
+)
+
+func TestPublishRateLimit(t *testing.T) {
+	for _, backend := range []string{"local", "redis"} {
+		t.Run(backend, func(t *testing.T) {
+			gw := NewTestGateway(t)
+			gw.config.PublishRateLimit = 2
+			gw.config.RateLimitWindowSeconds = 60
+			gw.config.RateLimiterBackend = backend
+			gw.publishLimiter = newPublishLimiter(gw.config, gw.redis)
+			alice := connectTestClient(t, gw)
+			bob := connectTestClient(t, gw)
+
+			for i := range 2 {
+				if err := publishAndWait(gw, alice, "chat", `{"text":"hello"}`); err != nil {
+					t.Fatalf("publish %d error = %v", i+1, err)
+				}
+			}
+			err := publishAndWait(gw, alice, "chat", `{"text":"hello"}`)
+			var replyErr *centrifuge.Error
+			if !errors.As(err, &replyErr) || replyErr.Code != uint32(ErrCodeRateLimited) {
+				t.Errorf("publish over limit error = %v, want code %d", err, ErrCodeRateLimited)
+			}
+			if err := publishAndWait(gw, bob, "chat", `{"text":"hello"}`); err != nil {
+				t.Errorf("publish of another user error = %v", err)
+			}
+
+			// Only the Redis backend counts the publishes of other instances
+			other := newPublishLimiter(gw.config, gw.redis)
+			allowed, err := other.Allow(context.Background(), alice.UserID())
+			if err != nil {
+				t.Fatalf("Allow() error = %v", err)
+			}
+			if want := backend == "local"; allowed != want {
+				t.Errorf("another instance allows alice = %v, want %v", allowed, want)
+			}
+		})
+	}
+}
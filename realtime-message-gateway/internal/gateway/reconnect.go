@@ -0,0 +1,49 @@
+package gateway
+
+import (
+	"encoding/json"
+
+	"github.com/centrifugal/centrifuge"
+)
+
+// reconnectPolicyPayload is the Reason of planned disconnects. Centrifuge has
+// no field for reconnect data, so clients parse the close reason as JSON:
+//
+//	{"initialDelayMs": 500, "maxDelayMs": 20000, "multiplier": 2}
+//
+// The first reconnect attempt waits initialDelayMs; each failed attempt
+// multiplies the delay by multiplier, capped at maxDelayMs. The payload must
+// stay under the 123-byte WebSocket close reason limit.
+type reconnectPolicyPayload struct {
+	InitialDelayMs int64   `json:"initialDelayMs"`
+	MaxDelayMs     int64   `json:"maxDelayMs"`
+	Multiplier     float64 `json:"multiplier"`
+}
+
+// PlannedDisconnect returns the disconnect sent to clients on planned
+// disconnects, carrying the configured reconnect policy
+func (g *Gateway) PlannedDisconnect() centrifuge.Disconnect {
+	policy := g.config.ReconnectPolicy
+	reason, _ := json.Marshal(reconnectPolicyPayload{
+		InitialDelayMs: policy.InitialDelay.Milliseconds(),
+		MaxDelayMs:     policy.MaxDelay.Milliseconds(),
+		Multiplier:     policy.Multiplier,
+	})
+	return centrifuge.Disconnect{
+		Code:   uint32(ErrCodePlannedDisconnect),
+		Reason: string(reason),
+	}
+}
+
+// disconnectAll drains and disconnects every local client with d
+func (g *Gateway) disconnectAll(d centrifuge.Disconnect) {
+	for clientID, client := range g.node.Hub().Connections() {
+		g.connectionsMu.RLock()
+		meta, ok := g.connections[clientID]
+		g.connectionsMu.RUnlock()
+		if ok {
+			meta.drain()
+		}
+		client.Disconnect(d)
+	}
+}
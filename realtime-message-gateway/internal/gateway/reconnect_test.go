@@ -0,0 +1,38 @@
+package gateway
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"realtime-message-gateway/internal/config"
+)
+
+func TestPlannedDisconnect(t *testing.T) {
+	gw := &Gateway{config: &config.Config{ReconnectPolicy: config.ReconnectPolicy{
+		InitialDelay: 500 * time.Millisecond,
+		MaxDelay:     20 * time.Second,
+		Multiplier:   1.5,
+	}}}
+
+	d := gw.PlannedDisconnect()
+	if d.Code != uint32(ErrCodePlannedDisconnect) {
+		t.Errorf("Code = %d, want %d", d.Code, ErrCodePlannedDisconnect)
+	}
+	// Codes 4000-4499 tell Centrifuge clients to reconnect
+	if d.Code < 4000 || d.Code >= 4500 {
+		t.Errorf("Code = %d is not a reconnect code", d.Code)
+	}
+	if len(d.Reason) > 123 {
+		t.Errorf("Reason is %d bytes, exceeds the WebSocket close reason limit", len(d.Reason))
+	}
+
+	var got reconnectPolicyPayload
+	if err := json.Unmarshal([]byte(d.Reason), &got); err != nil {
+		t.Fatalf("Reason is not JSON: %v", err)
+	}
+	want := reconnectPolicyPayload{InitialDelayMs: 500, MaxDelayMs: 20000, Multiplier: 1.5}
+	if got != want {
+		t.Errorf("Reason = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,85 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"path"
+	"slices"
+	"time"
+)
+
+// ErrInvalidRecoverSince is returned for subscribe data with a malformed
+// since timestamp
+var ErrInvalidRecoverSince = errors.New("since must be an RFC 3339 timestamp")
+
+// subscribeRequestData is the optional JSON data a client sends with a
+// subscribe. Since is the time of the last message the client received;
+// messages published after it are replayed on recoverable channels.
+type subscribeRequestData struct {
+	Since string `json:"since"`
+}
+
+// subscribeReplyData is the JSON data sent to the client in a subscribe reply
+type subscribeReplyData struct {
+	Metadata  json.RawMessage `json:"metadata,omitempty"`
+	Recovered []StreamMessage `json:"recovered,omitempty"`
+}
+
+// recoverSince returns the since timestamp of subscribe data, or the zero
+// time if the client did not ask for recovery
+func recoverSince(data []byte) (time.Time, error) {
+	if len(data) == 0 {
+		return time.Time{}, nil
+	}
+	var req subscribeRequestData
+	if err := json.Unmarshal(data, &req); err != nil || req.Since == "" {
+		return time.Time{}, nil
+	}
+	since, err := time.Parse(time.RFC3339Nano, req.Since)
+	if err != nil {
+		return time.Time{}, ErrInvalidRecoverSince
+	}
+	return since, nil
+}
+
+// isRecoverableChannel reports whether channel matches one of the
+// RecoverChannels patterns
+func (g *Gateway) isRecoverableChannel(channel string) bool {
+	for _, pattern := range g.config.RecoverChannels {
+		if ok, _ := path.Match(pattern, channel); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// recoverMessages returns the messages of channel published after since,
+// oldest first, looking back at most RecoverHistoryLimit messages
+func (g *Gateway) recoverMessages(ctx context.Context, channel string, since time.Time) ([]StreamMessage, error) {
+	history, err := g.ChannelHistory(ctx, channel, g.config.RecoverHistoryLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	var missed []StreamMessage
+	for _, msg := range history {
+		ts, err := time.Parse(time.RFC3339Nano, msg.Timestamp)
+		if err != nil || !ts.After(since) {
+			continue
+		}
+		missed = append(missed, msg)
+	}
+	slices.Reverse(missed)
+	return missed, nil
+}
+
+// marshalSubscribeReplyData encodes the subscribe reply data, or returns nil
+// if there is nothing to send. Without recovered messages the data is the
+// channel metadata itself, as sent before recovery was added.
+func marshalSubscribeReplyData(metadata []byte, recovered []StreamMessage) ([]byte, error) {
+	if len(recovered) == 0 {
+		return metadata, nil
+	}
+	return json.Marshal(subscribeReplyData{Metadata: metadata, Recovered: recovered})
+}
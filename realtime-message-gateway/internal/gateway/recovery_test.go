@@ -0,0 +1,155 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/centrifugal/centrifuge"
+	"github.com/centrifugal/protocol"
+)
+
+func TestRecoverSince(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    time.Time
+		wantErr error
+	}{
+		{"no data", "", time.Time{}, nil},
+		{"not json", "hello", time.Time{}, nil},
+		{"no since", `{"foo":1}`, time.Time{}, nil},
+		{"since", `{"since":"2026-01-01T00:00:02.5Z"}`, time.Date(2026, 1, 1, 0, 0, 2, 5e8, time.UTC), nil},
+		{"invalid since", `{"since":"yesterday"}`, time.Time{}, ErrInvalidRecoverSince},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := recoverSince([]byte(tt.data))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("recoverSince() error = %v, want %v", err, tt.wantErr)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("recoverSince() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSubscribeRecoversMissedMessages(t *testing.T) {
+	gw := NewTestGateway(t)
+	gw.config.RecoverChannels = []string{"chat:a*"}
+	gw.config.RecoverHistoryLimit = 10
+
+	for i, ts := range []string{"2026-01-01T00:00:01Z", "2026-01-01T00:00:02Z", "2026-01-01T00:00:03Z"} {
+		payload, _ := json.Marshal(StreamMessage{
+			SchemaVersion: 1,
+			ID:            string(rune('a' + i)),
+			Type:          EventTypeMessage,
+			Channel:       "chat:a",
+			Timestamp:     ts,
+		})
+		addTestHistory(t, gw, "chat:a", fmt.Sprintf("%d-0", i+1), payload)
+	}
+
+	tests := []struct {
+		name      string
+		channel   string
+		data      string
+		wantIDs   []string
+		wantError bool
+	}{
+		{"messages after since, oldest first", "chat:a", `{"since":"2026-01-01T00:00:01Z"}`, []string{"b", "c"}, false},
+		{"nothing missed", "chat:a", `{"since":"2026-01-01T00:00:03Z"}`, nil, false},
+		{"no since", "chat:a", "", nil, false},
+		{"invalid since", "chat:a", `{"since":"yesterday"}`, nil, true},
+		{"channel without recovery", "chat:b", `{"since":"yesterday"}`, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport := &testTransport{}
+			client, closeFn, err := centrifuge.NewClient(context.Background(), gw.node, transport)
+			if err != nil {
+				t.Fatalf("centrifuge.NewClient() error = %v", err)
+			}
+			defer closeFn()
+			client.HandleCommand(&protocol.Command{Id: 1, Connect: &protocol.ConnectRequest{}}, 0)
+			client.HandleCommand(&protocol.Command{
+				Id:        2,
+				Subscribe: &protocol.SubscribeRequest{Channel: tt.channel, Data: []byte(tt.data)},
+			}, 0)
+
+			reply := subscribeReplyFor(t, transport, 2)
+			if gotError := reply.Error != nil; gotError != tt.wantError {
+				t.Fatalf("subscribe error = %v, want error %v", reply.Error, tt.wantError)
+			}
+			if tt.wantError {
+				return
+			}
+
+			var data subscribeReplyData
+			if len(reply.Subscribe.Data) > 0 {
+				if err := json.Unmarshal(reply.Subscribe.Data, &data); err != nil {
+					t.Fatalf("subscribe reply data %s: %v", reply.Subscribe.Data, err)
+				}
+			}
+			var ids []string
+			for _, msg := range data.Recovered {
+				ids = append(ids, msg.ID)
+			}
+			if !slices.Equal(ids, tt.wantIDs) {
+				t.Errorf("recovered IDs = %v, want %v", ids, tt.wantIDs)
+			}
+		})
+	}
+}
+
+func TestMarshalSubscribeReplyData(t *testing.T) {
+	recovered := []StreamMessage{{ID: "a"}}
+	recoveredJSON, _ := json.Marshal(recovered)
+	tests := []struct {
+		name      string
+		metadata  string
+		recovered []StreamMessage
+		want      string
+	}{
+		{"nothing", "", nil, ""},
+		{"metadata only", `{"topic":"go"}`, nil, `{"topic":"go"}`},
+		{"recovered only", "", recovered, `{"recovered":` + string(recoveredJSON) + `}`},
+		{"both", `{"topic":"go"}`, recovered, `{"metadata":{"topic":"go"},"recovered":` + string(recoveredJSON) + `}`},
+	}
+
+	for _, tt := range tests {
+		got, err := marshalSubscribeReplyData([]byte(tt.metadata), tt.recovered)
+		if err != nil || string(got) != tt.want {
+			t.Errorf("%s: marshalSubscribeReplyData() = %s, %v, want %s", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+// subscribeReplyFor waits for the reply to command id to be written to
+// transport; the client writes replies asynchronously
+func subscribeReplyFor(t *testing.T, transport *testTransport, id uint32) *protocol.Reply {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		transport.mu.Lock()
+		for _, msg := range transport.messages {
+			reply := &protocol.Reply{}
+			if json.Unmarshal(msg, reply) == nil && reply.Id == id {
+				transport.mu.Unlock()
+				return reply
+			}
+		}
+		transport.mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("no reply to command %d written", id)
+	return nil
+}
@@ -0,0 +1,87 @@
+package gateway
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"realtime-message-gateway/internal/metrics"
+)
+
+const (
+	// redisHealthCheckInterval is how often the RedisHealthChecker pings Redis
+	redisHealthCheckInterval = 5 * time.Second
+
+	// redisHealthCheckTimeout bounds a single health check ping
+	redisHealthCheckTimeout = 2 * time.Second
+)
+
+// RedisHealthChecker pings Redis periodically and tracks whether the gateway
+// runs in degraded mode. While degraded, publishes skip Redis and wait on the
+// client queues; onRecover is called when a ping succeeds again.
+type RedisHealthChecker struct {
+	degraded  atomic.Bool
+	ping      func(ctx context.Context) error
+	onRecover func(ctx context.Context)
+	metrics   *metrics.Metrics
+}
+
+// NewRedisHealthChecker creates a RedisHealthChecker using ping to probe
+// Redis and counting degraded periods in m. onRecover may be nil.
+func NewRedisHealthChecker(ping func(ctx context.Context) error, onRecover func(ctx context.Context), m *metrics.Metrics) *RedisHealthChecker {
+	return &RedisHealthChecker{
+		ping:      ping,
+		onRecover: onRecover,
+		metrics:   m,
+	}
+}
+
+// Degraded reports whether the last ping failed
+func (h *RedisHealthChecker) Degraded() bool {
+	return h.degraded.Load()
+}
+
+// Run checks Redis every interval until ctx is cancelled
+func (h *RedisHealthChecker) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			h.check(ctx)
+		}
+	}
+}
+
+// check pings Redis once and switches between normal and degraded mode
+func (h *RedisHealthChecker) check(ctx context.Context) {
+	pingCtx, cancel := context.WithTimeout(ctx, redisHealthCheckTimeout)
+	err := h.ping(pingCtx)
+	cancel()
+
+	switch {
+	case err != nil && !h.degraded.Load():
+		if ctx.Err() != nil {
+			return
+		}
+		h.degraded.Store(true)
+		h.metrics.RedisDegradedModeTotal.Inc()
+		slog.Warn("redis unreachable, entering degraded mode", "error", err)
+	case err == nil && h.degraded.Load():
+		h.degraded.Store(false)
+		slog.Info("redis reachable again, leaving degraded mode")
+		if h.onRecover != nil {
+			h.onRecover(ctx)
+		}
+	}
+}
+
+// RedisDegraded reports whether the gateway is in degraded mode because
+// Redis is unreachable
+func (g *Gateway) RedisDegraded() bool {
+	return g.redisHealth.Degraded()
+}
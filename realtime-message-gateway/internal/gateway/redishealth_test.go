@@ -0,0 +1,120 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"realtime-message-gateway/internal/metrics"
+)
+
+func TestRedisHealthCheckerTransitions(t *testing.T) {
+	var pingErr error
+	recovered := 0
+	h := NewRedisHealthChecker(
+		func(context.Context) error { return pingErr },
+		func(context.Context) { recovered++ },
+		metrics.Default,
+	)
+	before := testutil.ToFloat64(metrics.Default.RedisDegradedModeTotal)
+
+	steps := []struct {
+		err           error
+		wantDegraded  bool
+		wantRecovered int
+	}{
+		{nil, false, 0},
+		{errors.New("connection refused"), true, 0},
+		{errors.New("connection refused"), true, 0}, // still down: no new transition
+		{nil, false, 1},
+		{nil, false, 1},
+		{errors.New("i/o timeout"), true, 1},
+		{nil, false, 2},
+	}
+
+	for i, step := range steps {
+		pingErr = step.err
+		h.check(context.Background())
+		if got := h.Degraded(); got != step.wantDegraded {
+			t.Errorf("step %d: Degraded() = %v, want %v", i, got, step.wantDegraded)
+		}
+		if recovered != step.wantRecovered {
+			t.Errorf("step %d: onRecover called %d times, want %d", i, recovered, step.wantRecovered)
+		}
+	}
+
+	if got := testutil.ToFloat64(metrics.Default.RedisDegradedModeTotal) - before; got != 2 {
+		t.Errorf("RedisDegradedModeTotal increased by %v, want 2", got)
+	}
+}
+
+func TestRedisHealthCheckerIgnoresCancelledContext(t *testing.T) {
+	h := NewRedisHealthChecker(func(ctx context.Context) error { return ctx.Err() }, nil, metrics.Default)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	h.check(ctx)
+
+	if h.Degraded() {
+		t.Error("Degraded() = true after a ping failed on shutdown")
+	}
+}
+
+func TestFlushClientQueuesOnRecovery(t *testing.T) {
+	gw := NewTestGateway(t)
+	client := connectTestClient(t, gw)
+
+	queue := newClientQueue(4)
+	gw.connectionsMu.Lock()
+	gw.connections[client.ID()].queue = queue
+	gw.connectionsMu.Unlock()
+
+	gw.enqueue(context.Background(), queue, queuedMessage{id: "m1", channel: "chat:a", streamKey: "messages:worker:w1:normal", payload: []byte(`{}`)})
+	gw.enqueue(context.Background(), queue, queuedMessage{id: "m2", channel: "chat:a", streamKey: "messages:worker:w1:normal", payload: []byte(`{}`)})
+
+	gw.flushClientQueues(context.Background())
+
+	if n := queue.len(); n != 0 {
+		t.Errorf("queue length after flush = %d, want 0", n)
+	}
+	entries, err := gw.redis.XRange(context.Background(), "messages:worker:w1:normal", "-", "+", 0)
+	if err != nil {
+		t.Fatalf("XRange() error = %v", err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("stream has %d entries, want 2", len(entries))
+	}
+}
+
+func TestFlushClientQueuesConcurrent(t *testing.T) {
+	gw := NewTestGateway(t)
+	client := connectTestClient(t, gw)
+
+	queue := newClientQueue(16)
+	gw.connectionsMu.Lock()
+	gw.connections[client.ID()].queue = queue
+	gw.connectionsMu.Unlock()
+	for i := range 16 {
+		gw.enqueue(context.Background(), queue, queuedMessage{id: fmt.Sprintf("m%d", i), channel: "chat:a", streamKey: "messages:worker:w1:normal", payload: []byte(`{}`)})
+	}
+
+	// The flusher and the health checker flushing at once send each
+	// message once
+	var wg sync.WaitGroup
+	for range 2 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			gw.flushClientQueues(context.Background())
+		}()
+	}
+	wg.Wait()
+
+	if n, err := gw.redis.XLen(context.Background(), "messages:worker:w1:normal"); err != nil || n != 16 {
+		t.Errorf("XLen() = %d, %v, want 16", n, err)
+	}
+}
@@ -0,0 +1,111 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"realtime-message-gateway/internal/routing"
+	"realtime-message-gateway/internal/tracing"
+)
+
+// replayBatchSize is how many entries ReplayStream reads per XRANGE
+const replayBatchSize = 100
+
+var (
+	// ErrInvalidReplayRange is returned when the replay start or end is not
+	// a stream ID or start is after end
+	ErrInvalidReplayRange = errors.New("invalid replay range")
+	// ErrReplayOverlapsHead is returned when the replay range reaches the
+	// newest entry of a stream, so replayed entries could be replayed again
+	ErrReplayOverlapsHead = errors.New("replay range overlaps stream head")
+)
+
+// ReplayStream re-adds the entries with IDs from start to end (inclusive)
+// of each priority stream of workerID to the same stream, with new IDs and
+// the payload marked as a replay, so a worker that missed them processes
+// them again. Entries are added at ReplayMaxMessagesPerSecond. end must be
+// before the newest entry of every stream. Returns how many entries were
+// replayed, also when replay stops with an error.
+func (g *Gateway) ReplayStream(ctx context.Context, workerID, start, end string) (int, error) {
+	if _, _, ok := parseStreamID(start); !ok {
+		return 0, fmt.Errorf("%w: start %q is not a stream ID", ErrInvalidReplayRange, start)
+	}
+	if _, _, ok := parseStreamID(end); !ok {
+		return 0, fmt.Errorf("%w: end %q is not a stream ID", ErrInvalidReplayRange, end)
+	}
+	if compareStreamIDs(start, end) > 0 {
+		return 0, fmt.Errorf("%w: start %s is after end %s", ErrInvalidReplayRange, start, end)
+	}
+
+	streamKeys := routing.GetWorkerStreamKeys(workerID)
+	for _, streamKey := range streamKeys {
+		head, err := g.redis.XRevRange(ctx, streamKey, "+", "-", 1)
+		if err != nil {
+			return 0, err
+		}
+		if len(head) > 0 && compareStreamIDs(end, head[0].ID) >= 0 {
+			return 0, fmt.Errorf("%w: %s ends at %s", ErrReplayOverlapsHead, streamKey, head[0].ID)
+		}
+	}
+
+	ticker := time.NewTicker(time.Second / time.Duration(g.config.ReplayMaxMessagesPerSecond))
+	defer ticker.Stop()
+
+	replayed := 0
+	for _, streamKey := range streamKeys {
+		n, err := g.replayRange(ctx, streamKey, start, end, ticker.C)
+		replayed += n
+		if err != nil {
+			return replayed, err
+		}
+	}
+
+	slog.InfoContext(ctx, "worker stream replayed", "workerId", workerID, "start", start, "end", end, "replayed", replayed)
+	return replayed, nil
+}
+
+// replayRange re-adds the entries of streamKey from start to end, one per
+// tick. Entries whose payload is not a StreamMessage are skipped.
+func (g *Gateway) replayRange(ctx context.Context, streamKey, start, end string, tick <-chan time.Time) (int, error) {
+	replayed := 0
+	for {
+		entries, err := g.redis.XRangeMessages(ctx, streamKey, start, end, replayBatchSize)
+		if err != nil {
+			return replayed, err
+		}
+
+		for _, entry := range entries {
+			payload, _ := entry.Values["payload"].(string)
+			var msg StreamMessage
+			if err := json.Unmarshal([]byte(payload), &msg); err != nil {
+				slog.WarnContext(ctx, "skipping undecodable entry in replay", "streamKey", streamKey, "entryId", entry.ID, "error", err)
+				continue
+			}
+			msg.Replay = true
+			replayPayload, err := json.Marshal(msg)
+			if err != nil {
+				return replayed, err
+			}
+			traceContext, _ := entry.Values[tracing.StreamField].(string)
+
+			select {
+			case <-ctx.Done():
+				return replayed, ctx.Err()
+			case <-tick:
+			}
+			if _, err := g.redis.XAdd(ctx, streamKey, streamEntry(replayPayload, traceContext)); err != nil {
+				return replayed, err
+			}
+			replayed++
+		}
+
+		if len(entries) < replayBatchSize {
+			return replayed, nil
+		}
+		start = "(" + entries[len(entries)-1].ID
+	}
+}
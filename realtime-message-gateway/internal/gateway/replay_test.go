@@ -0,0 +1,77 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"realtime-message-gateway/internal/routing"
+)
+
+func TestReplayStream(t *testing.T) {
+	gw := NewTestGateway(t)
+	ctx := context.Background()
+	addTestEntry(t, gw, "1000-0", "m1", "chat", routing.PriorityNormal)
+	addTestEntry(t, gw, "2000-0", "m2", "chat", routing.PriorityNormal)
+	addTestEntry(t, gw, "3000-0", "m3", "chat", routing.PriorityNormal)
+	addTestEntry(t, gw, "1500-0", "m4", "chat", routing.PriorityHigh)
+	addTestEntry(t, gw, "4000-0", "m5", "chat", routing.PriorityHigh)
+
+	replayed, err := gw.ReplayStream(ctx, "worker-0", "1000-0", "2000-0")
+	if err != nil {
+		t.Fatalf("ReplayStream() error = %v", err)
+	}
+	if replayed != 3 {
+		t.Errorf("ReplayStream() = %d, want 3", replayed)
+	}
+
+	want := map[string][]string{
+		routing.GetWorkerStreamKey("worker-0", routing.PriorityNormal): {"m1", "m2"},
+		routing.GetWorkerStreamKey("worker-0", routing.PriorityHigh):   {"m4"},
+	}
+	for streamKey, wantIDs := range want {
+		entries, err := gw.redis.XRange(ctx, streamKey, "(4000-0", "+", 0)
+		if err != nil {
+			t.Fatalf("XRange() error = %v", err)
+		}
+		if len(entries) != len(wantIDs) {
+			t.Fatalf("%s has %d replayed entries, want %d", streamKey, len(entries), len(wantIDs))
+		}
+		for i, entry := range entries {
+			var msg StreamMessage
+			if err := json.Unmarshal([]byte(entry.Payload), &msg); err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			if msg.ID != wantIDs[i] || !msg.Replay {
+				t.Errorf("%s replayed entry %d = %s (replay %v), want %s marked as replay", streamKey, i, msg.ID, msg.Replay, wantIDs[i])
+			}
+		}
+	}
+}
+
+func TestReplayStreamRejectedRanges(t *testing.T) {
+	gw := NewTestGateway(t)
+	addTestEntry(t, gw, "1000-0", "m1", "chat", routing.PriorityNormal)
+	addTestEntry(t, gw, "3000-0", "m2", "chat", routing.PriorityNormal)
+
+	tests := []struct {
+		name       string
+		start, end string
+		wantErr    error
+	}{
+		{"invalid start", "oops", "2000-0", ErrInvalidReplayRange},
+		{"open end", "1000-0", "+", ErrInvalidReplayRange},
+		{"start after end", "2000-0", "1000-0", ErrInvalidReplayRange},
+		{"end at head", "1000-0", "3000-0", ErrReplayOverlapsHead},
+		{"end past head", "1000-0", "9000-0", ErrReplayOverlapsHead},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := gw.ReplayStream(context.Background(), "worker-0", tt.start, tt.end); !errors.Is(err, tt.wantErr) {
+				t.Errorf("ReplayStream() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/centrifugal/centrifuge"
+
+	"realtime-message-gateway/internal/redis"
+)
+
+const (
+	// RoomChannelPrefix is the channel prefix of rooms: room {id} is
+	// channel chat:room-{id}
+	RoomChannelPrefix = "chat:room-"
+
+	// RoomKeyPrefix is the key prefix of room hashes
+	RoomKeyPrefix = "room:"
+
+	// RoomsKey is the set of all room IDs
+	RoomsKey = "rooms"
+)
+
+// roomIDPattern restricts room IDs to characters safe in channel names
+var roomIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+var (
+	// ErrInvalidRoom is wrapped by all room validation errors
+	ErrInvalidRoom = errors.New("invalid room")
+	// ErrRoomExists is returned when creating a room whose ID is taken
+	ErrRoomExists = errors.New("room already exists")
+	// ErrRoomNotFound is returned when a room does not exist
+	ErrRoomNotFound = errors.New("room not found")
+)
+
+// Room is a chat room stored in the room:{id} hash. Subscribers is only
+// set when listing rooms.
+type Room struct {
+	ID             string `json:"id"`
+	Name           string `json:"name"`
+	MaxSubscribers int    `json:"maxSubscribers"`
+	Subscribers    int64  `json:"subscribers"`
+}
+
+// Channel returns the channel of the room
+func (r Room) Channel() string {
+	return RoomChannelPrefix + r.ID
+}
+
+// roomIDFromChannel returns the room ID of a room channel
+func roomIDFromChannel(channel string) (string, bool) {
+	if !strings.HasPrefix(channel, RoomChannelPrefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(channel, RoomChannelPrefix), true
+}
+
+// CreateRoom stores a new room and returns it as stored; the name defaults
+// to the ID. Subscriptions to the room channel are rejected until the room
+// exists.
+func (g *Gateway) CreateRoom(ctx context.Context, room Room) (Room, error) {
+	if !roomIDPattern.MatchString(room.ID) {
+		return Room{}, fmt.Errorf("%w: id must be 1-64 letters, digits, '-' or '_'", ErrInvalidRoom)
+	}
+	if room.MaxSubscribers < 0 {
+		return Room{}, fmt.Errorf("%w: maxSubscribers must not be negative", ErrInvalidRoom)
+	}
+	if room.Name == "" {
+		room.Name = room.ID
+	}
+	room.Subscribers = 0
+
+	// The index doubles as the uniqueness check
+	added, err := g.redis.SAdd(ctx, RoomsKey, room.ID)
+	if err != nil {
+		return Room{}, err
+	}
+	if added == 0 {
+		return Room{}, ErrRoomExists
+	}
+
+	if err := g.redis.HSet(ctx, RoomKeyPrefix+room.ID, map[string]interface{}{
+		"id":             room.ID,
+		"name":           room.Name,
+		"maxSubscribers": room.MaxSubscribers,
+	}); err != nil {
+		if rmErr := g.redis.SRem(ctx, RoomsKey, room.ID); rmErr != nil {
+			slog.WarnContext(ctx, "failed to roll back room index", "roomId", room.ID, "error", rmErr)
+		}
+		return Room{}, err
+	}
+
+	slog.InfoContext(ctx, "room created", "roomId", room.ID, "maxSubscribers", room.MaxSubscribers)
+	return room, nil
+}
+
+// DeleteRoom removes a room and unsubscribes all of its subscribers
+func (g *Gateway) DeleteRoom(ctx context.Context, id string) error {
+	room, err := g.getRoom(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err := g.redis.Del(ctx, RoomKeyPrefix+id); err != nil {
+		return err
+	}
+	if err := g.redis.SRem(ctx, RoomsKey, id); err != nil {
+		return err
+	}
+
+	channel := room.Channel()
+	result, err := g.node.Presence(channel)
+	if err != nil {
+		return fmt.Errorf("room deleted but listing subscribers failed: %w", err)
+	}
+	for clientID, info := range result.Presence {
+		if err := g.node.Unsubscribe(info.UserID, channel, centrifuge.WithUnsubscribeClient(clientID)); err != nil {
+			slog.WarnContext(ctx, "failed to unsubscribe client from deleted room", "roomId", id, "clientId", clientID, "error", err)
+		}
+	}
+
+	slog.InfoContext(ctx, "room deleted", "roomId", id, "unsubscribed", len(result.Presence))
+	return nil
+}
+
+// ListRooms returns all rooms ordered by ID, with their subscriber counts
+func (g *Gateway) ListRooms(ctx context.Context) ([]Room, error) {
+	ids, err := g.redis.SMembers(ctx, RoomsKey)
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(ids)
+
+	rooms := make([]Room, 0, len(ids))
+	for _, id := range ids {
+		room, err := g.getRoom(ctx, id)
+		if errors.Is(err, ErrRoomNotFound) {
+			// Deleted between SMEMBERS and HGETALL
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if room.Subscribers, err = g.channelSubscribers(ctx, room.Channel()); err != nil {
+			return nil, err
+		}
+		rooms = append(rooms, room)
+	}
+	return rooms, nil
+}
+
+// roomMaxSubscribers reads only the subscriber limit of room id, for the
+// subscribe path
+func (g *Gateway) roomMaxSubscribers(ctx context.Context, id string) (int, error) {
+	value, err := g.redis.HGet(ctx, RoomKeyPrefix+id, "maxSubscribers")
+	if redis.IsNil(err) {
+		return 0, ErrRoomNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	maxSubscribers, _ := strconv.Atoi(value)
+	return maxSubscribers, nil
+}
+
+// getRoom reads the room:{id} hash
+func (g *Gateway) getRoom(ctx context.Context, id string) (Room, error) {
+	values, err := g.redis.HGetAll(ctx, RoomKeyPrefix+id)
+	if err != nil {
+		return Room{}, err
+	}
+	if len(values) == 0 {
+		return Room{}, ErrRoomNotFound
+	}
+
+	maxSubscribers, _ := strconv.Atoi(values["maxSubscribers"])
+	return Room{
+		ID:             id,
+		Name:           values["name"],
+		MaxSubscribers: maxSubscribers,
+	}, nil
+}
@@ -0,0 +1,93 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCreateRoomValidation(t *testing.T) {
+	gw := NewTestGateway(t)
+
+	tests := []struct {
+		name string
+		room Room
+	}{
+		{"empty id", Room{}},
+		{"id with colon", Room{ID: "a:b"}},
+		{"id with slash", Room{ID: "a/b"}},
+		{"id too long", Room{ID: strings.Repeat("a", 65)}},
+		{"negative max subscribers", Room{ID: "abc", MaxSubscribers: -1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := gw.CreateRoom(context.Background(), tt.room); !errors.Is(err, ErrInvalidRoom) {
+				t.Errorf("CreateRoom() error = %v, want %v", err, ErrInvalidRoom)
+			}
+		})
+	}
+}
+
+func TestRoomLifecycle(t *testing.T) {
+	gw := NewTestGateway(t)
+	ctx := context.Background()
+
+	room, err := gw.CreateRoom(ctx, Room{ID: "abc", MaxSubscribers: 10})
+	if err != nil {
+		t.Fatalf("CreateRoom() error = %v", err)
+	}
+	if room.Name != "abc" {
+		t.Errorf("Name = %q, want ID as default name", room.Name)
+	}
+	if room.Channel() != "chat:room-abc" {
+		t.Errorf("Channel() = %q, want chat:room-abc", room.Channel())
+	}
+	if _, err := gw.CreateRoom(ctx, Room{ID: "abc"}); !errors.Is(err, ErrRoomExists) {
+		t.Errorf("CreateRoom() duplicate error = %v, want %v", err, ErrRoomExists)
+	}
+
+	subscribeTestClient(connectTestClient(t, gw), 2, room.Channel())
+	if n := gw.node.Hub().NumSubscribers(room.Channel()); n != 1 {
+		t.Fatalf("NumSubscribers() = %d, want 1", n)
+	}
+
+	rooms, err := gw.ListRooms(ctx)
+	if err != nil {
+		t.Fatalf("ListRooms() error = %v", err)
+	}
+	want := Room{ID: "abc", Name: "abc", MaxSubscribers: 10, Subscribers: 1}
+	if len(rooms) != 1 || rooms[0] != want {
+		t.Errorf("ListRooms() = %+v, want [%+v]", rooms, want)
+	}
+
+	if err := gw.DeleteRoom(ctx, "abc"); err != nil {
+		t.Fatalf("DeleteRoom() error = %v", err)
+	}
+	if n := gw.node.Hub().NumSubscribers(room.Channel()); n != 0 {
+		t.Errorf("NumSubscribers() after delete = %d, want 0", n)
+	}
+	if err := gw.DeleteRoom(ctx, "abc"); !errors.Is(err, ErrRoomNotFound) {
+		t.Errorf("DeleteRoom() twice error = %v, want %v", err, ErrRoomNotFound)
+	}
+	if rooms, _ := gw.ListRooms(ctx); len(rooms) != 0 {
+		t.Errorf("ListRooms() after delete = %+v, want none", rooms)
+	}
+}
+
+func TestSubscribeUnknownRoomRejected(t *testing.T) {
+	gw := NewTestGateway(t)
+
+	client := connectTestClient(t, gw)
+	subscribeTestClient(client, 2, "chat:room-missing")
+	if n := gw.node.Hub().NumSubscribers("chat:room-missing"); n != 0 {
+		t.Errorf("NumSubscribers() = %d, want 0 for a room that does not exist", n)
+	}
+
+	// Other chat channels need no room
+	subscribeTestClient(client, 3, "chat:lobby")
+	if n := gw.node.Hub().NumSubscribers("chat:lobby"); n != 1 {
+		t.Errorf("NumSubscribers() = %d, want 1 for a non-room channel", n)
+	}
+}
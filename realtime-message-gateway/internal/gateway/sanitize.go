@@ -0,0 +1,54 @@
+package gateway
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// ErrTextTooLong is returned by DefaultSanitizer when sanitized text
+// exceeds the maximum length
+var ErrTextTooLong = errors.New("text too long")
+
+// Sanitizer cleans message text before it is routed to workers. Returning
+// an error rejects the message.
+type Sanitizer interface {
+	Sanitize(text string) (string, error)
+}
+
+// DefaultSanitizer strips null bytes and control characters other than
+// newline and tab, normalizes to Unicode NFC and enforces a maximum length
+// in bytes. Invalid UTF-8 sequences are replaced with U+FFFD.
+type DefaultSanitizer struct {
+	maxLength int
+}
+
+// NewDefaultSanitizer creates a DefaultSanitizer accepting up to maxLength
+// bytes of sanitized text
+func NewDefaultSanitizer(maxLength int) *DefaultSanitizer {
+	return &DefaultSanitizer{maxLength: maxLength}
+}
+
+// Sanitize implements Sanitizer
+func (s *DefaultSanitizer) Sanitize(text string) (string, error) {
+	text = strings.Map(func(r rune) rune {
+		if r == '\n' || r == '\t' {
+			return r
+		}
+		// Covers the null byte, C0, DEL and C1 controls
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, text)
+
+	text = norm.NFC.String(text)
+
+	if len(text) > s.maxLength {
+		return "", fmt.Errorf("%w: %d bytes exceeds %d", ErrTextTooLong, len(text), s.maxLength)
+	}
+	return text, nil
+}
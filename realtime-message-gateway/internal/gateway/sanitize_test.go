@@ -0,0 +1,72 @@
+package gateway
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDefaultSanitizer(t *testing.T) {
+	tests := []struct {
+		name      string
+		maxLength int
+		text      string
+		want      string
+		wantErr   error
+	}{
+		{"plain text", 100, "hello world", "hello world", nil},
+		{"null bytes", 100, "he\x00llo\x00", "hello", nil},
+		{"keeps newline and tab", 100, "a\nb\tc", "a\nb\tc", nil},
+		{"strips carriage return and escape", 100, "a\rb\x1b[31mc", "ab[31mc", nil},
+		{"strips DEL", 100, "a\x7fb", "ab", nil},
+		{"strips C1 control", 100, "a\u0085b\u009bc", "abc", nil},
+		{"NFC composes combining accent", 100, "cafe\u0301", "caf\u00e9", nil},
+		{"NFC composes hangul jamo", 100, "\u1100\u1161", "\uac00", nil},
+		{"keeps emoji", 100, "hi 👋🏽", "hi 👋🏽", nil},
+		{"keeps CJK", 100, "你好，世界", "你好，世界", nil},
+		{"replaces invalid UTF-8", 100, "a\xffb", "a\ufffdb", nil},
+		{"length counts bytes", 5, "你好", "", ErrTextTooLong},
+		{"exactly max length", 6, "你好", "你好", nil},
+		// Decomposed é is 3 bytes, composed is 2: the limit applies after NFC
+		{"length checked after NFC", 2, "e\u0301", "\u00e9", nil},
+		{"length checked after stripping", 3, "a\x00b\x00c", "abc", nil},
+		{"too long", 10, strings.Repeat("a", 11), "", ErrTextTooLong},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewDefaultSanitizer(tt.maxLength).Sanitize(tt.text)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Sanitize() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Sanitize() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+type upperSanitizer struct{}
+
+func (upperSanitizer) Sanitize(text string) (string, error) {
+	return strings.ToUpper(text), nil
+}
+
+func TestWithSanitizer(t *testing.T) {
+	redisClient := newTestRedisClient(t)
+	gw, err := NewGateway(WithRedis(redisClient), WithMaxTextLength(100), WithSanitizer(upperSanitizer{}))
+	if err != nil {
+		t.Fatalf("NewGateway() error = %v", err)
+	}
+	if _, ok := gw.sanitizer.(upperSanitizer); !ok {
+		t.Errorf("sanitizer = %T, want upperSanitizer", gw.sanitizer)
+	}
+
+	gw, err = NewGateway(WithRedis(redisClient), WithMaxTextLength(100))
+	if err != nil {
+		t.Fatalf("NewGateway() error = %v", err)
+	}
+	if _, ok := gw.sanitizer.(*DefaultSanitizer); !ok {
+		t.Errorf("default sanitizer = %T, want *DefaultSanitizer", gw.sanitizer)
+	}
+}
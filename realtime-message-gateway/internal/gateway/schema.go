@@ -0,0 +1,83 @@
+package gateway
+
+import (
+	"fmt"
+	"sync"
+)
+
+// legacySchemaVersion is assumed for payloads written before SchemaVersion existed
+const legacySchemaVersion = 1
+
+// MigrationFunc upgrades a StreamMessage in place by one schema step
+type MigrationFunc func(*StreamMessage) error
+
+// migration is a registered schema step
+type migration struct {
+	toVersion int
+	fn        MigrationFunc
+}
+
+// migrationRegistry holds schema migrations keyed by source version
+type migrationRegistry struct {
+	mu         sync.RWMutex
+	migrations map[int]migration
+}
+
+// newMigrationRegistry creates an empty migrationRegistry
+func newMigrationRegistry() *migrationRegistry {
+	return &migrationRegistry{migrations: make(map[int]migration)}
+}
+
+// defaultMigrations is the registry used by RegisterMigration
+var defaultMigrations = newMigrationRegistry()
+
+// RegisterMigration registers fn to upgrade StreamMessage payloads from
+// fromVersion to toVersion. Migrations are chained when reading older payloads.
+func RegisterMigration(fromVersion, toVersion int, fn func(*StreamMessage) error) {
+	defaultMigrations.register(fromVersion, toVersion, fn)
+}
+
+// MigrateStreamMessage upgrades msg to targetVersion using registered migrations
+func MigrateStreamMessage(msg *StreamMessage, targetVersion int) error {
+	return defaultMigrations.migrate(msg, targetVersion)
+}
+
+// register adds a migration, replacing any existing one from fromVersion
+func (r *migrationRegistry) register(fromVersion, toVersion int, fn MigrationFunc) {
+	if toVersion <= fromVersion {
+		panic(fmt.Sprintf("gateway: migration must upgrade schema version, got %d -> %d", fromVersion, toVersion))
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.migrations[fromVersion] = migration{toVersion: toVersion, fn: fn}
+}
+
+// migrate applies the chain of migrations from msg.SchemaVersion to targetVersion
+func (r *migrationRegistry) migrate(msg *StreamMessage, targetVersion int) error {
+	if msg.SchemaVersion == 0 {
+		msg.SchemaVersion = legacySchemaVersion
+	}
+	if msg.SchemaVersion > targetVersion {
+		return fmt.Errorf("stream message schema version %d is newer than %d", msg.SchemaVersion, targetVersion)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for msg.SchemaVersion < targetVersion {
+		m, ok := r.migrations[msg.SchemaVersion]
+		if !ok {
+			return fmt.Errorf("no migration registered from schema version %d", msg.SchemaVersion)
+		}
+		if m.toVersion > targetVersion {
+			return fmt.Errorf("migration from schema version %d overshoots target %d", msg.SchemaVersion, targetVersion)
+		}
+		if err := m.fn(msg); err != nil {
+			return fmt.Errorf("migrate schema version %d -> %d: %w", msg.SchemaVersion, m.toVersion, err)
+		}
+		msg.SchemaVersion = m.toVersion
+	}
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package gateway
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// migrateV1ToV2 is an example v2 schema step that trims display names
+func migrateV1ToV2(msg *StreamMessage) error {
+	msg.UserName = strings.TrimSpace(msg.UserName)
+	return nil
+}
+
+func TestMigrationV1ToV2(t *testing.T) {
+	r := newMigrationRegistry()
+	r.register(1, 2, migrateV1ToV2)
+
+	tests := []struct {
+		name     string
+		msg      StreamMessage
+		wantName string
+	}{
+		{"v1 message", StreamMessage{SchemaVersion: 1, UserName: " Alice "}, "Alice"},
+		{"legacy message without version", StreamMessage{UserName: " Bob "}, "Bob"},
+		{"already v2", StreamMessage{SchemaVersion: 2, UserName: " Carol "}, " Carol "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := tt.msg
+			if err := r.migrate(&msg, 2); err != nil {
+				t.Fatalf("migrate() error = %v", err)
+			}
+			if msg.SchemaVersion != 2 {
+				t.Errorf("SchemaVersion = %d, want 2", msg.SchemaVersion)
+			}
+			if msg.UserName != tt.wantName {
+				t.Errorf("UserName = %q, want %q", msg.UserName, tt.wantName)
+			}
+		})
+	}
+}
+
+func TestMigrationChain(t *testing.T) {
+	r := newMigrationRegistry()
+	var steps []int
+	r.register(1, 2, func(msg *StreamMessage) error {
+		steps = append(steps, 2)
+		return nil
+	})
+	r.register(2, 3, func(msg *StreamMessage) error {
+		steps = append(steps, 3)
+		return nil
+	})
+
+	msg := StreamMessage{SchemaVersion: 1}
+	if err := r.migrate(&msg, 3); err != nil {
+		t.Fatalf("migrate() error = %v", err)
+	}
+	if msg.SchemaVersion != 3 || len(steps) != 2 || steps[0] != 2 || steps[1] != 3 {
+		t.Errorf("migrate() version = %d, steps = %v, want 3, [2 3]", msg.SchemaVersion, steps)
+	}
+}
+
+func TestMigrationErrors(t *testing.T) {
+	errBroken := errors.New("broken")
+	r := newMigrationRegistry()
+	r.register(1, 2, func(msg *StreamMessage) error { return errBroken })
+
+	tests := []struct {
+		name   string
+		msg    StreamMessage
+		target int
+	}{
+		{"missing migration", StreamMessage{SchemaVersion: 2}, 3},
+		{"newer than target", StreamMessage{SchemaVersion: 3}, 2},
+		{"migration fails", StreamMessage{SchemaVersion: 1}, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := tt.msg
+			if err := r.migrate(&msg, tt.target); err == nil {
+				t.Error("migrate() error = nil, want error")
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package gateway
+
+import (
+	"context"
+	"testing"
+)
+
+func TestVerifySignature(t *testing.T) {
+	msg := StreamMessage{ID: "m1", Channel: "chat", UserID: "u1", Text: "hi", Timestamp: "2026-01-01T00:00:00Z"}
+	msg.Signature = SignMessage(msg, "key")
+
+	tests := []struct {
+		name   string
+		modify func(*StreamMessage)
+		key    string
+		want   bool
+	}{
+		{"valid", func(*StreamMessage) {}, "key", true},
+		{"unsigned field changed", func(m *StreamMessage) { m.UserName = "other" }, "key", true},
+		{"text changed", func(m *StreamMessage) { m.Text = "bye" }, "key", false},
+		{"channel changed", func(m *StreamMessage) { m.Channel = "chat:b" }, "key", false},
+		{"user changed", func(m *StreamMessage) { m.UserID = "u2" }, "key", false},
+		{"bytes moved between fields", func(m *StreamMessage) { m.UserID, m.Text = "u1h", "i" }, "key", false},
+		{"timestamp changed", func(m *StreamMessage) { m.Timestamp = "2026-01-01T00:00:01Z" }, "key", false},
+		{"wrong key", func(*StreamMessage) {}, "other", false},
+		{"empty key", func(*StreamMessage) {}, "", false},
+		{"no signature", func(m *StreamMessage) { m.Signature = "" }, "key", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := msg
+			tt.modify(&m)
+			if got := VerifySignature(m, tt.key); got != tt.want {
+				t.Errorf("VerifySignature() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPublishSignsMessages(t *testing.T) {
+	for _, key := range []string{"", "secret"} {
+		gw := NewTestGateway(t)
+		gw.config.MessageSigningKey = key
+		client := connectTestClient(t, gw)
+
+		if err := publishAndWait(gw, client, "chat", `{"text":"hi"}`); err != nil {
+			t.Fatalf("publish error = %v", err)
+		}
+		messages, err := gw.ChannelHistory(context.Background(), "chat", 1)
+		if err != nil || len(messages) != 1 {
+			t.Fatalf("ChannelHistory() = %v, %v, want the published message", messages, err)
+		}
+
+		msg := messages[0]
+		if key == "" {
+			if msg.Signature != "" {
+				t.Errorf("Signature = %q without signing key, want empty", msg.Signature)
+			}
+			continue
+		}
+		if !VerifySignature(msg, key) {
+			t.Errorf("VerifySignature() = false for published message %+v", msg)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package gateway
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/centrifugal/centrifuge"
+)
+
+// Transport names of the HTTP fallback transports
+const (
+	transportHTTPStream = "http_stream"
+	transportSSE        = "sse"
+)
+
+// SockJSHandler returns the HTTP fallback transports mounted under SockJSURL:
+//
+//	{SockJSURL}/http_stream - HTTP-streaming transport
+//	{SockJSURL}/sse         - EventSource transport
+//	{SockJSURL}/emulation   - client-to-server commands for both transports
+//
+// All transports share the gateway's Centrifuge node, so handlers, presence
+// and routing behave exactly as for WebSocket connections. Requests failing
+// CheckOrigin are rejected with 403, as WebSocket upgrades are.
+func (g *Gateway) SockJSHandler() http.Handler {
+	prefix := strings.TrimSuffix(g.config.SockJSURL, "/")
+	pingPong := centrifuge.PingPongConfig{
+		PingInterval: g.config.PingInterval,
+		PongTimeout:  g.config.PongTimeout,
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(prefix+"/http_stream", centrifuge.NewHTTPStreamHandler(g.node, centrifuge.HTTPStreamConfig{
+		PingPongConfig:     pingPong,
+		MaxRequestBodySize: g.config.MessageSizeLimit,
+	}))
+	mux.Handle(prefix+"/sse", centrifuge.NewSSEHandler(g.node, centrifuge.SSEConfig{
+		PingPongConfig:     pingPong,
+		MaxRequestBodySize: g.config.MessageSizeLimit,
+	}))
+	mux.Handle(prefix+"/emulation", centrifuge.NewEmulationHandler(g.node, centrifuge.EmulationConfig{
+		MaxRequestBodySize: g.config.MessageSizeLimit,
+	}))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !g.CheckOrigin(r) {
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// isSockJSTransport checks if transport is one of the HTTP fallback transports
+func isSockJSTransport(name string) bool {
+	return name == transportHTTPStream || name == transportSSE
+}
@@ -0,0 +1,158 @@
+package gateway
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"realtime-message-gateway/internal/config"
+)
+
+func TestSockJSHandlerHTTPStreamSession(t *testing.T) {
+	gw := NewTestGateway(t)
+
+	server := httptest.NewServer(gw.SockJSHandler())
+	defer server.Close()
+
+	// The streaming request stays open: the connect reply arrives as the
+	// first newline-delimited JSON frame
+	body := strings.NewReader(`{"id":1,"connect":{"data":{"name":"Alice"}}}`)
+	req, err := http.NewRequest(http.MethodPost, server.URL+"/connection/sockjs/http_stream", body)
+	if err != nil {
+		t.Fatalf("NewRequest() error = %v", err)
+	}
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("POST http_stream error = %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("POST http_stream status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+
+	line, err := bufio.NewReader(resp.Body).ReadBytes('\n')
+	if err != nil {
+		t.Fatalf("read connect reply error = %v", err)
+	}
+
+	var reply struct {
+		ID      uint32 `json:"id"`
+		Connect *struct {
+			Client  string `json:"client"`
+			Session string `json:"session"`
+		} `json:"connect"`
+	}
+	if err := json.Unmarshal(line, &reply); err != nil {
+		t.Fatalf("unmarshal connect reply %q error = %v", line, err)
+	}
+	if reply.ID != 1 || reply.Connect == nil {
+		t.Fatalf("connect reply = %s, want connect result for id 1", line)
+	}
+	if reply.Connect.Client == "" || reply.Connect.Session == "" {
+		t.Errorf("connect reply = %s, want client and session set", line)
+	}
+}
+
+func TestSockJSHandlerHTTPStreamSubscribe(t *testing.T) {
+	gw := NewTestGateway(t)
+
+	server := httptest.NewServer(gw.SockJSHandler())
+	defer server.Close()
+	client := &http.Client{Timeout: 5 * time.Second}
+
+	body := strings.NewReader(`{"id":1,"connect":{"data":{"name":"Alice"}}}`)
+	resp, err := client.Post(server.URL+"/connection/sockjs/http_stream", "application/json", body)
+	if err != nil {
+		t.Fatalf("POST http_stream error = %v", err)
+	}
+	defer resp.Body.Close()
+	stream := bufio.NewReader(resp.Body)
+
+	// next returns the next stream frame matching match
+	next := func(match func(line []byte) bool) []byte {
+		t.Helper()
+		for {
+			line, err := stream.ReadBytes('\n')
+			if err != nil {
+				t.Fatalf("read stream frame error = %v", err)
+			}
+			if match(line) {
+				return line
+			}
+		}
+	}
+
+	var connected struct {
+		Connect struct {
+			Session string `json:"session"`
+			Node    string `json:"node"`
+		} `json:"connect"`
+	}
+	if err := json.Unmarshal(next(func(line []byte) bool { return bytes.Contains(line, []byte(`"connect"`)) }), &connected); err != nil {
+		t.Fatalf("unmarshal connect reply error = %v", err)
+	}
+
+	// Client-to-server commands of the session go through the emulation endpoint
+	emulation, _ := json.Marshal(map[string]interface{}{
+		"session": connected.Connect.Session,
+		"node":    connected.Connect.Node,
+		"data":    `{"id":2,"subscribe":{"channel":"chat"}}`,
+	})
+	emuResp, err := client.Post(server.URL+"/connection/sockjs/emulation", "application/json", bytes.NewReader(emulation))
+	if err != nil {
+		t.Fatalf("POST emulation error = %v", err)
+	}
+	emuResp.Body.Close()
+	if emuResp.StatusCode != http.StatusNoContent {
+		t.Fatalf("POST emulation status = %d, want %d", emuResp.StatusCode, http.StatusNoContent)
+	}
+	next(func(line []byte) bool {
+		var reply wsReply
+		return json.Unmarshal(line, &reply) == nil && reply.ID == 2
+	})
+
+	if _, err := gw.node.Publish("chat", []byte(`{"text":"over http"}`)); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	line := next(func(line []byte) bool {
+		var reply wsReply
+		return json.Unmarshal(line, &reply) == nil && reply.Push != nil && reply.Push.Pub != nil
+	})
+	var push wsReply
+	json.Unmarshal(line, &push)
+	if push.Push.Channel != "chat" || string(push.Push.Pub.Data) != `{"text":"over http"}` {
+		t.Errorf("push = %s, want publication in chat", line)
+	}
+}
+
+func TestSockJSHandlerCheckOrigin(t *testing.T) {
+	gw := &Gateway{config: &config.Config{
+		SockJSURL:      "/connection/sockjs",
+		AllowedOrigins: []string{"https://app.example.com"},
+	}}
+
+	req := httptest.NewRequest(http.MethodPost, "/connection/sockjs/http_stream", nil)
+	req.Header.Set("Origin", "https://evil.example.com")
+	rec := httptest.NewRecorder()
+	gw.SockJSHandler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("POST from disallowed origin status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+}
+
+func TestSockJSHandlerUnknownPath(t *testing.T) {
+	gw := &Gateway{config: &config.Config{SockJSURL: "/connection/sockjs/"}}
+
+	rec := httptest.NewRecorder()
+	gw.SockJSHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/connection/sockjs/unknown", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("GET unknown path status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
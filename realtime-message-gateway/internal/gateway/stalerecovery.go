@@ -0,0 +1,191 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"time"
+
+	"realtime-message-gateway/internal/redis"
+	"realtime-message-gateway/internal/routing"
+	"realtime-message-gateway/internal/tracing"
+)
+
+// staleClaimBatchSize is how many entries one XAUTOCLAIM claims per stream
+// and run; the rest are claimed on the next run
+const staleClaimBatchSize = 100
+
+// deliveriesField is the worker stream entry field counting the deliveries
+// of re-added entries before they were re-added
+const deliveriesField = "deliveries"
+
+// MaxRescueIDs is the most entry IDs one RescueStreamMessages call claims
+const MaxRescueIDs = 100
+
+// ErrInvalidRescueIDs is returned when the IDs passed to
+// RescueStreamMessages are empty, too many or not stream IDs
+var ErrInvalidRescueIDs = errors.New("invalid rescue IDs")
+
+// staleMessageRecovery periodically recovers worker stream entries that
+// stayed unacknowledged for StaleMessageMinIdle
+func (g *Gateway) staleMessageRecovery(ctx context.Context) {
+	defer g.wg.Done()
+
+	ticker := time.NewTicker(g.config.StaleClaimInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if _, err := g.recoverStaleMessages(ctx); err != nil && ctx.Err() == nil {
+			slog.Warn("failed to recover stale messages", "error", err)
+		}
+	}
+}
+
+// recoverStaleMessages claims the entries of every active worker's streams
+// that a consumer read but did not acknowledge within StaleMessageMinIdle,
+// most likely because it crashed, and re-adds them to the same stream
+// marked as retried so any consumer of the group reads them again. The
+// claimed originals are acknowledged and deleted. Returns the number of
+// re-added entries.
+func (g *Gateway) recoverStaleMessages(ctx context.Context) (int, error) {
+	workers, err := g.redis.ZRange(ctx, routing.ActiveWorkersKey, 0, -1)
+	if err != nil {
+		return 0, err
+	}
+
+	reclaimed := 0
+	for _, workerID := range workers {
+		if err := g.ensureConsumerGroups(ctx, workerID); err != nil {
+			return reclaimed, err
+		}
+		for _, streamKey := range routing.GetWorkerStreamKeys(workerID) {
+			n, err := g.reclaimStream(ctx, streamKey)
+			reclaimed += n
+			if err != nil {
+				return reclaimed, err
+			}
+		}
+	}
+
+	if reclaimed > 0 {
+		slog.InfoContext(ctx, "stale stream messages reclaimed", "reclaimed", reclaimed)
+	}
+	return reclaimed, nil
+}
+
+// RescueStreamMessages claims the pending entries with ids of the priority
+// streams of workerID to this gateway, regardless of how long they have
+// been idle, and re-adds them like recoverStaleMessages does. It is the
+// manual fallback for entries the periodic recovery misses, e.g. while it
+// is disabled. IDs that are not pending in a stream are skipped. Returns
+// the number of re-added entries, also when it stops with an error.
+func (g *Gateway) RescueStreamMessages(ctx context.Context, workerID string, ids []string) (int, error) {
+	if len(ids) == 0 || len(ids) > MaxRescueIDs {
+		return 0, fmt.Errorf("%w: got %d IDs, want 1 to %d", ErrInvalidRescueIDs, len(ids), MaxRescueIDs)
+	}
+	for _, id := range ids {
+		if _, _, ok := parseStreamID(id); !ok {
+			return 0, fmt.Errorf("%w: %q is not a stream ID", ErrInvalidRescueIDs, id)
+		}
+	}
+	// XCLAIM must not see an ID twice
+	ids = slices.Clone(ids)
+	slices.Sort(ids)
+	ids = slices.Compact(ids)
+
+	if err := g.ensureConsumerGroups(ctx, workerID); err != nil {
+		return 0, err
+	}
+
+	rescued := 0
+	for _, streamKey := range routing.GetWorkerStreamKeys(workerID) {
+		entries, err := g.redis.XClaim(ctx, streamKey, g.config.ConsumerGroupName, g.instanceID, 0, ids)
+		if err != nil {
+			return rescued, err
+		}
+		n, err := g.requeueClaimed(ctx, streamKey, entries)
+		rescued += n
+		if err != nil {
+			return rescued, err
+		}
+	}
+
+	slog.InfoContext(ctx, "stream messages rescued", "workerId", workerID, "requested", len(ids), "rescued", rescued)
+	return rescued, nil
+}
+
+// reclaimStream claims up to staleClaimBatchSize stale entries of streamKey
+// to this gateway and re-adds them
+func (g *Gateway) reclaimStream(ctx context.Context, streamKey string) (int, error) {
+	entries, err := g.redis.XAutoClaim(ctx, streamKey, g.config.ConsumerGroupName, g.instanceID, g.config.StaleMessageMinIdle, "0-0", staleClaimBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	return g.requeueClaimed(ctx, streamKey, entries)
+}
+
+// requeueClaimed re-adds entries claimed from streamKey to the same stream
+// marked as retried, then acknowledges and deletes the originals. Entries
+// whose payload is not a StreamMessage are acknowledged and deleted without
+// being re-added. Entries delivered StaleMessageMaxDeliveries times, counted
+// by XPENDING across re-adds, are dead-lettered instead, so a message that
+// crashes every worker is not reclaimed forever.
+func (g *Gateway) requeueClaimed(ctx context.Context, streamKey string, entries []redis.XMessage) (int, error) {
+	ids := make([]string, len(entries))
+	for i, entry := range entries {
+		ids[i] = entry.ID
+	}
+	pending, err := g.redis.XPendingDeliveries(ctx, streamKey, g.config.ConsumerGroupName, ids)
+	if err != nil {
+		return 0, err
+	}
+
+	reclaimed := 0
+	for i, entry := range entries {
+		payload, _ := entry.Values["payload"].(string)
+		// The claim by this gateway counts as one of the pending deliveries
+		previous, _ := entry.Values[deliveriesField].(string)
+		deliveries, _ := strconv.Atoi(previous)
+		deliveries += max(int(pending[i])-1, 0)
+
+		var msg StreamMessage
+		if err := json.Unmarshal([]byte(payload), &msg); err != nil {
+			slog.WarnContext(ctx, "dropping undecodable stale entry", "streamKey", streamKey, "entryId", entry.ID, "error", err)
+		} else if deliveries >= g.config.StaleMessageMaxDeliveries {
+			slog.WarnContext(ctx, "stale entry exceeded max deliveries", "streamKey", streamKey, "entryId", entry.ID, "messageId", msg.ID, "deliveries", deliveries)
+			g.deadLetter(ctx, streamKey, []byte(payload), fmt.Errorf("not acknowledged after %d deliveries", deliveries), deliveries)
+		} else {
+			msg.Retried = true
+			retryPayload, err := json.Marshal(msg)
+			if err != nil {
+				return reclaimed, err
+			}
+			traceContext, _ := entry.Values[tracing.StreamField].(string)
+			values := streamEntry(retryPayload, traceContext)
+			values[deliveriesField] = deliveries
+			if _, err := g.redis.XAdd(ctx, streamKey, values); err != nil {
+				return reclaimed, err
+			}
+			reclaimed++
+			g.metrics.StaleMessagesReclaimedTotal.Inc()
+		}
+
+		if err := g.redis.XAck(ctx, streamKey, g.config.ConsumerGroupName, entry.ID); err != nil {
+			return reclaimed, err
+		}
+		if err := g.redis.XDel(ctx, streamKey, entry.ID); err != nil {
+			return reclaimed, err
+		}
+	}
+	return reclaimed, nil
+}
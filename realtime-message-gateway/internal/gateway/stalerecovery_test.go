@@ -0,0 +1,193 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"realtime-message-gateway/internal/routing"
+)
+
+func TestRecoverStaleMessages(t *testing.T) {
+	gw := NewTestGateway(t)
+	ctx := context.Background()
+	group := gw.config.ConsumerGroupName
+	normal := routing.GetWorkerStreamKey("worker-0", routing.PriorityNormal)
+	high := routing.GetWorkerStreamKey("worker-0", routing.PriorityHigh)
+
+	if err := gw.ensureConsumerGroups(ctx, "worker-0"); err != nil {
+		t.Fatalf("ensureConsumerGroups() error = %v", err)
+	}
+	for _, entry := range []struct{ streamKey, payload string }{
+		{normal, `{"id":"m1","type":"message","channel":"chat","text":"hi"}`},
+		{normal, `not json`},
+		{high, `{"id":"m2","type":"message","channel":"chat"}`},
+	} {
+		if _, err := gw.redis.XAdd(ctx, entry.streamKey, map[string]interface{}{"payload": entry.payload}); err != nil {
+			t.Fatalf("XAdd() error = %v", err)
+		}
+	}
+
+	// The worker reads everything but only acknowledges the high entry
+	for _, streamKey := range routing.GetWorkerStreamKeys("worker-0") {
+		entries, err := gw.redis.XReadGroup(ctx, streamKey, group, "worker-0", ">", 10, 10*time.Millisecond)
+		if err != nil {
+			t.Fatalf("XReadGroup() error = %v", err)
+		}
+		if streamKey == high {
+			if err := gw.redis.XAck(ctx, high, group, entries[0].ID); err != nil {
+				t.Fatalf("XAck() error = %v", err)
+			}
+		}
+	}
+
+	gw.config.StaleMessageMinIdle = time.Hour
+	if n, err := gw.recoverStaleMessages(ctx); err != nil || n != 0 {
+		t.Fatalf("recoverStaleMessages() before min idle = %d, %v, want 0, nil", n, err)
+	}
+
+	gw.config.StaleMessageMinIdle = time.Millisecond
+	time.Sleep(5 * time.Millisecond)
+	n, err := gw.recoverStaleMessages(ctx)
+	if err != nil {
+		t.Fatalf("recoverStaleMessages() error = %v", err)
+	}
+	if n != 1 {
+		t.Errorf("recoverStaleMessages() = %d, want 1", n)
+	}
+
+	pending, err := gw.redis.XPendingCount(ctx, normal, group)
+	if err != nil {
+		t.Fatalf("XPendingCount() error = %v", err)
+	}
+	if pending != 0 {
+		t.Errorf("pending entries = %d, want 0", pending)
+	}
+
+	entries, err := gw.redis.XReadGroup(ctx, normal, group, "worker-1", ">", 10, 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("XReadGroup() error = %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("read %d entries after recovery, want 1", len(entries))
+	}
+	var msg StreamMessage
+	if err := json.Unmarshal([]byte(entries[0].Values["payload"].(string)), &msg); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if msg.ID != "m1" || msg.Text != "hi" || !msg.Retried {
+		t.Errorf("retried message = %+v, want m1 with retried set", msg)
+	}
+
+	length, err := gw.redis.XLen(ctx, normal)
+	if err != nil {
+		t.Fatalf("XLen() error = %v", err)
+	}
+	if length != 1 {
+		t.Errorf("stream length = %d, want only the re-added entry", length)
+	}
+}
+
+func TestRecoverStaleMessagesDeadLetter(t *testing.T) {
+	gw := NewTestGateway(t)
+	ctx := context.Background()
+	group := gw.config.ConsumerGroupName
+	normal := routing.GetWorkerStreamKey("worker-0", routing.PriorityNormal)
+	gw.config.DeadLetterEnabled = true
+	gw.config.StaleMessageMaxDeliveries = 2
+	gw.config.StaleMessageMinIdle = time.Millisecond
+
+	if err := gw.ensureConsumerGroups(ctx, "worker-0"); err != nil {
+		t.Fatalf("ensureConsumerGroups() error = %v", err)
+	}
+	if _, err := gw.redis.XAdd(ctx, normal, map[string]interface{}{"payload": `{"id":"m1","type":"message","channel":"chat"}`}); err != nil {
+		t.Fatalf("XAdd() error = %v", err)
+	}
+
+	// The message crashes the worker on every delivery
+	for _, want := range []int{1, 0} {
+		entries, err := gw.redis.XReadGroup(ctx, normal, group, "worker-0", ">", 10, 10*time.Millisecond)
+		if err != nil || len(entries) != 1 {
+			t.Fatalf("XReadGroup() = %d entries, %v, want 1", len(entries), err)
+		}
+		time.Sleep(5 * time.Millisecond)
+		if n, err := gw.recoverStaleMessages(ctx); err != nil || n != want {
+			t.Fatalf("recoverStaleMessages() = %d, %v, want %d, nil", n, err, want)
+		}
+	}
+
+	if length, err := gw.redis.XLen(ctx, normal); err != nil || length != 0 {
+		t.Errorf("stream length = %d, %v, want 0", length, err)
+	}
+	letters, err := gw.DeadLetters(ctx, 10)
+	if err != nil {
+		t.Fatalf("DeadLetters() error = %v", err)
+	}
+	if len(letters) != 1 || letters[0].StreamKey != normal || letters[0].Attempts != 2 {
+		t.Errorf("DeadLetters() = %+v, want the message after 2 deliveries", letters)
+	}
+}
+
+func TestRescueStreamMessages(t *testing.T) {
+	gw := NewTestGateway(t)
+	ctx := context.Background()
+	group := gw.config.ConsumerGroupName
+	normal := routing.GetWorkerStreamKey("worker-0", routing.PriorityNormal)
+	high := routing.GetWorkerStreamKey("worker-0", routing.PriorityHigh)
+
+	if err := gw.ensureConsumerGroups(ctx, "worker-0"); err != nil {
+		t.Fatalf("ensureConsumerGroups() error = %v", err)
+	}
+	// The high entry comes first, so no later normal entry can share its ID
+	var ids []string
+	for _, streamKey := range []string{high, normal, normal} {
+		id, err := gw.redis.XAdd(ctx, streamKey, map[string]interface{}{"payload": `{"id":"m","type":"message","channel":"chat"}`})
+		if err != nil {
+			t.Fatalf("XAdd() error = %v", err)
+		}
+		ids = append(ids, id)
+	}
+	for _, streamKey := range routing.GetWorkerStreamKeys("worker-0") {
+		if _, err := gw.redis.XReadGroup(ctx, streamKey, group, "worker-0", ">", 10, 10*time.Millisecond); err != nil {
+			t.Fatalf("XReadGroup() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		ids  []string
+	}{
+		{"no IDs", nil},
+		{"invalid ID", []string{"abc"}},
+		{"too many IDs", make([]string, MaxRescueIDs+1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := gw.RescueStreamMessages(ctx, "worker-0", tt.ids); !errors.Is(err, ErrInvalidRescueIDs) {
+				t.Errorf("RescueStreamMessages() error = %v, want %v", err, ErrInvalidRescueIDs)
+			}
+		})
+	}
+
+	// Entries are claimed without waiting for them to go idle; the second
+	// normal entry stays with the worker and unknown IDs are skipped. The
+	// first entries of both streams can share an ID, claiming both.
+	n, err := gw.RescueStreamMessages(ctx, "worker-0", []string{ids[1], ids[0], ids[1], "1-1"})
+	if err != nil {
+		t.Fatalf("RescueStreamMessages() error = %v", err)
+	}
+	if n != 2 {
+		t.Errorf("RescueStreamMessages() = %d, want 2", n)
+	}
+	for streamKey, want := range map[string]int64{normal: 1, high: 0} {
+		pending, err := gw.redis.XPendingCount(ctx, streamKey, group)
+		if err != nil {
+			t.Fatalf("XPendingCount() error = %v", err)
+		}
+		if pending != want {
+			t.Errorf("%s pending = %d, want %d", streamKey, pending, want)
+		}
+	}
+}
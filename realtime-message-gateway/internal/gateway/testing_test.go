@@ -0,0 +1,120 @@
+package gateway
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+
+	"realtime-message-gateway/internal/config"
+	"realtime-message-gateway/internal/redis"
+	"realtime-message-gateway/internal/routing"
+)
+
+// testOptions collects the settings applied by TestOption
+type testOptions struct {
+	cfg         *config.Config
+	workers     []string
+	gatewayOpts []Option
+}
+
+// TestOption customizes the gateway created by NewTestGateway
+type TestOption func(*testOptions)
+
+// WithTokenSecret sets the HMAC secret for connection and subscription tokens
+func WithTokenSecret(secret string) TestOption {
+	return func(o *testOptions) {
+		o.cfg.TokenHMACSecret = secret
+	}
+}
+
+// WithGatewayOptions applies opts after the test config when creating the
+// gateway
+func WithGatewayOptions(opts ...Option) TestOption {
+	return func(o *testOptions) {
+		o.gatewayOpts = append(o.gatewayOpts, opts...)
+	}
+}
+
+// WithWorkers registers ids as the active workers instead of worker-0
+func WithWorkers(ids []string) TestOption {
+	return func(o *testOptions) {
+		o.workers = ids
+	}
+}
+
+// NewTestGateway runs a gateway backed by a fresh miniredis instance like
+// gatewaytest.New, which the tests of this package cannot import. Ports are
+// left at 0 so any listener started from the config gets a free port. The
+// gateway and Redis are shut down when the test ends.
+func NewTestGateway(t testing.TB, opts ...TestOption) *Gateway {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	o := &testOptions{
+		cfg: &config.Config{
+			RedisURL:              "redis://" + mr.Addr(),
+			MaxTextLength:         100,
+			AllowedContentTypes:   []string{ContentTypeText, ContentTypeJSON, ContentTypeReaction},
+			MaxMetaKeys:           10,
+			MaxMetaValueLen:       256,
+			StreamSchemaVersion:   1,
+			OutboundStreamBlock:   50 * time.Millisecond,
+			PublishContextTimeout: 5 * time.Second,
+			ConsumerGroupName:     "gw-consumer",
+
+			ReplayMaxMessagesPerSecond: 1000,
+			WorkerSelectionStrategy:    "round-robin",
+			StaleMessageMaxDeliveries:  5,
+
+			ChannelMetadataMaxSize: 64,
+			RecoverHistoryLimit:    100,
+			HistoryRetain:          100,
+			UserHistoryRetain:      50,
+			HistoryCacheSize:       100,
+
+			PingInterval:     25 * time.Second,
+			PongTimeout:      10 * time.Second,
+			MessageSizeLimit: 65536,
+			SockJSURL:        "/connection/sockjs",
+		},
+		workers: []string{"worker-0"},
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	for i, workerID := range o.workers {
+		mr.ZAdd(routing.ActiveWorkersKey, float64(i+1), workerID)
+	}
+
+	redisClient, err := redis.NewClient(o.cfg)
+	if err != nil {
+		t.Fatalf("redis.NewClient() error = %v", err)
+	}
+	t.Cleanup(func() { redisClient.Close() })
+
+	gw, err := NewGateway(append([]Option{WithConfig(o.cfg), WithRedis(redisClient)}, o.gatewayOpts...)...)
+	if err != nil {
+		t.Fatalf("NewGateway() error = %v", err)
+	}
+	if err := gw.Run(); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	t.Cleanup(func() { gw.Shutdown(context.Background()) })
+	return gw
+}
+
+// newTestRedisClient returns a client of a fresh miniredis instance that is
+// closed when the test ends
+func newTestRedisClient(t testing.TB) *redis.Client {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	client, err := redis.NewClient(&config.Config{RedisURL: "redis://" + mr.Addr()})
+	if err != nil {
+		t.Fatalf("redis.NewClient() error = %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
@@ -0,0 +1,24 @@
+package gateway
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// SignSubscriptionToken returns the subscription token the application backend
+// issues for a private channel: hex(HMAC-SHA256(secret, clientID+channel))
+func SignSubscriptionToken(secret, clientID, channel string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(clientID + channel))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// verifySubscriptionToken checks a subscription token in constant time
+func verifySubscriptionToken(secret, clientID, channel, token string) bool {
+	if secret == "" || token == "" {
+		return false
+	}
+	expected := SignSubscriptionToken(secret, clientID, channel)
+	return hmac.Equal([]byte(expected), []byte(token))
+}
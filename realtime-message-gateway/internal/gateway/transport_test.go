@@ -0,0 +1,75 @@
+package gateway
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/centrifugal/centrifuge"
+	"github.com/centrifugal/protocol"
+)
+
+// testTransport is an in-memory bidirectional JSON transport recording
+// everything written to the client
+type testTransport struct {
+	mu         sync.Mutex
+	messages   [][]byte
+	closed     bool
+	disconnect centrifuge.Disconnect
+}
+
+func (t *testTransport) Name() string                      { return "test" }
+func (t *testTransport) Protocol() centrifuge.ProtocolType { return centrifuge.ProtocolTypeJSON }
+func (t *testTransport) ProtocolVersion() centrifuge.ProtocolVersion {
+	return centrifuge.ProtocolVersion2
+}
+func (t *testTransport) Unidirectional() bool      { return false }
+func (t *testTransport) Emulation() bool           { return false }
+func (t *testTransport) DisabledPushFlags() uint64 { return 0 }
+func (t *testTransport) PingPongConfig() centrifuge.PingPongConfig {
+	return centrifuge.PingPongConfig{}
+}
+
+func (t *testTransport) Write(data []byte) error {
+	return t.WriteMany(data)
+}
+
+func (t *testTransport) WriteMany(data ...[]byte) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, d := range data {
+		t.messages = append(t.messages, append([]byte(nil), d...))
+	}
+	return nil
+}
+
+func (t *testTransport) Close(d centrifuge.Disconnect) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.closed = true
+	t.disconnect = d
+	return nil
+}
+
+// connectTestClient connects an in-memory client to gw
+func connectTestClient(t *testing.T, gw *Gateway) *centrifuge.Client {
+	t.Helper()
+
+	client, closeFn, err := centrifuge.NewClient(context.Background(), gw.node, &testTransport{})
+	if err != nil {
+		t.Fatalf("centrifuge.NewClient() error = %v", err)
+	}
+	t.Cleanup(func() { closeFn() })
+
+	client.HandleCommand(&protocol.Command{Id: 1, Connect: &protocol.ConnectRequest{}}, 0)
+	return client
+}
+
+// subscribeTestClient sends a subscribe command for channel; id must be
+// unique per client and greater than 1 (the connect command)
+func subscribeTestClient(client *centrifuge.Client, id uint32, channel string) {
+	client.HandleCommand(&protocol.Command{
+		Id:        id,
+		Subscribe: &protocol.SubscribeRequest{Channel: channel},
+	}, 0)
+}
@@ -0,0 +1,52 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+
+	"realtime-message-gateway/internal/redis"
+)
+
+// UserHistoryPrefix is the key prefix of the per-user lists of published
+// messages, newest first
+const UserHistoryPrefix = "user:history:"
+
+// DefaultRecentMessagesLimit is the number of messages returned by
+// GET /admin/users/{userId}/recent-messages without ?limit=
+const DefaultRecentMessagesLimit = 50
+
+// userHistoryList returns the history list of userID, or false when
+// UserHistoryRetain disables user history
+func (g *Gateway) userHistoryList(userID string) (redis.CappedList, bool) {
+	if g.config.UserHistoryRetain <= 0 {
+		return redis.CappedList{}, false
+	}
+	return redis.CappedList{Key: UserHistoryPrefix + userID, MaxLen: int64(g.config.UserHistoryRetain)}, true
+}
+
+// GetUserRecentMessages returns up to limit of the most recent messages
+// userID published, newest first, for moderation. Each message carries its
+// channel and timestamp. Records that do not decode are skipped.
+func (g *Gateway) GetUserRecentMessages(ctx context.Context, userID string, limit int) ([]StreamMessage, error) {
+	if limit <= 0 {
+		return []StreamMessage{}, nil
+	}
+	records, err := g.redis.LRange(ctx, UserHistoryPrefix+userID, 0, int64(limit-1))
+	if err != nil {
+		return nil, err
+	}
+
+	messages := make([]StreamMessage, 0, len(records))
+	for _, raw := range records {
+		var record historyRecord
+		var msg StreamMessage
+		if json.Unmarshal([]byte(raw), &record) != nil || json.Unmarshal(record.Message, &msg) != nil {
+			continue
+		}
+		if err := MigrateStreamMessage(&msg, g.config.StreamSchemaVersion); err != nil {
+			return nil, err
+		}
+		messages = append(messages, msg)
+	}
+	return messages, nil
+}
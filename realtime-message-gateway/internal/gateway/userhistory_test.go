@@ -0,0 +1,68 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestGetUserRecentMessages(t *testing.T) {
+	gw := NewTestGateway(t)
+	gw.config.UserHistoryRetain = 3
+	gw.config.HistoryTTL = time.Hour
+	alice := connectTestClient(t, gw)
+	bob := connectTestClient(t, gw)
+	ctx := context.Background()
+
+	for i, channel := range []string{"chat:a", "chat:b", "chat:a", "chat:b"} {
+		if err := publishAndWait(gw, alice, channel, fmt.Sprintf(`{"text":"alice %d"}`, i+1)); err != nil {
+			t.Fatalf("publish error = %v", err)
+		}
+	}
+	if err := publishAndWait(gw, bob, "chat:a", `{"text":"bob 1"}`); err != nil {
+		t.Fatalf("publish error = %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		userID string
+		limit  int
+		want   []string
+	}{
+		{"newest first", alice.UserID(), 2, []string{"alice 4", "alice 3"}},
+		{"trimmed to retain", alice.UserID(), 10, []string{"alice 4", "alice 3", "alice 2"}},
+		{"other user", bob.UserID(), 10, []string{"bob 1"}},
+		{"no messages", "nobody", 10, nil},
+		{"zero limit", alice.UserID(), 0, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messages, err := gw.GetUserRecentMessages(ctx, tt.userID, tt.limit)
+			if err != nil {
+				t.Fatalf("GetUserRecentMessages() error = %v", err)
+			}
+			var texts []string
+			for _, msg := range messages {
+				texts = append(texts, msg.Text)
+				if msg.UserID != tt.userID || msg.Channel == "" || msg.Timestamp == "" {
+					t.Errorf("message %+v, want userId %s with channel and timestamp", msg, tt.userID)
+				}
+			}
+			if !slices.Equal(texts, tt.want) {
+				t.Errorf("GetUserRecentMessages() texts = %v, want %v", texts, tt.want)
+			}
+		})
+	}
+
+	// Disabled with a retain of 0
+	gw.config.UserHistoryRetain = 0
+	if err := publishAndWait(gw, bob, "chat:a", `{"text":"bob 2"}`); err != nil {
+		t.Fatalf("publish error = %v", err)
+	}
+	if messages, _ := gw.GetUserRecentMessages(ctx, bob.UserID(), 10); len(messages) != 1 {
+		t.Errorf("bob has %d recent messages with USER_HISTORY_RETAIN=0, want 1", len(messages))
+	}
+}
@@ -0,0 +1,137 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"realtime-message-gateway/internal/metrics"
+)
+
+// WebhookSignatureHeader carries "sha256=" followed by the hex HMAC-SHA256
+// of the request body, keyed with WEBHOOK_SECRET
+const WebhookSignatureHeader = "X-Gateway-Signature"
+
+// Presence webhook delivery policy
+const (
+	webhookMaxRetries = 3
+	webhookBaseDelay  = 500 * time.Millisecond
+	webhookTimeout    = 5 * time.Second
+)
+
+// webhookDispatcher POSTs presence events to the application backend from a
+// pool of workers reading a bounded queue, so slow webhooks never block
+// subscribe and unsubscribe handling
+type webhookDispatcher struct {
+	url       string
+	secret    string
+	client    *http.Client
+	queue     chan []byte
+	baseDelay time.Duration
+	metrics   *metrics.Metrics
+}
+
+// newWebhookDispatcher creates a webhookDispatcher buffering up to queueSize
+// events and recording deliveries in m
+func newWebhookDispatcher(url, secret string, queueSize int, m *metrics.Metrics) *webhookDispatcher {
+	return &webhookDispatcher{
+		url:       url,
+		secret:    secret,
+		client:    &http.Client{Timeout: webhookTimeout},
+		queue:     make(chan []byte, queueSize),
+		baseDelay: webhookBaseDelay,
+		metrics:   m,
+	}
+}
+
+// webhookHandler queues presence events for the webhook when WEBHOOK_URL
+// is set
+func (g *Gateway) webhookHandler(ctx context.Context, event GatewayEvent) {
+	if g.webhook == nil {
+		return
+	}
+	payload, err := json.Marshal(event.Message)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to marshal webhook event", "error", err)
+		return
+	}
+	g.webhook.enqueue(payload)
+}
+
+// enqueue queues payload for delivery, dropping it if the queue is full
+func (d *webhookDispatcher) enqueue(payload []byte) {
+	select {
+	case d.queue <- payload:
+	default:
+		d.metrics.WebhookDroppedTotal.Inc()
+		slog.Warn("webhook queue full, dropping presence event")
+	}
+}
+
+// run delivers queued events until ctx is cancelled; events still queued
+// on shutdown are not sent
+func (d *webhookDispatcher) run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case payload := <-d.queue:
+			if err := d.deliver(ctx, payload); err != nil {
+				d.metrics.WebhookDeliveriesTotal.WithLabelValues("failed").Inc()
+				slog.Error("presence webhook failed", "url", d.url, "error", err)
+				continue
+			}
+			d.metrics.WebhookDeliveriesTotal.WithLabelValues("success").Inc()
+		}
+	}
+}
+
+// deliver posts payload, retrying failures up to webhookMaxRetries times
+// with exponential backoff
+func (d *webhookDispatcher) deliver(ctx context.Context, payload []byte) error {
+	delay := d.baseDelay
+	err := d.post(ctx, payload)
+	for retry := 1; err != nil && retry <= webhookMaxRetries; retry++ {
+		sleepCtx(ctx, delay)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		delay *= 2
+		err = d.post(ctx, payload)
+	}
+	return err
+}
+
+// post sends one signed webhook request; non-2xx responses are errors
+func (d *webhookDispatcher) post(ctx context.Context, payload []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookSignatureHeader, signWebhook(d.secret, payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// signWebhook returns the X-Gateway-Signature value for body
+func signWebhook(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,143 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"realtime-message-gateway/internal/metrics"
+	"realtime-message-gateway/internal/routing"
+)
+
+func TestSignWebhook(t *testing.T) {
+	// echo -n '{"a":1}' | openssl dgst -sha256 -hmac secret
+	want := "sha256=aa9e2e3575f5d7098b6caccd790888c36d5fdb63342a73bada2d6a51747a8494"
+	if got := signWebhook("secret", []byte(`{"a":1}`)); got != want {
+		t.Errorf("signWebhook() = %q, want %q", got, want)
+	}
+}
+
+func TestWebhookDeliverRetries(t *testing.T) {
+	tests := []struct {
+		name      string
+		failures  int32
+		wantCalls int32
+		wantErr   bool
+	}{
+		{"first attempt succeeds", 0, 1, false},
+		{"succeeds on last retry", webhookMaxRetries, webhookMaxRetries + 1, false},
+		{"gives up after retries", webhookMaxRetries + 1, webhookMaxRetries + 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if calls.Add(1) <= tt.failures {
+					w.WriteHeader(http.StatusBadGateway)
+				}
+			}))
+			defer srv.Close()
+
+			d := newWebhookDispatcher(srv.URL, "secret", 1, metrics.Default)
+			d.baseDelay = time.Millisecond
+
+			err := d.deliver(context.Background(), []byte(`{}`))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("deliver() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := calls.Load(); got != tt.wantCalls {
+				t.Errorf("webhook called %d times, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestWebhookEnqueueDropsWhenFull(t *testing.T) {
+	d := newWebhookDispatcher("http://127.0.0.1:0", "secret", 1, metrics.Default)
+	before := testutil.ToFloat64(metrics.Default.WebhookDroppedTotal)
+
+	d.enqueue([]byte(`{"n":1}`))
+	d.enqueue([]byte(`{"n":2}`))
+
+	if got := testutil.ToFloat64(metrics.Default.WebhookDroppedTotal) - before; got != 1 {
+		t.Errorf("WebhookDroppedTotal increased by %v, want 1", got)
+	}
+	if got := string(<-d.queue); got != `{"n":1}` {
+		t.Errorf("queued payload = %s, want the first event", got)
+	}
+}
+
+func TestPresenceEventWebhook(t *testing.T) {
+	type request struct {
+		signature string
+		body      []byte
+	}
+	var (
+		mu       sync.Mutex
+		requests []request
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		requests = append(requests, request{r.Header.Get(WebhookSignatureHeader), body})
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	gw := NewTestGateway(t)
+	gw.webhook = newWebhookDispatcher(srv.URL, "secret", 10, gw.metrics)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go gw.webhook.run(ctx)
+
+	client := connectTestClient(t, gw)
+
+	// No webhook for an event that was not written to the worker stream
+	streamKey := routing.GetWorkerStreamKey("worker-0", routing.PriorityNormal)
+	if err := gw.redis.Set(context.Background(), streamKey, "not a stream", 0); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	gw.pushPresenceEvent(context.Background(), client, "chat:lobby", EventTypeLeave, nil)
+	if err := gw.redis.Del(context.Background(), streamKey); err != nil {
+		t.Fatalf("Del() error = %v", err)
+	}
+
+	gw.pushPresenceEvent(context.Background(), client, "chat:lobby", EventTypeJoin, nil)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		mu.Lock()
+		n := len(requests)
+		mu.Unlock()
+		if n > 0 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(requests) != 1 {
+		t.Fatalf("webhook received %d requests, want 1", len(requests))
+	}
+	req := requests[0]
+	if want := signWebhook("secret", req.body); req.signature != want {
+		t.Errorf("%s = %q, want %q", WebhookSignatureHeader, req.signature, want)
+	}
+	var event StreamMessage
+	if err := json.Unmarshal(req.body, &event); err != nil {
+		t.Fatalf("webhook body is not a StreamMessage: %v", err)
+	}
+	if event.Type != EventTypeJoin || event.Channel != "chat:lobby" || event.UserID != client.UserID() {
+		t.Errorf("webhook event = %+v, want join of %s to chat:lobby", event, client.UserID())
+	}
+}
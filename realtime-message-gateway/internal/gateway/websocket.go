@@ -0,0 +1,80 @@
+package gateway
+
+import (
+	"log/slog"
+	"net/http"
+	"slices"
+	"sync"
+
+	"github.com/centrifugal/centrifuge"
+
+	"realtime-message-gateway/internal/config"
+)
+
+// compressionSettings is the per-request outcome of a CompressionPolicy
+type compressionSettings struct {
+	enabled bool
+	level   int
+	minSize int
+}
+
+// websocketHandler dispatches each upgrade request to a Centrifuge
+// WebSocket handler built for the compression settings the policy chose.
+// Centrifuge fixes compression per handler, so one is kept per settings.
+type websocketHandler struct {
+	node     *centrifuge.Node
+	base     centrifuge.WebsocketConfig
+	policy   config.CompressionPolicy
+	handlers sync.Map // compressionSettings -> *centrifuge.WebsocketHandler
+}
+
+// CheckOrigin reports whether a client transport request may connect: its
+// Origin must be in ALLOWED_ORIGINS, when set, and its client IP in
+// ALLOWED_IP_CIDRS. The Origin header is easily set by non-browser clients,
+// hence the additional IP check, which only trusts forwarding headers set
+// by TRUSTED_PROXY_CIDRS.
+func (g *Gateway) CheckOrigin(r *http.Request) bool {
+	if ip := g.ClientIP(r); !g.IPAllowed(ip) {
+		slog.DebugContext(r.Context(), "connection from disallowed ip", "ip", ip)
+		return false
+	}
+	// Allow all origins if not configured
+	if len(g.config.AllowedOrigins) == 0 {
+		return true
+	}
+	return slices.Contains(g.config.AllowedOrigins, r.Header.Get("Origin"))
+}
+
+// WebsocketHandler returns the WebSocket endpoint handler. Compression
+// fields of base are overridden per request by the configured
+// CompressionPolicy; without a policy base is used as is.
+func (g *Gateway) WebsocketHandler(base centrifuge.WebsocketConfig) http.Handler {
+	if g.config.CompressionPolicy == nil {
+		return centrifuge.NewWebsocketHandler(g.node, base)
+	}
+	return &websocketHandler{
+		node:   g.node,
+		base:   base,
+		policy: g.config.CompressionPolicy,
+	}
+}
+
+func (h *websocketHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	enabled, level, minSize := h.policy(r)
+	h.handlerFor(compressionSettings{enabled: enabled, level: level, minSize: minSize}).ServeHTTP(w, r)
+}
+
+// handlerFor returns the cached handler for settings, creating it on first use
+func (h *websocketHandler) handlerFor(settings compressionSettings) http.Handler {
+	if handler, ok := h.handlers.Load(settings); ok {
+		return handler.(*centrifuge.WebsocketHandler)
+	}
+
+	cfg := h.base
+	cfg.Compression = settings.enabled
+	cfg.CompressionLevel = settings.level
+	cfg.CompressionMinSize = settings.minSize
+
+	handler, _ := h.handlers.LoadOrStore(settings, centrifuge.NewWebsocketHandler(h.node, cfg))
+	return handler.(*centrifuge.WebsocketHandler)
+}
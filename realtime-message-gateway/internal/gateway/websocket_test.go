@@ -0,0 +1,37 @@
+package gateway
+
+import (
+	"testing"
+
+	"github.com/centrifugal/centrifuge"
+
+	"realtime-message-gateway/internal/config"
+)
+
+func TestWebsocketHandlerCachesPerSettings(t *testing.T) {
+	gw := &Gateway{config: &config.Config{CompressionPolicy: config.DefaultCompressionPolicy(4, 1024)}}
+
+	h, ok := gw.WebsocketHandler(centrifuge.WebsocketConfig{}).(*websocketHandler)
+	if !ok {
+		t.Fatal("WebsocketHandler() did not return a policy-aware handler")
+	}
+
+	enabled := compressionSettings{enabled: true, level: 4, minSize: 1024}
+	disabled := compressionSettings{}
+
+	first := h.handlerFor(enabled)
+	if h.handlerFor(enabled) != first {
+		t.Error("handlerFor() built a second handler for the same settings")
+	}
+	if h.handlerFor(disabled) == first {
+		t.Error("handlerFor() reused a handler across different settings")
+	}
+}
+
+func TestWebsocketHandlerWithoutPolicy(t *testing.T) {
+	gw := &Gateway{config: &config.Config{}}
+
+	if _, ok := gw.WebsocketHandler(centrifuge.WebsocketConfig{}).(*centrifuge.WebsocketHandler); !ok {
+		t.Error("WebsocketHandler() without policy should return a plain Centrifuge handler")
+	}
+}
@@ -0,0 +1,109 @@
+// Package gatewaytest runs gateways backed by miniredis for the tests of
+// packages that use the gateway. It is imported only by tests, so miniredis
+// stays out of the gateway binary.
+package gatewaytest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+
+	"realtime-message-gateway/internal/config"
+	"realtime-message-gateway/internal/gateway"
+	"realtime-message-gateway/internal/redis"
+	"realtime-message-gateway/internal/routing"
+)
+
+// options collects the settings applied by Option
+type options struct {
+	cfg         *config.Config
+	workers     []string
+	gatewayOpts []gateway.Option
+}
+
+// Option customizes the gateway created by New
+type Option func(*options)
+
+// WithTokenSecret sets the HMAC secret for connection and subscription tokens
+func WithTokenSecret(secret string) Option {
+	return func(o *options) {
+		o.cfg.TokenHMACSecret = secret
+	}
+}
+
+// WithGatewayOptions applies opts after the test config when creating the
+// gateway
+func WithGatewayOptions(opts ...gateway.Option) Option {
+	return func(o *options) {
+		o.gatewayOpts = append(o.gatewayOpts, opts...)
+	}
+}
+
+// WithWorkers registers ids as the active workers instead of worker-0
+func WithWorkers(ids []string) Option {
+	return func(o *options) {
+		o.workers = ids
+	}
+}
+
+// New runs a gateway backed by a fresh miniredis instance. Ports are left
+// at 0 so any listener started from the config gets a free port. The
+// gateway and Redis are shut down when the test ends.
+func New(t testing.TB, opts ...Option) *gateway.Gateway {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	o := &options{
+		cfg: &config.Config{
+			RedisURL:              "redis://" + mr.Addr(),
+			MaxTextLength:         100,
+			AllowedContentTypes:   []string{gateway.ContentTypeText, gateway.ContentTypeJSON, gateway.ContentTypeReaction},
+			MaxMetaKeys:           10,
+			MaxMetaValueLen:       256,
+			StreamSchemaVersion:   1,
+			OutboundStreamBlock:   50 * time.Millisecond,
+			PublishContextTimeout: 5 * time.Second,
+			ConsumerGroupName:     "gw-consumer",
+
+			ReplayMaxMessagesPerSecond: 1000,
+			WorkerSelectionStrategy:    "round-robin",
+			StaleMessageMaxDeliveries:  5,
+
+			ChannelMetadataMaxSize: 64,
+			RecoverHistoryLimit:    100,
+			HistoryRetain:          100,
+			UserHistoryRetain:      50,
+			HistoryCacheSize:       100,
+
+			PingInterval:     25 * time.Second,
+			PongTimeout:      10 * time.Second,
+			MessageSizeLimit: 65536,
+			SockJSURL:        "/connection/sockjs",
+		},
+		workers: []string{"worker-0"},
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	for i, workerID := range o.workers {
+		mr.ZAdd(routing.ActiveWorkersKey, float64(i+1), workerID)
+	}
+
+	redisClient, err := redis.NewClient(o.cfg)
+	if err != nil {
+		t.Fatalf("redis.NewClient() error = %v", err)
+	}
+	t.Cleanup(func() { redisClient.Close() })
+
+	gw, err := gateway.NewGateway(append([]gateway.Option{gateway.WithConfig(o.cfg), gateway.WithRedis(redisClient)}, o.gatewayOpts...)...)
+	if err != nil {
+		t.Fatalf("NewGateway() error = %v", err)
+	}
+	if err := gw.Run(); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	t.Cleanup(func() { gw.Shutdown(context.Background()) })
+	return gw
+}
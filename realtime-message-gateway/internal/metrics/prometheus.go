@@ -7,111 +7,425 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promauto"
 )
 
-var (
+// Metrics holds the gateway's Prometheus collectors
+type Metrics struct {
 	// Connect metrics
-	ConnectTotal = promauto.NewCounterVec(prometheus.CounterOpts{
-		Namespace: "gateway",
-		Name:      "connect_total",
-		Help:      "Total connect requests by status",
-	}, []string{"status"})
+	ConnectTotal           *prometheus.CounterVec
+	ConnectIPLimitTotal    *prometheus.CounterVec
+	LoadShedTotal          prometheus.Counter
+	IdleDisconnectTotal    prometheus.Counter
+	RedisDegradedModeTotal prometheus.Counter
+	ShutdownTimeoutTotal   *prometheus.CounterVec
 
 	// Subscribe metrics
-	SubscribeTotal = promauto.NewCounterVec(prometheus.CounterOpts{
-		Namespace: "gateway",
-		Name:      "subscribe_total",
-		Help:      "Total subscribe requests by status",
-	}, []string{"status", "reason"})
+	SubscribeTotal               *prometheus.CounterVec
+	SubscribeRejectedChannelFull *prometheus.CounterVec
+	SubscribeRecoveredMessages   prometheus.Counter
+
+	// Channel stats (from channel:stats:{channel}, refreshed periodically)
+	ChannelMessages    *prometheus.GaugeVec
+	ChannelSubscribers *prometheus.GaugeVec
+
+	// Local channel distribution (sampled from the Centrifuge hub)
+	ChannelSubscriberCount prometheus.Summary
+	ActiveChannelsTotal    prometheus.Gauge
 
 	// Publish metrics
-	PublishTotal = promauto.NewCounterVec(prometheus.CounterOpts{
-		Namespace: "gateway",
-		Name:      "publish_total",
-		Help:      "Total publish requests by status",
-	}, []string{"status", "reason"})
-
-	PublishLatency = promauto.NewHistogram(prometheus.HistogramOpts{
-		Namespace: "gateway",
-		Name:      "publish_latency_seconds",
-		Help:      "Publish request latency",
-		Buckets:   []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1},
-	})
+	PublishTotal                *prometheus.CounterVec
+	PublishContentFilteredTotal prometheus.Counter
+	DeadLetterMessagesTotal     prometheus.Counter
+	ClientQueueOverflowTotal    prometheus.Counter
+	DedupBloomPositivesTotal    prometheus.Counter
+	WebhookDroppedTotal         prometheus.Counter
+	EventBusDroppedTotal        *prometheus.CounterVec
+	WebhookDeliveriesTotal      *prometheus.CounterVec
+	BatchChannelPublishTotal    *prometheus.CounterVec
+	BatchPublishSize            prometheus.Histogram
+
+	// Publish latencies are also exposed as native histograms, with buckets
+	// 10% apart, for accurate low-latency quantiles on Prometheus 2.40+
+	// servers scraping with native histograms enabled. Others keep reading
+	// the classic buckets.
+	PublishLatency prometheus.Histogram
+	E2ELatency     prometheus.Histogram
+
+	// Outbound metrics - messages pushed from workers back to clients
+	OutboundTotal *prometheus.CounterVec
 
 	// WebSocket metrics
-	WebSocketConnections = promauto.NewGauge(prometheus.GaugeOpts{
-		Namespace: "gateway",
-		Name:      "websocket_connections",
-		Help:      "Current number of WebSocket connections",
-	})
-
-	WebSocketMessagesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
-		Namespace: "gateway",
-		Name:      "websocket_messages_total",
-		Help:      "Total WebSocket messages by direction",
-	}, []string{"direction"})
+	WebSocketConnections   prometheus.Gauge
+	SockJSConnections      prometheus.Gauge
+	WebSocketMessagesTotal *prometheus.CounterVec
 
 	// Disconnect metrics - tracks disconnect reasons for reconnection analysis
-	DisconnectTotal = promauto.NewCounterVec(prometheus.CounterOpts{
-		Namespace: "gateway",
-		Name:      "disconnect_total",
-		Help:      "Total disconnections by reason code and whether it was a reconnect",
-	}, []string{"reason", "code", "reconnect"})
+	DisconnectTotal *prometheus.CounterVec
 
 	// Connection duration - helps understand connection stability
-	ConnectionDuration = promauto.NewHistogram(prometheus.HistogramOpts{
-		Namespace: "gateway",
-		Name:      "connection_duration_seconds",
-		Help:      "Duration of WebSocket connections",
-		Buckets:   []float64{1, 5, 10, 30, 60, 300, 600, 1800, 3600},
-	})
+	ConnectionDuration              prometheus.Histogram
+	WSPingRTT                       prometheus.Histogram
+	ConnectionStateTransitionsTotal *prometheus.CounterVec
 
 	// Reconnection metrics
-	ReconnectTotal = promauto.NewCounterVec(prometheus.CounterOpts{
-		Namespace: "gateway",
-		Name:      "reconnect_total",
-		Help:      "Total reconnection attempts by user",
-	}, []string{"status"})
+	ReconnectTotal *prometheus.CounterVec
 
 	// HTTP metrics
-	HTTPRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
-		Namespace: "gateway",
-		Name:      "http_requests_total",
-		Help:      "Total HTTP requests by method, path, status",
-	}, []string{"method", "path", "status"})
-
-	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
-		Namespace: "gateway",
-		Name:      "http_request_duration_seconds",
-		Help:      "HTTP request duration",
-		Buckets:   prometheus.DefBuckets,
-	}, []string{"method", "path"})
+	HTTPRequestsTotal     *prometheus.CounterVec
+	HTTPRequestDuration   *prometheus.HistogramVec
+	HealthComponentStatus *prometheus.GaugeVec
+	MetricsStreamClients  prometheus.Gauge
 
 	// Routing cache metrics
-	RouteCacheHits = promauto.NewCounter(prometheus.CounterOpts{
-		Namespace: "gateway",
-		Name:      "route_cache_hits_total",
-		Help:      "Route cache hits",
-	})
-
-	RouteCacheMisses = promauto.NewCounter(prometheus.CounterOpts{
-		Namespace: "gateway",
-		Name:      "route_cache_misses_total",
-		Help:      "Route cache misses",
-	})
+	RouteCacheHits                  prometheus.Counter
+	RouteCacheMisses                prometheus.Counter
+	RouteCacheKeyspaceInvalidations prometheus.Counter
+
+	// Channel history cache metrics
+	HistoryCacheHits            prometheus.Counter
+	HistoryCacheMisses          prometheus.Counter
+	StreamBacklogRatio          *prometheus.GaugeVec
+	StreamLagMessages           *prometheus.GaugeVec
+	StreamUnackedMessages       *prometheus.GaugeVec
+	StaleMessagesReclaimedTotal prometheus.Counter
 
 	// Redis metrics
-	RedisOperations = promauto.NewCounterVec(prometheus.CounterOpts{
-		Namespace: "gateway",
-		Name:      "redis_operations_total",
-		Help:      "Total Redis operations",
-	}, []string{"operation", "status"})
-
-	RedisLatency = promauto.NewHistogramVec(prometheus.HistogramOpts{
-		Namespace: "gateway",
-		Name:      "redis_latency_seconds",
-		Help:      "Redis operation latency",
-		Buckets:   []float64{.0005, .001, .005, .01, .025, .05, .1},
-	}, []string{"operation"})
-)
+	RedisOperations *prometheus.CounterVec
+	RedisLatency    *prometheus.HistogramVec
+}
+
+// Default holds the collectors registered with the global Prometheus
+// registry. It is used wherever no other Metrics is passed in.
+var Default = NewMetrics(prometheus.DefaultRegisterer)
+
+// NewMetrics creates all collectors and registers them with r. It panics if
+// r already holds them, so create one Metrics per registry.
+func NewMetrics(r prometheus.Registerer) *Metrics {
+	f := promauto.With(r)
+	return &Metrics{
+		// Connect metrics
+		ConnectTotal: f.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "gateway",
+			Name:      "connect_total",
+			Help:      "Total connect requests by status",
+		}, []string{"status"}),
+
+		ConnectIPLimitTotal: f.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "gateway",
+			Name:      "connect_ip_limit_total",
+			Help:      "Total connections rejected by the per-IP limit, by client /24 network",
+		}, []string{"cidr"}),
+
+		LoadShedTotal: f.NewCounter(prometheus.CounterOpts{
+			Namespace: "gateway",
+			Name:      "loadshed_total",
+			Help:      "Total connections rejected while the gateway was shedding load",
+		}),
+
+		IdleDisconnectTotal: f.NewCounter(prometheus.CounterOpts{
+			Namespace: "gateway",
+			Name:      "idle_disconnect_total",
+			Help:      "Total clients disconnected after MAX_IDLE_CONNECTION_TIME without subscribing or publishing",
+		}),
+
+		RedisDegradedModeTotal: f.NewCounter(prometheus.CounterOpts{
+			Namespace: "gateway",
+			Name:      "redis_degraded_mode_total",
+			Help:      "Total times the gateway entered degraded mode because Redis was unreachable",
+		}),
+
+		ShutdownTimeoutTotal: f.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "gateway",
+			Name:      "shutdown_timeout_total",
+			Help:      "Total graceful shutdowns that ran out of time, by server",
+		}, []string{"server"}),
+
+		// Subscribe metrics
+		SubscribeTotal: f.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "gateway",
+			Name:      "subscribe_total",
+			Help:      "Total subscribe requests by status",
+		}, []string{"status", "reason"}),
+
+		SubscribeRejectedChannelFull: f.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "gateway",
+			Name:      "subscribe_rejected_channel_full_total",
+			Help:      "Total subscribe requests rejected because the channel reached its subscriber limit",
+		}, []string{"channel"}),
+
+		SubscribeRecoveredMessages: f.NewCounter(prometheus.CounterOpts{
+			Namespace: "gateway",
+			Name:      "subscribe_recovered_messages_total",
+			Help:      "Total missed messages replayed to clients in subscribe replies",
+		}),
+
+		// Channel stats (from channel:stats:{channel}, refreshed periodically)
+		ChannelMessages: f.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "gateway",
+			Name:      "channel_messages",
+			Help:      "Messages published to a channel, from its stats hash",
+		}, []string{"channel"}),
+
+		ChannelSubscribers: f.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "gateway",
+			Name:      "channel_subscribers",
+			Help:      "Subscribers of a channel across all gateways, from its stats hash",
+		}, []string{"channel"}),
+
+		// Local channel distribution (sampled from the Centrifuge hub)
+		ChannelSubscriberCount: f.NewSummary(prometheus.SummaryOpts{
+			Namespace:  "gateway",
+			Name:       "channel_subscriber_count",
+			Help:       "Local subscribers per non-empty channel, sampled periodically",
+			Objectives: map[float64]float64{0.5: 0.05, 0.9: 0.01, 0.99: 0.001},
+		}),
+
+		ActiveChannelsTotal: f.NewGauge(prometheus.GaugeOpts{
+			Namespace: "gateway",
+			Name:      "active_channels_total",
+			Help:      "Channels with at least one local subscriber",
+		}),
+
+		// Publish metrics
+		PublishTotal: f.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "gateway",
+			Name:      "publish_total",
+			Help:      "Total publish requests by status",
+		}, []string{"status", "reason"}),
+
+		PublishContentFilteredTotal: f.NewCounter(prometheus.CounterOpts{
+			Namespace: "gateway",
+			Name:      "publish_content_filtered_total",
+			Help:      "Publishes rejected because the text contains a blocked phrase",
+		}),
+
+		DeadLetterMessagesTotal: f.NewCounter(prometheus.CounterOpts{
+			Namespace: "gateway",
+			Name:      "deadletter_messages_total",
+			Help:      "Total messages written to the dead-letter stream after exhausting retries",
+		}),
+
+		ClientQueueOverflowTotal: f.NewCounter(prometheus.CounterOpts{
+			Namespace: "gateway",
+			Name:      "client_queue_overflow_total",
+			Help:      "Queued messages dropped because a client's publish queue was full",
+		}),
+
+		WebhookDroppedTotal: f.NewCounter(prometheus.CounterOpts{
+			Namespace: "gateway",
+			Name:      "webhook_dropped_total",
+			Help:      "Presence webhooks dropped because the webhook queue was full",
+		}),
+
+		EventBusDroppedTotal: f.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "gateway",
+			Name:      "event_bus_dropped_total",
+			Help:      "Presence events dropped for an event bus handler whose queue was full",
+		}, []string{"handler"}), // audit, webhook
+
+		WebhookDeliveriesTotal: f.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "gateway",
+			Name:      "webhook_deliveries_total",
+			Help:      "Presence webhook deliveries by status",
+		}, []string{"status"}), // success, failed
+
+		DedupBloomPositivesTotal: f.NewCounter(prometheus.CounterOpts{
+			Namespace: "gateway",
+			Name:      "dedup_bloom_positives_total",
+			Help:      "Publishes the duplicate message Bloom filter reported as probably seen, checked in Redis",
+		}),
+
+		BatchChannelPublishTotal: f.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "gateway",
+			Name:      "batch_channel_publish_total",
+			Help:      "Fan-out publishes to multiple channels by status",
+		}, []string{"status"}), // success, rolled_back, error
+
+		BatchPublishSize: f.NewHistogram(prometheus.HistogramOpts{
+			Namespace: "gateway",
+			Name:      "batch_publish_size",
+			Help:      "Number of messages per batch publish request",
+			Buckets:   []float64{1, 2, 5, 10, 20, 30, 40, 50},
+		}),
+
+		// Publish latencies are also exposed as native histograms, with buckets
+		// 10% apart, for accurate low-latency quantiles on Prometheus 2.40+
+		// servers scraping with native histograms enabled. Others keep reading
+		// the classic buckets.
+		PublishLatency: f.NewHistogram(prometheus.HistogramOpts{
+			Namespace:                      "gateway",
+			Name:                           "publish_latency_seconds",
+			Help:                           "Publish request latency",
+			Buckets:                        []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1},
+			NativeHistogramBucketFactor:    1.1,
+			NativeHistogramMaxBucketNumber: 100,
+		}),
+
+		E2ELatency: f.NewHistogram(prometheus.HistogramOpts{
+			Namespace:                      "gateway",
+			Name:                           "e2e_latency_seconds",
+			Help:                           "Time from receiving a client publish to its worker stream XADD completing, including time spent in the client queue",
+			Buckets:                        []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 5, 30},
+			NativeHistogramBucketFactor:    1.1,
+			NativeHistogramMaxBucketNumber: 100,
+		}),
+
+		// Outbound metrics - messages pushed from workers back to clients
+		OutboundTotal: f.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "gateway",
+			Name:      "outbound_messages_total",
+			Help:      "Total outbound messages from workers by target and status",
+		}, []string{"target", "status"}),
+
+		// WebSocket metrics
+		WebSocketConnections: f.NewGauge(prometheus.GaugeOpts{
+			Namespace: "gateway",
+			Name:      "websocket_connections",
+			Help:      "Current number of WebSocket connections",
+		}),
+
+		SockJSConnections: f.NewGauge(prometheus.GaugeOpts{
+			Namespace: "gateway",
+			Name:      "sockjs_connections",
+			Help:      "Current number of HTTP fallback (http_stream/sse) connections",
+		}),
+
+		WebSocketMessagesTotal: f.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "gateway",
+			Name:      "websocket_messages_total",
+			Help:      "Total WebSocket messages by direction",
+		}, []string{"direction"}),
+
+		// Disconnect metrics - tracks disconnect reasons for reconnection analysis
+		DisconnectTotal: f.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "gateway",
+			Name:      "disconnect_total",
+			Help:      "Total disconnections by reason code and whether it was a reconnect",
+		}, []string{"reason", "code", "reconnect"}),
+
+		// Connection duration - helps understand connection stability
+		ConnectionDuration: f.NewHistogram(prometheus.HistogramOpts{
+			Namespace: "gateway",
+			Name:      "connection_duration_seconds",
+			Help:      "Duration of WebSocket connections",
+			Buckets:   []float64{1, 5, 10, 30, 60, 300, 600, 1800, 3600},
+		}),
+
+		WSPingRTT: f.NewHistogram(prometheus.HistogramOpts{
+			Namespace: "gateway",
+			Name:      "ws_ping_rtt_seconds",
+			Help:      "Round-trip time of application-level pings",
+			Buckets:   []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+		}),
+
+		ConnectionStateTransitionsTotal: f.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "gateway",
+			Name:      "connection_state_transitions_total",
+			Help:      "Connection state machine transitions",
+		}, []string{"from", "to"}), // new, reconnecting, active, draining
+
+		// Reconnection metrics
+		ReconnectTotal: f.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "gateway",
+			Name:      "reconnect_total",
+			Help:      "Total reconnection attempts by user",
+		}, []string{"status"}),
+
+		// HTTP metrics
+		HTTPRequestsTotal: f.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "gateway",
+			Name:      "http_requests_total",
+			Help:      "Total HTTP requests by method, path, status",
+		}, []string{"method", "path", "status"}),
+
+		HTTPRequestDuration: f.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "gateway",
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request duration",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "path"}),
+
+		HealthComponentStatus: f.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "gateway",
+			Name:      "health_component_status",
+			Help:      "Component health from the last health report (1 = healthy, 0 = unhealthy)",
+		}, []string{"component"}), // redis, centrifuge, routing, stream_backlog
+
+		MetricsStreamClients: f.NewGauge(prometheus.GaugeOpts{
+			Namespace: "gateway",
+			Name:      "metrics_stream_clients",
+			Help:      "Consumers connected to /metrics/stream",
+		}),
+
+		// Routing cache metrics
+		RouteCacheHits: f.NewCounter(prometheus.CounterOpts{
+			Namespace: "gateway",
+			Name:      "route_cache_hits_total",
+			Help:      "Route cache hits",
+		}),
+
+		RouteCacheMisses: f.NewCounter(prometheus.CounterOpts{
+			Namespace: "gateway",
+			Name:      "route_cache_misses_total",
+			Help:      "Route cache misses",
+		}),
+
+		RouteCacheKeyspaceInvalidations: f.NewCounter(prometheus.CounterOpts{
+			Namespace: "gateway",
+			Name:      "route_cache_keyspace_invalidations_total",
+			Help:      "Cached routes invalidated by channel:route key deletion or expiry in Redis",
+		}),
+
+		// Channel history cache metrics
+		HistoryCacheHits: f.NewCounter(prometheus.CounterOpts{
+			Namespace: "gateway",
+			Name:      "history_cache_hits_total",
+			Help:      "Channel history reads served from the local cache",
+		}),
+
+		HistoryCacheMisses: f.NewCounter(prometheus.CounterOpts{
+			Namespace: "gateway",
+			Name:      "history_cache_misses_total",
+			Help:      "Channel history reads that went to Redis",
+		}),
+
+		StreamBacklogRatio: f.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "gateway",
+			Name:      "stream_backlog_ratio",
+			Help:      "Worker stream length as a fraction of STREAM_MAX_LEN",
+		}, []string{"worker"}),
+
+		StreamLagMessages: f.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "gateway",
+			Name:      "stream_lag_messages",
+			Help:      "Worker stream entries not yet delivered to the consumer group",
+		}, []string{"worker"}),
+
+		StreamUnackedMessages: f.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "gateway",
+			Name:      "stream_unacked_messages",
+			Help:      "Worker stream entries read through the consumer group but not yet acknowledged",
+		}, []string{"worker"}),
+
+		StaleMessagesReclaimedTotal: f.NewCounter(prometheus.CounterOpts{
+			Namespace: "gateway",
+			Name:      "stale_messages_reclaimed_total",
+			Help:      "Worker stream entries left unacknowledged too long and re-added for another worker consumer",
+		}),
+
+		// Redis metrics
+		RedisOperations: f.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "gateway",
+			Name:      "redis_operations_total",
+			Help:      "Total Redis operations",
+		}, []string{"operation", "status"}),
+
+		RedisLatency: f.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "gateway",
+			Name:      "redis_latency_seconds",
+			Help:      "Redis operation latency",
+			Buckets:   []float64{.0005, .001, .005, .01, .025, .05, .1},
+		}, []string{"operation"}),
+	}
+}
 
 // Timer helps measure operation duration
 type Timer struct {
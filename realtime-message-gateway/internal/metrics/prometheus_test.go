@@ -0,0 +1,25 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+)
+
+// Recording a publish must stay well under 1µs, including the native
+// histogram buckets
+func BenchmarkPublishLatencyObserve(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		Default.PublishLatency.Observe(float64(i%1000) * 1e-5)
+	}
+}
+
+func BenchmarkE2ELatencyObserveParallel(b *testing.B) {
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		start := time.Now()
+		for pb.Next() {
+			Default.E2ELatency.Observe(time.Since(start).Seconds())
+		}
+	})
+}
@@ -0,0 +1,120 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// StreamSnapshot is one line of the /metrics/stream JSON-lines stream.
+// Rates are per second since the previous snapshot.
+type StreamSnapshot struct {
+	Timestamp      time.Time `json:"ts"`
+	Connections    float64   `json:"connections"` // WebSocket and HTTP fallback
+	ActiveChannels float64   `json:"active_channels"`
+	PublishRate    float64   `json:"publish_rate"`  // successful publishes
+	OutboundRate   float64   `json:"outbound_rate"` // delivered worker messages
+	// CacheHitRate is the route cache hit ratio of the lookups since the
+	// previous snapshot, absent when there were none
+	CacheHitRate *float64 `json:"cache_hit_rate,omitempty"`
+}
+
+// streamTotals are the metric values a StreamSnapshot is computed from
+type streamTotals struct {
+	connections    float64
+	activeChannels float64
+	published      float64
+	outbound       float64
+	cacheHits      float64
+	cacheMisses    float64
+}
+
+// Snapshotter takes StreamSnapshots from the metrics of a Gatherer,
+// usually prometheus.DefaultGatherer. It is not safe for concurrent use;
+// each stream has its own.
+type Snapshotter struct {
+	gatherer prometheus.Gatherer
+	prev     streamTotals
+	prevAt   time.Time
+}
+
+// NewSnapshotter creates a Snapshotter whose first snapshot reports the
+// rates since now
+func NewSnapshotter(gatherer prometheus.Gatherer, now time.Time) (*Snapshotter, error) {
+	totals, err := gatherStreamTotals(gatherer)
+	if err != nil {
+		return nil, err
+	}
+	return &Snapshotter{gatherer: gatherer, prev: totals, prevAt: now}, nil
+}
+
+// Next returns the snapshot at now, with rates since the previous snapshot
+func (s *Snapshotter) Next(now time.Time) (StreamSnapshot, error) {
+	totals, err := gatherStreamTotals(s.gatherer)
+	if err != nil {
+		return StreamSnapshot{}, err
+	}
+
+	snapshot := StreamSnapshot{
+		Timestamp:      now.UTC(),
+		Connections:    totals.connections,
+		ActiveChannels: totals.activeChannels,
+	}
+	if elapsed := now.Sub(s.prevAt).Seconds(); elapsed > 0 {
+		snapshot.PublishRate = (totals.published - s.prev.published) / elapsed
+		snapshot.OutboundRate = (totals.outbound - s.prev.outbound) / elapsed
+	}
+	hits := totals.cacheHits - s.prev.cacheHits
+	if lookups := hits + totals.cacheMisses - s.prev.cacheMisses; lookups > 0 {
+		ratio := hits / lookups
+		snapshot.CacheHitRate = &ratio
+	}
+
+	s.prev, s.prevAt = totals, now
+	return snapshot, nil
+}
+
+// gatherStreamTotals reads the metrics of a snapshot from gatherer
+func gatherStreamTotals(gatherer prometheus.Gatherer) (streamTotals, error) {
+	families, err := gatherer.Gather()
+	if err != nil {
+		return streamTotals{}, err
+	}
+
+	var totals streamTotals
+	for _, family := range families {
+		for _, m := range family.GetMetric() {
+			value := m.GetGauge().GetValue() + m.GetCounter().GetValue()
+			switch family.GetName() {
+			case "gateway_websocket_connections", "gateway_sockjs_connections":
+				totals.connections += value
+			case "gateway_active_channels_total":
+				totals.activeChannels += value
+			case "gateway_publish_total":
+				if hasLabel(m.GetLabel(), "status", "success") {
+					totals.published += value
+				}
+			case "gateway_outbound_messages_total":
+				if hasLabel(m.GetLabel(), "status", "success") {
+					totals.outbound += value
+				}
+			case "gateway_route_cache_hits_total":
+				totals.cacheHits += value
+			case "gateway_route_cache_misses_total":
+				totals.cacheMisses += value
+			}
+		}
+	}
+	return totals, nil
+}
+
+// hasLabel reports whether labels contain name=value
+func hasLabel(labels []*dto.LabelPair, name, value string) bool {
+	for _, label := range labels {
+		if label.GetName() == name && label.GetValue() == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,58 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestSnapshotter(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	connections := prometheus.NewGauge(prometheus.GaugeOpts{Name: "gateway_websocket_connections"})
+	fallback := prometheus.NewGauge(prometheus.GaugeOpts{Name: "gateway_sockjs_connections"})
+	publishes := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "gateway_publish_total"}, []string{"status", "reason"})
+	hits := prometheus.NewCounter(prometheus.CounterOpts{Name: "gateway_route_cache_hits_total"})
+	misses := prometheus.NewCounter(prometheus.CounterOpts{Name: "gateway_route_cache_misses_total"})
+	reg.MustRegister(connections, fallback, publishes, hits, misses)
+
+	// Totals before the snapshotter starts do not count toward rates
+	publishes.WithLabelValues("success", "").Add(100)
+	hits.Add(50)
+
+	start := time.Now()
+	s, err := NewSnapshotter(reg, start)
+	if err != nil {
+		t.Fatalf("NewSnapshotter() error = %v", err)
+	}
+
+	connections.Set(3)
+	fallback.Set(1)
+	publishes.WithLabelValues("success", "").Add(20)
+	publishes.WithLabelValues("error", "redis_error").Add(5)
+	hits.Add(9)
+	misses.Add(1)
+
+	snapshot, err := s.Next(start.Add(2 * time.Second))
+	if err != nil {
+		t.Fatalf("Next() error = %v", err)
+	}
+	if snapshot.Connections != 4 {
+		t.Errorf("Connections = %v, want 4", snapshot.Connections)
+	}
+	if snapshot.PublishRate != 10 {
+		t.Errorf("PublishRate = %v, want 10 successful publishes per second", snapshot.PublishRate)
+	}
+	if snapshot.CacheHitRate == nil || *snapshot.CacheHitRate != 0.9 {
+		t.Errorf("CacheHitRate = %v, want 0.9", snapshot.CacheHitRate)
+	}
+
+	// Rates are deltas from the previous snapshot
+	snapshot, err = s.Next(start.Add(3 * time.Second))
+	if err != nil {
+		t.Fatalf("Next() error = %v", err)
+	}
+	if snapshot.PublishRate != 0 || snapshot.CacheHitRate != nil {
+		t.Errorf("idle snapshot = %+v, want zero publish rate and no cache hit rate", snapshot)
+	}
+}
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+)
+
+// MaxBodySize limits request bodies to limit bytes. Requests declaring a
+// larger Content-Length are rejected with 413 before next runs; bodies of
+// unknown length are cut off at the limit, so reading past it fails with
+// an error WriteBodyTooLarge recognizes.
+func MaxBodySize(limit int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > limit {
+				writeBodyTooLarge(w)
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// WriteBodyTooLarge writes a 413 response if err comes from reading a body
+// past the MaxBodySize limit, and reports whether it did
+func WriteBodyTooLarge(w http.ResponseWriter, err error) bool {
+	var maxBytesErr *http.MaxBytesError
+	if !errors.As(err, &maxBytesErr) {
+		return false
+	}
+	writeBodyTooLarge(w)
+	return true
+}
+
+// writeBodyTooLarge writes the 413 response for an oversized body
+func writeBodyTooLarge(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusRequestEntityTooLarge)
+	w.Write([]byte(`{"error":"request too large"}`))
+}
@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMaxBodySize(t *testing.T) {
+	tests := []struct {
+		name          string
+		body          string
+		unknownLength bool
+		wantStatus    int
+	}{
+		{"within limit", "0123456789", false, http.StatusOK},
+		{"declared length over limit", "0123456789a", false, http.StatusRequestEntityTooLarge},
+		{"unknown length within limit", "0123456789", true, http.StatusOK},
+		{"unknown length over limit", "0123456789a", true, http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if _, err := io.ReadAll(r.Body); err != nil {
+					if !WriteBodyTooLarge(w, err) {
+						t.Errorf("WriteBodyTooLarge() did not recognize %v", err)
+					}
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+			})
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			if tt.unknownLength {
+				req.ContentLength = -1
+			}
+			rec := httptest.NewRecorder()
+
+			MaxBodySize(10)(next).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantStatus == http.StatusRequestEntityTooLarge && rec.Body.String() != `{"error":"request too large"}` {
+				t.Errorf("body = %s, want request too large error", rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestWriteBodyTooLargeOtherError(t *testing.T) {
+	rec := httptest.NewRecorder()
+	if WriteBodyTooLarge(rec, io.ErrUnexpectedEOF) {
+		t.Error("WriteBodyTooLarge() = true for an unrelated error")
+	}
+}
@@ -0,0 +1,75 @@
+// Package middleware contains HTTP middleware for the gateway's HTTP servers.
+package middleware
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// corsMaxAge is how long browsers may cache a preflight response, in seconds
+const corsMaxAge = 600
+
+// corsAllowedHeaders are the request headers browsers may send cross-origin
+const corsAllowedHeaders = "Content-Type, Authorization, X-Request-ID"
+
+// corsExposedHeaders are the response headers readable by cross-origin scripts
+const corsExposedHeaders = "X-Request-ID, X-Total-Count"
+
+// CORSMiddleware answers preflight requests and adds CORS headers for
+// requests from allowedOrigins. "*" allows any origin. Requests from other
+// origins pass through without CORS headers, so browsers block the
+// response; their preflights are rejected with 403. With no allowed
+// origins the middleware is a no-op.
+func CORSMiddleware(allowedOrigins []string, allowedMethods []string) func(http.Handler) http.Handler {
+	wildcard := slices.Contains(allowedOrigins, "*")
+	methods := strings.Join(allowedMethods, ", ")
+
+	return func(next http.Handler) http.Handler {
+		if len(allowedOrigins) == 0 {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+			w.Header().Add("Vary", "Origin")
+			if origin == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if !wildcard && !slices.Contains(allowedOrigins, origin) {
+				if preflight {
+					w.WriteHeader(http.StatusForbidden)
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if wildcard {
+				w.Header().Set("Access-Control-Allow-Origin", "*")
+			} else {
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+			}
+
+			if !preflight {
+				w.Header().Set("Access-Control-Expose-Headers", corsExposedHeaders)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if !slices.Contains(allowedMethods, r.Header.Get("Access-Control-Request-Method")) {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			w.Header().Set("Access-Control-Allow-Methods", methods)
+			w.Header().Set("Access-Control-Allow-Headers", corsAllowedHeaders)
+			w.Header().Set("Access-Control-Max-Age", strconv.Itoa(corsMaxAge))
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCORSMiddleware(t *testing.T) {
+	methods := []string{http.MethodGet, http.MethodPost}
+
+	tests := []struct {
+		name            string
+		allowedOrigins  []string
+		method          string
+		origin          string
+		requestMethod   string // Access-Control-Request-Method, marks a preflight
+		wantStatus      int
+		wantAllowOrigin string
+		wantAllowMethod string
+		wantNextCalled  bool
+	}{
+		{
+			name:           "no origin header",
+			allowedOrigins: []string{"https://admin.example.com"},
+			method:         http.MethodGet,
+			wantStatus:     http.StatusOK,
+			wantNextCalled: true,
+		},
+		{
+			name:            "simple request from allowed origin",
+			allowedOrigins:  []string{"https://admin.example.com"},
+			method:          http.MethodGet,
+			origin:          "https://admin.example.com",
+			wantStatus:      http.StatusOK,
+			wantAllowOrigin: "https://admin.example.com",
+			wantNextCalled:  true,
+		},
+		{
+			name:           "simple request from disallowed origin",
+			allowedOrigins: []string{"https://admin.example.com"},
+			method:         http.MethodGet,
+			origin:         "https://evil.example.com",
+			wantStatus:     http.StatusOK,
+			wantNextCalled: true,
+		},
+		{
+			name:            "preflight from allowed origin",
+			allowedOrigins:  []string{"https://admin.example.com"},
+			method:          http.MethodOptions,
+			origin:          "https://admin.example.com",
+			requestMethod:   http.MethodPost,
+			wantStatus:      http.StatusNoContent,
+			wantAllowOrigin: "https://admin.example.com",
+			wantAllowMethod: "GET, POST",
+		},
+		{
+			name:           "preflight from disallowed origin",
+			allowedOrigins: []string{"https://admin.example.com"},
+			method:         http.MethodOptions,
+			origin:         "https://evil.example.com",
+			requestMethod:  http.MethodPost,
+			wantStatus:     http.StatusForbidden,
+		},
+		{
+			name:            "preflight for disallowed method",
+			allowedOrigins:  []string{"https://admin.example.com"},
+			method:          http.MethodOptions,
+			origin:          "https://admin.example.com",
+			requestMethod:   http.MethodDelete,
+			wantStatus:      http.StatusForbidden,
+			wantAllowOrigin: "https://admin.example.com",
+		},
+		{
+			name:            "wildcard origin",
+			allowedOrigins:  []string{"*"},
+			method:          http.MethodOptions,
+			origin:          "https://any.example.com",
+			requestMethod:   http.MethodGet,
+			wantStatus:      http.StatusNoContent,
+			wantAllowOrigin: "*",
+			wantAllowMethod: "GET, POST",
+		},
+		{
+			name:            "options without preflight header reaches handler",
+			allowedOrigins:  []string{"https://admin.example.com"},
+			method:          http.MethodOptions,
+			origin:          "https://admin.example.com",
+			wantStatus:      http.StatusOK,
+			wantAllowOrigin: "https://admin.example.com",
+			wantNextCalled:  true,
+		},
+		{
+			name:           "cors disabled",
+			allowedOrigins: nil,
+			method:         http.MethodGet,
+			origin:         "https://admin.example.com",
+			wantStatus:     http.StatusOK,
+			wantNextCalled: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nextCalled := false
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				nextCalled = true
+			})
+			handler := CORSMiddleware(tt.allowedOrigins, methods)(next)
+
+			req := httptest.NewRequest(tt.method, "/channels/chat/presence", nil)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			if tt.requestMethod != "" {
+				req.Header.Set("Access-Control-Request-Method", tt.requestMethod)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if got := rec.Header().Get("Access-Control-Allow-Origin"); got != tt.wantAllowOrigin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tt.wantAllowOrigin)
+			}
+			if got := rec.Header().Get("Access-Control-Allow-Methods"); got != tt.wantAllowMethod {
+				t.Errorf("Access-Control-Allow-Methods = %q, want %q", got, tt.wantAllowMethod)
+			}
+			if nextCalled != tt.wantNextCalled {
+				t.Errorf("next called = %v, want %v", nextCalled, tt.wantNextCalled)
+			}
+		})
+	}
+}
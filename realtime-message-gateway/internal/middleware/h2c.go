@@ -0,0 +1,15 @@
+package middleware
+
+import (
+	"net/http"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// H2C serves HTTP/2 cleartext (prior knowledge and Upgrade: h2c) alongside
+// HTTP/1.1, so clients can multiplex requests without TLS. It must not wrap
+// WebSocket handlers: WebSocket over h2c is non-standard.
+func H2C(next http.Handler) http.Handler {
+	return h2c.NewHandler(next, &http2.Server{})
+}
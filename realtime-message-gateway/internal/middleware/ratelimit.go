@@ -0,0 +1,145 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"realtime-message-gateway/internal/redis"
+)
+
+// RateLimitKeyPrefix prefixes the Redis keys of RedisRateLimiter
+const RateLimitKeyPrefix = "ratelimit:"
+
+// RateLimiter allows at most a fixed number of events per key in each
+// fixed window
+type RateLimiter interface {
+	// Allow counts an event for key and reports whether it is within the
+	// limit. Rejected events are not counted.
+	Allow(ctx context.Context, key string) (bool, error)
+}
+
+// LocalRateLimiter is an in-memory RateLimiter. Its limits apply to a
+// single gateway instance. Windows are aligned to multiples of the window
+// length, so all counts reset together and need no per-key expiry.
+type LocalRateLimiter struct {
+	limit  int
+	window time.Duration
+
+	mu          sync.Mutex
+	windowStart time.Time
+	counts      map[string]int
+}
+
+// NewLocalRateLimiter creates a LocalRateLimiter allowing limit events per
+// key in each window
+func NewLocalRateLimiter(limit int, window time.Duration) *LocalRateLimiter {
+	return &LocalRateLimiter{
+		limit:  limit,
+		window: window,
+		counts: make(map[string]int),
+	}
+}
+
+// Allow implements RateLimiter
+func (l *LocalRateLimiter) Allow(_ context.Context, key string) (bool, error) {
+	return l.allowAt(key, time.Now()), nil
+}
+
+// allowAt counts an event for key at now
+func (l *LocalRateLimiter) allowAt(key string, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if start := now.Truncate(l.window); !start.Equal(l.windowStart) {
+		l.windowStart = start
+		clear(l.counts)
+	}
+	if l.counts[key] >= l.limit {
+		return false
+	}
+	l.counts[key]++
+	return true
+}
+
+// rateLimitScript increments KEYS[1] unless it reached the limit ARGV[1],
+// starting a window of ARGV[2] milliseconds on the first event. Returns 1
+// if the event was counted, 0 if it was rejected.
+const rateLimitScript = `
+local count = tonumber(redis.call('GET', KEYS[1]) or '0')
+if count >= tonumber(ARGV[1]) then
+	return 0
+end
+if redis.call('INCR', KEYS[1]) == 1 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 1
+`
+
+// RedisRateLimiter is a RateLimiter shared by all gateway instances using
+// the same Redis. A key's window starts at its first counted event and is
+// enforced by the key's expiry.
+type RedisRateLimiter struct {
+	redis  *redis.Client
+	limit  int
+	window time.Duration
+
+	scriptMu  sync.Mutex
+	scriptSHA string
+}
+
+// NewRedisRateLimiter creates a RedisRateLimiter allowing limit events per
+// key in each window
+func NewRedisRateLimiter(client *redis.Client, limit int, window time.Duration) *RedisRateLimiter {
+	return &RedisRateLimiter{
+		redis:  client,
+		limit:  limit,
+		window: window,
+	}
+}
+
+// Allow implements RateLimiter. The check and the increment run in one Lua
+// script, so concurrent events cannot both pass the last free slot.
+func (l *RedisRateLimiter) Allow(ctx context.Context, key string) (bool, error) {
+	keys := []string{RateLimitKeyPrefix + key}
+	args := []interface{}{l.limit, l.window.Milliseconds()}
+
+	sha, err := l.script(ctx, false)
+	if err != nil {
+		return false, err
+	}
+	result, err := l.redis.EvalSha(ctx, sha, keys, args...)
+	if redis.IsNoScript(err) {
+		if sha, err = l.script(ctx, true); err != nil {
+			return false, err
+		}
+		result, err = l.redis.EvalSha(ctx, sha, keys, args...)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	allowed, ok := result.(int64)
+	if !ok {
+		return false, fmt.Errorf("unexpected rate limit script result %v", result)
+	}
+	return allowed == 1, nil
+}
+
+// script returns the cached SHA of rateLimitScript, loading the script
+// when not cached or when reload is set
+func (l *RedisRateLimiter) script(ctx context.Context, reload bool) (string, error) {
+	l.scriptMu.Lock()
+	defer l.scriptMu.Unlock()
+
+	if l.scriptSHA != "" && !reload {
+		return l.scriptSHA, nil
+	}
+	sha, err := l.redis.ScriptLoad(ctx, rateLimitScript)
+	if err != nil {
+		return "", err
+	}
+	l.scriptSHA = sha
+	return sha, nil
+}
@@ -0,0 +1,146 @@
+package middleware
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+
+	"realtime-message-gateway/internal/config"
+	"realtime-message-gateway/internal/redis"
+)
+
+// newTestRedisRateLimiter creates a RedisRateLimiter on a miniredis server
+func newTestRedisRateLimiter(t testing.TB, limit int, window time.Duration) (*miniredis.Miniredis, *RedisRateLimiter) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	client, err := redis.NewClient(&config.Config{RedisURL: "redis://" + mr.Addr()})
+	if err != nil {
+		t.Fatalf("redis.NewClient() error = %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return mr, NewRedisRateLimiter(client, limit, window)
+}
+
+func TestLocalRateLimiter(t *testing.T) {
+	l := NewLocalRateLimiter(2, time.Second)
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		key  string
+		at   time.Duration
+		want bool
+	}{
+		{"alice", 0, true},
+		{"alice", 100 * time.Millisecond, true},
+		{"alice", 200 * time.Millisecond, false},
+		{"bob", 300 * time.Millisecond, true},
+		{"alice", 999 * time.Millisecond, false},
+		// All counts reset at the next window
+		{"alice", time.Second, true},
+		{"alice", 1500 * time.Millisecond, true},
+		{"alice", 1600 * time.Millisecond, false},
+		{"alice", 3 * time.Second, true},
+	}
+	for _, tt := range tests {
+		if got := l.allowAt(tt.key, start.Add(tt.at)); got != tt.want {
+			t.Errorf("allowAt(%s, +%s) = %v, want %v", tt.key, tt.at, got, tt.want)
+		}
+	}
+}
+
+func TestRedisRateLimiter(t *testing.T) {
+	mr, l := newTestRedisRateLimiter(t, 3, time.Second)
+	ctx := context.Background()
+
+	for i := range 5 {
+		allowed, err := l.Allow(ctx, "alice")
+		if err != nil {
+			t.Fatalf("Allow() error = %v", err)
+		}
+		if want := i < 3; allowed != want {
+			t.Errorf("Allow(alice) #%d = %v, want %v", i+1, allowed, want)
+		}
+	}
+	if allowed, err := l.Allow(ctx, "bob"); err != nil || !allowed {
+		t.Errorf("Allow(bob) = %v, %v, want true", allowed, err)
+	}
+	if got := mr.TTL(RateLimitKeyPrefix + "alice"); got != time.Second {
+		t.Errorf("TTL = %s, want 1s", got)
+	}
+	if got, _ := mr.Get(RateLimitKeyPrefix + "alice"); got != "3" {
+		t.Errorf("count = %s, want 3 without the rejected events", got)
+	}
+
+	// The window ends with the key
+	mr.FastForward(time.Second)
+	if allowed, err := l.Allow(ctx, "alice"); err != nil || !allowed {
+		t.Errorf("Allow(alice) after window = %v, %v, want true", allowed, err)
+	}
+}
+
+func TestRedisRateLimiterConcurrent(t *testing.T) {
+	const limit = 10
+	_, l := newTestRedisRateLimiter(t, limit, time.Minute)
+
+	// Gateway instances sharing the Redis never let more than limit through
+	var allowed atomic.Int32
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ok, err := l.Allow(context.Background(), "alice")
+			if err != nil {
+				t.Errorf("Allow() error = %v", err)
+			}
+			if ok {
+				allowed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := allowed.Load(); n != limit {
+		t.Errorf("allowed %d of 50 concurrent events, want %d", n, limit)
+	}
+}
+
+// BenchmarkRedisRateLimiter issues events at 10k per second, reports the
+// p50 and p99 latency of Allow and fails when the p99 reaches 1ms, the
+// budget the Redis backend is accepted with. The in-process miniredis
+// shares CPUs with the benchmark, so on machines with few CPUs the results
+// overstate the latency.
+func BenchmarkRedisRateLimiter(b *testing.B) {
+	const interval = time.Second / 10000
+	_, l := newTestRedisRateLimiter(b, 1000, time.Second)
+	ctx := context.Background()
+	keys := []string{"alice", "bob", "carol", "dave"}
+
+	latencies := make([]time.Duration, b.N)
+	b.ResetTimer()
+	start := time.Now()
+	for i := range b.N {
+		if wait := time.Until(start.Add(time.Duration(i) * interval)); wait > 0 {
+			time.Sleep(wait)
+		}
+		sent := time.Now()
+		if _, err := l.Allow(ctx, keys[i%len(keys)]); err != nil {
+			b.Fatalf("Allow() error = %v", err)
+		}
+		latencies[i] = time.Since(sent)
+	}
+	b.StopTimer()
+
+	slices.Sort(latencies)
+	b.ReportMetric(float64(latencies[len(latencies)/2].Nanoseconds()), "p50-ns")
+	p99 := latencies[len(latencies)*99/100]
+	b.ReportMetric(float64(p99.Nanoseconds()), "p99-ns")
+	if p99 >= time.Millisecond {
+		b.Errorf("p99 latency = %s, want under 1ms", p99)
+	}
+}
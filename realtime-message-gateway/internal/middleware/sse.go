@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sseBufferPool recycles the buffers SSE frames are formatted into
+var sseBufferPool = sync.Pool{
+	New: func() interface{} { return new(bytes.Buffer) },
+}
+
+// SSEWriter writes Server-Sent Events frames to a response and flushes each
+// one, so clients receive events as they are written
+type SSEWriter struct {
+	w  http.ResponseWriter
+	rc *http.ResponseController
+}
+
+// NewSSEWriter sets the event stream headers on w and clears its write
+// deadline, if it has one, since a stream outlives the server's
+// WriteTimeout. Headers are sent with the first frame.
+func NewSSEWriter(w http.ResponseWriter) (*SSEWriter, error) {
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return nil, err
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // disable proxy buffering
+	return &SSEWriter{w: w, rc: rc}, nil
+}
+
+// Retry tells the client to wait d before reconnecting after the stream
+// ends
+func (s *SSEWriter) Retry(d time.Duration) error {
+	buf := sseBufferPool.Get().(*bytes.Buffer)
+	defer putSSEBuffer(buf)
+
+	buf.WriteString("retry: ")
+	buf.WriteString(strconv.FormatInt(d.Milliseconds(), 10))
+	buf.WriteString("\n\n")
+	return s.write(buf)
+}
+
+// Event writes an event frame. An empty event or id omits its field;
+// clients send the last id they received as Last-Event-ID on reconnect.
+// Each line of data becomes a data field, so data may span lines.
+func (s *SSEWriter) Event(event, id string, data []byte) error {
+	buf := sseBufferPool.Get().(*bytes.Buffer)
+	defer putSSEBuffer(buf)
+
+	if event != "" {
+		buf.WriteString("event: ")
+		buf.WriteString(event)
+		buf.WriteByte('\n')
+	}
+	if id != "" {
+		buf.WriteString("id: ")
+		buf.WriteString(id)
+		buf.WriteByte('\n')
+	}
+	for _, line := range strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n") {
+		buf.WriteString("data: ")
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+	}
+	buf.WriteByte('\n')
+	return s.write(buf)
+}
+
+// write sends a formatted frame and flushes it
+func (s *SSEWriter) write(buf *bytes.Buffer) error {
+	if _, err := s.w.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	return s.rc.Flush()
+}
+
+// putSSEBuffer returns buf to the pool
+func putSSEBuffer(buf *bytes.Buffer) {
+	buf.Reset()
+	sseBufferPool.Put(buf)
+}
@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSSEWriter(t *testing.T) {
+	rec := httptest.NewRecorder()
+	sse, err := NewSSEWriter(rec)
+	if err != nil {
+		t.Fatalf("NewSSEWriter() error = %v", err)
+	}
+
+	if err := sse.Retry(5 * time.Second); err != nil {
+		t.Fatalf("Retry() error = %v", err)
+	}
+	if err := sse.Event("health", "1", []byte(`{"healthy":true}`)); err != nil {
+		t.Fatalf("Event() error = %v", err)
+	}
+	if err := sse.Event("warning", "", []byte("line one\r\nline two")); err != nil {
+		t.Fatalf("Event() error = %v", err)
+	}
+	if err := sse.Event("", "3", nil); err != nil {
+		t.Fatalf("Event() error = %v", err)
+	}
+
+	if got := rec.Header().Get("Content-Type"); got != "text/event-stream" {
+		t.Errorf("Content-Type = %q, want text/event-stream", got)
+	}
+	if !rec.Flushed {
+		t.Error("frames were not flushed")
+	}
+
+	want := "retry: 5000\n\n" +
+		"event: health\nid: 1\ndata: {\"healthy\":true}\n\n" +
+		"event: warning\ndata: line one\ndata: line two\n\n" +
+		"id: 3\ndata: \n\n"
+	if got := rec.Body.String(); got != want {
+		t.Errorf("stream = %q, want %q", got, want)
+	}
+}
@@ -2,7 +2,9 @@ package redis
 
 import (
 	"context"
+	"errors"
 	"log/slog"
+	"strconv"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -10,36 +12,116 @@ import (
 	"realtime-message-gateway/internal/config"
 )
 
+// Cmdable is satisfied by both *redis.Client and *redis.ClusterClient
+type Cmdable interface {
+	redis.Cmdable
+	Subscribe(ctx context.Context, channels ...string) *redis.PubSub
+	Close() error
+}
+
+var (
+	_ Cmdable = (*redis.Client)(nil)
+	_ Cmdable = (*redis.ClusterClient)(nil)
+)
+
 type Client struct {
-	rdb *redis.Client
+	rdb          Cmdable
+	db           int   // selected database, always 0 for Redis Cluster
+	streamMaxLen int64 // approximate MAXLEN for XADD, 0 = unlimited
 }
 
+// NewClient connects to a Redis Cluster when RedisClusterAddrs is set,
+// otherwise to the single node at RedisURL
 func NewClient(cfg *config.Config) (*Client, error) {
+	var rdb Cmdable
+	var db int
+	if len(cfg.RedisClusterAddrs) > 0 {
+		opt, err := clusterOptions(cfg)
+		if err != nil {
+			return nil, err
+		}
+		rdb = redis.NewClusterClient(opt)
+	} else {
+		opt, err := nodeOptions(cfg)
+		if err != nil {
+			return nil, err
+		}
+		rdb = redis.NewClient(opt)
+		db = opt.DB
+	}
+
+	// Test connection
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := rdb.Ping(ctx).Err(); err != nil {
+		rdb.Close()
+		return nil, err
+	}
+
+	if len(cfg.RedisClusterAddrs) > 0 {
+		slog.Info("Connected to Redis Cluster", "addrs", cfg.RedisClusterAddrs)
+	} else {
+		slog.Info("Connected to Redis", "url", cfg.RedisURL)
+	}
+
+	return &Client{
+		rdb:          rdb,
+		db:           db,
+		streamMaxLen: int64(cfg.StreamMaxLen),
+	}, nil
+}
+
+// IsCluster reports whether the client is connected to a Redis Cluster,
+// where a script may only access keys in one hash slot
+func (c *Client) IsCluster() bool {
+	_, ok := c.rdb.(*redis.ClusterClient)
+	return ok
+}
+
+// nodeOptions builds the options of a single-node client from RedisURL
+// and the pool and timeout settings
+func nodeOptions(cfg *config.Config) (*redis.Options, error) {
 	opt, err := redis.ParseURL(cfg.RedisURL)
 	if err != nil {
 		return nil, err
 	}
 
+	opt.Protocol = cfg.RedisProtocol
 	opt.PoolSize = cfg.RedisPoolSize
 	opt.MinIdleConns = cfg.RedisMinIdle
 	opt.MaxRetries = cfg.RedisMaxRetries
 	opt.DialTimeout = cfg.RedisDialTimeout
 	opt.ReadTimeout = 3 * time.Second
 	opt.WriteTimeout = 3 * time.Second
+	return opt, nil
+}
 
-	rdb := redis.NewClient(opt)
-
-	// Test connection
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	if err := rdb.Ping(ctx).Err(); err != nil {
-		return nil, err
+// clusterOptions builds cluster options with the same pool and timeout
+// settings as single-node mode. Credentials and TLS come from RedisURL
+func clusterOptions(cfg *config.Config) (*redis.ClusterOptions, error) {
+	opt := &redis.ClusterOptions{
+		Addrs:        cfg.RedisClusterAddrs,
+		Protocol:     cfg.RedisProtocol,
+		PoolSize:     cfg.RedisPoolSize,
+		MinIdleConns: cfg.RedisMinIdle,
+		MaxRetries:   cfg.RedisMaxRetries,
+		DialTimeout:  cfg.RedisDialTimeout,
+		ReadTimeout:  3 * time.Second,
+		WriteTimeout: 3 * time.Second,
 	}
 
-	slog.Info("Connected to Redis", "url", cfg.RedisURL)
+	if cfg.RedisURL != "" {
+		urlOpt, err := redis.ParseURL(cfg.RedisURL)
+		if err != nil {
+			return nil, err
+		}
+		opt.Username = urlOpt.Username
+		opt.Password = urlOpt.Password
+		opt.TLSConfig = urlOpt.TLSConfig
+	}
 
-	return &Client{rdb: rdb}, nil
+	return opt, nil
 }
 
 func (c *Client) Close() error {
@@ -55,6 +137,81 @@ func (c *Client) HSet(ctx context.Context, key string, values map[string]interfa
 	return c.rdb.HSet(ctx, key, values).Err()
 }
 
+// HIncrByExpire increments a hash field and resets the key expiration
+// in one pipelined round trip. Returns the new field value
+func (c *Client) HIncrByExpire(ctx context.Context, key, field string, incr int64, expiration time.Duration) (int64, error) {
+	pipe := c.rdb.TxPipeline()
+	incrCmd := pipe.HIncrBy(ctx, key, field, incr)
+	pipe.Expire(ctx, key, expiration)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return incrCmd.Val(), nil
+}
+
+// CappedList is a list ListPush prepends to, trimmed to its first MaxLen
+// elements
+type CappedList struct {
+	Key    string
+	MaxLen int64
+}
+
+// ListPush prepends Value to each of Lists, trims them to their MaxLen and,
+// if TTL is positive, resets their expiration
+type ListPush struct {
+	Value interface{}
+	Lists []CappedList
+	TTL   time.Duration
+}
+
+// queue adds the commands of p to pipe
+func (p *ListPush) queue(ctx context.Context, pipe redis.Pipeliner) []redis.Cmder {
+	cmds := make([]redis.Cmder, 0, 3*len(p.Lists))
+	for _, list := range p.Lists {
+		cmds = append(cmds,
+			pipe.LPush(ctx, list.Key, p.Value),
+			pipe.LTrim(ctx, list.Key, 0, list.MaxLen-1))
+		if p.TTL > 0 {
+			cmds = append(cmds, pipe.Expire(ctx, list.Key, p.TTL))
+		}
+	}
+	return cmds
+}
+
+// LRange returns the elements of list key from start to stop, empty if
+// the key does not exist
+func (c *Client) LRange(ctx context.Context, key string, start, stop int64) ([]string, error) {
+	return c.rdb.LRange(ctx, key, start, stop).Result()
+}
+
+// LRem removes up to count occurrences of value from list key, all of
+// them if count is 0
+func (c *Client) LRem(ctx context.Context, key string, count int64, value interface{}) error {
+	return c.rdb.LRem(ctx, key, count, value).Err()
+}
+
+// HGet returns one field of a hash. A missing key or field returns an
+// error satisfying IsNil.
+func (c *Client) HGet(ctx context.Context, key, field string) (string, error) {
+	return c.rdb.HGet(ctx, key, field).Result()
+}
+
+// HGetAll returns all fields of a hash, empty if the key does not exist
+func (c *Client) HGetAll(ctx context.Context, key string) (map[string]string, error) {
+	return c.rdb.HGetAll(ctx, key).Result()
+}
+
+// HDel removes fields from a hash; missing fields are ignored
+func (c *Client) HDel(ctx context.Context, key string, fields ...string) error {
+	return c.rdb.HDel(ctx, key, fields...).Err()
+}
+
+// HIncrBy increments a hash field, created at 0 if missing. Returns the
+// new field value
+func (c *Client) HIncrBy(ctx context.Context, key, field string, incr int64) (int64, error) {
+	return c.rdb.HIncrBy(ctx, key, field, incr).Result()
+}
+
 // Expire sets key expiration
 func (c *Client) Expire(ctx context.Context, key string, expiration time.Duration) error {
 	return c.rdb.Expire(ctx, key, expiration).Err()
@@ -75,26 +232,498 @@ func (c *Client) Del(ctx context.Context, keys ...string) error {
 	return c.rdb.Del(ctx, keys...).Err()
 }
 
+// SetNX stores a string value only if key does not exist and reports
+// whether it was stored
+func (c *Client) SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) (bool, error) {
+	return c.rdb.SetNX(ctx, key, value, expiration).Result()
+}
+
+// delIfEqualScript deletes KEYS[1] if its value is ARGV[1]
+const delIfEqualScript = `
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`
+
+// DelIfEqual deletes key only if its value is value, e.g. to release a lock
+// that may have expired and been taken by someone else
+func (c *Client) DelIfEqual(ctx context.Context, key, value string) error {
+	return c.rdb.Eval(ctx, delIfEqualScript, []string{key}, value).Err()
+}
+
+// GetMany returns the values of keys in one pipelined round trip (safe
+// across cluster slots, unlike MGET). Missing keys have an empty value.
+func (c *Client) GetMany(ctx context.Context, keys []string) ([]string, error) {
+	pipe := c.rdb.Pipeline()
+	cmds := make([]*redis.StringCmd, len(keys))
+	for i, key := range keys {
+		cmds[i] = pipe.Get(ctx, key)
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	values := make([]string, len(keys))
+	for i, cmd := range cmds {
+		values[i] = cmd.Val()
+	}
+	return values, nil
+}
+
+// Scan calls fn with each batch of about count keys matching pattern until
+// all keys are scanned or fn returns an error. On Redis Cluster every master
+// is scanned, concurrently, so fn must be safe for concurrent use.
+func (c *Client) Scan(ctx context.Context, pattern string, count int64, fn func(keys []string) error) error {
+	scan := func(ctx context.Context, rdb redis.Cmdable) error {
+		var cursor uint64
+		for {
+			keys, next, err := rdb.Scan(ctx, cursor, pattern, count).Result()
+			if err != nil {
+				return err
+			}
+			if len(keys) > 0 {
+				if err := fn(keys); err != nil {
+					return err
+				}
+			}
+			if next == 0 {
+				return nil
+			}
+			cursor = next
+		}
+	}
+
+	if cluster, ok := c.rdb.(*redis.ClusterClient); ok {
+		return cluster.ForEachMaster(ctx, func(ctx context.Context, node *redis.Client) error {
+			return scan(ctx, node)
+		})
+	}
+	return scan(ctx, c.rdb)
+}
+
+// KeysExist reports for each key whether it exists, in one pipelined
+// round trip (safe across cluster slots, unlike a multi-key EXISTS)
+func (c *Client) KeysExist(ctx context.Context, keys []string) ([]bool, error) {
+	pipe := c.rdb.Pipeline()
+	cmds := make([]*redis.IntCmd, len(keys))
+	for i, key := range keys {
+		cmds[i] = pipe.Exists(ctx, key)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	exists := make([]bool, len(keys))
+	for i, cmd := range cmds {
+		exists[i] = cmd.Val() > 0
+	}
+	return exists, nil
+}
+
+// SAdd adds members to a set and returns how many were not already present
+func (c *Client) SAdd(ctx context.Context, key string, members ...interface{}) (int64, error) {
+	return c.rdb.SAdd(ctx, key, members...).Result()
+}
+
+// SRem removes members from a set
+func (c *Client) SRem(ctx context.Context, key string, members ...interface{}) error {
+	return c.rdb.SRem(ctx, key, members...).Err()
+}
+
+// SMembers returns all members of a set
+func (c *Client) SMembers(ctx context.Context, key string) ([]string, error) {
+	return c.rdb.SMembers(ctx, key).Result()
+}
+
 // ZRange returns members in sorted set
 func (c *Client) ZRange(ctx context.Context, key string, start, stop int64) ([]string, error) {
 	return c.rdb.ZRange(ctx, key, start, stop).Result()
 }
 
+// ZRangeWithScores returns members with scores in sorted set
+func (c *Client) ZRangeWithScores(ctx context.Context, key string, start, stop int64) ([]redis.Z, error) {
+	return c.rdb.ZRangeWithScores(ctx, key, start, stop).Result()
+}
+
 // ZScore returns score of member in sorted set
 func (c *Client) ZScore(ctx context.Context, key, member string) (float64, error) {
 	return c.rdb.ZScore(ctx, key, member).Result()
 }
 
-// XAdd adds entry to stream
+// Z is a sorted set member with its score
+type Z = redis.Z
+
+// ZAddNX adds members to sorted set key, leaving the scores of existing
+// members unchanged. Returns the number of members added.
+func (c *Client) ZAddNX(ctx context.Context, key string, members ...Z) (int64, error) {
+	return c.rdb.ZAddNX(ctx, key, members...).Result()
+}
+
+// ZAddXX updates the scores of members already in sorted set key without
+// adding new ones. Returns the number of members whose score changed.
+func (c *Client) ZAddXX(ctx context.Context, key string, members ...Z) (int64, error) {
+	return c.rdb.ZAddArgs(ctx, key, redis.ZAddArgs{XX: true, Ch: true, Members: members}).Result()
+}
+
+// ZRem removes members from sorted set key
+func (c *Client) ZRem(ctx context.Context, key string, members ...string) error {
+	args := make([]interface{}, len(members))
+	for i, m := range members {
+		args[i] = m
+	}
+	return c.rdb.ZRem(ctx, key, args...).Err()
+}
+
+// ZMScore returns the scores of members in sorted set key in one round
+// trip, 0 for members not in the set
+func (c *Client) ZMScore(ctx context.Context, key string, members ...string) ([]float64, error) {
+	return c.rdb.ZMScore(ctx, key, members...).Result()
+}
+
+// ZRemExpired removes the members of sorted set key whose score, a Unix
+// time in milliseconds, is older than maxAge, and returns how many were
+// removed. Used for heartbeat sets such as workers:active.
+func (c *Client) ZRemExpired(ctx context.Context, key string, maxAge time.Duration) (int64, error) {
+	cutoff := time.Now().Add(-maxAge).UnixMilli()
+	return c.rdb.ZRemRangeByScore(ctx, key, "-inf", "("+strconv.FormatInt(cutoff, 10)).Result()
+}
+
+// XAdd adds entry to stream, trimming it to approximately StreamMaxLen
 func (c *Client) XAdd(ctx context.Context, stream string, values map[string]interface{}) (string, error) {
-	return c.rdb.XAdd(ctx, &redis.XAddArgs{
+	return c.rdb.XAdd(ctx, c.xaddArgs(stream, values)).Result()
+}
+
+// XMessage is a stream entry read from Redis, with its ID
+type XMessage = redis.XMessage
+
+// KeyExpire resets the expiration of Key to TTL
+type KeyExpire struct {
+	Key string
+	TTL time.Duration
+}
+
+// StreamEntry is an entry to add to Stream with XAddPipeline. Push, when
+// set, is written in the same transaction as the entry, for records of it
+// that do not need its ID; Expire, when set, is applied in it too.
+type StreamEntry struct {
+	Stream string
+	Values map[string]interface{}
+	Push   *ListPush
+	Expire *KeyExpire
+}
+
+// XAddPipeline adds entries to their streams, with their pushes and
+// expirations, in a single MULTI/EXEC round trip. Returns entry IDs and
+// per-entry errors in input order; failed entries have an empty ID. A push
+// or expiration is applied unless the transaction fails as a whole; its
+// failures are logged. On Redis Cluster the transaction is split per hash
+// slot.
+func (c *Client) XAddPipeline(ctx context.Context, entries []StreamEntry) ([]string, []error) {
+	pipe := c.rdb.TxPipeline()
+	cmds := make([]*redis.StringCmd, len(entries))
+	keyCmds := make([][]redis.Cmder, len(entries))
+	for i, entry := range entries {
+		cmds[i] = pipe.XAdd(ctx, c.xaddArgs(entry.Stream, entry.Values))
+		if entry.Push != nil {
+			keyCmds[i] = entry.Push.queue(ctx, pipe)
+		}
+		if entry.Expire != nil {
+			keyCmds[i] = append(keyCmds[i], pipe.Expire(ctx, entry.Expire.Key, entry.Expire.TTL))
+		}
+	}
+	// Per-command errors are reported below
+	pipe.Exec(ctx)
+
+	ids := make([]string, len(entries))
+	errs := make([]error, len(entries))
+	for i, cmd := range cmds {
+		ids[i], errs[i] = cmd.Result()
+		if errs[i] != nil {
+			continue
+		}
+		for _, keyCmd := range keyCmds[i] {
+			if err := keyCmd.Err(); err != nil {
+				slog.WarnContext(ctx, "failed to update key with stream entry", "stream", entries[i].Stream, "key", keyCmd.Args()[1], "error", err)
+				break
+			}
+		}
+	}
+	return ids, errs
+}
+
+// xaddArgs builds XADD arguments with the configured stream length cap
+func (c *Client) xaddArgs(stream string, values map[string]interface{}) *redis.XAddArgs {
+	args := &redis.XAddArgs{
 		Stream: stream,
 		Values: values,
+	}
+	if c.streamMaxLen > 0 {
+		args.MaxLen = c.streamMaxLen
+		args.Approx = true
+	}
+	return args
+}
+
+// XLen returns the number of entries in stream
+func (c *Client) XLen(ctx context.Context, stream string) (int64, error) {
+	return c.rdb.XLen(ctx, stream).Result()
+}
+
+// StreamRecord is a stream entry read back by XRange and XRevRange: its ID
+// and its payload field
+type StreamRecord struct {
+	ID      string
+	Payload string
+}
+
+// XRange returns up to count entries of stream with IDs between start and
+// end, oldest first; count <= 0 returns all of them. IDs prefixed with ( are
+// exclusive.
+func (c *Client) XRange(ctx context.Context, stream, start, end string, count int) ([]StreamRecord, error) {
+	messages, err := c.XRangeMessages(ctx, stream, start, end, count)
+	return streamRecords(messages), err
+}
+
+// XRevRange is XRange newest first, so start is the higher ID
+func (c *Client) XRevRange(ctx context.Context, stream, start, end string, count int) ([]StreamRecord, error) {
+	messages, err := c.XRevRangeMessages(ctx, stream, start, end, count)
+	return streamRecords(messages), err
+}
+
+// XRangeMessages is XRange returning all fields of the entries
+func (c *Client) XRangeMessages(ctx context.Context, stream, start, end string, count int) ([]XMessage, error) {
+	if count <= 0 {
+		return c.rdb.XRange(ctx, stream, start, end).Result()
+	}
+	return c.rdb.XRangeN(ctx, stream, start, end, int64(count)).Result()
+}
+
+// XRevRangeMessages is XRevRange returning all fields of the entries
+func (c *Client) XRevRangeMessages(ctx context.Context, stream, start, end string, count int) ([]XMessage, error) {
+	if count <= 0 {
+		return c.rdb.XRevRange(ctx, stream, start, end).Result()
+	}
+	return c.rdb.XRevRangeN(ctx, stream, start, end, int64(count)).Result()
+}
+
+// streamRecords returns the ID and payload field of messages
+func streamRecords(messages []XMessage) []StreamRecord {
+	if messages == nil {
+		return nil
+	}
+	records := make([]StreamRecord, len(messages))
+	for i, msg := range messages {
+		payload, _ := msg.Values["payload"].(string)
+		records[i] = StreamRecord{ID: msg.ID, Payload: payload}
+	}
+	return records
+}
+
+// XDel deletes entries from stream
+func (c *Client) XDel(ctx context.Context, stream string, ids ...string) error {
+	return c.rdb.XDel(ctx, stream, ids...).Err()
+}
+
+// DB returns the selected database index
+func (c *Client) DB() int {
+	return c.db
+}
+
+// Subscribe subscribes to Pub/Sub channels; the caller must close the
+// returned PubSub
+func (c *Client) Subscribe(ctx context.Context, channels ...string) *redis.PubSub {
+	return c.rdb.Subscribe(ctx, channels...)
+}
+
+// XTrim trims stream to maxLen entries and returns the number of entries
+// removed. With approx Redis only removes whole internal nodes (MAXLEN ~),
+// which is much cheaper but may leave more than maxLen entries
+func (c *Client) XTrim(ctx context.Context, stream string, maxLen int64, approx bool) (int64, error) {
+	if approx {
+		return c.rdb.XTrimMaxLenApprox(ctx, stream, maxLen, 0).Result()
+	}
+	return c.rdb.XTrimMaxLen(ctx, stream, maxLen).Result()
+}
+
+// XGroupCreateMkStream creates consumer group on stream, creating the stream
+// if needed, with new entries delivered after start. Creating a group that
+// already exists is not an error.
+func (c *Client) XGroupCreateMkStream(ctx context.Context, stream, group, start string) error {
+	err := c.rdb.XGroupCreateMkStream(ctx, stream, group, start).Err()
+	if redis.HasErrorPrefix(err, "BUSYGROUP") {
+		return nil
+	}
+	return err
+}
+
+// XReadGroup reads entries from a single stream as consumer of group,
+// blocking up to block. id ">" reads new entries; any other ID reads the
+// consumer's pending entries after it without blocking. Returns no entries
+// and no error when the block expires
+func (c *Client) XReadGroup(ctx context.Context, stream, group, consumer, id string, count int64, block time.Duration) ([]redis.XMessage, error) {
+	streams, err := c.rdb.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    group,
+		Consumer: consumer,
+		Streams:  []string{stream, id},
+		Count:    count,
+		Block:    block,
+	}).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(streams) == 0 {
+		return nil, nil
+	}
+	return streams[0].Messages, nil
+}
+
+// XAck acknowledges entries of stream processed by group
+func (c *Client) XAck(ctx context.Context, stream, group string, ids ...string) error {
+	return c.rdb.XAck(ctx, stream, group, ids...).Err()
+}
+
+// XAutoClaim transfers up to count entries of stream pending in group for
+// at least minIdleTime to consumer, scanning the pending list from start,
+// and returns them. Entries deleted from the stream are not returned.
+func (c *Client) XAutoClaim(ctx context.Context, stream, group, consumer string, minIdleTime time.Duration, start string, count int) ([]redis.XMessage, error) {
+	messages, _, err := c.rdb.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+		Stream:   stream,
+		Group:    group,
+		Consumer: consumer,
+		MinIdle:  minIdleTime,
+		Start:    start,
+		Count:    int64(count),
 	}).Result()
+	return messages, err
 }
 
-// SetNX sets a value only if key does not exist
-// Returns true if key was set, false if key already existed
-func (c *Client) SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) (bool, error) {
-	return c.rdb.SetNX(ctx, key, value, expiration).Result()
+// XClaim transfers the entries with ids of stream pending in group for at
+// least minIdleTime to consumer and returns them. IDs that are not pending
+// or whose entries were deleted are skipped.
+func (c *Client) XClaim(ctx context.Context, stream, group, consumer string, minIdleTime time.Duration, ids []string) ([]redis.XMessage, error) {
+	return c.rdb.XClaim(ctx, &redis.XClaimArgs{
+		Stream:   stream,
+		Group:    group,
+		Consumer: consumer,
+		MinIdle:  minIdleTime,
+		Messages: ids,
+	}).Result()
+}
+
+// XPendingDeliveries returns how often each entry with ids of stream was
+// delivered in group, in one pipelined round trip. Entries that are not
+// pending have 0 deliveries.
+func (c *Client) XPendingDeliveries(ctx context.Context, stream, group string, ids []string) ([]int64, error) {
+	pipe := c.rdb.Pipeline()
+	cmds := make([]*redis.XPendingExtCmd, len(ids))
+	for i, id := range ids {
+		cmds[i] = pipe.XPendingExt(ctx, &redis.XPendingExtArgs{Stream: stream, Group: group, Start: id, End: id, Count: 1})
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	deliveries := make([]int64, len(ids))
+	for i, cmd := range cmds {
+		if pending := cmd.Val(); len(pending) > 0 {
+			deliveries[i] = pending[0].RetryCount
+		}
+	}
+	return deliveries, nil
+}
+
+// XPendingCount returns the number of entries of stream delivered to group
+// but not yet acknowledged
+func (c *Client) XPendingCount(ctx context.Context, stream, group string) (int64, error) {
+	pending, err := c.rdb.XPending(ctx, stream, group).Result()
+	if err != nil {
+		return 0, err
+	}
+	return pending.Count, nil
+}
+
+// StreamInfo is the length of a stream and the read position of its
+// consumer groups
+type StreamInfo struct {
+	Length          int64
+	LastGeneratedID string
+	Groups          []StreamGroupInfo
+}
+
+// StreamGroupInfo is the read position of a consumer group. Lag is the
+// number of entries not yet delivered to the group as reported by Redis
+// (entries added minus entries read), so it stays correct after trimming.
+type StreamGroupInfo struct {
+	Name            string
+	LastDeliveredID string
+	Pending         int64
+	Lag             int64
+}
+
+// XInfoStream returns the length and consumer groups of stream. A stream
+// that does not exist has zero length and no groups.
+func (c *Client) XInfoStream(ctx context.Context, stream string) (StreamInfo, error) {
+	info, err := c.rdb.XInfoStream(ctx, stream).Result()
+	if redis.HasErrorPrefix(err, "no such key") {
+		return StreamInfo{}, nil
+	}
+	if err != nil {
+		return StreamInfo{}, err
+	}
+	groups, err := c.rdb.XInfoGroups(ctx, stream).Result()
+	if err != nil {
+		return StreamInfo{}, err
+	}
+
+	result := StreamInfo{
+		Length:          info.Length,
+		LastGeneratedID: info.LastGeneratedID,
+		Groups:          make([]StreamGroupInfo, len(groups)),
+	}
+	for i, group := range groups {
+		result.Groups[i] = StreamGroupInfo{
+			Name:            group.Name,
+			LastDeliveredID: group.LastDeliveredID,
+			Pending:         group.Pending,
+			Lag:             group.Lag,
+		}
+	}
+	return result, nil
+}
+
+// Incr increments an integer key and returns the new value
+func (c *Client) Incr(ctx context.Context, key string) (int64, error) {
+	return c.rdb.Incr(ctx, key).Result()
+}
+
+// Eval runs a Lua script. Prefer ScriptLoad and EvalSha for scripts run
+// on hot paths.
+func (c *Client) Eval(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error) {
+	return c.rdb.Eval(ctx, script, keys, args...).Result()
+}
+
+// ScriptLoad loads a Lua script into the script cache and returns its SHA1
+func (c *Client) ScriptLoad(ctx context.Context, script string) (string, error) {
+	return c.rdb.ScriptLoad(ctx, script).Result()
+}
+
+// EvalSha runs a cached Lua script. An unknown SHA returns an error
+// satisfying IsNoScript
+func (c *Client) EvalSha(ctx context.Context, sha string, keys []string, args ...interface{}) (interface{}, error) {
+	return c.rdb.EvalSha(ctx, sha, keys, args...).Result()
+}
+
+// IsNoScript reports whether err means the script is not in the script
+// cache, e.g. after a Redis restart or failover
+func IsNoScript(err error) bool {
+	return redis.HasErrorPrefix(err, "NOSCRIPT")
+}
+
+// IsNil reports whether err means the key does not exist
+func IsNil(err error) bool {
+	return errors.Is(err, redis.Nil)
 }
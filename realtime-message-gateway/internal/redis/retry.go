@@ -0,0 +1,72 @@
+package redis
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+// Retry policy for stream writes
+const (
+	retryBaseDelay = 10 * time.Millisecond
+	retryFactor    = 2
+	retryJitter    = 0.2 // ±20%
+)
+
+// xaddFunc matches the signature of Client.XAdd
+type xaddFunc func(ctx context.Context, stream string, values map[string]interface{}) (string, error)
+
+// RetryXAdd adds entry to stream, retrying transient failures with
+// exponential backoff and jitter up to maxAttempts total attempts
+func (c *Client) RetryXAdd(ctx context.Context, stream string, values map[string]interface{}, maxAttempts int) (string, error) {
+	return retryXAdd(ctx, c.XAdd, stream, values, maxAttempts, retryBaseDelay)
+}
+
+// RetryXAddEntry adds entry with its push and expiration like
+// XAddPipeline, retrying like RetryXAdd
+func (c *Client) RetryXAddEntry(ctx context.Context, entry StreamEntry, maxAttempts int) (string, error) {
+	xadd := func(ctx context.Context, stream string, values map[string]interface{}) (string, error) {
+		ids, errs := c.XAddPipeline(ctx, []StreamEntry{{Stream: stream, Values: values, Push: entry.Push, Expire: entry.Expire}})
+		return ids[0], errs[0]
+	}
+	return retryXAdd(ctx, xadd, entry.Stream, entry.Values, maxAttempts, retryBaseDelay)
+}
+
+// retryXAdd calls xadd until it succeeds, maxAttempts is reached or ctx is cancelled
+func retryXAdd(ctx context.Context, xadd xaddFunc, stream string, values map[string]interface{}, maxAttempts int, baseDelay time.Duration) (string, error) {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	var (
+		id  string
+		err error
+	)
+	delay := baseDelay
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		id, err = xadd(ctx, stream, values)
+		if err == nil {
+			return id, nil
+		}
+		if attempt == maxAttempts {
+			break
+		}
+
+		timer := time.NewTimer(withJitter(delay))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return "", ctx.Err()
+		case <-timer.C:
+		}
+		delay *= retryFactor
+	}
+
+	return "", err
+}
+
+// withJitter randomizes d by ±retryJitter
+func withJitter(d time.Duration) time.Duration {
+	factor := 1 - retryJitter + 2*retryJitter*rand.Float64()
+	return time.Duration(float64(d) * factor)
+}
@@ -0,0 +1,75 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// failingXAdd returns an xaddFunc that fails the first n calls
+func failingXAdd(n int, calls *int) xaddFunc {
+	return func(ctx context.Context, stream string, values map[string]interface{}) (string, error) {
+		*calls++
+		if *calls <= n {
+			return "", errors.New("connection reset")
+		}
+		return "1-0", nil
+	}
+}
+
+func TestRetryXAdd(t *testing.T) {
+	tests := []struct {
+		name        string
+		failures    int
+		maxAttempts int
+		wantErr     bool
+		wantCalls   int
+	}{
+		{"succeeds first try", 0, 3, false, 1},
+		{"succeeds after retries", 2, 3, false, 3},
+		{"exhausts attempts", 3, 3, true, 3},
+		{"single attempt", 1, 1, true, 1},
+		{"zero attempts treated as one", 0, 0, false, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			id, err := retryXAdd(context.Background(), failingXAdd(tt.failures, &calls), "stream", nil, tt.maxAttempts, time.Millisecond)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("retryXAdd() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && id != "1-0" {
+				t.Errorf("retryXAdd() id = %q, want %q", id, "1-0")
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("retryXAdd() calls = %d, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestRetryXAddContextCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	calls := 0
+	_, err := retryXAdd(ctx, failingXAdd(5, &calls), "stream", nil, 5, time.Second)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("retryXAdd() error = %v, want %v", err, context.Canceled)
+	}
+	if calls != 1 {
+		t.Errorf("retryXAdd() calls = %d, want 1", calls)
+	}
+}
+
+func TestWithJitter(t *testing.T) {
+	base := 100 * time.Millisecond
+	for i := 0; i < 100; i++ {
+		d := withJitter(base)
+		if d < 80*time.Millisecond || d > 120*time.Millisecond {
+			t.Fatalf("withJitter(%v) = %v, want within ±20%%", base, d)
+		}
+	}
+}
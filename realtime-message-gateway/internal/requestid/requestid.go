@@ -0,0 +1,79 @@
+// Package requestid carries a per-request correlation ID through contexts
+// and adds it to every slog record logged with that context.
+package requestid
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// Header is the HTTP header a request ID is read from and echoed in
+const Header = "X-Request-ID"
+
+// maxLength bounds request IDs accepted from clients
+const maxLength = 128
+
+// contextKey is the context key for the request ID
+type contextKey struct{}
+
+// New returns a copy of ctx carrying a freshly generated request ID
+func New(ctx context.Context) context.Context {
+	return WithID(ctx, uuid.New().String())
+}
+
+// WithID returns a copy of ctx carrying id
+func WithID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the request ID stored in ctx, or "" if there is none
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// Middleware stores the X-Request-ID header in the request context,
+// generating an ID when the header is missing or too long, and echoes it
+// in the response
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(Header)
+		if id == "" || len(id) > maxLength {
+			id = uuid.New().String()
+		}
+		w.Header().Set(Header, id)
+		next.ServeHTTP(w, r.WithContext(WithID(r.Context(), id)))
+	})
+}
+
+// LogHandler adds a requestId attribute to records logged with a context
+// carrying a request ID (slog.InfoContext and friends)
+type LogHandler struct {
+	slog.Handler
+}
+
+// NewLogHandler wraps next with request ID enrichment
+func NewLogHandler(next slog.Handler) *LogHandler {
+	return &LogHandler{Handler: next}
+}
+
+// Handle adds the request ID, if any, before passing the record on
+func (h *LogHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := FromContext(ctx); id != "" {
+		r.AddAttrs(slog.String("requestId", id))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+// WithAttrs keeps request ID enrichment on derived handlers
+func (h *LogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &LogHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+// WithGroup keeps request ID enrichment on derived handlers
+func (h *LogHandler) WithGroup(name string) slog.Handler {
+	return &LogHandler{Handler: h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,93 @@
+package requestid
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name       string
+		header     string
+		wantReused bool
+	}{
+		{"header present", "req-123", true},
+		{"header missing", "", false},
+		{"header too long", strings.Repeat("x", maxLength+1), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotID string
+			handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotID = FromContext(r.Context())
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/health", nil)
+			if tt.header != "" {
+				req.Header.Set(Header, tt.header)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if gotID == "" {
+				t.Fatal("request ID missing from context")
+			}
+			if reused := gotID == tt.header; reused != tt.wantReused {
+				t.Errorf("request ID = %q, header = %q, want reused %v", gotID, tt.header, tt.wantReused)
+			}
+			if echoed := rec.Header().Get(Header); echoed != gotID {
+				t.Errorf("response %s = %q, want %q", Header, echoed, gotID)
+			}
+		})
+	}
+}
+
+func TestNewGeneratesUniqueIDs(t *testing.T) {
+	first := FromContext(New(context.Background()))
+	second := FromContext(New(context.Background()))
+
+	if first == "" || second == "" {
+		t.Fatal("New() did not store a request ID")
+	}
+	if first == second {
+		t.Errorf("New() generated duplicate ID %q", first)
+	}
+}
+
+func TestLogHandler(t *testing.T) {
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want string
+	}{
+		{"with request ID", WithID(context.Background(), "req-123"), "req-123"},
+		{"without request ID", context.Background(), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(NewLogHandler(slog.NewJSONHandler(&buf, nil))).With("component", "test")
+			logger.InfoContext(tt.ctx, "hello")
+
+			var record map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+				t.Fatalf("invalid log output %q: %v", buf.String(), err)
+			}
+			got, _ := record["requestId"].(string)
+			if got != tt.want {
+				t.Errorf("requestId = %q, want %q", got, tt.want)
+			}
+			if record["component"] != "test" {
+				t.Errorf("component = %v, want test", record["component"])
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package routing
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"realtime-message-gateway/internal/metrics"
+	"realtime-message-gateway/internal/redis"
+)
+
+// Backlog thresholds as a fraction of the stream max length
+const (
+	BacklogWarnRatio    = 0.80
+	BacklogDegradeRatio = 0.95
+)
+
+// BacklogMonitor watches the stream length of every active worker. Workers
+// whose stream approaches the max length are marked degraded for a cooldown
+// so the router stops assigning new channels to them before XADD trimming
+// silently drops their unprocessed messages.
+type BacklogMonitor struct {
+	redis    *redis.Client
+	maxLen   int64
+	cooldown time.Duration
+	metrics  *metrics.Metrics
+}
+
+// NewBacklogMonitor creates a monitor for streams capped at maxLen entries,
+// exporting backlog ratios to m
+func NewBacklogMonitor(redisClient *redis.Client, maxLen int64, cooldown time.Duration, m *metrics.Metrics) *BacklogMonitor {
+	return &BacklogMonitor{
+		redis:    redisClient,
+		maxLen:   maxLen,
+		cooldown: cooldown,
+		metrics:  m,
+	}
+}
+
+// Run checks worker streams every interval until ctx is cancelled
+func (m *BacklogMonitor) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := m.Check(ctx); err != nil && ctx.Err() == nil {
+			slog.Error("failed to check worker stream backlog", "error", err)
+		}
+	}
+}
+
+// Check measures each active worker's stream once, updating the backlog
+// gauge and marking workers past BacklogDegradeRatio as degraded
+func (m *BacklogMonitor) Check(ctx context.Context) error {
+	workers, err := m.redis.ZRange(ctx, ActiveWorkersKey, 0, -1)
+	if err != nil {
+		return err
+	}
+
+	for _, workerID := range workers {
+		// Each priority stream is trimmed separately: the fuller one counts
+		var length int64
+		for _, streamKey := range GetWorkerStreamKeys(workerID) {
+			n, err := m.redis.XLen(ctx, streamKey)
+			if err != nil {
+				return err
+			}
+			length = max(length, n)
+		}
+
+		ratio := float64(length) / float64(m.maxLen)
+		m.metrics.StreamBacklogRatio.WithLabelValues(workerID).Set(ratio)
+
+		switch {
+		case ratio >= BacklogDegradeRatio:
+			if err := m.redis.Set(ctx, DegradedWorkerPrefix+workerID, length, m.cooldown); err != nil {
+				return err
+			}
+			slog.Warn("worker stream backlogged, marked degraded",
+				"worker", workerID,
+				"length", length,
+				"ratio", ratio,
+				"cooldown", m.cooldown,
+			)
+		case ratio >= BacklogWarnRatio:
+			slog.Warn("worker stream backlog high", "worker", workerID, "length", length, "ratio", ratio)
+		}
+	}
+	return nil
+}
+
+// filterDegradedWorkers returns the workers not currently marked degraded
+func (r *Router) filterDegradedWorkers(ctx context.Context, workers []string) ([]string, error) {
+	keys := make([]string, len(workers))
+	for i, workerID := range workers {
+		keys[i] = DegradedWorkerPrefix + workerID
+	}
+
+	degraded, err := r.redis.KeysExist(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	healthy := make([]string, 0, len(workers))
+	for i, workerID := range workers {
+		if !degraded[i] {
+			healthy = append(healthy, workerID)
+		}
+	}
+	return healthy, nil
+}
@@ -0,0 +1,125 @@
+package routing
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+
+	"realtime-message-gateway/internal/config"
+	"realtime-message-gateway/internal/metrics"
+	"realtime-message-gateway/internal/redis"
+)
+
+// newTestRedis starts a miniredis server and connects a client to it
+func newTestRedis(t testing.TB) (*miniredis.Miniredis, *redis.Client) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	client, err := redis.NewClient(&config.Config{RedisURL: "redis://" + mr.Addr()})
+	if err != nil {
+		t.Fatalf("redis.NewClient() error = %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return mr, client
+}
+
+// fillStream adds n entries to a worker's stream of priority
+func fillStream(t *testing.T, mr *miniredis.Miniredis, workerID string, priority, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		if _, err := mr.XAdd(GetWorkerStreamKey(workerID, priority), "*", []string{"payload", fmt.Sprint(i)}); err != nil {
+			t.Fatalf("XAdd() error = %v", err)
+		}
+	}
+}
+
+func TestBacklogMonitorCheck(t *testing.T) {
+	tests := []struct {
+		name         string
+		normal       int
+		high         int
+		wantDegraded bool
+	}{
+		{"empty stream", 0, 0, false},
+		{"below warn threshold", 50, 0, false},
+		{"above warn threshold", 85, 0, false},
+		{"at degrade threshold", 95, 0, true},
+		{"full stream", 100, 0, true},
+		{"full high stream", 10, 100, true},
+		{"both streams below threshold", 60, 60, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mr, client := newTestRedis(t)
+			mr.ZAdd(ActiveWorkersKey, 1, "worker-0")
+			fillStream(t, mr, "worker-0", PriorityNormal, tt.normal)
+			fillStream(t, mr, "worker-0", PriorityHigh, tt.high)
+
+			monitor := NewBacklogMonitor(client, 100, time.Minute, metrics.Default)
+			if err := monitor.Check(context.Background()); err != nil {
+				t.Fatalf("Check() error = %v", err)
+			}
+
+			if got := mr.Exists(DegradedWorkerPrefix + "worker-0"); got != tt.wantDegraded {
+				t.Errorf("worker degraded = %v, want %v", got, tt.wantDegraded)
+			}
+		})
+	}
+}
+
+func TestBacklogMonitorCooldownExpires(t *testing.T) {
+	mr, client := newTestRedis(t)
+	mr.ZAdd(ActiveWorkersKey, 1, "worker-0")
+	fillStream(t, mr, "worker-0", PriorityNormal, 10)
+
+	monitor := NewBacklogMonitor(client, 10, time.Minute, metrics.Default)
+	if err := monitor.Check(context.Background()); err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if !mr.Exists(DegradedWorkerPrefix + "worker-0") {
+		t.Fatal("worker not marked degraded")
+	}
+
+	mr.FastForward(time.Minute)
+	if mr.Exists(DegradedWorkerPrefix + "worker-0") {
+		t.Error("worker still degraded after cooldown")
+	}
+}
+
+func TestRouterSkipsDegradedWorkers(t *testing.T) {
+	mr, client := newTestRedis(t)
+	mr.ZAdd(ActiveWorkersKey, 1, "worker-0")
+	mr.ZAdd(ActiveWorkersKey, 2, "worker-1")
+	mr.Set(DegradedWorkerPrefix+"worker-0", "100")
+
+	router := newTestRouter(t, client, time.Minute)
+	for i := 0; i < 4; i++ {
+		channel := fmt.Sprintf("chat:room-%d", i)
+		workerID, err := router.GetWorkerForChannel(context.Background(), channel)
+		if err != nil {
+			t.Fatalf("GetWorkerForChannel(%q) error = %v", channel, err)
+		}
+		if workerID != "worker-1" {
+			t.Errorf("GetWorkerForChannel(%q) = %q, want worker-1", channel, workerID)
+		}
+	}
+}
+
+func TestRouterUsesDegradedWorkersWhenAllDegraded(t *testing.T) {
+	mr, client := newTestRedis(t)
+	mr.ZAdd(ActiveWorkersKey, 1, "worker-0")
+	mr.Set(DegradedWorkerPrefix+"worker-0", "100")
+
+	router := newTestRouter(t, client, time.Minute)
+	workerID, err := router.GetWorkerForChannel(context.Background(), "chat")
+	if err != nil {
+		t.Fatalf("GetWorkerForChannel() error = %v", err)
+	}
+	if workerID != "worker-0" {
+		t.Errorf("GetWorkerForChannel() = %q, want worker-0", workerID)
+	}
+}
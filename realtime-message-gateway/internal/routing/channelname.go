@@ -0,0 +1,64 @@
+package routing
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// ChannelNameSanitizer maps a channel name to the name its route is stored
+// under, so different spellings of one channel share a route and worker
+type ChannelNameSanitizer func(channel string) string
+
+// WithChannelNameSanitizer routes channels under the name fn returns
+// instead of the name as given
+func WithChannelNameSanitizer(fn ChannelNameSanitizer) RouterOption {
+	return func(r *Router) {
+		r.channelName = fn
+	}
+}
+
+// NormalizeChannelName is the default ChannelNameSanitizer. It trims
+// spaces, applies Unicode NFKC normalization, lowercases, and in each
+// colon-separated segment replaces runs of characters other than letters
+// and digits with a single hyphen, trimming hyphens at the segment ends.
+// "chat:Room 1", "chat:ROOM-1" and "chat:room--1" all become "chat:room-1".
+func NormalizeChannelName(channel string) string {
+	channel = strings.ToLower(norm.NFKC.String(strings.TrimSpace(channel)))
+
+	segments := strings.Split(channel, ":")
+	for i, segment := range segments {
+		segments[i] = hyphenate(segment)
+	}
+	return strings.Join(segments, ":")
+}
+
+// hyphenate replaces runs of characters other than letters and digits in s
+// with a single hyphen and trims hyphens at both ends
+func hyphenate(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	pendingHyphen := false
+	for _, c := range s {
+		if !unicode.IsLetter(c) && !unicode.IsDigit(c) {
+			pendingHyphen = b.Len() > 0
+			continue
+		}
+		if pendingHyphen {
+			b.WriteByte('-')
+			pendingHyphen = false
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// NormalizeChannel returns the name channel is routed under: the result of
+// the WithChannelNameSanitizer function, or channel itself without one
+func (r *Router) NormalizeChannel(channel string) string {
+	if r.channelName == nil {
+		return channel
+	}
+	return r.channelName(channel)
+}
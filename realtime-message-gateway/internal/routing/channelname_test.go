@@ -0,0 +1,84 @@
+package routing
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNormalizeChannelName(t *testing.T) {
+	tests := []struct {
+		name    string
+		channel string
+		want    string
+	}{
+		{"canonical", "chat:room-1", "chat:room-1"},
+		{"space", "chat:Room 1", "chat:room-1"},
+		{"uppercase", "chat:ROOM-1", "chat:room-1"},
+		{"surrounding spaces", "  chat:room-1 ", "chat:room-1"},
+		{"punctuation run", "chat:room -_- 1", "chat:room-1"},
+		{"segment ends", "chat: room-1!", "chat:room-1"},
+		{"segments kept", "chat:Team A:General", "chat:team-a:general"},
+		{"global chat", "chat", "chat"},
+
+		// Unicode
+		{"fullwidth", "chat:Ｒｏｏｍ　１", "chat:room-1"},
+		{"composed accent", "chat:Caf\u00e9", "chat:caf\u00e9"},
+		{"decomposed accent", "chat:Cafe\u0301", "chat:caf\u00e9"},
+		{"non-latin letters", "chat:Москва Чат", "chat:москва-чат"},
+		{"cjk", "chat:日本語 ルーム", "chat:日本語-ルーム"},
+		{"ligature", "chat:ﬁle", "chat:file"},
+		{"emoji", "chat:room 🎉 1", "chat:room-1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NormalizeChannelName(tt.channel)
+			if got != tt.want {
+				t.Errorf("NormalizeChannelName(%q) = %q, want %q", tt.channel, got, tt.want)
+			}
+			if again := NormalizeChannelName(got); again != got {
+				t.Errorf("NormalizeChannelName(%q) = %q, want idempotent %q", got, again, got)
+			}
+		})
+	}
+}
+
+func TestGetWorkerForChannelSanitizer(t *testing.T) {
+	mr, client := newTestRedis(t)
+	for i, workerID := range []string{"worker-0", "worker-1", "worker-2"} {
+		mr.ZAdd(ActiveWorkersKey, float64(i), workerID)
+	}
+	router := newTestRouter(t, client, time.Minute, WithChannelNameSanitizer(NormalizeChannelName))
+	ctx := context.Background()
+
+	want, err := router.GetWorkerForChannel(ctx, "chat:room-1")
+	if err != nil {
+		t.Fatalf("GetWorkerForChannel() error = %v", err)
+	}
+	for _, channel := range []string{"chat:Room 1", "chat:ROOM-1"} {
+		// Skip the local cache so the Redis route is used
+		router.ClearCache()
+		if got, err := router.GetWorkerForChannel(ctx, channel); err != nil || got != want {
+			t.Errorf("GetWorkerForChannel(%q) = %q, %v, want %q", channel, got, err, want)
+		}
+	}
+
+	var routes []string
+	for _, key := range mr.Keys() {
+		if strings.HasPrefix(key, ChannelRoutePrefix) {
+			routes = append(routes, key)
+		}
+	}
+	if len(routes) != 1 {
+		t.Errorf("route keys = %v, want only %s", routes, ChannelRoutePrefix+"chat:room-1")
+	}
+
+	if deleted, err := router.DeleteChannelRoute(ctx, "chat:Room 1"); err != nil || !deleted {
+		t.Errorf("DeleteChannelRoute(chat:Room 1) = %v, %v, want true", deleted, err)
+	}
+	if mr.Exists(ChannelRoutePrefix + "chat:room-1") {
+		t.Error("route of chat:room-1 exists after deleting chat:Room 1")
+	}
+}
@@ -0,0 +1,69 @@
+package routing
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"realtime-message-gateway/internal/redis"
+)
+
+// Keyspace events that end a channel route. Redis only publishes them when
+// notify-keyspace-events includes E (keyevent), g (del) and x (expired).
+var routeKeyEvents = []string{"del", "expired"}
+
+// KeyspaceSubscriber listens to Redis keyspace notifications and drops a
+// channel from the router's local cache as soon as its channel:route key is
+// deleted or expires, e.g. when workers rebalance channels, instead of
+// serving the stale worker until RouteCacheTTL runs out.
+type KeyspaceSubscriber struct {
+	redis  *redis.Client
+	router *Router
+}
+
+// NewKeyspaceSubscriber creates a subscriber invalidating routes of router
+func NewKeyspaceSubscriber(redisClient *redis.Client, router *Router) *KeyspaceSubscriber {
+	return &KeyspaceSubscriber{
+		redis:  redisClient,
+		router: router,
+	}
+}
+
+// keyEventChannel returns the Pub/Sub channel of event in database db
+func keyEventChannel(db int, event string) string {
+	return fmt.Sprintf("__keyevent@%d__:%s", db, event)
+}
+
+// Run handles keyspace notifications until ctx is cancelled or the router
+// is closed. The Pub/Sub connection resubscribes by itself after network
+// errors.
+func (s *KeyspaceSubscriber) Run(ctx context.Context) {
+	channels := make([]string, len(routeKeyEvents))
+	for i, event := range routeKeyEvents {
+		channels[i] = keyEventChannel(s.redis.DB(), event)
+	}
+
+	pubsub := s.redis.Subscribe(ctx, channels...)
+	defer pubsub.Close()
+
+	messages := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.router.Done():
+			return
+		case msg, ok := <-messages:
+			if !ok {
+				return
+			}
+			// The payload of a keyevent notification is the key name
+			if channel, isRoute := strings.CutPrefix(msg.Payload, ChannelRoutePrefix); isRoute {
+				s.router.InvalidateCache(channel)
+				s.router.metrics.RouteCacheKeyspaceInvalidations.Inc()
+				slog.Debug("route invalidated by keyspace event", "channel", channel, "event", msg.Channel)
+			}
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package routing
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestKeyspaceSubscriberInvalidatesRoutes(t *testing.T) {
+	mr, client := newTestRedis(t)
+	router := newTestRouter(t, client, time.Minute)
+	router.updateCache("chat:a", "worker-0")
+	router.updateCache("chat:b", "worker-0")
+	router.updateCache("chat:c", "worker-0")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		NewKeyspaceSubscriber(client, router).Run(ctx)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	// Wait for the subscription before publishing events
+	deadline := time.Now().Add(time.Second)
+	for mr.PubSubNumSub(keyEventChannel(0, "del"))[keyEventChannel(0, "del")] == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("subscriber did not subscribe")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Unrelated events first: once chat:b is invalidated they were handled
+	mr.Publish(keyEventChannel(0, "del"), "room:chat:c")
+	mr.Publish(keyEventChannel(1, "del"), ChannelRoutePrefix+"chat:c")
+	mr.Publish(keyEventChannel(0, "del"), ChannelRoutePrefix+"chat:a")
+	mr.Publish(keyEventChannel(0, "expired"), ChannelRoutePrefix+"chat:b")
+
+	cached := func(channel string) bool {
+		_, ok := router.cache.Load(channel)
+		return ok
+	}
+	for deadline := time.Now().Add(time.Second); cached("chat:a") || cached("chat:b"); {
+		if time.Now().After(deadline) {
+			t.Fatalf("routes not invalidated: chat:a cached %v, chat:b cached %v", cached("chat:a"), cached("chat:b"))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !cached("chat:c") {
+		t.Error("chat:c invalidated by an unrelated key or another database")
+	}
+}
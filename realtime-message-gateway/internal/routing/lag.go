@@ -0,0 +1,86 @@
+package routing
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"realtime-message-gateway/internal/metrics"
+	"realtime-message-gateway/internal/redis"
+)
+
+// StreamLagMonitor measures how far the consumer group of each active
+// worker is behind its streams, so slow workers show up before their
+// backlog reaches STREAM_MAX_LEN
+type StreamLagMonitor struct {
+	redis         *redis.Client
+	group         string
+	warnThreshold int64
+	metrics       *metrics.Metrics
+}
+
+// NewStreamLagMonitor creates a monitor for consumer group that warns when
+// a worker lags more than warnThreshold entries, exporting lags to m
+func NewStreamLagMonitor(redisClient *redis.Client, group string, warnThreshold int64, m *metrics.Metrics) *StreamLagMonitor {
+	return &StreamLagMonitor{
+		redis:         redisClient,
+		group:         group,
+		warnThreshold: warnThreshold,
+		metrics:       m,
+	}
+}
+
+// Run checks worker streams every interval until ctx is cancelled
+func (m *StreamLagMonitor) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := m.Check(ctx); err != nil && ctx.Err() == nil {
+			slog.Error("failed to check worker stream lag", "error", err)
+		}
+	}
+}
+
+// Check sets gateway_stream_lag_messages for each active worker, summed
+// over its priority streams, and logs workers past the warn threshold
+func (m *StreamLagMonitor) Check(ctx context.Context) error {
+	workers, err := m.redis.ZRange(ctx, ActiveWorkersKey, 0, -1)
+	if err != nil {
+		return err
+	}
+
+	for _, workerID := range workers {
+		var lag int64
+		for _, streamKey := range GetWorkerStreamKeys(workerID) {
+			info, err := m.redis.XInfoStream(ctx, streamKey)
+			if err != nil {
+				return err
+			}
+			lag += m.streamLag(info)
+		}
+
+		m.metrics.StreamLagMessages.WithLabelValues(workerID).Set(float64(lag))
+		if lag > m.warnThreshold {
+			slog.Warn("worker stream lag high", "worker", workerID, "lag", lag, "threshold", m.warnThreshold)
+		}
+	}
+	return nil
+}
+
+// streamLag returns the entries of a stream not yet delivered to the
+// monitor's group; without the group nothing was read, so it is the length
+func (m *StreamLagMonitor) streamLag(info redis.StreamInfo) int64 {
+	for _, group := range info.Groups {
+		if group.Name == m.group {
+			return group.Lag
+		}
+	}
+	return info.Length
+}
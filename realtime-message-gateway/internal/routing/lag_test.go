@@ -0,0 +1,51 @@
+package routing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"realtime-message-gateway/internal/metrics"
+)
+
+func TestStreamLagMonitorCheck(t *testing.T) {
+	// miniredis reports a group's lag as the stream length, so these cases
+	// cover summing and the fallbacks rather than partly read groups
+	tests := []struct {
+		name    string
+		normal  int
+		high    int
+		group   bool
+		wantLag float64
+	}{
+		{"no streams", 0, 0, false, 0},
+		{"stream without group", 7, 0, false, 7},
+		{"stream with group", 7, 0, true, 7},
+		{"lag summed over priorities", 7, 3, true, 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mr, client := newTestRedis(t)
+			mr.ZAdd(ActiveWorkersKey, 1, "worker-0")
+			fillStream(t, mr, "worker-0", PriorityNormal, tt.normal)
+			fillStream(t, mr, "worker-0", PriorityHigh, tt.high)
+			if tt.group {
+				for _, streamKey := range GetWorkerStreamKeys("worker-0") {
+					if err := client.XGroupCreateMkStream(context.Background(), streamKey, "gw-consumer", "0"); err != nil {
+						t.Fatalf("XGroupCreateMkStream() error = %v", err)
+					}
+				}
+			}
+
+			monitor := NewStreamLagMonitor(client, "gw-consumer", 5, metrics.Default)
+			if err := monitor.Check(context.Background()); err != nil {
+				t.Fatalf("Check() error = %v", err)
+			}
+			if got := testutil.ToFloat64(metrics.Default.StreamLagMessages.WithLabelValues("worker-0")); got != tt.wantLag {
+				t.Errorf("stream_lag_messages = %v, want %v", got, tt.wantLag)
+			}
+		})
+	}
+}
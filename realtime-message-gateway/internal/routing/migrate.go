@@ -0,0 +1,89 @@
+package routing
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// migrationLockPrefix is the key prefix of the per-worker migration lock
+	migrationLockPrefix = "worker:migrating:"
+	// migrationLockTTL bounds how long a crashed migration blocks others
+	migrationLockTTL = 30 * time.Second
+	// migrationBatchSize is the SCAN count and GET pipeline size
+	migrationBatchSize = 100
+)
+
+// ErrMigrationInProgress is returned when another migration of the same
+// worker holds the lock
+var ErrMigrationInProgress = errors.New("migration already in progress")
+
+// MigrationResult summarizes a MigrateWorkerChannels run
+type MigrationResult struct {
+	WorkerID string            `json:"workerId"`
+	Migrated map[string]string `json:"migrated"` // channel -> new worker
+	Failed   []string          `json:"failed"`
+}
+
+// MigrateWorkerChannels reassigns every channel routed to workerID to
+// another active worker and drops it from the local cache, so a worker can
+// be decommissioned while it drains its streams. Gateways that still cache
+// the old route keep publishing to workerID for up to RouteCacheTTL.
+// Channels already moved elsewhere are left alone, so a failed run can be
+// repeated; a Redis lock stops concurrent runs for the same worker.
+func (r *Router) MigrateWorkerChannels(ctx context.Context, workerID string) (MigrationResult, error) {
+	lockKey := migrationLockPrefix + workerID
+	token := uuid.New().String()
+	locked, err := r.redis.SetNX(ctx, lockKey, token, migrationLockTTL)
+	if err != nil {
+		return MigrationResult{}, err
+	}
+	if !locked {
+		return MigrationResult{}, ErrMigrationInProgress
+	}
+	defer func() {
+		if err := r.redis.DelIfEqual(context.WithoutCancel(ctx), lockKey, token); err != nil {
+			slog.WarnContext(ctx, "failed to release migration lock", "worker", workerID, "error", err)
+		}
+	}()
+
+	result := MigrationResult{WorkerID: workerID, Migrated: make(map[string]string), Failed: []string{}}
+	var mu sync.Mutex
+	err = r.redis.Scan(ctx, ChannelRoutePrefix+"*", migrationBatchSize, func(keys []string) error {
+		routes, err := r.redis.GetMany(ctx, keys)
+		if err != nil {
+			return err
+		}
+
+		for i, key := range keys {
+			if routes[i] != workerID {
+				continue
+			}
+			channel := strings.TrimPrefix(key, ChannelRoutePrefix)
+			newWorkerID, err := r.assignWorkerToChannel(ctx, channel, workerID)
+			r.InvalidateCache(channel)
+
+			mu.Lock()
+			if err != nil {
+				slog.WarnContext(ctx, "failed to migrate channel", "channel", channel, "worker", workerID, "error", err)
+				result.Failed = append(result.Failed, channel)
+			} else {
+				result.Migrated[channel] = newWorkerID
+			}
+			mu.Unlock()
+		}
+		return nil
+	})
+	if err != nil {
+		return result, err
+	}
+
+	slog.InfoContext(ctx, "worker channels migrated", "worker", workerID, "migrated", len(result.Migrated), "failed", len(result.Failed))
+	return result, nil
+}
@@ -0,0 +1,87 @@
+package routing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestMigrateWorkerChannels(t *testing.T) {
+	mr, client := newTestRedis(t)
+	mr.ZAdd(ActiveWorkersKey, 1, "worker-0")
+	mr.ZAdd(ActiveWorkersKey, 1, "worker-1")
+	mr.ZAdd(ActiveWorkersKey, 1, "worker-2")
+	// More routes than one SCAN batch
+	for i := 0; i < 250; i++ {
+		mr.Set(fmt.Sprintf("%schat:%d", ChannelRoutePrefix, i), fmt.Sprintf("worker-%d", i%2))
+	}
+	router := newTestRouter(t, client, time.Minute)
+	router.updateCache("chat:0", "worker-0")
+
+	result, err := router.MigrateWorkerChannels(context.Background(), "worker-0")
+	if err != nil {
+		t.Fatalf("MigrateWorkerChannels() error = %v", err)
+	}
+	if len(result.Migrated) != 125 || len(result.Failed) != 0 {
+		t.Errorf("migrated %d, failed %d channels, want 125 and 0", len(result.Migrated), len(result.Failed))
+	}
+	for i := 0; i < 250; i++ {
+		channel := fmt.Sprintf("chat:%d", i)
+		route, _ := mr.Get(ChannelRoutePrefix + channel)
+		switch {
+		case route == "worker-0":
+			t.Fatalf("%s still routed to worker-0", channel)
+		case i%2 == 1 && route != "worker-1":
+			t.Fatalf("%s moved from worker-1 to %s", channel, route)
+		case i%2 == 0 && result.Migrated[channel] != route:
+			t.Fatalf("%s routed to %s, result says %s", channel, route, result.Migrated[channel])
+		}
+	}
+	if _, ok := router.cache.Load("chat:0"); ok {
+		t.Error("migrated channel still cached")
+	}
+	if mr.Exists(migrationLockPrefix + "worker-0") {
+		t.Error("migration lock not released")
+	}
+
+	// Running again finds nothing left to move
+	result, err = router.MigrateWorkerChannels(context.Background(), "worker-0")
+	if err != nil {
+		t.Fatalf("second MigrateWorkerChannels() error = %v", err)
+	}
+	if len(result.Migrated) != 0 || len(result.Failed) != 0 {
+		t.Errorf("second run migrated %d, failed %d channels, want 0", len(result.Migrated), len(result.Failed))
+	}
+}
+
+func TestMigrateWorkerChannelsLocked(t *testing.T) {
+	mr, client := newTestRedis(t)
+	mr.Set(migrationLockPrefix+"worker-0", "other")
+
+	_, err := newTestRouter(t, client, time.Minute).MigrateWorkerChannels(context.Background(), "worker-0")
+	if !errors.Is(err, ErrMigrationInProgress) {
+		t.Errorf("MigrateWorkerChannels() error = %v, want ErrMigrationInProgress", err)
+	}
+	if lock, _ := mr.Get(migrationLockPrefix + "worker-0"); lock != "other" {
+		t.Errorf("lock = %q, want the other migration's lock kept", lock)
+	}
+}
+
+func TestMigrateWorkerChannelsNoOtherWorker(t *testing.T) {
+	mr, client := newTestRedis(t)
+	mr.ZAdd(ActiveWorkersKey, 1, "worker-0")
+	mr.Set(ChannelRoutePrefix+"chat", "worker-0")
+
+	result, err := newTestRouter(t, client, time.Minute).MigrateWorkerChannels(context.Background(), "worker-0")
+	if err != nil {
+		t.Fatalf("MigrateWorkerChannels() error = %v", err)
+	}
+	if len(result.Failed) != 1 || result.Failed[0] != "chat" {
+		t.Errorf("Failed = %v, want [chat]", result.Failed)
+	}
+	if route, _ := mr.Get(ChannelRoutePrefix + "chat"); route != "worker-0" {
+		t.Errorf("route = %q, want worker-0 kept", route)
+	}
+}
@@ -0,0 +1,128 @@
+package routing
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// RateWindowSeconds is the longest window GetChannelMessageRate can cover
+const RateWindowSeconds = 60
+
+// RateIdleTimeout is how long after its last message a channel's counter
+// is kept. Evicted channels report a rate of 0 and no last message.
+const RateIdleTimeout = time.Hour
+
+// rateCleanupInterval is how often idle counters are evicted
+const rateCleanupInterval = RateWindowSeconds * time.Second
+
+// rateCounter counts events in a ring of one-second buckets. Each bucket
+// packs the Unix second it belongs to (high 32 bits) and its count (low 32
+// bits) into one word so it can be reset and incremented with a single CAS.
+type rateCounter struct {
+	buckets [RateWindowSeconds]atomic.Uint64
+	last    atomic.Int64 // Unix nanoseconds of the latest event
+}
+
+// packBucket combines a second and a count into a bucket word
+func packBucket(second uint32, count uint32) uint64 {
+	return uint64(second)<<32 | uint64(count)
+}
+
+// unpackBucket splits a bucket word into its second and count
+func unpackBucket(v uint64) (second uint32, count uint32) {
+	return uint32(v >> 32), uint32(v)
+}
+
+// record counts one event at now, resetting the bucket if it still holds
+// a count from a previous lap of the ring
+func (c *rateCounter) record(now time.Time) {
+	c.last.Store(now.UnixNano())
+	second := uint32(now.Unix())
+	bucket := &c.buckets[second%RateWindowSeconds]
+	for {
+		old := bucket.Load()
+		oldSecond, count := unpackBucket(old)
+		if oldSecond != second {
+			count = 0
+		}
+		if bucket.CompareAndSwap(old, packBucket(second, count+1)) {
+			return
+		}
+	}
+}
+
+// rate returns the average events per second over the last window seconds,
+// including the current one
+func (c *rateCounter) rate(now time.Time, window int) float64 {
+	current := uint32(now.Unix())
+	var total uint64
+	for i := range c.buckets {
+		second, count := unpackBucket(c.buckets[i].Load())
+		if current-second < uint32(window) {
+			total += uint64(count)
+		}
+	}
+	return float64(total) / float64(window)
+}
+
+// RecordChannelMessage counts a message published to channel through this
+// gateway
+func (r *Router) RecordChannelMessage(channel string) {
+	counter, ok := r.rates.Load(channel)
+	if !ok {
+		counter, _ = r.rates.LoadOrStore(channel, &rateCounter{})
+	}
+	counter.(*rateCounter).record(time.Now())
+}
+
+// GetChannelMessageRate returns the messages per second published to channel
+// through this gateway, averaged over the last windowSeconds seconds.
+// windowSeconds is clamped to [1, RateWindowSeconds].
+func (r *Router) GetChannelMessageRate(channel string, windowSeconds int) float64 {
+	counter, ok := r.rates.Load(channel)
+	if !ok {
+		return 0
+	}
+	windowSeconds = min(max(windowSeconds, 1), RateWindowSeconds)
+	return counter.(*rateCounter).rate(time.Now(), windowSeconds)
+}
+
+// LastChannelMessage returns when a message was last published to channel
+// through this gateway; ok is false if none was
+func (r *Router) LastChannelMessage(channel string) (at time.Time, ok bool) {
+	counter, found := r.rates.Load(channel)
+	if !found {
+		return time.Time{}, false
+	}
+	return time.Unix(0, counter.(*rateCounter).last.Load()), true
+}
+
+// rateCleanup evicts idle counters every interval, so channels that went
+// quiet do not keep their counters, until Close
+func (r *Router) rateCleanup(interval time.Duration) {
+	defer r.wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.done:
+			return
+		case now := <-ticker.C:
+			r.evictIdleRates(now)
+		}
+	}
+}
+
+// evictIdleRates removes the counters without a message in the
+// RateIdleTimeout before now. A message recorded while its counter is
+// removed is not counted.
+func (r *Router) evictIdleRates(now time.Time) {
+	idleSince := now.Add(-RateIdleTimeout).UnixNano()
+	r.rates.Range(func(key, value interface{}) bool {
+		if value.(*rateCounter).last.Load() <= idleSince {
+			r.rates.CompareAndDelete(key, value)
+		}
+		return true
+	})
+}
@@ -0,0 +1,152 @@
+package routing
+
+import (
+	"math"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRateCounter(t *testing.T) {
+	start := time.Unix(1_700_000_000, 0)
+	var c rateCounter
+
+	// 3 events per second for 10 seconds
+	for s := 0; s < 10; s++ {
+		for i := 0; i < 3; i++ {
+			c.record(start.Add(time.Duration(s) * time.Second))
+		}
+	}
+	now := start.Add(9 * time.Second)
+
+	tests := []struct {
+		name   string
+		now    time.Time
+		window int
+		want   float64
+	}{
+		{"last second", now, 1, 3},
+		{"last 10 seconds", now, 10, 3},
+		{"window larger than data", now, 60, 0.5},
+		{"after data aged out", now.Add(RateWindowSeconds * time.Second), 60, 0},
+		{"partly aged out", now.Add(5 * time.Second), 10, 1.5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := c.rate(tt.now, tt.window); got != tt.want {
+				t.Errorf("rate(%d) = %v, want %v", tt.window, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRateCounterResetsLappedBucket(t *testing.T) {
+	start := time.Unix(1_700_000_000, 0)
+	var c rateCounter
+
+	c.record(start)
+	c.record(start)
+	lapped := start.Add(RateWindowSeconds * time.Second)
+	c.record(lapped)
+
+	if got := c.rate(lapped, 1); got != 1 {
+		t.Errorf("rate() after a full lap = %v, want 1", got)
+	}
+}
+
+func TestGetChannelMessageRate(t *testing.T) {
+	r := newTestRouter(t, nil, time.Minute)
+
+	if got := r.GetChannelMessageRate("chat", 10); got != 0 {
+		t.Errorf("GetChannelMessageRate() for unknown channel = %v, want 0", got)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				r.RecordChannelMessage("chat")
+			}
+		}()
+	}
+	wg.Wait()
+
+	// The window is clamped to RateWindowSeconds, which covers all messages
+	if got := math.Round(r.GetChannelMessageRate("chat", RateWindowSeconds*2) * RateWindowSeconds); got != 1000 {
+		t.Errorf("messages in window = %v, want 1000", got)
+	}
+	if got := r.GetChannelMessageRate("chat:other", 10); got != 0 {
+		t.Errorf("GetChannelMessageRate() for other channel = %v, want 0", got)
+	}
+}
+
+func BenchmarkGetChannelMessageRate(b *testing.B) {
+	r := newTestRouter(b, nil, time.Minute)
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+					r.RecordChannelMessage("chat")
+				}
+			}
+		}()
+	}
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			r.GetChannelMessageRate("chat", RateWindowSeconds)
+		}
+	})
+	b.StopTimer()
+
+	close(done)
+	wg.Wait()
+}
+
+func TestLastChannelMessage(t *testing.T) {
+	r := newTestRouter(t, nil, time.Minute)
+
+	if _, ok := r.LastChannelMessage("chat"); ok {
+		t.Error("LastChannelMessage() for unknown channel ok = true, want false")
+	}
+
+	before := time.Now()
+	r.RecordChannelMessage("chat")
+	last, ok := r.LastChannelMessage("chat")
+	if !ok {
+		t.Fatal("LastChannelMessage() ok = false, want true")
+	}
+	if last.Before(before) || last.After(time.Now()) {
+		t.Errorf("LastChannelMessage() = %v, want about %v", last, before)
+	}
+}
+
+func TestEvictIdleRates(t *testing.T) {
+	r := newTestRouter(t, nil, time.Minute)
+
+	now := time.Now()
+	idle := &rateCounter{}
+	idle.record(now.Add(-RateIdleTimeout))
+	r.rates.Store("chat:idle", idle)
+	r.RecordChannelMessage("chat:active")
+
+	r.evictIdleRates(now)
+	if _, ok := r.LastChannelMessage("chat:idle"); ok {
+		t.Error("idle channel kept its counter")
+	}
+	if _, ok := r.LastChannelMessage("chat:active"); !ok {
+		t.Error("active channel lost its counter")
+	}
+}
@@ -3,7 +3,10 @@ package routing
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log/slog"
+	"slices"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -14,13 +17,55 @@ import (
 
 // Redis key constants - must match TypeScript implementation
 const (
-	ActiveWorkersKey   = "workers:active"
-	ChannelRoutePrefix = "channel:route:"
-	WorkerStreamPrefix = "messages:worker:"
+	ActiveWorkersKey     = "workers:active"
+	ChannelRoutePrefix   = "channel:route:"
+	WorkerStreamPrefix   = "messages:worker:"
+	GatewayStreamPrefix  = "messages:gateway:"
+	DeadLetterStreamKey  = "messages:deadletter"
+	DegradedWorkerPrefix = "worker:degraded:"
+	RoundRobinIndexKey   = "workers:rr_index"
+	// Sorted set of worker IDs scored by the number of channels routed to them
+	WorkerChannelCountKey = "workers:channel_count"
+	// Sorted set of running gateway instance IDs scored by their last
+	// heartbeat in Unix milliseconds
+	GatewayRegistryKey = "gateway:registry"
 )
 
-// ErrNoActiveWorkers is returned when no workers are available
-var ErrNoActiveWorkers = errors.New("no active workers available")
+// Message priorities. High-priority messages go to a worker's high stream,
+// which workers read before its normal stream; low and normal priority
+// messages share the normal stream.
+const (
+	PriorityLow    = 0
+	PriorityNormal = 1
+	PriorityHigh   = 2
+)
+
+// Worker stream key suffixes by priority
+const (
+	highStreamSuffix   = ":high"
+	normalStreamSuffix = ":normal"
+)
+
+// SelectionStrategy picks the worker a new channel is assigned to
+type SelectionStrategy string
+
+const (
+	// SelectRoundRobin cycles through the candidate workers
+	SelectRoundRobin SelectionStrategy = "round-robin"
+	// SelectLeastChannels picks the candidate with the fewest channels in
+	// WorkerChannelCountKey, round-robin among ties
+	SelectLeastChannels SelectionStrategy = "least-channels"
+)
+
+var (
+	// ErrNoActiveWorkers is returned when no workers are available
+	ErrNoActiveWorkers = errors.New("no active workers available")
+	// ErrWorkerNotActive is returned when routing a channel to a worker
+	// missing from workers:active
+	ErrWorkerNotActive = errors.New("worker not active")
+	// ErrRouterClosed is returned when closing a router twice
+	ErrRouterClosed = errors.New("router already closed")
+)
 
 // cacheEntry holds cached routing information
 type cacheEntry struct {
@@ -28,35 +73,146 @@ type cacheEntry struct {
 	expiresAt time.Time
 }
 
+// ChannelRegionAffinityFunc returns the preferred worker region for a channel,
+// or an empty string for no preference
+type ChannelRegionAffinityFunc func(channel string) string
+
 // Router handles channel-to-worker routing with local caching
 type Router struct {
-	redis    *redis.Client
-	cacheTTL time.Duration
-	cache    sync.Map // map[string]*cacheEntry
-	rrIndex  uint64   // round-robin index (atomic)
+	redis          *redis.Client
+	cacheTTL       time.Duration
+	routeTTL       time.Duration // expiration of channel route keys, 0 for none
+	cache          sync.Map      // map[string]*cacheEntry
+	regionAffinity ChannelRegionAffinityFunc
+	strategy       SelectionStrategy
+	reconcileEvery time.Duration        // ReconcileChannelCounts interval, 0 for none
+	channelName    ChannelNameSanitizer // nil routes channels as named
+	metrics        *metrics.Metrics
+
+	// SHA of assignWorkerScript, loaded on first use
+	assignScriptMu  sync.Mutex
+	assignScriptSHA string
+
+	// Local publish counters for GetChannelMessageRate
+	rates sync.Map // map[string]*rateCounter
+
+	// Route cache lookups for CacheHitRatio
+	cacheHits   atomic.Int64
+	cacheMisses atomic.Int64
+
+	// Closed by Close to stop background goroutines
+	done      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
 }
 
-// NewRouter creates a new Router
-func NewRouter(redisClient *redis.Client, cacheTTL time.Duration) *Router {
-	return &Router{
+// RouterOption configures optional Router behavior
+type RouterOption func(*Router)
+
+// WithRegionAffinity pins new channel assignments to workers in the region
+// returned by fn. Workers advertise their region in their ID as workerID:region.
+func WithRegionAffinity(fn ChannelRegionAffinityFunc) RouterOption {
+	return func(r *Router) {
+		r.regionAffinity = fn
+	}
+}
+
+// WithRouteTTL expires channel route keys ttl after assignment or the last
+// stream entry written with RouteExpire, so routes of channels that went
+// quiet, including those to workers since replaced under a new ID, do not
+// persist forever
+func WithRouteTTL(ttl time.Duration) RouterOption {
+	return func(r *Router) {
+		r.routeTTL = ttl
+	}
+}
+
+// WithMetrics records route cache metrics in m instead of metrics.Default
+func WithMetrics(m *metrics.Metrics) RouterOption {
+	return func(r *Router) {
+		r.metrics = m
+	}
+}
+
+// WithSelectionStrategy sets how new channels pick a worker among the
+// candidates; the default is SelectRoundRobin
+func WithSelectionStrategy(strategy SelectionStrategy) RouterOption {
+	return func(r *Router) {
+		r.strategy = strategy
+	}
+}
+
+// WithChannelCountReconcile recounts the channels of each worker from the
+// channel routes every interval with ReconcileChannelCounts, dropping
+// routes that expired through WithRouteTTL from the counts
+func WithChannelCountReconcile(interval time.Duration) RouterOption {
+	return func(r *Router) {
+		r.reconcileEvery = interval
+	}
+}
+
+// NewRouter creates a new Router. It starts a goroutine evicting idle
+// channel rate counters, with a positive cacheTTL one evicting expired
+// cache entries every cacheTTL, and with WithChannelCountReconcile one
+// reconciling channel counts, until Close.
+func NewRouter(redisClient *redis.Client, cacheTTL time.Duration, opts ...RouterOption) *Router {
+	r := &Router{
 		redis:    redisClient,
 		cacheTTL: cacheTTL,
+		strategy: SelectRoundRobin,
+		metrics:  metrics.Default,
+		done:     make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	r.wg.Add(1)
+	go r.rateCleanup(rateCleanupInterval)
+	if cacheTTL > 0 {
+		r.wg.Add(1)
+		go r.cacheCleanup(cacheTTL)
 	}
+	if r.reconcileEvery > 0 {
+		r.wg.Add(1)
+		go r.channelCountReconcile(r.reconcileEvery)
+	}
+	return r
+}
+
+// Close stops the background goroutines of the router and waits for them
+// to exit. It returns ErrRouterClosed if the router is already closed.
+func (r *Router) Close() error {
+	err := ErrRouterClosed
+	r.closeOnce.Do(func() {
+		close(r.done)
+		err = nil
+	})
+	r.wg.Wait()
+	return err
+}
+
+// Done returns a channel closed when the router is closed
+func (r *Router) Done() <-chan struct{} {
+	return r.done
 }
 
 // GetWorkerForChannel returns the worker ID for a channel
 // Uses local cache, falls back to Redis, assigns new worker if needed
 func (r *Router) GetWorkerForChannel(ctx context.Context, channel string) (string, error) {
+	channel = r.NormalizeChannel(channel)
+
 	// 1. Check local cache
 	if entry, ok := r.cache.Load(channel); ok {
 		ce := entry.(*cacheEntry)
 		if time.Now().Before(ce.expiresAt) {
-			metrics.RouteCacheHits.Inc()
+			r.cacheHits.Add(1)
+			r.metrics.RouteCacheHits.Inc()
 			return ce.workerID, nil
 		}
 		r.cache.Delete(channel)
 	}
-	metrics.RouteCacheMisses.Inc()
+	r.cacheMisses.Add(1)
+	r.metrics.RouteCacheMisses.Inc()
 
 	// 2. Check Redis for existing mapping
 	routeKey := ChannelRoutePrefix + channel
@@ -71,13 +227,12 @@ func (r *Router) GetWorkerForChannel(ctx context.Context, channel string) (strin
 			return workerID, nil
 		}
 
-		// Worker offline, delete stale mapping
-		slog.Info("worker offline, reassigning channel", "worker", workerID, "channel", channel)
-		r.redis.Del(ctx, routeKey)
+		// Worker offline: the assign script replaces the stale mapping
+		slog.InfoContext(ctx, "worker offline, reassigning channel", "worker", workerID, "channel", channel)
 	}
 
 	// 3. Assign new worker
-	newWorkerID, err := r.assignWorkerToChannel(ctx, channel)
+	newWorkerID, err := r.assignWorkerToChannel(ctx, channel, "")
 	if err != nil {
 		return "", err
 	}
@@ -86,52 +241,421 @@ func (r *Router) GetWorkerForChannel(ctx context.Context, channel string) (strin
 	return newWorkerID, nil
 }
 
-// assignWorkerToChannel assigns a worker using round-robin with SetNX to avoid race conditions
-func (r *Router) assignWorkerToChannel(ctx context.Context, channel string) (string, error) {
-	workers, err := r.redis.ZRange(ctx, ActiveWorkersKey, 0, -1)
+// adjustCountLua defines adjustCount, which adds delta to the channel
+// count of worker in the sorted set key and removes the worker once it has
+// no channels, so the set never holds zero or negative counts
+const adjustCountLua = `
+local function adjustCount(key, worker, delta)
+	local count = tonumber(redis.call('ZINCRBY', key, delta, worker))
+	if count <= 0 then
+		redis.call('ZREM', key, worker)
+		return 0
+	end
+	return count
+end
+`
+
+// assignWorkerScript atomically assigns a channel to a worker.
+//
+//	KEYS[1]      channel route key
+//	KEYS[2]      WorkerChannelCountKey, omitted on Redis Cluster
+//	ARGV[1]      round-robin index
+//	ARGV[2]      number of active workers N
+//	ARGV[3]      route expiration in milliseconds, 0 for none
+//	ARGV[4..N+3] active workers
+//	ARGV[N+4..]  candidate workers, filtered for degradation and region
+//
+// An existing route to an active worker wins; otherwise the candidate at
+// the round-robin index is stored, and the channel counts of the stored
+// and replaced workers are adjusted. Returns {worker, 1 if the route was
+// stored, the replaced worker or ”}.
+const assignWorkerScript = adjustCountLua + `
+local n = tonumber(ARGV[2])
+local ttl = tonumber(ARGV[3])
+local current = redis.call('GET', KEYS[1])
+if current then
+	for i = 4, n + 3 do
+		if ARGV[i] == current then
+			return {current, 0, ''}
+		end
+	end
+end
+
+local candidates = #ARGV - n - 3
+local worker = ARGV[n + 4 + (tonumber(ARGV[1]) % candidates)]
+if ttl > 0 then
+	redis.call('SET', KEYS[1], worker, 'PX', ttl)
+else
+	redis.call('SET', KEYS[1], worker)
+end
+if KEYS[2] then
+	adjustCount(KEYS[2], worker, 1)
+	if current then
+		adjustCount(KEYS[2], current, -1)
+	end
+end
+return {worker, 1, current or ''}
+`
+
+// deleteRouteScript deletes the channel route KEYS[1] and decrements the
+// channel count of its worker in KEYS[2], if given. Returns the worker.
+const deleteRouteScript = adjustCountLua + `
+local worker = redis.call('GETDEL', KEYS[1])
+if worker and KEYS[2] then
+	adjustCount(KEYS[2], worker, -1)
+end
+return worker
+`
+
+// restoreRouteScript stores worker ARGV[1] as the channel route KEYS[1],
+// expiring in ARGV[2] milliseconds unless 0, if the channel has no route,
+// and increments the worker's channel count in KEYS[2], if given. Returns
+// 1 if the route was stored.
+const restoreRouteScript = adjustCountLua + `
+local stored
+if tonumber(ARGV[2]) > 0 then
+	stored = redis.call('SET', KEYS[1], ARGV[1], 'NX', 'PX', ARGV[2])
+else
+	stored = redis.call('SET', KEYS[1], ARGV[1], 'NX')
+end
+if not stored then
+	return 0
+end
+if KEYS[2] then
+	adjustCount(KEYS[2], ARGV[1], 1)
+end
+return 1
+`
+
+// adjustChannelCountScript adds ARGV[2] to the channel count of worker
+// ARGV[1] in KEYS[1]
+const adjustChannelCountScript = adjustCountLua + `
+return adjustCount(KEYS[1], ARGV[1], ARGV[2])
+`
+
+// replaceChannelCountsScript replaces the channel counts in KEYS[1] with
+// the worker and count pairs in ARGV
+const replaceChannelCountsScript = `
+redis.call('DEL', KEYS[1])
+for i = 1, #ARGV, 2 do
+	redis.call('ZADD', KEYS[1], ARGV[i + 1], ARGV[i])
+end
+return #ARGV / 2
+`
+
+// assignWorkerToChannel picks candidate workers for channel and assigns one
+// atomically with assignWorkerScript. A non-empty exclude is treated as
+// inactive, so a route to it is replaced.
+func (r *Router) assignWorkerToChannel(ctx context.Context, channel, exclude string) (string, error) {
+	active, err := r.redis.ZRange(ctx, ActiveWorkersKey, 0, -1)
 	if err != nil {
 		return "", err
 	}
+	if exclude != "" {
+		active = slices.DeleteFunc(active, func(workerID string) bool { return workerID == exclude })
+	}
 
-	if len(workers) == 0 {
+	if len(active) == 0 {
 		return "", ErrNoActiveWorkers
 	}
+	workers := active
 
-	routeKey := ChannelRoutePrefix + channel
+	// Skip workers with a backlogged stream, unless all of them are
+	if healthy, err := r.filterDegradedWorkers(ctx, workers); err != nil {
+		slog.WarnContext(ctx, "failed to check degraded workers", "channel", channel, "error", err)
+	} else if len(healthy) > 0 {
+		workers = healthy
+	} else {
+		slog.WarnContext(ctx, "all active workers degraded, using all workers", "channel", channel)
+	}
+
+	// Prefer workers in the channel's region, fall back to all workers
+	if r.regionAffinity != nil {
+		if region := r.regionAffinity(channel); region != "" {
+			if regional := filterWorkersByRegion(workers, region); len(regional) > 0 {
+				workers = regional
+			} else {
+				slog.WarnContext(ctx, "no active workers in preferred region, using all workers", "channel", channel, "region", region)
+			}
+		}
+	}
+
+	if r.strategy == SelectLeastChannels {
+		if least, err := r.leastChannelWorkers(ctx, workers); err != nil {
+			slog.WarnContext(ctx, "failed to read worker channel counts, using round-robin", "channel", channel, "error", err)
+		} else {
+			workers = least
+		}
+	}
+
+	// The shared index spreads assignments from all gateways evenly
+	idx, err := r.redis.Incr(ctx, RoundRobinIndexKey)
+	if err != nil {
+		return "", err
+	}
 
-	// Try to atomically set the worker assignment
-	// This prevents race conditions where multiple requests try to assign different workers
-	for i := 0; i < len(workers); i++ {
-		// Round-robin selection
-		idx := atomic.AddUint64(&r.rrIndex, 1)
-		selectedWorker := workers[int(idx)%len(workers)]
+	args := make([]interface{}, 0, 3+len(active)+len(workers))
+	args = append(args, idx, len(active), r.routeTTL.Milliseconds())
+	for _, workerID := range active {
+		args = append(args, workerID)
+	}
+	for _, workerID := range workers {
+		args = append(args, workerID)
+	}
+	keys := r.routeScriptKeys(channel)
+
+	result, err := r.evalAssignScript(ctx, keys, args)
+	if err != nil {
+		return "", err
+	}
+
+	values, ok := result.([]interface{})
+	if !ok || len(values) != 3 {
+		return "", fmt.Errorf("unexpected assign script result %v", result)
+	}
+	workerID, _ := values[0].(string)
+	assigned, _ := values[1].(int64)
+	previous, _ := values[2].(string)
+	if assigned == 0 {
+		return workerID, nil
+	}
+
+	if len(keys) == 1 {
+		r.adjustChannelCount(ctx, workerID, 1)
+		if previous != "" {
+			r.adjustChannelCount(ctx, previous, -1)
+		}
+	}
+	slog.InfoContext(ctx, "assigned channel to worker", "channel", channel, "worker", workerID, "previous", previous)
+	return workerID, nil
+}
+
+// leastChannelWorkers returns the workers with the fewest channels routed
+// to them according to WorkerChannelCountKey
+func (r *Router) leastChannelWorkers(ctx context.Context, workers []string) ([]string, error) {
+	counts, err := r.redis.ZMScore(ctx, WorkerChannelCountKey, workers...)
+	if err != nil {
+		return nil, err
+	}
+
+	least := slices.Min(counts)
+	var selected []string
+	for i, workerID := range workers {
+		if counts[i] == least {
+			selected = append(selected, workerID)
+		}
+	}
+	return selected, nil
+}
 
-		// Try to set atomically - only succeeds if key doesn't exist
-		wasSet, err := r.redis.SetNX(ctx, routeKey, selectedWorker, 0)
+// routeScriptKeys returns the keys of a script writing the route of channel:
+// the route key and WorkerChannelCountKey, so the script updates the count
+// atomically with the route. On Redis Cluster the two keys are in different
+// hash slots, so only the route key is returned and the caller adjusts the
+// count with adjustChannelCount; ReconcileChannelCounts repairs counts the
+// separate update misses.
+func (r *Router) routeScriptKeys(channel string) []string {
+	if r.redis.IsCluster() {
+		return []string{ChannelRoutePrefix + channel}
+	}
+	return []string{ChannelRoutePrefix + channel, WorkerChannelCountKey}
+}
+
+// adjustChannelCount adds delta to the channel count of workerID on Redis
+// Cluster, after the route script. Failures are logged rather than
+// returned since the route itself is in place.
+func (r *Router) adjustChannelCount(ctx context.Context, workerID string, delta int) {
+	if _, err := r.redis.Eval(ctx, adjustChannelCountScript, []string{WorkerChannelCountKey}, workerID, delta); err != nil {
+		slog.WarnContext(ctx, "failed to update worker channel count", "worker", workerID, "delta", delta, "error", err)
+	}
+}
+
+// DeleteChannelRoute removes the route of channel so its next publish or
+// subscribe assigns a worker again, and decrements the channel count of
+// the worker it was routed to. Reports whether the channel had a route.
+// Routes that expire through WithRouteTTL are subtracted by the next
+// ReconcileChannelCounts.
+func (r *Router) DeleteChannelRoute(ctx context.Context, channel string) (bool, error) {
+	channel = r.NormalizeChannel(channel)
+	keys := r.routeScriptKeys(channel)
+	result, err := r.redis.Eval(ctx, deleteRouteScript, keys)
+	r.InvalidateCache(channel)
+	if redis.IsNil(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	workerID, _ := result.(string)
+	if len(keys) == 1 {
+		r.adjustChannelCount(ctx, workerID, -1)
+	}
+	slog.InfoContext(ctx, "channel route deleted", "channel", channel, "worker", workerID)
+	return true, nil
+}
+
+// RestoreChannelRoute routes channel to workerID unless the channel already
+// has a route, and increments the worker's channel count. Reports whether
+// the route was stored; returns ErrWorkerNotActive if workerID is not in
+// workers:active.
+func (r *Router) RestoreChannelRoute(ctx context.Context, channel, workerID string) (bool, error) {
+	channel = r.NormalizeChannel(channel)
+	if _, err := r.redis.ZScore(ctx, ActiveWorkersKey, workerID); err != nil {
+		if redis.IsNil(err) {
+			return false, fmt.Errorf("%w: %s", ErrWorkerNotActive, workerID)
+		}
+		return false, err
+	}
+
+	keys := r.routeScriptKeys(channel)
+	result, err := r.redis.Eval(ctx, restoreRouteScript, keys, workerID, r.routeTTL.Milliseconds())
+	if err != nil {
+		return false, err
+	}
+	if stored, _ := result.(int64); stored == 0 {
+		return false, nil
+	}
+
+	if len(keys) == 1 {
+		r.adjustChannelCount(ctx, workerID, 1)
+	}
+	r.updateCache(channel, workerID)
+	slog.InfoContext(ctx, "channel route restored", "channel", channel, "worker", workerID)
+	return true, nil
+}
+
+// WorkerChannelCounts returns the number of channels routed to each worker
+// with at least one channel
+func (r *Router) WorkerChannelCounts(ctx context.Context) (map[string]int64, error) {
+	entries, err := r.redis.ZRangeWithScores(ctx, WorkerChannelCountKey, 0, -1)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int64, len(entries))
+	for _, entry := range entries {
+		workerID, _ := entry.Member.(string)
+		counts[workerID] = int64(entry.Score)
+	}
+	return counts, nil
+}
+
+// ReconcileChannelCounts replaces WorkerChannelCountKey with the number of
+// channel routes to each worker and returns the counts. Assignments made
+// while the routes are scanned may be missed until the next run.
+func (r *Router) ReconcileChannelCounts(ctx context.Context) (map[string]int64, error) {
+	counts := make(map[string]int64)
+	var mu sync.Mutex
+	err := r.redis.Scan(ctx, ChannelRoutePrefix+"*", migrationBatchSize, func(keys []string) error {
+		routes, err := r.redis.GetMany(ctx, keys)
 		if err != nil {
-			return "", err
+			return err
 		}
 
-		if wasSet {
-			// We successfully assigned this worker
-			slog.Info("assigned channel to worker", "channel", channel, "worker", selectedWorker)
-			return selectedWorker, nil
+		mu.Lock()
+		defer mu.Unlock()
+		for _, workerID := range routes {
+			if workerID != "" {
+				counts[workerID]++
+			}
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	args := make([]interface{}, 0, 2*len(counts))
+	for workerID, count := range counts {
+		args = append(args, workerID, count)
+	}
+	if _, err := r.redis.Eval(ctx, replaceChannelCountsScript, []string{WorkerChannelCountKey}, args...); err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+// channelCountReconcile runs ReconcileChannelCounts every interval until
+// Close
+func (r *Router) channelCountReconcile(interval time.Duration) {
+	defer r.wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-r.done
+		cancel()
+	}()
 
-		// Another request already assigned a worker, get it
-		existingWorker, err := r.redis.Get(ctx, routeKey)
-		if err == nil && existingWorker != "" {
-			// Verify the assigned worker is still active
-			_, err := r.redis.ZScore(ctx, ActiveWorkersKey, existingWorker)
-			if err == nil {
-				return existingWorker, nil
+	for {
+		select {
+		case <-r.done:
+			return
+		case <-ticker.C:
+			if _, err := r.ReconcileChannelCounts(ctx); err != nil && ctx.Err() == nil {
+				slog.Warn("failed to reconcile worker channel counts", "error", err)
 			}
-			// Worker is offline, delete and retry
-			r.redis.Del(ctx, routeKey)
 		}
 	}
+}
 
-	return "", ErrNoActiveWorkers
+// evalAssignScript runs assignWorkerScript by SHA, loading it on first use
+// and again if Redis lost its script cache
+func (r *Router) evalAssignScript(ctx context.Context, keys []string, args []interface{}) (interface{}, error) {
+	sha, err := r.assignScript(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := r.redis.EvalSha(ctx, sha, keys, args...)
+	if redis.IsNoScript(err) {
+		if sha, err = r.assignScript(ctx, true); err != nil {
+			return nil, err
+		}
+		result, err = r.redis.EvalSha(ctx, sha, keys, args...)
+	}
+	return result, err
+}
+
+// assignScript returns the cached SHA of assignWorkerScript, loading the
+// script when not cached or when reload is set
+func (r *Router) assignScript(ctx context.Context, reload bool) (string, error) {
+	r.assignScriptMu.Lock()
+	defer r.assignScriptMu.Unlock()
+
+	if r.assignScriptSHA != "" && !reload {
+		return r.assignScriptSHA, nil
+	}
+	sha, err := r.redis.ScriptLoad(ctx, assignWorkerScript)
+	if err != nil {
+		return "", err
+	}
+	r.assignScriptSHA = sha
+	return sha, nil
+}
+
+// WorkerRegion returns the region of a worker ID in workerID:region form,
+// or an empty string if the worker has no region
+func WorkerRegion(workerID string) string {
+	idx := strings.LastIndex(workerID, ":")
+	if idx < 0 {
+		return ""
+	}
+	return workerID[idx+1:]
+}
+
+// filterWorkersByRegion returns the workers located in region
+func filterWorkersByRegion(workers []string, region string) []string {
+	var filtered []string
+	for _, workerID := range workers {
+		if WorkerRegion(workerID) == region {
+			filtered = append(filtered, workerID)
+		}
+	}
+	return filtered
 }
 
 // updateCache updates the local cache
@@ -142,14 +666,72 @@ func (r *Router) updateCache(channel, workerID string) {
 	})
 }
 
-// GetWorkerStreamKey returns the Redis stream key for a worker
-func GetWorkerStreamKey(workerID string) string {
-	return WorkerStreamPrefix + workerID
+// cacheCleanup evicts expired cache entries every interval, so channels
+// that are never looked up again do not stay cached, until Close
+func (r *Router) cacheCleanup(interval time.Duration) {
+	defer r.wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.done:
+			return
+		case now := <-ticker.C:
+			r.evictExpired(now)
+		}
+	}
+}
+
+// evictExpired removes the cache entries expired at now, leaving entries
+// updateCache replaced meanwhile
+func (r *Router) evictExpired(now time.Time) {
+	r.cache.Range(func(key, value interface{}) bool {
+		if !now.Before(value.(*cacheEntry).expiresAt) {
+			r.cache.CompareAndDelete(key, value)
+		}
+		return true
+	})
+}
+
+// GetWorkerStreamKey returns the Redis stream key for messages of priority
+// routed to a worker
+func GetWorkerStreamKey(workerID string, priority int) string {
+	if priority >= PriorityHigh {
+		return WorkerStreamPrefix + workerID + highStreamSuffix
+	}
+	return WorkerStreamPrefix + workerID + normalStreamSuffix
+}
+
+// GetWorkerStreamKeys returns all stream keys of a worker, highest priority
+// first
+func GetWorkerStreamKeys(workerID string) []string {
+	return []string{
+		GetWorkerStreamKey(workerID, PriorityHigh),
+		GetWorkerStreamKey(workerID, PriorityNormal),
+	}
+}
+
+// GetGatewayStreamKey returns the Redis stream key workers use to push
+// outbound messages to a gateway instance
+func GetGatewayStreamKey(instanceID string) string {
+	return GatewayStreamPrefix + instanceID
+}
+
+// RouteExpire returns the expiration resetting the route key of channel to
+// the route TTL, for a publish to write with its stream entry. It is nil
+// without WithRouteTTL; applied to a channel without a route it does
+// nothing.
+func (r *Router) RouteExpire(channel string) *redis.KeyExpire {
+	if r.routeTTL <= 0 {
+		return nil
+	}
+	return &redis.KeyExpire{Key: ChannelRoutePrefix + r.NormalizeChannel(channel), TTL: r.routeTTL}
 }
 
 // InvalidateCache removes a channel from the local cache
 func (r *Router) InvalidateCache(channel string) {
-	r.cache.Delete(channel)
+	r.cache.Delete(r.NormalizeChannel(channel))
 }
 
 // ClearCache clears all cached routes
@@ -159,3 +741,13 @@ func (r *Router) ClearCache() {
 		return true
 	})
 }
+
+// CacheHitRatio returns the fraction of GetWorkerForChannel calls answered
+// from the local cache since the router was created, or 0 before the first call
+func (r *Router) CacheHitRatio() float64 {
+	hits, misses := r.cacheHits.Load(), r.cacheMisses.Load()
+	if hits+misses == 0 {
+		return 0
+	}
+	return float64(hits) / float64(hits+misses)
+}
@@ -0,0 +1,582 @@
+package routing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"reflect"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	goredis "github.com/redis/go-redis/v9"
+
+	"realtime-message-gateway/internal/metrics"
+	"realtime-message-gateway/internal/redis"
+)
+
+// newTestRouter creates a Router that is closed when the test ends
+func newTestRouter(t testing.TB, client *redis.Client, cacheTTL time.Duration, opts ...RouterOption) *Router {
+	t.Helper()
+
+	r := NewRouter(client, cacheTTL, opts...)
+	t.Cleanup(func() { r.Close() })
+	return r
+}
+
+func TestWorkerRegion(t *testing.T) {
+	tests := []struct {
+		workerID string
+		want     string
+	}{
+		{"worker-0:us-east", "us-east"},
+		{"worker-0", ""},
+		{"pool:worker-0:eu-west", "eu-west"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.workerID, func(t *testing.T) {
+			if got := WorkerRegion(tt.workerID); got != tt.want {
+				t.Errorf("WorkerRegion(%q) = %q, want %q", tt.workerID, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetWorkerStreamKey(t *testing.T) {
+	tests := []struct {
+		priority int
+		want     string
+	}{
+		{PriorityLow, "messages:worker:worker-0:normal"},
+		{PriorityNormal, "messages:worker:worker-0:normal"},
+		{PriorityHigh, "messages:worker:worker-0:high"},
+	}
+
+	for _, tt := range tests {
+		if got := GetWorkerStreamKey("worker-0", tt.priority); got != tt.want {
+			t.Errorf("GetWorkerStreamKey(%d) = %q, want %q", tt.priority, got, tt.want)
+		}
+	}
+
+	want := []string{"messages:worker:worker-0:high", "messages:worker:worker-0:normal"}
+	if got := GetWorkerStreamKeys("worker-0"); !reflect.DeepEqual(got, want) {
+		t.Errorf("GetWorkerStreamKeys() = %v, want %v", got, want)
+	}
+}
+
+func TestFilterWorkersByRegion(t *testing.T) {
+	workers := []string{"worker-0:us-east", "worker-1:eu-west", "worker-2:us-east", "worker-3"}
+
+	tests := []struct {
+		name   string
+		region string
+		want   []string
+	}{
+		{"matching region", "us-east", []string{"worker-0:us-east", "worker-2:us-east"}},
+		{"single match", "eu-west", []string{"worker-1:eu-west"}},
+		{"no workers in region", "ap-south", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := filterWorkersByRegion(workers, tt.region)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("filterWorkersByRegion(%q) = %v, want %v", tt.region, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithRegionAffinity(t *testing.T) {
+	r := newTestRouter(t, nil, 0, WithRegionAffinity(func(channel string) string {
+		if channel == "chat:eu" {
+			return "eu-west"
+		}
+		return ""
+	}))
+
+	if r.regionAffinity == nil {
+		t.Fatal("WithRegionAffinity() did not set affinity func")
+	}
+	if got := r.regionAffinity("chat:eu"); got != "eu-west" {
+		t.Errorf("regionAffinity(%q) = %q, want %q", "chat:eu", got, "eu-west")
+	}
+}
+
+func TestGetWorkerForChannel(t *testing.T) {
+	mr, client := newTestRedis(t)
+	workers := []string{"worker-0", "worker-1", "worker-2"}
+	for i, workerID := range workers {
+		mr.ZAdd(ActiveWorkersKey, float64(i), workerID)
+	}
+	router := newTestRouter(t, client, time.Minute)
+	ctx := context.Background()
+
+	// Every channel is assigned one active worker, stored in Redis
+	assigned := make(map[string]string)
+	counts := make(map[string]int)
+	for i := 0; i < 100; i++ {
+		channel := fmt.Sprintf("chat:room-%d", i)
+		workerID, err := router.GetWorkerForChannel(ctx, channel)
+		if err != nil {
+			t.Fatalf("GetWorkerForChannel(%q) error = %v", channel, err)
+		}
+		if !slices.Contains(workers, workerID) {
+			t.Fatalf("GetWorkerForChannel(%q) = %q, not an active worker", channel, workerID)
+		}
+		if route, _ := mr.Get(ChannelRoutePrefix + channel); route != workerID {
+			t.Errorf("route of %q = %q, want %q", channel, route, workerID)
+		}
+		assigned[channel] = workerID
+		counts[workerID]++
+	}
+	for _, workerID := range workers {
+		if counts[workerID] == 0 {
+			t.Errorf("no channels assigned to %s: %v", workerID, counts)
+		}
+	}
+
+	// Repeated lookups are served from the cache, even without the route key
+	hits := testutil.ToFloat64(metrics.Default.RouteCacheHits)
+	mr.Del(ChannelRoutePrefix + "chat:room-0")
+	for channel, want := range assigned {
+		if got, err := router.GetWorkerForChannel(ctx, channel); err != nil || got != want {
+			t.Errorf("cached GetWorkerForChannel(%q) = %q, %v, want %q", channel, got, err, want)
+		}
+	}
+	if got := testutil.ToFloat64(metrics.Default.RouteCacheHits) - hits; got != float64(len(assigned)) {
+		t.Errorf("cache hits increased by %v, want %d", got, len(assigned))
+	}
+	// 100 misses on assignment, then 100 hits
+	if got := router.CacheHitRatio(); got != 0.5 {
+		t.Errorf("CacheHitRatio() = %v, want 0.5", got)
+	}
+
+	// Channels of a removed worker move to the remaining workers once the
+	// cache no longer holds them; other channels keep their worker
+	mr.ZRem(ActiveWorkersKey, "worker-1")
+	router.ClearCache()
+	for channel, previous := range assigned {
+		got, err := router.GetWorkerForChannel(ctx, channel)
+		if err != nil {
+			t.Fatalf("GetWorkerForChannel(%q) after removal error = %v", channel, err)
+		}
+		switch {
+		case got == "worker-1":
+			t.Errorf("GetWorkerForChannel(%q) = removed worker-1", channel)
+		case previous != "worker-1" && channel != "chat:room-0" && got != previous:
+			t.Errorf("GetWorkerForChannel(%q) moved from %q to %q", channel, previous, got)
+		}
+	}
+}
+
+func TestAssignWorkerConcurrent(t *testing.T) {
+	mr, client := newTestRedis(t)
+	for i := 0; i < 3; i++ {
+		mr.ZAdd(ActiveWorkersKey, float64(i), fmt.Sprintf("worker-%d", i))
+	}
+
+	// Each goroutine acts as a separate gateway with its own Router
+	const goroutines = 50
+	results := make([]string, goroutines)
+	errs := make([]error, goroutines)
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			router := newTestRouter(t, client, time.Minute)
+			<-start
+			results[i], errs[i] = router.GetWorkerForChannel(context.Background(), "chat")
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	route, err := mr.Get(ChannelRoutePrefix + "chat")
+	if err != nil {
+		t.Fatalf("route key not set: %v", err)
+	}
+	for i := 0; i < goroutines; i++ {
+		if errs[i] != nil {
+			t.Fatalf("GetWorkerForChannel() error = %v", errs[i])
+		}
+		if results[i] != route {
+			t.Errorf("goroutine %d got %q, want stored route %q", i, results[i], route)
+		}
+	}
+}
+
+func TestAssignWorkerRoundRobin(t *testing.T) {
+	mr, client := newTestRedis(t)
+	mr.ZAdd(ActiveWorkersKey, 1, "worker-0")
+	mr.ZAdd(ActiveWorkersKey, 2, "worker-1")
+
+	// Separate routers share the round-robin index in Redis
+	counts := make(map[string]int)
+	for i := 0; i < 4; i++ {
+		workerID, err := newTestRouter(t, client, time.Minute).GetWorkerForChannel(context.Background(), fmt.Sprintf("chat:%d", i))
+		if err != nil {
+			t.Fatalf("GetWorkerForChannel() error = %v", err)
+		}
+		counts[workerID]++
+	}
+	if counts["worker-0"] != 2 || counts["worker-1"] != 2 {
+		t.Errorf("assignments = %v, want 2 per worker", counts)
+	}
+}
+
+func TestAssignWorkerReplacesOfflineRoute(t *testing.T) {
+	mr, client := newTestRedis(t)
+	mr.ZAdd(ActiveWorkersKey, 1, "worker-1")
+	mr.Set(ChannelRoutePrefix+"chat", "worker-0")
+
+	workerID, err := newTestRouter(t, client, time.Minute).assignWorkerToChannel(context.Background(), "chat", "")
+	if err != nil {
+		t.Fatalf("assignWorkerToChannel() error = %v", err)
+	}
+	if workerID != "worker-1" {
+		t.Errorf("assignWorkerToChannel() = %q, want worker-1", workerID)
+	}
+	if route, _ := mr.Get(ChannelRoutePrefix + "chat"); route != "worker-1" {
+		t.Errorf("route = %q, want worker-1", route)
+	}
+}
+
+func TestWorkerChannelCountConcurrent(t *testing.T) {
+	mr, client := newTestRedis(t)
+	for i := 0; i < 3; i++ {
+		mr.ZAdd(ActiveWorkersKey, float64(i), fmt.Sprintf("worker-%d", i))
+	}
+	ctx := context.Background()
+
+	// Two gateways race for each channel, then one deletes every other route
+	const channels = 60
+	var wg sync.WaitGroup
+	for i := 0; i < channels; i++ {
+		for j := 0; j < 2; j++ {
+			wg.Add(1)
+			go func(channel string) {
+				defer wg.Done()
+				if _, err := newTestRouter(t, client, time.Minute).GetWorkerForChannel(ctx, channel); err != nil {
+					t.Errorf("GetWorkerForChannel() error = %v", err)
+				}
+			}(fmt.Sprintf("chat:%d", i))
+		}
+	}
+	wg.Wait()
+	for i := 0; i < channels; i += 2 {
+		wg.Add(1)
+		go func(channel string) {
+			defer wg.Done()
+			if _, err := newTestRouter(t, client, time.Minute).DeleteChannelRoute(ctx, channel); err != nil {
+				t.Errorf("DeleteChannelRoute() error = %v", err)
+			}
+		}(fmt.Sprintf("chat:%d", i))
+	}
+	wg.Wait()
+
+	want := make(map[string]int64)
+	for i := 1; i < channels; i += 2 {
+		workerID, err := mr.Get(fmt.Sprintf("%schat:%d", ChannelRoutePrefix, i))
+		if err != nil {
+			t.Fatalf("route of chat:%d missing: %v", i, err)
+		}
+		want[workerID]++
+	}
+	got, err := newTestRouter(t, client, time.Minute).WorkerChannelCounts(ctx)
+	if err != nil {
+		t.Fatalf("WorkerChannelCounts() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("WorkerChannelCounts() = %v, want %v", got, want)
+	}
+
+	// Deleting a missing route leaves the counts alone
+	if deleted, err := newTestRouter(t, client, time.Minute).DeleteChannelRoute(ctx, "chat:0"); err != nil || deleted {
+		t.Errorf("DeleteChannelRoute() of missing route = %v, %v, want false, nil", deleted, err)
+	}
+}
+
+func TestReconcileChannelCounts(t *testing.T) {
+	mr, client := newTestRedis(t)
+	mr.ZAdd(ActiveWorkersKey, 1, "worker-0")
+	ctx := context.Background()
+
+	router := newTestRouter(t, client, time.Minute, WithRouteTTL(time.Hour))
+	for i := 0; i < 3; i++ {
+		if _, err := router.GetWorkerForChannel(ctx, fmt.Sprintf("chat:%d", i)); err != nil {
+			t.Fatalf("GetWorkerForChannel() error = %v", err)
+		}
+	}
+	mr.Set(ChannelRoutePrefix+"chat:pinned", "worker-1")
+	mr.ZAdd(WorkerChannelCountKey, 7, "worker-2")
+
+	// Expired routes are not subtracted until the counts are reconciled
+	mr.FastForward(time.Hour)
+	want := map[string]int64{"worker-1": 1}
+	if counts, err := router.ReconcileChannelCounts(ctx); err != nil || !reflect.DeepEqual(counts, want) {
+		t.Errorf("ReconcileChannelCounts() = %v, %v, want %v", counts, err, want)
+	}
+	if counts, err := router.WorkerChannelCounts(ctx); err != nil || !reflect.DeepEqual(counts, want) {
+		t.Errorf("WorkerChannelCounts() = %v, %v, want %v", counts, err, want)
+	}
+}
+
+func TestAssignWorkerLeastChannels(t *testing.T) {
+	mr, client := newTestRedis(t)
+	ctx := context.Background()
+	mr.ZAdd(ActiveWorkersKey, 1, "worker-0")
+	mr.ZAdd(ActiveWorkersKey, 2, "worker-1")
+	mr.ZAdd(ActiveWorkersKey, 3, "worker-2")
+	mr.ZAdd(WorkerChannelCountKey, 5, "worker-0")
+	mr.ZAdd(WorkerChannelCountKey, 3, "worker-1")
+	mr.ZAdd(WorkerChannelCountKey, 4, "worker-2")
+
+	router := newTestRouter(t, client, time.Minute, WithSelectionStrategy(SelectLeastChannels))
+	var assigned []string
+	for i := 0; i < 3; i++ {
+		workerID, err := router.GetWorkerForChannel(ctx, fmt.Sprintf("chat:%d", i))
+		if err != nil {
+			t.Fatalf("GetWorkerForChannel() error = %v", err)
+		}
+		assigned = append(assigned, workerID)
+	}
+	if assigned[0] != "worker-1" || slices.Contains(assigned, "worker-0") {
+		t.Errorf("assignments = %v, want worker-1 first and never worker-0", assigned)
+	}
+	want := map[string]int64{"worker-0": 5, "worker-1": 5, "worker-2": 5}
+	if counts, err := router.WorkerChannelCounts(ctx); err != nil || !reflect.DeepEqual(counts, want) {
+		t.Errorf("WorkerChannelCounts() = %v, %v, want %v", counts, err, want)
+	}
+
+	// A replaced route moves the channel to the new worker's count
+	mr.ZRem(ActiveWorkersKey, "worker-1")
+	router.ClearCache()
+	if _, err := router.GetWorkerForChannel(ctx, "chat:0"); err != nil {
+		t.Fatalf("GetWorkerForChannel() error = %v", err)
+	}
+	counts, err := router.WorkerChannelCounts(ctx)
+	if err != nil {
+		t.Fatalf("WorkerChannelCounts() error = %v", err)
+	}
+	if counts["worker-1"] != 4 || counts["worker-0"]+counts["worker-2"] != 11 {
+		t.Errorf("WorkerChannelCounts() after reassignment = %v, want worker-1 down to 4", counts)
+	}
+}
+
+func TestChannelRouteTTL(t *testing.T) {
+	mr, client := newTestRedis(t)
+	mr.ZAdd(ActiveWorkersKey, 1, "worker-0")
+	ctx := context.Background()
+
+	router := newTestRouter(t, client, time.Minute, WithRouteTTL(time.Hour))
+	if _, err := router.GetWorkerForChannel(ctx, "chat"); err != nil {
+		t.Fatalf("GetWorkerForChannel() error = %v", err)
+	}
+	if ttl := mr.TTL(ChannelRoutePrefix + "chat"); ttl != time.Hour {
+		t.Fatalf("route TTL after assignment = %s, want 1h", ttl)
+	}
+
+	mr.FastForward(45 * time.Minute)
+	entry := redis.StreamEntry{Stream: GetWorkerStreamKey("worker-0", PriorityNormal), Values: map[string]interface{}{"data": "{}"}, Expire: router.RouteExpire("chat")}
+	if _, err := client.RetryXAddEntry(ctx, entry, 1); err != nil {
+		t.Fatalf("RetryXAddEntry() error = %v", err)
+	}
+	mr.FastForward(45 * time.Minute)
+	if !mr.Exists(ChannelRoutePrefix + "chat") {
+		t.Fatal("route expired although it was refreshed")
+	}
+	mr.FastForward(15 * time.Minute)
+	if mr.Exists(ChannelRoutePrefix + "chat") {
+		t.Error("route still exists an hour after the last refresh")
+	}
+
+	// Without a route TTL routes never expire
+	router = newTestRouter(t, client, time.Minute)
+	if _, err := router.GetWorkerForChannel(ctx, "chat:other"); err != nil {
+		t.Fatalf("GetWorkerForChannel() error = %v", err)
+	}
+	if expire := router.RouteExpire("chat:other"); expire != nil {
+		t.Errorf("RouteExpire() without WithRouteTTL = %+v, want nil", expire)
+	}
+	if ttl := mr.TTL(ChannelRoutePrefix + "chat:other"); ttl != 0 {
+		t.Errorf("route TTL without WithRouteTTL = %s, want none", ttl)
+	}
+}
+
+func TestAssignWorkerNoActiveWorkers(t *testing.T) {
+	_, client := newTestRedis(t)
+
+	if _, err := newTestRouter(t, client, time.Minute).GetWorkerForChannel(context.Background(), "chat"); !errors.Is(err, ErrNoActiveWorkers) {
+		t.Errorf("GetWorkerForChannel() error = %v, want %v", err, ErrNoActiveWorkers)
+	}
+}
+
+func TestAssignWorkerReloadsFlushedScript(t *testing.T) {
+	mr, client := newTestRedis(t)
+	mr.ZAdd(ActiveWorkersKey, 1, "worker-0")
+
+	router := newTestRouter(t, client, time.Minute)
+	if _, err := router.assignWorkerToChannel(context.Background(), "chat:1", ""); err != nil {
+		t.Fatalf("assignWorkerToChannel() error = %v", err)
+	}
+
+	// Simulate a Redis restart losing the script cache
+	raw := goredis.NewClient(&goredis.Options{Addr: mr.Addr()})
+	defer raw.Close()
+	if err := raw.ScriptFlush(context.Background()).Err(); err != nil {
+		t.Fatalf("SCRIPT FLUSH error = %v", err)
+	}
+
+	if _, err := router.assignWorkerToChannel(context.Background(), "chat:2", ""); err != nil {
+		t.Errorf("assignWorkerToChannel() after SCRIPT FLUSH error = %v", err)
+	}
+}
+
+func TestRouterClose(t *testing.T) {
+	_, client := newTestRedis(t)
+	router := newTestRouter(t, client, 10*time.Millisecond)
+	router.updateCache("chat:a", "worker-0")
+
+	// The cleanup goroutine evicts the entry without any lookup
+	for deadline := time.Now().Add(time.Second); ; time.Sleep(10 * time.Millisecond) {
+		if _, ok := router.cache.Load("chat:a"); !ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expired cache entry not evicted")
+		}
+	}
+
+	// Goroutines tied to the router exit when it is closed
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		NewKeyspaceSubscriber(client, router).Run(context.Background())
+	}()
+
+	closed := make(chan error, 1)
+	go func() {
+		err := router.Close()
+		wg.Wait()
+		closed <- err
+	}()
+	select {
+	case err := <-closed:
+		if err != nil {
+			t.Errorf("Close() error = %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Close() did not stop the router goroutines")
+	}
+	if err := router.Close(); !errors.Is(err, ErrRouterClosed) {
+		t.Errorf("second Close() error = %v, want %v", err, ErrRouterClosed)
+	}
+}
+
+// newBenchRouter returns a Router backed by miniredis with workers active
+// workers and channels channels already assigned
+func newBenchRouter(b *testing.B, cacheTTL time.Duration, workers, channels int) *Router {
+	b.Helper()
+
+	// Assignment logs would dominate the timings
+	logger := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	b.Cleanup(func() { slog.SetDefault(logger) })
+
+	mr, client := newTestRedis(b)
+	for i := 0; i < workers; i++ {
+		mr.ZAdd(ActiveWorkersKey, float64(i), fmt.Sprintf("worker-%d", i))
+	}
+	router := newTestRouter(b, client, cacheTTL)
+	for i := 0; i < channels; i++ {
+		if _, err := router.GetWorkerForChannel(context.Background(), fmt.Sprintf("chat:%d", i)); err != nil {
+			b.Fatalf("GetWorkerForChannel() error = %v", err)
+		}
+	}
+	return router
+}
+
+func BenchmarkGetWorkerForChannel_CacheHit(b *testing.B) {
+	router := newBenchRouter(b, time.Hour, 3, 1)
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := router.GetWorkerForChannel(ctx, "chat:0"); err != nil {
+			b.Fatalf("GetWorkerForChannel() error = %v", err)
+		}
+	}
+}
+
+func BenchmarkGetWorkerForChannel_CacheMiss(b *testing.B) {
+	// A zero TTL expires every cache entry immediately, so each lookup reads
+	// the stored route from Redis
+	router := newBenchRouter(b, 0, 3, 1)
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := router.GetWorkerForChannel(ctx, "chat:0"); err != nil {
+			b.Fatalf("GetWorkerForChannel() error = %v", err)
+		}
+	}
+}
+
+func BenchmarkAssignWorkerConcurrent(b *testing.B) {
+	benchmarks := []struct {
+		channels   int
+		goroutines int
+	}{
+		{1, 8},
+		{1000, 1},
+		{1000, 8},
+	}
+
+	for _, bm := range benchmarks {
+		b.Run(fmt.Sprintf("channels=%d/goroutines=%d", bm.channels, bm.goroutines), func(b *testing.B) {
+			// Channels are assigned on first lookup inside the timed loop
+			router := newBenchRouter(b, time.Minute, 3, 0)
+			ctx := context.Background()
+			channels := make([]string, bm.channels)
+			for i := range channels {
+				channels[i] = fmt.Sprintf("chat:%d", i)
+			}
+			hits := testutil.ToFloat64(metrics.Default.RouteCacheHits)
+			misses := testutil.ToFloat64(metrics.Default.RouteCacheMisses)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			var wg sync.WaitGroup
+			for g := 0; g < bm.goroutines; g++ {
+				wg.Add(1)
+				go func(g int) {
+					defer wg.Done()
+					for i := g; i < b.N; i += bm.goroutines {
+						if _, err := router.GetWorkerForChannel(ctx, channels[i%bm.channels]); err != nil {
+							b.Errorf("GetWorkerForChannel() error = %v", err)
+							return
+						}
+					}
+				}(g)
+			}
+			wg.Wait()
+			b.StopTimer()
+
+			hits = testutil.ToFloat64(metrics.Default.RouteCacheHits) - hits
+			misses = testutil.ToFloat64(metrics.Default.RouteCacheMisses) - misses
+			b.ReportMetric(100*hits/(hits+misses), "hit%")
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package routing
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"realtime-message-gateway/internal/redis"
+)
+
+// WorkerMonitor removes workers that stopped sending heartbeats from
+// workers:active, so crashed workers lose their channels without a manual
+// migration. Workers heartbeat by setting their score to the current Unix
+// time in milliseconds.
+type WorkerMonitor struct {
+	redis   *redis.Client
+	timeout time.Duration
+}
+
+// NewWorkerMonitor creates a monitor removing workers whose last heartbeat
+// is older than timeout
+func NewWorkerMonitor(redisClient *redis.Client, timeout time.Duration) *WorkerMonitor {
+	return &WorkerMonitor{
+		redis:   redisClient,
+		timeout: timeout,
+	}
+}
+
+// Run removes stale workers every interval until ctx is cancelled
+func (m *WorkerMonitor) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := m.Check(ctx); err != nil && ctx.Err() == nil {
+			slog.Error("failed to remove stale workers", "error", err)
+		}
+	}
+}
+
+// Check removes all workers whose heartbeat is older than the timeout in a
+// single ZREMRANGEBYSCORE. Their channels are reassigned on the next
+// lookup once route caches expire.
+func (m *WorkerMonitor) Check(ctx context.Context) error {
+	removed, err := m.redis.ZRemExpired(ctx, ActiveWorkersKey, m.timeout)
+	if err != nil {
+		return err
+	}
+	if removed > 0 {
+		slog.Warn("removed workers without heartbeat", "count", removed, "timeout", m.timeout)
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package routing
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestWorkerMonitorCheck(t *testing.T) {
+	mr, client := newTestRedis(t)
+	now := time.Now()
+	heartbeats := map[string]time.Duration{
+		"worker-0": 0,
+		"worker-1": 5 * time.Second,
+		"worker-2": 2 * time.Minute, // stale
+		"worker-3": 20 * time.Second,
+		"worker-4": time.Hour, // stale
+	}
+	for workerID, age := range heartbeats {
+		mr.ZAdd(ActiveWorkersKey, float64(now.Add(-age).UnixMilli()), workerID)
+	}
+
+	if err := NewWorkerMonitor(client, 30*time.Second).Check(context.Background()); err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+
+	workers, err := mr.ZMembers(ActiveWorkersKey)
+	if err != nil {
+		t.Fatalf("ZMembers() error = %v", err)
+	}
+	slices.Sort(workers)
+	if want := []string{"worker-0", "worker-1", "worker-3"}; !slices.Equal(workers, want) {
+		t.Errorf("active workers = %v, want %v", workers, want)
+	}
+}
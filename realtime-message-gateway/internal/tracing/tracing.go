@@ -0,0 +1,63 @@
+// Package tracing sets up OpenTelemetry tracing and carries trace context
+// from the gateway to workers through Redis stream entries.
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// StreamField is the stream entry field holding the W3C traceparent of the
+// span that wrote the entry
+const StreamField = "traceContext"
+
+// tracerName is the instrumentation scope of gateway spans
+const tracerName = "realtime-message-gateway"
+
+// propagator serializes span contexts as W3C trace context
+var propagator = propagation.TraceContext{}
+
+// Init installs a global tracer provider exporting spans over OTLP gRPC to
+// endpoint. The returned function flushes and stops the exporter.
+func Init(ctx context.Context, endpoint, serviceName string) (func(context.Context) error, error) {
+	exporter, err := otlptracegrpc.New(ctx,
+		otlptracegrpc.WithEndpoint(endpoint),
+		otlptracegrpc.WithInsecure(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(
+			semconv.SchemaURL,
+			semconv.ServiceName(serviceName),
+		)),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagator)
+
+	return provider.Shutdown, nil
+}
+
+// Tracer returns the gateway tracer of the global provider; spans are
+// no-ops until Init is called
+func Tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// Inject returns the traceparent of the span in ctx, or "" if ctx carries
+// no valid span
+func Inject(ctx context.Context) string {
+	carrier := propagation.MapCarrier{}
+	propagator.Inject(ctx, carrier)
+	return carrier.Get("traceparent")
+}
@@ -0,0 +1,27 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func TestInject(t *testing.T) {
+	if got := Inject(context.Background()); got != "" {
+		t.Errorf("Inject() without a span = %q, want empty", got)
+	}
+
+	provider := sdktrace.NewTracerProvider()
+	t.Cleanup(func() { provider.Shutdown(context.Background()) })
+
+	ctx, span := provider.Tracer("test").Start(context.Background(), "test")
+	defer span.End()
+
+	sc := span.SpanContext()
+	want := fmt.Sprintf("00-%s-%s-01", sc.TraceID(), sc.SpanID())
+	if got := Inject(ctx); got != want {
+		t.Errorf("Inject() = %q, want %q", got, want)
+	}
+}
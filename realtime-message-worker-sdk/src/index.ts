@@ -65,12 +65,17 @@ export { DEFAULT_CONFIG } from './types.js';
 // Routing utilities
 export {
   ROUTING_KEYS,
+  PRIORITY,
   getWorkerStreamKey,
+  getWorkerStreamKeys,
   registerWorker,
   unregisterWorker,
   updateWorkerHeartbeat,
   getActiveWorkers,
+  zaddNX,
+  zaddXX,
 } from './routing.js';
+export type { ScoredMember } from './routing.js';
 
 // Factory function
 import { RealtimeWorker } from './worker.js';
@@ -10,13 +10,75 @@ export const ROUTING_KEYS = {
   CHANNEL_ROUTE_PREFIX: 'channel:route:',
   /** PREFIX for worker message streams */
   WORKER_STREAM_PREFIX: 'messages:worker:',
+  /** PREFIX for keys marking a worker with a backlogged stream (set by the gateway) */
+  WORKER_DEGRADED_PREFIX: 'worker:degraded:',
 } as const;
 
 /**
- * Get the Redis stream key for a worker
+ * Message priorities - must match Go gateway routing.Priority* constants.
+ * Low and normal priority messages share a worker's normal stream.
  */
-export function getWorkerStreamKey(workerId: string): string {
-  return `${ROUTING_KEYS.WORKER_STREAM_PREFIX}${workerId}`;
+export const PRIORITY = {
+  LOW: 0,
+  NORMAL: 1,
+  HIGH: 2,
+} as const;
+
+export type Priority = (typeof PRIORITY)[keyof typeof PRIORITY];
+
+/**
+ * Get the Redis stream key for messages of a priority routed to a worker
+ */
+export function getWorkerStreamKey(
+  workerId: string,
+  priority: Priority = PRIORITY.NORMAL
+): string {
+  const suffix = priority >= PRIORITY.HIGH ? 'high' : 'normal';
+  return `${ROUTING_KEYS.WORKER_STREAM_PREFIX}${workerId}:${suffix}`;
+}
+
+/**
+ * Get all stream keys of a worker, highest priority first
+ */
+export function getWorkerStreamKeys(workerId: string): string[] {
+  return [
+    getWorkerStreamKey(workerId, PRIORITY.HIGH),
+    getWorkerStreamKey(workerId, PRIORITY.NORMAL),
+  ];
+}
+
+/**
+ * A sorted set member with its score
+ */
+export interface ScoredMember {
+  score: number;
+  member: string;
+}
+
+/**
+ * Add members to a sorted set without changing the scores of existing
+ * members (ZADD NX). Returns the number of members added.
+ */
+export async function zaddNX(
+  redis: Redis,
+  key: string,
+  ...members: ScoredMember[]
+): Promise<number> {
+  const scoreMembers = members.flatMap(({ score, member }) => [score, member]);
+  return redis.zadd(key, 'NX', ...scoreMembers);
+}
+
+/**
+ * Update the scores of members already in a sorted set without adding new
+ * ones (ZADD XX CH). Returns the number of members whose score changed.
+ */
+export async function zaddXX(
+  redis: Redis,
+  key: string,
+  ...members: ScoredMember[]
+): Promise<number> {
+  const scoreMembers = members.flatMap(({ score, member }) => [score, member]);
+  return redis.zadd(key, 'XX', 'CH', ...scoreMembers);
 }
 
 /**
@@ -43,13 +105,21 @@ export async function unregisterWorker(
 
 /**
  * Update worker heartbeat timestamp
- * Can be called periodically to indicate worker is still alive
+ * Can be called periodically to indicate worker is still alive.
+ * Only refreshes a registered worker (ZADD XX), so a heartbeat racing
+ * unregisterWorker cannot leave a ghost entry. Returns false when the worker
+ * is not registered, e.g. after the gateway removed it for a missed
+ * heartbeat; call registerWorker to become active again.
  */
 export async function updateWorkerHeartbeat(
   redis: Redis,
   workerId: string
-): Promise<void> {
-  await redis.zadd(ROUTING_KEYS.ACTIVE_WORKERS, Date.now(), workerId);
+): Promise<boolean> {
+  const changed = await zaddXX(redis, ROUTING_KEYS.ACTIVE_WORKERS, {
+    score: Date.now(),
+    member: workerId,
+  });
+  return changed > 0;
 }
 
 /**
@@ -3,37 +3,53 @@ import type { StreamEvent, Logger } from './types.js';
 
 export interface StreamConsumerConfig {
   redis: Redis;
-  streamKey: string;
+  /** Streams to read, highest priority first */
+  streamKeys: string[];
+  /** Consumer group shared with the gateway (its CONSUMER_GROUP_NAME) */
+  group: string;
+  /** Consumer name within the group, the worker ID */
+  consumer: string;
   batchSize: number;
   blockTime: number;
+  /** Where a consumer group created by this consumer starts reading */
   startFrom: 'earliest' | 'latest';
   logger: Logger;
 }
 
 /**
- * Consumes messages from a Redis Stream using XREAD BLOCK
+ * Consumes messages from Redis Streams through a consumer group using
+ * XREADGROUP BLOCK, acknowledging each message with XACK once it has been
+ * handled. A message whose handler throws stays pending; the gateway re-adds
+ * it after STALE_MESSAGE_MIN_IDLE, so messages are processed at least once.
+ * Each batch is processed in stream order, so earlier (higher priority)
+ * streams go first.
  */
 export class StreamConsumer {
   private redis: Redis;
-  private streamKey: string;
+  private streamKeys: string[];
+  private group: string;
+  private consumer: string;
   private batchSize: number;
   private blockTime: number;
   private startFrom: 'earliest' | 'latest';
   private logger: Logger;
 
   private running: boolean = false;
-  private lastId: string;
+  private lastIds: string[];
 
   constructor(config: StreamConsumerConfig) {
     this.redis = config.redis;
-    this.streamKey = config.streamKey;
+    this.streamKeys = config.streamKeys;
+    this.group = config.group;
+    this.consumer = config.consumer;
     this.batchSize = config.batchSize;
     this.blockTime = config.blockTime;
     this.startFrom = config.startFrom;
     this.logger = config.logger;
 
-    // '$' = only new messages, '0' = from beginning
-    this.lastId = this.startFrom === 'latest' ? '$' : '0';
+    // Messages delivered to this consumer before a restart are read again
+    // first ('0' onwards), then new messages ('>')
+    this.lastIds = this.streamKeys.map(() => '0');
   }
 
   /**
@@ -42,33 +58,49 @@ export class StreamConsumer {
    */
   async start(onMessage: (event: StreamEvent) => Promise<void>): Promise<void> {
     this.running = true;
-    this.logger.info(`StreamConsumer started: ${this.streamKey}`);
+    await this.createGroups();
+    this.logger.info(`StreamConsumer started: ${this.streamKeys.join(', ')} (group ${this.group})`);
 
     while (this.running) {
       try {
-        const results = await this.redis.xread(
+        const results = await this.redis.xreadgroup(
+          'GROUP',
+          this.group,
+          this.consumer,
           'COUNT',
           this.batchSize,
           'BLOCK',
           this.blockTime,
           'STREAMS',
-          this.streamKey,
-          this.lastId
+          ...this.streamKeys,
+          ...this.lastIds
         );
 
         if (!results) continue;
 
-        for (const [, messages] of results) {
-          for (const [messageId, fields] of messages as [string, string[]][]) {
+        // Redis returns streams in request order: higher priority first
+        for (const [streamKey, messages] of results as [string, [string, string[] | null][]][]) {
+          const streamIndex = this.streamKeys.indexOf(streamKey);
+          if (this.lastIds[streamIndex] !== '>' && messages.length === 0) {
+            this.lastIds[streamIndex] = '>'; // Pending messages done
+            continue;
+          }
+
+          for (const [messageId, fields] of messages) {
+            if (this.lastIds[streamIndex] !== '>') {
+              // Continue after this pending message even if it fails again
+              this.lastIds[streamIndex] = messageId;
+            }
             try {
-              const message = this.parseMessage(fields);
+              // Fields are null for a pending message trimmed from the stream
+              const message = fields ? this.parseMessage(fields) : null;
               if (message) {
                 await onMessage(message);
               }
-              this.lastId = messageId;
+              await this.redis.xack(streamKey, this.group, messageId);
             } catch (err) {
+              // Left pending for the gateway to re-add
               this.logger.error(`Error processing message ${messageId}:`, err);
-              this.lastId = messageId; // Advance to avoid getting stuck
             }
           }
         }
@@ -81,7 +113,7 @@ export class StreamConsumer {
       }
     }
 
-    this.logger.info(`StreamConsumer stopped: ${this.streamKey}`);
+    this.logger.info(`StreamConsumer stopped: ${this.streamKeys.join(', ')}`);
   }
 
   /**
@@ -99,7 +131,24 @@ export class StreamConsumer {
   }
 
   /**
-   * Parse event fields from Redis XREAD result
+   * Create the consumer group on each stream unless it exists. The gateway
+   * creates it on its first write, reading from the beginning.
+   */
+  private async createGroups(): Promise<void> {
+    const startId = this.startFrom === 'latest' ? '$' : '0';
+    for (const streamKey of this.streamKeys) {
+      try {
+        await this.redis.xgroup('CREATE', streamKey, this.group, startId, 'MKSTREAM');
+      } catch (err) {
+        if (!(err instanceof Error) || !err.message.startsWith('BUSYGROUP')) {
+          throw err;
+        }
+      }
+    }
+  }
+
+  /**
+   * Parse event fields from Redis XREADGROUP result
    */
   private parseMessage(fields: string[]): StreamEvent | null {
     const payloadIndex = fields.indexOf('payload');
@@ -9,6 +9,8 @@ export type EventType = 'message' | 'join' | 'leave';
  * Base stream event from the gateway
  */
 export interface StreamEvent {
+  /** Payload schema version, see realtime-message-gateway/SCHEMA.md */
+  schemaVersion: number;
   id: string;
   type: EventType;
   channel: string;
@@ -17,6 +19,14 @@ export interface StreamEvent {
   userName: string;
   timestamp: string;
   clientId: string;
+  /** Gateway instance that received the event; reply via messages:gateway:{gatewayId} */
+  gatewayId: string;
+  /** 0 = low, 1 = normal, 2 = high (see PRIORITY); absent from older gateways */
+  priority?: number;
+  /** Re-added by an operator replay of a stream range; the event may have been handled before */
+  replay?: boolean;
+  /** Re-added after staying unacknowledged in the consumer group; the event may have been handled before */
+  retried?: boolean;
 }
 
 /**
@@ -25,7 +35,13 @@ export interface StreamEvent {
 export interface Message extends StreamEvent {
   type: 'message';
   text: string;
+  /** e.g. 'text/plain', 'application/json' (text is JSON), 'application/x-reaction'; absent = 'text/plain' */
+  contentType?: string;
+  /** Client metadata from the publish meta object, e.g. { replyTo, threadId } */
+  meta?: Record<string, string>;
   raw: string;
+  /** hex(HMAC-SHA256(MESSAGE_SIGNING_KEY, id, channel, userId, text, timestamp)), each field prefixed with its UTF-8 byte length as a 4-byte big-endian integer; absent when the gateway does not sign */
+  signature?: string;
 }
 
 /**
@@ -92,7 +108,14 @@ export interface WorkerConfig {
   /** Interval for checking inactive channels in ms (default: 5000) */
   inactivityCheckInterval?: number;
 
-  /** Whether to start from earliest messages or latest (default: 'latest') */
+  /** Consumer group on the worker streams; must match the gateway's CONSUMER_GROUP_NAME (default: 'gw-consumer') */
+  consumerGroup?: string;
+
+  /**
+   * Whether a consumer group created by the worker starts from the earliest
+   * or the latest messages (default: 'latest'). An existing group, such as
+   * one the gateway created on its first write, keeps its position.
+   */
   startFrom?: 'earliest' | 'latest';
 
   /** Custom logger (default: console) */
@@ -150,5 +173,6 @@ export const DEFAULT_CONFIG = {
   blockTime: 5000,
   channelInactivityTimeout: 30000,
   inactivityCheckInterval: 5000,
+  consumerGroup: 'gw-consumer',
   startFrom: 'latest' as const,
 } as const;
@@ -14,7 +14,7 @@ import type {
 import { DEFAULT_CONFIG } from './types.js';
 import { ChannelTracker } from './channel-tracker.js';
 import { StreamConsumer } from './stream-consumer.js';
-import { registerWorker, unregisterWorker, getWorkerStreamKey } from './routing.js';
+import { registerWorker, unregisterWorker, getWorkerStreamKeys } from './routing.js';
 
 /**
  * RealtimeWorker - Event-driven worker SDK for consuming channel messages
@@ -48,6 +48,7 @@ export class RealtimeWorker extends EventEmitter {
     blockTime: number;
     channelInactivityTimeout: number;
     inactivityCheckInterval: number;
+    consumerGroup: string;
     startFrom: 'earliest' | 'latest';
     logger: Logger;
   };
@@ -81,6 +82,7 @@ export class RealtimeWorker extends EventEmitter {
         config.channelInactivityTimeout ?? DEFAULT_CONFIG.channelInactivityTimeout,
       inactivityCheckInterval:
         config.inactivityCheckInterval ?? DEFAULT_CONFIG.inactivityCheckInterval,
+      consumerGroup: config.consumerGroup ?? DEFAULT_CONFIG.consumerGroup,
       startFrom: config.startFrom ?? DEFAULT_CONFIG.startFrom,
       logger: config.logger ?? console,
     };
@@ -106,11 +108,12 @@ export class RealtimeWorker extends EventEmitter {
     await registerWorker(this.redis, this.workerId);
     this.config.logger.info(`Worker ${this.workerId} registered`);
 
-    // Create stream consumer
-    const streamKey = getWorkerStreamKey(this.workerId);
+    // Create stream consumer, reading high priority messages first
     this.streamConsumer = new StreamConsumer({
       redis: this.redis,
-      streamKey,
+      streamKeys: getWorkerStreamKeys(this.workerId),
+      group: this.config.consumerGroup,
+      consumer: this.workerId,
       batchSize: this.config.batchSize,
       blockTime: this.config.blockTime,
       startFrom: this.config.startFrom,